@@ -1,7 +1,12 @@
 use chrono::{DateTime, NaiveDateTime, Utc};
-use clap::{Command, Subcommand};
-use serde::{Serialize, Serializer};
-use std::process::Command as StdCommand;
+use clap::{ArgMatches, Command, Subcommand};
+use serde::{Deserialize, Serialize, Serializer};
+use std::collections::{BTreeMap, HashMap, HashSet};
+use std::fs;
+use std::io::{BufRead, Write};
+use std::os::unix::fs::symlink;
+use std::path::{Path, PathBuf};
+use std::process::{Command as StdCommand, Stdio};
 use thiserror::Error;
 
 #[derive(Error, Debug)]
@@ -12,6 +17,68 @@ pub enum Error {
     NixOutputParseFailed(String),
     #[error("Failed to parse generation diff: {0}")]
     DiffParseFailed(String),
+    #[error("Failed to mark generation: {0}")]
+    MarkFailed(String),
+    #[error("Failed to roll back: {0}")]
+    RollbackFailed(String),
+    #[error("Failed to delete generations: {0}")]
+    DeleteFailed(String),
+    #[error("Failed to set the boot default: {0}")]
+    BootFailed(String),
+    #[error("Failed to collect garbage: {0}")]
+    GcFailed(String),
+    #[error("Failed to pin generation: {0}")]
+    PinFailed(String),
+    #[error("Failed to move generations to the trash: {0}")]
+    TrashFailed(String),
+    #[error("Failed to restore generation: {0}")]
+    RestoreFailed(String),
+}
+
+impl Error {
+    fn kind(&self) -> &'static str {
+        match self {
+            Error::NixCommandFailed(_) => "nix_command_failed",
+            Error::NixOutputParseFailed(_) => "nix_output_parse_failed",
+            Error::DiffParseFailed(_) => "diff_parse_failed",
+            Error::MarkFailed(_) => "mark_failed",
+            Error::RollbackFailed(_) => "rollback_failed",
+            Error::DeleteFailed(_) => "delete_failed",
+            Error::BootFailed(_) => "boot_failed",
+            Error::GcFailed(_) => "gc_failed",
+            Error::PinFailed(_) => "pin_failed",
+            Error::TrashFailed(_) => "trash_failed",
+            Error::RestoreFailed(_) => "restore_failed",
+        }
+    }
+
+    // The error as the frontend reads it: a kind it can match on and a
+    // message for the user.
+    fn report(&self) -> serde_json::Value {
+        error_report(self.kind(), &self.to_string())
+    }
+}
+
+fn error_report(kind: &str, message: &str) -> serde_json::Value {
+    serde_json::json!({ "kind": kind, "message": message })
+}
+
+// The version of the JSON this backend writes, which the frontend checks
+// before reading any. Bump it when output changes in a way an older
+// frontend would misread; new subcommands and fields don't count.
+const PROTOCOL_VERSION: u32 = 1;
+
+// How many changes diff-stream sizes, and writes out, at a time.
+const STREAM_CHUNK: usize = 200;
+
+const GCROOTS_DIR: &str = "/nix/var/nix/gcroots/nix-timemach";
+const SYSTEM_PROFILE: &str = "/nix/var/nix/profiles/system";
+const BOOTED_SYSTEM: &str = "/run/booted-system";
+const SYSTEMD_BOOT_CONF: &str = "/boot/loader/loader.conf";
+
+// Generation id of profile, e.g. /nix/var/nix/profiles/system-42-link.
+fn link(profile: &str, id: &str) -> String {
+    format!("{}-{}-link", profile, id)
 }
 
 #[derive(Serialize)]
@@ -21,15 +88,167 @@ struct Generation {
     timestamp: DateTime<Utc>,
     description: String,
     profiles: Vec<String>,
+    profile: String,
+    known_good: bool,
+    closure_hash: String,
+    current: bool,
+    closure_size: Option<i64>,
+    booted: bool,
+    boot_default: bool,
+    kernel_version: String,
+    nixos_version: String,
+    store_path: String,
+    specialisations: Vec<String>,
+    flake_url: String,
+    flake_revision: String,
+    flake_dirty: bool,
+    pinned: bool,
+    pin_name: String,
+}
+
+// An added package has only a new version and a removed one only an old
+// one. A modified package's path is its old path; new_path is what it
+// became.
+#[derive(Serialize, Default, Clone)]
+struct PackageChange {
+    path: String,
+    #[serde(skip_serializing_if = "String::is_empty")]
+    new_path: String,
+    name: String,
+    old_version: String,
+    new_version: String,
+    size_delta: i64,
+    size_known: bool,
+    explicit: bool,
 }
 
 #[derive(Serialize)]
 struct GenerationDiff {
+    added: Vec<PackageChange>,
+    removed: Vec<PackageChange>,
+    modified: Vec<PackageChange>,
+    explicit_known: bool,
+}
+
+// One entry of a diff-bulk result: exactly one of diff and error is set.
+#[derive(Serialize)]
+struct BulkDiff {
+    diff: Option<GenerationDiff>,
+    error: Option<String>,
+}
+
+// One entry of a diff-stats-bulk result, like BulkDiff.
+#[derive(Serialize)]
+struct BulkStats {
+    stats: Option<DiffStats>,
+    error: Option<String>,
+}
+
+#[derive(Serialize)]
+struct DiffStats {
+    added: usize,
+    removed: usize,
+    modified: usize,
+    size_delta: i64,
+    size_known: bool,
+}
+
+#[derive(Serialize)]
+struct ConfigChange {
+    name: String,
+    from: String,
+    to: String,
+}
+
+#[derive(Serialize)]
+struct ConfigDiff {
+    flake: bool,
+    inputs: Vec<ConfigChange>,
+    options: Vec<ConfigChange>,
+}
+
+// A flake input as pinned by a lock file; last_modified is in seconds since
+// the epoch, zero when the lock doesn't say.
+#[derive(Serialize, Default, Clone, PartialEq)]
+struct LockedInput {
+    rev: String,
+    last_modified: i64,
+}
+
+// A direct input of the configuration's flake whose pin differs between
+// two generations. An input only one side has is empty on the other.
+#[derive(Serialize)]
+struct InputChange {
+    name: String,
+    from: LockedInput,
+    to: LockedInput,
+}
+
+// Files under /etc that differ between two generations, by path.
+#[derive(Serialize)]
+struct FileDiff {
     added: Vec<String>,
     removed: Vec<String>,
     modified: Vec<String>,
 }
 
+// What gc would do: the generations it would delete and the store paths,
+// and bytes, that collecting would then free.
+#[derive(Serialize)]
+struct GcPreview {
+    generations: Vec<GcGeneration>,
+    paths: usize,
+    bytes: i64,
+}
+
+#[derive(Serialize)]
+struct GcGeneration {
+    profile: String,
+    id: String,
+}
+
+#[derive(Serialize)]
+struct PackagePresence {
+    generation: String,
+    present: bool,
+    version: String,
+}
+
+#[derive(Serialize)]
+struct DependencyNode {
+    path: String,
+    children: Vec<DependencyNode>,
+}
+
+#[derive(Serialize)]
+struct StoreNode {
+    path: String,
+    size: i64,
+    references: Vec<String>,
+}
+
+#[derive(Serialize)]
+struct PathSize {
+    path: String,
+    size: i64,
+}
+
+// A deleted generation whose system is kept by a trash root until the
+// trash is emptied.
+#[derive(Serialize)]
+struct TrashedGeneration {
+    id: String,
+    store_path: String,
+    #[serde(serialize_with = "serialize_timestamp_as_string")]
+    trashed: DateTime<Utc>,
+}
+
+#[derive(Serialize)]
+struct Version {
+    version: &'static str,
+    protocol: u32,
+}
+
 #[derive(Subcommand)]
 enum Commands {
     ListGenerations,
@@ -53,11 +272,65 @@ where
     serializer.serialize_str(&timestamp.to_rfc3339())
 }
 
-fn list_generations() -> Result<Vec<Generation>, Error> {
-    let output = StdCommand::new("nixos-rebuild")
-        .arg("list-generations")
-        .output()
-        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+#[derive(Serialize)]
+struct Profile {
+    name: String,
+    path: String,
+}
+
+// The profiles worth browsing: the system's, the user's own and Home
+// Manager's. With use-xdg-base-directories user profiles live under
+// ~/.local/state rather than per-user, so both places are tried.
+fn list_profiles() -> Vec<Profile> {
+    let home = std::env::var("HOME").unwrap_or_default();
+    let user = std::env::var("USER").unwrap_or_default();
+    let candidates = [
+        ("system", vec![SYSTEM_PROFILE.to_string()]),
+        (
+            "user",
+            vec![
+                format!("{}/.local/state/nix/profiles/profile", home),
+                format!("/nix/var/nix/profiles/per-user/{}/profile", user),
+            ],
+        ),
+        (
+            "home-manager",
+            vec![
+                format!("{}/.local/state/nix/profiles/home-manager", home),
+                format!("/nix/var/nix/profiles/per-user/{}/home-manager", user),
+            ],
+        ),
+    ];
+    candidates
+        .into_iter()
+        .filter_map(|(name, paths)| {
+            paths
+                .into_iter()
+                .find(|path| Path::new(path).symlink_metadata().is_ok())
+                .map(|path| Profile {
+                    name: name.to_string(),
+                    path,
+                })
+        })
+        .collect()
+}
+
+// Closure sizes cost a nix query per generation, so they are only filled in
+// when asked for. Profiles other than the system's are listed with nix-env,
+// whose lines have no header and mark the current generation with a
+// trailing "(current)".
+fn list_generations(profile: &str, with_sizes: bool) -> Result<Vec<Generation>, Error> {
+    let system = profile == SYSTEM_PROFILE;
+    let output = if system {
+        StdCommand::new("nixos-rebuild")
+            .arg("list-generations")
+            .output()
+    } else {
+        StdCommand::new("nix-env")
+            .args(["--list-generations", "--profile", profile])
+            .output()
+    }
+    .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
 
     if !output.status.success() {
         return Err(Error::NixCommandFailed(
@@ -68,27 +341,65 @@ fn list_generations() -> Result<Vec<Generation>, Error> {
     let output_str = String::from_utf8_lossy(&output.stdout);
     let generations: Vec<Generation> = output_str
         .lines()
-        .skip(1) // Skip header
+        .skip(if system { 1 } else { 0 }) // Skip header
         .filter_map(|line| {
             let parts: Vec<&str> = line.split_whitespace().collect();
-            if parts.len() >= 4 {
+            if parts.len() >= 4 || !system && parts.len() >= 3 {
                 let id = parts[0].trim_end_matches("current").to_string();
                 let date = parts[1];
                 let time = parts[2];
-                let description = if parts[0].contains("current") {
+                let current = parts[0].contains("current") || parts.get(3) == Some(&"(current)");
+                let description = if current {
                     "(current)".to_string()
                 } else {
                     "".to_string()
                 };
 
                 let timestamp = parse_timestamp(date, time).ok()?;
-                let profiles = vec![format!("/nix/var/nix/profiles/system-{}-link", &id)];
+                let profiles = vec![link(profile, &id)];
+                // Known-good marks are kept for system generations only.
+                let known_good = system && known_good_root(&id).exists();
+                let closure_hash = closure_hash(&profiles[0]);
+                let closure_size = if with_sizes {
+                    closure_size(&profiles[0])
+                } else {
+                    None
+                };
+                let kernel_version = kernel_version(&profiles[0]);
+                let nixos_version = read_toplevel_file(&profiles[0], "nixos-version");
+                let store_path = fs::read_link(&profiles[0])
+                    .map(|target| target.display().to_string())
+                    .unwrap_or_default();
+                let specialisations = specialisations(&profiles[0]);
+                let (flake_url, flake_revision, flake_dirty) = if system {
+                    flake_metadata(&profiles[0])
+                } else {
+                    (String::new(), String::new(), false)
+                };
+                // Pins, like known-good marks, are for system generations.
+                let pin = if system { find_pin(&id) } else { None };
 
                 Some(Generation {
                     id,
                     timestamp,
                     description,
                     profiles,
+                    profile: profile.to_string(),
+                    known_good,
+                    closure_hash,
+                    current,
+                    closure_size,
+                    booted: false,
+                    boot_default: false,
+                    kernel_version,
+                    nixos_version,
+                    store_path,
+                    specialisations,
+                    flake_url,
+                    flake_revision,
+                    flake_dirty,
+                    pinned: pin.is_some(),
+                    pin_name: pin.map(|(_, name)| name).unwrap_or_default(),
                 })
             } else {
                 None
@@ -96,101 +407,1813 @@ fn list_generations() -> Result<Vec<Generation>, Error> {
         })
         .collect();
 
-    Ok(generations)
+    Ok(if system {
+        mark_boot(generations)
+    } else {
+        generations
+    })
+}
+
+// Flags the generation the machine booted into and the one it will boot
+// next. systemd-boot names its default entry in loader.conf; otherwise the
+// boot loader was last installed for the current generation, which is
+// what nixos-rebuild switch and boot both do.
+fn mark_boot(mut generations: Vec<Generation>) -> Vec<Generation> {
+    let booted = fs::canonicalize(BOOTED_SYSTEM).ok();
+    let default = systemd_boot_default().or_else(|| {
+        generations
+            .iter()
+            .find(|gen| gen.current)
+            .map(|gen| gen.id.clone())
+    });
+    for gen in &mut generations {
+        gen.booted = booted.is_some() && fs::canonicalize(&gen.profiles[0]).ok() == booted;
+        gen.boot_default = default.as_deref() == Some(gen.id.as_str());
+    }
+    generations
+}
+
+// Reads a line like "default nixos-generation-42.conf" from loader.conf.
+fn systemd_boot_default() -> Option<String> {
+    let conf = fs::read_to_string(SYSTEMD_BOOT_CONF).ok()?;
+    let re = regex::Regex::new(r"^default\s+nixos-generation-(\d+)\b").ok()?;
+    conf.lines()
+        .find_map(|line| re.captures(line.trim()))
+        .map(|caps| caps[1].to_string())
+}
+
+// The version of the kernel a system generation boots, from the store path
+// its kernel link points into, e.g. /nix/store/<hash>-linux-6.6.1/bzImage.
+fn kernel_version(link: &str) -> String {
+    fs::canonicalize(format!("{}/kernel", link))
+        .ok()
+        .and_then(|kernel| {
+            kernel
+                .parent()
+                .and_then(|dir| dir.to_str())
+                .map(|dir| parse_store_name(dir).1.to_string())
+        })
+        .unwrap_or_default()
+}
+
+// The flake URL, revision and dirty flag of the configuration a system
+// generation was built from. nixos-version --json only has the revision,
+// which ends in "-dirty" when the tree had uncommitted changes; a
+// configuration can record the rest in etc/nixos-version.json, which takes
+// precedence. Channel-based systems have none of these.
+fn flake_metadata(link: &str) -> (String, String, bool) {
+    let mut info = read_version_info(link);
+    if let Some(serde_json::Value::Object(recorded)) =
+        fs::read(format!("{}/etc/nixos-version.json", link))
+            .ok()
+            .and_then(|data| serde_json::from_slice(&data).ok())
+    {
+        info.extend(recorded);
+    }
+    let field = |key: &str| {
+        info.get(key)
+            .and_then(|v| v.as_str())
+            .unwrap_or("")
+            .to_string()
+    };
+    let url = field("flakeUrl");
+    let mut revision = field("configurationRevision");
+    let mut dirty = info.get("dirty").and_then(|v| v.as_bool()).unwrap_or(false);
+    if let Some(rev) = revision.strip_suffix("-dirty") {
+        revision = rev.to_string();
+        dirty = true;
+    }
+    (url, revision, dirty)
+}
+
+// The names of a system generation's specialisations, sorted.
+fn specialisations(link: &str) -> Vec<String> {
+    let mut names: Vec<String> = fs::read_dir(format!("{}/specialisation", link))
+        .map(|entries| {
+            entries
+                .flatten()
+                .map(|entry| entry.file_name().to_string_lossy().to_string())
+                .collect()
+        })
+        .unwrap_or_default();
+    names.sort();
+    names
+}
+
+// Two generations whose links resolve to the same system store path have
+// identical closures, so the store hash identifies the closure.
+fn closure_hash(link: &str) -> String {
+    fs::read_link(link)
+        .ok()
+        .and_then(|target| {
+            target
+                .file_name()
+                .and_then(|name| name.to_str())
+                .and_then(|name| name.split('-').next())
+                .map(|hash| hash.to_string())
+        })
+        .unwrap_or_default()
+}
+
+fn get_diff(profile: &str, from: &str, to: &str) -> Result<GenerationDiff, Error> {
+    diff_paths(&link(profile, from), &link(profile, to))
+}
+
+// Runs f on each "from:to" pair independently so one failure doesn't lose
+// the rest of the batch.
+fn for_each_pair<T>(
+    pairs: &[String],
+    f: impl Fn(&str, &str) -> Result<T, Error>,
+) -> Vec<Result<T, String>> {
+    pairs
+        .iter()
+        .map(|pair| match pair.split_once(':') {
+            Some((from, to)) => f(from, to).map_err(|e| e.to_string()),
+            None => Err(format!("invalid pair {:?}, expected FROM:TO", pair)),
+        })
+        .collect()
+}
+
+fn get_diffs_bulk(profile: &str, pairs: &[String]) -> Vec<BulkDiff> {
+    for_each_pair(pairs, |from, to| get_diff(profile, from, to))
+        .into_iter()
+        .map(|result| match result {
+            Ok(diff) => BulkDiff {
+                diff: Some(diff),
+                error: None,
+            },
+            Err(error) => BulkDiff {
+                diff: None,
+                error: Some(error),
+            },
+        })
+        .collect()
 }
 
-fn get_diff(from: &str, to: &str) -> Result<GenerationDiff, Error> {
-    let from_path = format!("/nix/var/nix/profiles/system-{}-link", from);
-    let to_path = format!("/nix/var/nix/profiles/system-{}-link", to);
+fn get_diff_stats_bulk(profile: &str, pairs: &[String]) -> Vec<BulkStats> {
+    for_each_pair(pairs, |from, to| get_diff_stats(profile, from, to))
+        .into_iter()
+        .map(|result| match result {
+            Ok(stats) => BulkStats {
+                stats: Some(stats),
+                error: None,
+            },
+            Err(error) => BulkStats {
+                stats: None,
+                error: Some(error),
+            },
+        })
+        .collect()
+}
 
+fn query_store(query: &str, path: &str) -> Result<Vec<String>, Error> {
     let output = StdCommand::new("nix-store")
-        .args(["-q", "--references"])
-        .arg(&from_path)
+        .args(["-q", query])
+        .arg(path)
         .output()
         .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
 
-    let from_refs: Vec<String> = String::from_utf8_lossy(&output.stdout)
+    Ok(String::from_utf8_lossy(&output.stdout)
         .lines()
         .map(|s| s.to_string())
-        .collect();
+        .collect())
+}
 
-    let output = StdCommand::new("nix-store")
-        .args(["-q", "--references"])
-        .arg(&to_path)
-        .output()
-        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+fn diff_paths(from_path: &str, to_path: &str) -> Result<GenerationDiff, Error> {
+    let from_refs = query_store("--references", from_path)?;
+    let to_refs = query_store("--references", to_path)?;
+
+    let mut diff = diff_refs(&from_refs, &to_refs);
+    mark_explicit(&mut diff, &[from_path, to_path])?;
+    fill_sizes(&mut diff);
+    Ok(diff)
+}
+
+// Writes the diff between two generations as it is computed, one JSON
+// object per line: first {"total": n, "explicit_known": b}, then
+// {"kind": "added" | "removed" | "modified", "change": {...}} for each
+// change. Sizes are looked up a chunk at a time, so a diff of thousands of
+// packages starts arriving before all of them are sized.
+fn stream_diff(profile: &str, from: &str, to: &str) -> Result<(), Error> {
+    let from_path = link(profile, from);
+    let to_path = link(profile, to);
+    let from_refs = query_store("--references", &from_path)?;
+    let to_refs = query_store("--references", &to_path)?;
+    let mut diff = diff_refs(&from_refs, &to_refs);
+    mark_explicit(&mut diff, &[&from_path, &to_path])?;
+
+    let mut out = std::io::stdout().lock();
+    let mut emit = |value: serde_json::Value| -> Result<(), Error> {
+        writeln!(out, "{}", value).map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+        out.flush()
+            .map_err(|e| Error::NixCommandFailed(e.to_string()))
+    };
+    let total = diff.added.len() + diff.removed.len() + diff.modified.len();
+    emit(serde_json::json!({ "total": total, "explicit_known": diff.explicit_known }))?;
+
+    let kinds = [
+        ("added", std::mem::take(&mut diff.added)),
+        ("removed", std::mem::take(&mut diff.removed)),
+        ("modified", std::mem::take(&mut diff.modified)),
+    ];
+    for (kind, changes) in kinds {
+        for chunk in changes.chunks(STREAM_CHUNK) {
+            let mut part = GenerationDiff {
+                added: Vec::new(),
+                removed: Vec::new(),
+                modified: Vec::new(),
+                explicit_known: false,
+            };
+            let slot = match kind {
+                "added" => &mut part.added,
+                "removed" => &mut part.removed,
+                _ => &mut part.modified,
+            };
+            *slot = chunk.to_vec();
+            fill_sizes(&mut part);
+            for change in part.added.iter().chain(&part.removed).chain(&part.modified) {
+                emit(serde_json::json!({ "kind": kind, "change": change }))?;
+            }
+        }
+    }
+    Ok(())
+}
 
-    let to_refs: Vec<String> = String::from_utf8_lossy(&output.stdout)
+// Sets each change's size delta from the packages' own sizes, looked up
+// in one nix path-info call. Changes stay without a size if that fails.
+fn fill_sizes(diff: &mut GenerationDiff) {
+    let changes = diff
+        .added
+        .iter()
+        .chain(diff.removed.iter())
+        .chain(diff.modified.iter());
+    let paths: Vec<&str> = changes
+        .flat_map(|c| [c.path.as_str(), c.new_path.as_str()])
+        .filter(|p| !p.is_empty())
+        .collect();
+    if paths.is_empty() {
+        return;
+    }
+    let output = match StdCommand::new("nix")
+        .args(["path-info", "-s"])
+        .args(&paths)
+        .output()
+    {
+        Ok(output) => output,
+        Err(_) => return,
+    };
+    let sizes: HashMap<String, i64> = String::from_utf8_lossy(&output.stdout)
         .lines()
-        .map(|s| s.to_string())
+        .filter_map(|line| {
+            let mut fields = line.split_whitespace();
+            let path = fields.next()?;
+            let size = fields.next()?.parse().ok()?;
+            Some((path.to_string(), size))
+        })
         .collect();
 
-    let added: Vec<String> = to_refs
+    for change in diff.added.iter_mut() {
+        if let Some(size) = sizes.get(&change.path) {
+            change.size_delta = *size;
+            change.size_known = true;
+        }
+    }
+    for change in diff.removed.iter_mut() {
+        if let Some(size) = sizes.get(&change.path) {
+            change.size_delta = -size;
+            change.size_known = true;
+        }
+    }
+    for change in diff.modified.iter_mut() {
+        if let (Some(old), Some(new)) = (sizes.get(&change.path), sizes.get(&change.new_path)) {
+            change.size_delta = new - old;
+            change.size_known = true;
+        }
+    }
+}
+
+// The system-path derivation behind a system's `sw` link references exactly
+// the packages listed in environment.systemPackages, so those are the ones
+// the user asked for. Other profiles have no such link and are left unmarked.
+fn mark_explicit(diff: &mut GenerationDiff, systems: &[&str]) -> Result<(), Error> {
+    if !systems
         .iter()
-        .filter(|x| !from_refs.contains(x))
-        .cloned()
+        .all(|system| Path::new(&format!("{}/sw", system)).exists())
+    {
+        return Ok(());
+    }
+    let mut explicit = Vec::new();
+    for system in systems {
+        explicit.extend(query_store("--references", &format!("{}/sw", system))?);
+    }
+    for change in diff
+        .added
+        .iter_mut()
+        .chain(diff.removed.iter_mut())
+        .chain(diff.modified.iter_mut())
+    {
+        change.explicit = explicit.contains(&change.path);
+    }
+    diff.explicit_known = true;
+    Ok(())
+}
+
+fn diff_refs(from_refs: &[String], to_refs: &[String]) -> GenerationDiff {
+    let (added, removed, modified) = classify(from_refs, to_refs);
+    let added = added
+        .into_iter()
+        .map(|path| {
+            let (name, version) = parse_store_name(path);
+            PackageChange {
+                path: path.to_string(),
+                name: name.to_string(),
+                new_version: version.to_string(),
+                ..Default::default()
+            }
+        })
         .collect();
 
-    let removed: Vec<String> = from_refs
-        .iter()
-        .filter(|x| !to_refs.contains(x))
-        .cloned()
+    let removed = removed
+        .into_iter()
+        .map(|path| {
+            let (name, version) = parse_store_name(path);
+            PackageChange {
+                path: path.to_string(),
+                name: name.to_string(),
+                old_version: version.to_string(),
+                ..Default::default()
+            }
+        })
         .collect();
 
-    // For modified, we'll look for packages with the same name but different hashes
-    let modified: Vec<String> = from_refs
-        .iter()
-        .filter(|x| {
-            let name = x.split("-").nth(1).unwrap_or("");
-            to_refs
-                .iter()
-                .any(|y| y.split("-").nth(1).unwrap_or("") == name && y != *x)
+    let modified = modified
+        .into_iter()
+        .map(|(path, new_path)| {
+            let (name, old_version) = parse_store_name(path);
+            PackageChange {
+                path: path.to_string(),
+                new_path: new_path.to_string(),
+                name: name.to_string(),
+                old_version: old_version.to_string(),
+                new_version: parse_store_name(new_path).1.to_string(),
+                ..Default::default()
+            }
         })
-        .cloned()
         .collect();
 
-    Ok(GenerationDiff {
+    GenerationDiff {
+        added,
+        removed,
+        modified,
+        explicit_known: false,
+    }
+}
+
+// Sorts the paths of two package sets the way nvd does: a path that went
+// away is modified when a new path with the same name replaced it, and
+// otherwise removed; a new path that replaced nothing is added. Each new
+// path replaces at most one old one.
+fn classify<'a>(
+    from_refs: &'a [String],
+    to_refs: &'a [String],
+) -> (Vec<&'a str>, Vec<&'a str>, Vec<(&'a str, &'a str)>) {
+    let from: HashSet<&str> = from_refs.iter().map(String::as_str).collect();
+    let to: HashSet<&str> = to_refs.iter().map(String::as_str).collect();
+    let mut replacements: HashMap<&str, Vec<&str>> = HashMap::new();
+    for path in to_refs.iter().rev().filter(|p| !from.contains(p.as_str())) {
+        replacements
+            .entry(parse_store_name(path).0)
+            .or_default()
+            .push(path);
+    }
+
+    let mut removed = Vec::new();
+    let mut modified = Vec::new();
+    let mut replaced = HashSet::new();
+    for path in from_refs.iter().filter(|p| !to.contains(p.as_str())) {
+        let name = parse_store_name(path).0;
+        match replacements.get_mut(name).and_then(Vec::pop) {
+            Some(new_path) => {
+                replaced.insert(new_path);
+                modified.push((path.as_str(), new_path));
+            }
+            None => removed.push(path.as_str()),
+        }
+    }
+    let added = to_refs
+        .iter()
+        .map(String::as_str)
+        .filter(|p| !from.contains(p) && !replaced.contains(p))
+        .collect();
+    (added, removed, modified)
+}
+
+// Diffs the runtime closures of one package as it appears in two
+// generations, which shows the dependencies behind a modified entry.
+fn get_deps_diff(profile: &str, pkg: &str, from: &str, to: &str) -> Result<GenerationDiff, Error> {
+    let (name, _) = parse_store_name(pkg);
+    let find = |generation: &str| -> Result<String, Error> {
+        query_store("--requisites", &link(profile, generation))?
+            .into_iter()
+            .find(|path| parse_store_name(path).0 == name)
+            .ok_or_else(|| {
+                Error::DiffParseFailed(format!("{} not found in generation {}", name, generation))
+            })
+    };
+    let from_path = find(from)?;
+    let to_path = find(to)?;
+
+    let from_deps = query_store("--requisites", &from_path)?;
+    let to_deps = query_store("--requisites", &to_path)?;
+
+    Ok(diff_refs(&from_deps, &to_deps))
+}
+
+// Explains why a generation's closure contains a package, as the tree of
+// every chain of references from the generation down to it. pkg is a store
+// path or a package name looked up in the closure.
+fn why_depends(profile: &str, id: &str, pkg: &str) -> Result<Option<DependencyNode>, Error> {
+    let link = link(profile, id);
+    let path = if pkg.starts_with('/') {
+        pkg.to_string()
+    } else {
+        query_store("--requisites", &link)?
+            .into_iter()
+            .find(|path| parse_store_name(path).0 == pkg)
+            .ok_or_else(|| {
+                Error::NixCommandFailed(format!("{} not found in generation {}", pkg, id))
+            })?
+    };
+    let output = StdCommand::new("nix")
+        .args(["why-depends", "--all"])
+        .arg(&link)
+        .arg(&path)
+        .env("NO_COLOR", "1")
+        .output()
+        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::NixCommandFailed(
+            String::from_utf8_lossy(&output.stderr).to_string(),
+        ));
+    }
+    Ok(parse_why_depends(&String::from_utf8_lossy(&output.stdout)))
+}
+
+// Builds the tree nix why-depends draws. Each level of the drawing indents
+// a path by four columns of box-drawing characters.
+fn parse_why_depends(output: &str) -> Option<DependencyNode> {
+    let mut stack: Vec<(usize, DependencyNode)> = Vec::new();
+    for line in output.lines() {
+        let start = match line.find('/') {
+            Some(start) => start,
+            None => continue,
+        };
+        let depth = line[..start].chars().count() / 4;
+        let path = line[start..].split_whitespace().next().unwrap_or_default();
+        while stack.len() > 1 && stack.last().map_or(false, |(d, _)| *d >= depth) {
+            let (_, node) = stack.pop().unwrap();
+            stack.last_mut().unwrap().1.children.push(node);
+        }
+        stack.push((
+            depth,
+            DependencyNode {
+                path: path.to_string(),
+                children: Vec::new(),
+            },
+        ));
+    }
+    while stack.len() > 1 {
+        let (_, node) = stack.pop().unwrap();
+        stack.last_mut().unwrap().1.children.push(node);
+    }
+    stack.pop().map(|(_, node)| node)
+}
+
+fn closure_size(path: &str) -> Option<i64> {
+    let output = StdCommand::new("nix")
+        .args(["path-info", "-S"])
+        .arg(path)
+        .output()
+        .ok()?;
+
+    if !output.status.success() {
+        return None;
+    }
+
+    String::from_utf8_lossy(&output.stdout)
+        .split_whitespace()
+        .nth(1)
+        .and_then(|size| size.parse().ok())
+}
+
+// Builds the current configuration without activating it and diffs the
+// result against the running system.
+fn get_pending_diff() -> Result<GenerationDiff, Error> {
+    let build_dir = std::env::temp_dir().join(format!("nix-timemach-{}", std::process::id()));
+    fs::create_dir_all(&build_dir).map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+
+    // The build's progress goes straight to our stderr so the frontend can
+    // show it live; stdout is reserved for the JSON result.
+    let status = StdCommand::new("nixos-rebuild")
+        .arg("build")
+        .current_dir(&build_dir)
+        .stdout(Stdio::null())
+        .stderr(Stdio::inherit())
+        .status()
+        .map_err(|e| Error::NixCommandFailed(e.to_string()));
+
+    let result = status.and_then(|status| {
+        if !status.success() {
+            return Err(Error::NixCommandFailed(format!(
+                "nixos-rebuild build failed ({}); see the build log above",
+                status
+            )));
+        }
+        let result_link = build_dir.join("result");
+        diff_paths("/run/current-system", &result_link.to_string_lossy())
+    });
+
+    let _ = fs::remove_dir_all(&build_dir);
+    result
+}
+
+// Counts the changes without building the diff, which would also look up
+// explicit packages and the size of every changed path.
+fn get_diff_stats(profile: &str, from: &str, to: &str) -> Result<DiffStats, Error> {
+    let from_link = link(profile, from);
+    let to_link = link(profile, to);
+    let from_refs = query_store("--references", &from_link)?;
+    let to_refs = query_store("--references", &to_link)?;
+    let (added, removed, modified) = count_changes(&from_refs, &to_refs);
+
+    let from_size = closure_size(&from_link);
+    let to_size = closure_size(&to_link);
+    let (size_delta, size_known) = match (from_size, to_size) {
+        (Some(a), Some(b)) => (b - a, true),
+        _ => (0, false),
+    };
+
+    Ok(DiffStats {
         added,
         removed,
         modified,
+        size_delta,
+        size_known,
     })
 }
 
-fn main() -> Result<(), Error> {
-    let cli = Command::new("nix-timemach-backend")
-        .version("0.0.1")
-        .about("Nix Time Machine")
-        .subcommand_required(true)
-        .subcommand(Command::new("list-generations").about("List all generations"))
-        .subcommand(
-            Command::new("diff")
-                .about("Show diff between two generations")
-                .arg(clap::arg!(<from> "From generation ID"))
-                .arg(clap::arg!(<to> "To generation ID")),
-        )
-        .get_matches();
+// Counts what diff_refs would list as added, removed and modified.
+fn count_changes(from_refs: &[String], to_refs: &[String]) -> (usize, usize, usize) {
+    let (added, removed, modified) = classify(from_refs, to_refs);
+    (added.len(), removed.len(), modified.len())
+}
 
-    match cli.subcommand() {
-        Some(("list-generations", _)) => {
-            let generations = list_generations()?;
-            println!(
-                "{}",
-                serde_json::to_string(&generations)
-                    .map_err(|e| Error::NixOutputParseFailed(e.to_string()))?
-            );
+// Reads what `nixos-version --json` reports for a generation. Flake-based
+// systems carry a configurationRevision; channel-based ones do not.
+fn read_version_info(link: &str) -> serde_json::Map<String, serde_json::Value> {
+    let output = StdCommand::new(format!("{}/sw/bin/nixos-version", link))
+        .arg("--json")
+        .output();
+
+    match output {
+        Ok(output) if output.status.success() => {
+            serde_json::from_slice::<serde_json::Value>(&output.stdout)
+                .ok()
+                .and_then(|v| v.as_object().cloned())
+                .unwrap_or_default()
         }
-        Some(("diff", matches)) => {
-            let from = matches.get_one::<String>("from").unwrap();
-            let to = matches.get_one::<String>("to").unwrap();
-            let diff = get_diff(from, to)?;
-            println!(
-                "{}",
-                serde_json::to_string(&diff)
-                    .map_err(|e| Error::NixOutputParseFailed(e.to_string()))?
-            );
+        _ => serde_json::Map::new(),
+    }
+}
+
+fn read_toplevel_file(link: &str, name: &str) -> String {
+    fs::read_to_string(format!("{}/{}", link, name))
+        .map(|s| s.trim().to_string())
+        .unwrap_or_default()
+}
+
+// The direct inputs pinned by the flake.lock a system generation carries
+// as etc/flake.lock, which a configuration provides with
+// environment.etc."flake.lock".source = ./flake.lock. Inputs that follow
+// another are left out, as they share its pin.
+fn locked_inputs(link: &str) -> BTreeMap<String, LockedInput> {
+    let lock = match fs::read(format!("{}/etc/flake.lock", link))
+        .ok()
+        .and_then(|data| serde_json::from_slice::<serde_json::Value>(&data).ok())
+    {
+        Some(lock) => lock,
+        None => return BTreeMap::new(),
+    };
+    let nodes = &lock["nodes"];
+    let root = lock["root"].as_str().unwrap_or("root");
+    let direct = match nodes[root]["inputs"].as_object() {
+        Some(direct) => direct,
+        None => return BTreeMap::new(),
+    };
+    direct
+        .iter()
+        .filter_map(|(name, node)| {
+            let locked = &nodes[node.as_str()?]["locked"];
+            Some((
+                name.clone(),
+                LockedInput {
+                    rev: locked["rev"].as_str().unwrap_or("").to_string(),
+                    last_modified: locked["lastModified"].as_i64().unwrap_or(0),
+                },
+            ))
+        })
+        .collect()
+}
+
+fn get_lock_diff(profile: &str, from: &str, to: &str) -> Vec<InputChange> {
+    let old = locked_inputs(&link(profile, from));
+    let new = locked_inputs(&link(profile, to));
+    let mut names: Vec<&String> = old.keys().chain(new.keys()).collect();
+    names.sort();
+    names.dedup();
+    names
+        .into_iter()
+        .filter_map(|name| {
+            let from = old.get(name).cloned().unwrap_or_default();
+            let to = new.get(name).cloned().unwrap_or_default();
+            (from != to).then(|| InputChange {
+                name: name.clone(),
+                from,
+                to,
+            })
+        })
+        .collect()
+}
+
+fn get_config_diff(profile: &str, from: &str, to: &str) -> Result<ConfigDiff, Error> {
+    let from_link = link(profile, from);
+    let to_link = link(profile, to);
+
+    let from_info = read_version_info(&from_link);
+    let to_info = read_version_info(&to_link);
+
+    let flake = [(&from_link, &from_info), (&to_link, &to_info)]
+        .iter()
+        .any(|(link, info)| {
+            info.contains_key("configurationRevision")
+                || Path::new(&format!("{}/etc/flake.lock", link)).exists()
+        });
+
+    // An input only one side has was added or removed, and is empty on the
+    // other.
+    let mut inputs = Vec::new();
+    if flake {
+        let from_inputs = input_revisions(&from_link, &from_info);
+        let to_inputs = input_revisions(&to_link, &to_info);
+        let mut names: Vec<&String> = from_inputs.keys().chain(to_inputs.keys()).collect();
+        names.sort();
+        names.dedup();
+        for name in names {
+            let old = from_inputs.get(name).map(String::as_str).unwrap_or("");
+            let new = to_inputs.get(name).map(String::as_str).unwrap_or("");
+            if old != new {
+                inputs.push(ConfigChange {
+                    name: name.clone(),
+                    from: old.to_string(),
+                    to: new.to_string(),
+                });
+            }
         }
-        _ => unreachable!(),
     }
 
-    Ok(())
+    let mut options = Vec::new();
+    for name in ["nixos-version", "kernel-params", "configuration-name"] {
+        let old = read_toplevel_file(&from_link, name);
+        let new = read_toplevel_file(&to_link, name);
+        if old != new {
+            options.push(ConfigChange {
+                name: name.to_string(),
+                from: old,
+                to: new,
+            });
+        }
+    }
+
+    Ok(ConfigDiff {
+        flake,
+        inputs,
+        options,
+    })
+}
+
+// The revision of every direct input of a generation's flake, by name, from
+// the flake.lock it carries. nixos-version fills in nixpkgs and self, the
+// configuration's own revision, when the lock doesn't name them or there is
+// none.
+fn input_revisions(
+    link: &str,
+    info: &serde_json::Map<String, serde_json::Value>,
+) -> BTreeMap<String, String> {
+    let mut revs: BTreeMap<String, String> = locked_inputs(link)
+        .into_iter()
+        .map(|(name, input)| (name, input.rev))
+        .collect();
+    for (key, name) in [
+        ("nixpkgsRevision", "nixpkgs"),
+        ("configurationRevision", "self"),
+    ] {
+        if let Some(rev) = info.get(key).and_then(|v| v.as_str()) {
+            revs.entry(name.to_string())
+                .or_insert_with(|| rev.to_string());
+        }
+    }
+    revs
+}
+
+// Maps every file under dir, e.g. /etc/ssh/sshd_config, to the store file
+// it resolves to. NixOS's etc tree is mostly symlinks into the store.
+fn etc_files(dir: &Path, prefix: &str, files: &mut BTreeMap<String, PathBuf>) {
+    let Ok(entries) = fs::read_dir(dir) else {
+        return;
+    };
+    for entry in entries.flatten() {
+        let path = entry.path();
+        let name = format!("{}/{}", prefix, entry.file_name().to_string_lossy());
+        if path.is_dir() {
+            etc_files(&path, &name, files);
+        } else {
+            let target = fs::canonicalize(&path)
+                .or_else(|_| fs::read_link(&path))
+                .unwrap_or(path);
+            files.insert(name, target);
+        }
+    }
+}
+
+// Two files are the same if they resolve to the same store file or, failing
+// that, have the same contents.
+fn same_file(a: &Path, b: &Path) -> bool {
+    a == b || matches!((fs::read(a), fs::read(b)), (Ok(x), Ok(y)) if x == y)
+}
+
+// Diffs the /etc trees of two generations, which shows configuration
+// changes that don't change any package, like an edited sshd_config.
+fn get_etc_diff(profile: &str, from: &str, to: &str) -> Result<FileDiff, Error> {
+    let mut trees = Vec::new();
+    for id in [from, to] {
+        let etc = PathBuf::from(format!("{}/etc", link(profile, id)));
+        if !etc.exists() {
+            return Err(Error::NixCommandFailed(format!(
+                "{} does not exist",
+                etc.display()
+            )));
+        }
+        let mut files = BTreeMap::new();
+        etc_files(&etc, "/etc", &mut files);
+        trees.push(files);
+    }
+    let (from_files, to_files) = (&trees[0], &trees[1]);
+
+    let mut diff = FileDiff {
+        added: Vec::new(),
+        removed: Vec::new(),
+        modified: Vec::new(),
+    };
+    for (name, target) in from_files {
+        match to_files.get(name) {
+            None => diff.removed.push(name.clone()),
+            Some(other) if !same_file(target, other) => diff.modified.push(name.clone()),
+            Some(_) => {}
+        }
+    }
+    for name in to_files.keys() {
+        if !from_files.contains_key(name) {
+            diff.added.push(name.clone());
+        }
+    }
+    Ok(diff)
+}
+
+// Splits a store path into package name and version the way Nix's
+// parseDrvName does: the version starts at the first dash followed by a digit.
+fn parse_store_name(path: &str) -> (&str, &str) {
+    let base = path.rsplit('/').next().unwrap_or(path);
+    let base = base.split_once('-').map(|(_, rest)| rest).unwrap_or(base);
+    let bytes = base.as_bytes();
+    for i in 0..bytes.len().saturating_sub(1) {
+        if bytes[i] == b'-' && bytes[i + 1].is_ascii_digit() {
+            return (&base[..i], &base[i + 1..]);
+        }
+    }
+    (base, "")
+}
+
+fn find_package(profile: &str, name: &str) -> Result<Vec<PackagePresence>, Error> {
+    let mut presence = Vec::new();
+    for generation in list_generations(profile, false)? {
+        let output = StdCommand::new("nix-store")
+            .args(["-q", "--requisites"])
+            .arg(&generation.profiles[0])
+            .output()
+            .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+
+        // A generation whose closure can't be queried (e.g. it was garbage
+        // collected) is reported as not containing the package.
+        let version = String::from_utf8_lossy(&output.stdout)
+            .lines()
+            .map(parse_store_name)
+            .find(|(pkg, _)| *pkg == name)
+            .map(|(_, version)| version.to_string());
+
+        presence.push(PackagePresence {
+            generation: generation.id,
+            present: version.is_some(),
+            version: version.unwrap_or_default(),
+        });
+    }
+    Ok(presence)
+}
+
+// Every path in a generation's closure with its own size, largest first.
+fn path_sizes(profile: &str, id: &str) -> Result<Vec<PathSize>, Error> {
+    let link = link(profile, id);
+    let output = StdCommand::new("nix")
+        .args(["path-info", "-rs"])
+        .arg(&link)
+        .output()
+        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::NixCommandFailed(
+            String::from_utf8_lossy(&output.stderr).to_string(),
+        ));
+    }
+
+    let mut sizes: Vec<PathSize> = String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .filter_map(|line| {
+            let mut fields = line.split_whitespace();
+            let path = fields.next()?.to_string();
+            let size = fields.next()?.parse().ok()?;
+            Some(PathSize { path, size })
+        })
+        .collect();
+    sizes.sort_by(|a, b| b.size.cmp(&a.size).then_with(|| a.path.cmp(&b.path)));
+    Ok(sizes)
+}
+
+// Every path in a generation's closure with its own size and the paths it
+// references, from which the frontend draws the closure as a tree.
+fn closure_graph(profile: &str, id: &str) -> Result<Vec<StoreNode>, Error> {
+    let output = StdCommand::new("nix")
+        .args(["path-info", "-r", "--json"])
+        .arg(link(profile, id))
+        .output()
+        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::NixCommandFailed(
+            String::from_utf8_lossy(&output.stderr).to_string(),
+        ));
+    }
+    let info: serde_json::Value = serde_json::from_slice(&output.stdout)
+        .map_err(|e| Error::NixOutputParseFailed(e.to_string()))?;
+
+    // Nix 2.19 and later key the paths' info by path; older versions list
+    // it with the path inside.
+    let entries: Vec<(String, &serde_json::Value)> = match &info {
+        serde_json::Value::Object(map) => map.iter().map(|(k, v)| (k.clone(), v)).collect(),
+        serde_json::Value::Array(items) => items
+            .iter()
+            .filter_map(|v| Some((v.get("path")?.as_str()?.to_string(), v)))
+            .collect(),
+        _ => Vec::new(),
+    };
+    Ok(entries
+        .into_iter()
+        .map(|(path, v)| StoreNode {
+            size: v.get("narSize").and_then(|s| s.as_i64()).unwrap_or(0),
+            references: v
+                .get("references")
+                .and_then(|r| r.as_array())
+                .map(|refs| {
+                    refs.iter()
+                        .filter_map(|r| r.as_str())
+                        .filter(|r| *r != path)
+                        .map(str::to_string)
+                        .collect()
+                })
+                .unwrap_or_default(),
+            path,
+        })
+        .collect())
+}
+
+fn known_good_root(id: &str) -> PathBuf {
+    Path::new(GCROOTS_DIR).join(format!("known-good-{}", id))
+}
+
+// A symlink under the gcroots directory both protects the generation's
+// closure from garbage collection and records the mark across runs.
+fn mark_known_good(id: &str) -> Result<(), Error> {
+    let link = link(SYSTEM_PROFILE, id);
+    let store_path =
+        fs::read_link(&link).map_err(|e| Error::MarkFailed(format!("{}: {}", link, e)))?;
+
+    fs::create_dir_all(GCROOTS_DIR)
+        .map_err(|e| Error::MarkFailed(format!("{}: {}", GCROOTS_DIR, e)))?;
+
+    let root = known_good_root(id);
+    if root.symlink_metadata().is_ok() {
+        fs::remove_file(&root).map_err(|e| Error::MarkFailed(e.to_string()))?;
+    }
+    symlink(&store_path, &root).map_err(|e| Error::MarkFailed(e.to_string()))?;
+
+    Ok(())
+}
+
+// A pinned generation's root is pin-<id>, or pin-<id>-<name> when the pin
+// was given a name, so the name survives without a separate record.
+// Characters that don't belong in a file name become underscores.
+fn pin_root(id: &str, name: &str) -> PathBuf {
+    if name.is_empty() {
+        return Path::new(GCROOTS_DIR).join(format!("pin-{}", id));
+    }
+    let name: String = name
+        .chars()
+        .map(|c| {
+            if c.is_ascii_alphanumeric() || "._-".contains(c) {
+                c
+            } else {
+                '_'
+            }
+        })
+        .collect();
+    Path::new(GCROOTS_DIR).join(format!("pin-{}-{}", id, name))
+}
+
+// The root pinning generation id and the pin's name, if it is pinned.
+fn find_pin(id: &str) -> Option<(PathBuf, String)> {
+    let prefix = format!("pin-{}", id);
+    fs::read_dir(GCROOTS_DIR).ok()?.flatten().find_map(|entry| {
+        let file_name = entry.file_name().to_string_lossy().into_owned();
+        let rest = file_name.strip_prefix(&prefix)?;
+        let name = match rest.strip_prefix('-') {
+            Some(name) => name.to_string(),
+            None if rest.is_empty() => String::new(),
+            None => return None,
+        };
+        Some((entry.path(), name))
+    })
+}
+
+// Pins a system generation with a GC root of its own, which keeps its
+// closure alive and which delete-generations and gc refuse to go past.
+fn pin(id: &str, name: &str) -> Result<(), Error> {
+    let link = link(SYSTEM_PROFILE, id);
+    let store_path =
+        fs::read_link(&link).map_err(|e| Error::PinFailed(format!("{}: {}", link, e)))?;
+
+    fs::create_dir_all(GCROOTS_DIR)
+        .map_err(|e| Error::PinFailed(format!("{}: {}", GCROOTS_DIR, e)))?;
+    unpin(id)?;
+    symlink(&store_path, pin_root(id, name)).map_err(|e| Error::PinFailed(e.to_string()))?;
+
+    Ok(())
+}
+
+fn unpin(id: &str) -> Result<(), Error> {
+    if let Some((root, _)) = find_pin(id) {
+        fs::remove_file(&root).map_err(|e| Error::PinFailed(e.to_string()))?;
+    }
+    Ok(())
+}
+
+// A trashed generation's root is trash-<id>-<seconds since the epoch>,
+// which records when it was trashed without a separate record, like pins.
+fn trash_root(id: &str, trashed: DateTime<Utc>) -> PathBuf {
+    Path::new(GCROOTS_DIR).join(format!("trash-{}-{}", id, trashed.timestamp()))
+}
+
+// The generations in the trash with their roots, oldest first.
+fn trashed_generations() -> Vec<(PathBuf, TrashedGeneration)> {
+    let Ok(entries) = fs::read_dir(GCROOTS_DIR) else {
+        return Vec::new();
+    };
+    let mut trashed: Vec<_> = entries
+        .flatten()
+        .filter_map(|entry| {
+            let file_name = entry.file_name().to_string_lossy().into_owned();
+            let (id, seconds) = file_name.strip_prefix("trash-")?.split_once('-')?;
+            let trashed = DateTime::from_timestamp(seconds.parse().ok()?, 0)?;
+            let store_path = fs::read_link(entry.path()).ok()?;
+            Some((
+                entry.path(),
+                TrashedGeneration {
+                    id: id.to_string(),
+                    store_path: store_path.to_string_lossy().into_owned(),
+                    trashed,
+                },
+            ))
+        })
+        .collect();
+    trashed.sort_by_key(|(_, gen)| gen.trashed);
+    trashed
+}
+
+// Deletes system generations like delete-generations, but first gives each
+// a root of its own so its closure survives garbage collection and it can
+// be restored. Generations trashed more than keep_days ago go for good.
+fn trash_generations(ids: &[String], keep_days: i64) -> Result<(), Error> {
+    fs::create_dir_all(GCROOTS_DIR)
+        .map_err(|e| Error::TrashFailed(format!("{}: {}", GCROOTS_DIR, e)))?;
+    let now = Utc::now();
+    for id in ids {
+        let link = link(SYSTEM_PROFILE, id);
+        let store_path =
+            fs::read_link(&link).map_err(|e| Error::TrashFailed(format!("{}: {}", link, e)))?;
+        symlink(&store_path, trash_root(id, now)).map_err(|e| Error::TrashFailed(e.to_string()))?;
+    }
+    if let Err(e) = delete_generations(ids) {
+        for id in ids {
+            let _ = fs::remove_file(trash_root(id, now));
+        }
+        return Err(e);
+    }
+    empty_trash(keep_days)
+}
+
+// Takes a generation back out of the trash by pointing its profile link at
+// its system again. It is back in the list straight away, and in the boot
+// menu after the next rebuild.
+fn restore_generation(id: &str) -> Result<(), Error> {
+    let (root, gen) = trashed_generations()
+        .into_iter()
+        .rev()
+        .find(|(_, gen)| gen.id == id)
+        .ok_or_else(|| Error::RestoreFailed(format!("generation {} is not in the trash", id)))?;
+    let link = link(SYSTEM_PROFILE, id);
+    if Path::new(&link).symlink_metadata().is_ok() {
+        return Err(Error::RestoreFailed(format!(
+            "{} exists; a newer generation took its number",
+            link
+        )));
+    }
+    symlink(&gen.store_path, &link).map_err(|e| Error::RestoreFailed(e.to_string()))?;
+    fs::remove_file(&root).map_err(|e| Error::RestoreFailed(e.to_string()))?;
+    Ok(())
+}
+
+// Removes the trash roots older than days, 0 for all of them, leaving
+// their closures to the next garbage collection.
+fn empty_trash(days: i64) -> Result<(), Error> {
+    let cutoff = Utc::now() - chrono::Duration::days(days);
+    for (root, gen) in trashed_generations() {
+        if days == 0 || gen.trashed < cutoff {
+            fs::remove_file(&root).map_err(|e| Error::TrashFailed(e.to_string()))?;
+        }
+    }
+    Ok(())
+}
+
+// Points the system profile at generation id and activates it, like
+// nixos-rebuild --rollback does for the previous generation.
+fn rollback(id: &str) -> Result<(), Error> {
+    switch_generation(id, "switch", Error::RollbackFailed)
+}
+
+// Points the system profile at generation id and makes it the boot
+// loader's default without activating it, like nixos-rebuild boot.
+fn set_boot_default(id: &str) -> Result<(), Error> {
+    switch_generation(id, "boot", Error::BootFailed)
+}
+
+// Runs the generation's switch-to-configuration dry-activate and returns
+// what it printed, a line per kind of unit change; nothing is activated.
+// The frontend reads the lines.
+fn dry_activate(id: &str) -> Result<Vec<String>, Error> {
+    let output = StdCommand::new(format!(
+        "{}/bin/switch-to-configuration",
+        link(SYSTEM_PROFILE, id)
+    ))
+    .arg("dry-activate")
+    .output()
+    .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::NixCommandFailed(format!(
+            "switch-to-configuration dry-activate exited with {}: {}",
+            output.status,
+            String::from_utf8_lossy(&output.stderr).trim()
+        )));
+    }
+    Ok(String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .chain(String::from_utf8_lossy(&output.stderr).lines())
+        .map(str::trim)
+        .filter(|line| !line.is_empty())
+        .map(str::to_string)
+        .collect())
+}
+
+fn switch_generation(id: &str, action: &str, fail: fn(String) -> Error) -> Result<(), Error> {
+    let status = StdCommand::new("nix-env")
+        .args(["--profile", SYSTEM_PROFILE, "--switch-generation", id])
+        .status()
+        .map_err(|e| fail(e.to_string()))?;
+    if !status.success() {
+        return Err(fail(format!(
+            "nix-env --switch-generation {} exited with {}",
+            id, status
+        )));
+    }
+
+    let status = StdCommand::new(format!("{}/bin/switch-to-configuration", SYSTEM_PROFILE))
+        .arg(action)
+        .status()
+        .map_err(|e| fail(e.to_string()))?;
+    if !status.success() {
+        return Err(fail(format!(
+            "switch-to-configuration {} exited with {}",
+            action, status
+        )));
+    }
+
+    Ok(())
+}
+
+// Deletes the generations in one nix-env call, which refuses to delete the
+// current one, and drops their known-good GC roots so the closures can be
+// collected.
+fn delete_generations(ids: &[String]) -> Result<(), Error> {
+    if let Some(id) = ids.iter().find(|id| find_pin(id).is_some()) {
+        return Err(Error::DeleteFailed(format!(
+            "generation {} is pinned; unpin it first",
+            id
+        )));
+    }
+    let status = StdCommand::new("nix-env")
+        .args(["--profile", SYSTEM_PROFILE, "--delete-generations"])
+        .args(ids)
+        .status()
+        .map_err(|e| Error::DeleteFailed(e.to_string()))?;
+    if !status.success() {
+        return Err(Error::DeleteFailed(format!(
+            "nix-env --delete-generations exited with {}",
+            status
+        )));
+    }
+
+    for id in ids {
+        let root = known_good_root(id);
+        if root.symlink_metadata().is_ok() {
+            fs::remove_file(&root).map_err(|e| Error::DeleteFailed(e.to_string()))?;
+        }
+    }
+
+    Ok(())
+}
+
+// Parses an age as --delete-older-than takes it, a number of days like
+// "30d".
+fn parse_age(age: &str) -> Result<i64, Error> {
+    age.strip_suffix('d')
+        .and_then(|days| days.parse::<u32>().ok())
+        .map(i64::from)
+        .ok_or_else(|| Error::GcFailed(format!("invalid age {:?}, want days like 30d", age)))
+}
+
+// The generations of profile --delete-older-than would delete, less the
+// pinned ones. Like nix-env, it keeps the current generation and the newest
+// one older than the cutoff, which was the one in use at the cutoff.
+fn expiring_generations(profile: &str, days: i64) -> Result<Vec<Generation>, Error> {
+    let cutoff = Utc::now() - chrono::Duration::days(days);
+    let mut generations = list_generations(profile, false)?;
+    generations.sort_by_key(|gen| std::cmp::Reverse(gen.id.parse::<u64>().unwrap_or(0)));
+    let mut expired = false;
+    Ok(generations
+        .into_iter()
+        .filter(|gen| {
+            if expired {
+                return !gen.current && !gen.pinned;
+            }
+            expired = gen.timestamp < cutoff;
+            false
+        })
+        .collect())
+}
+
+// The garbage collector's roots, as (link, store path) pairs.
+fn gc_roots() -> Result<Vec<(String, String)>, Error> {
+    let output = StdCommand::new("nix-store")
+        .args(["--gc", "--print-roots"])
+        .output()
+        .map_err(|e| Error::GcFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::GcFailed(format!(
+            "nix-store --gc --print-roots exited with {}",
+            output.status
+        )));
+    }
+    Ok(String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .filter_map(|line| {
+            let (link, path) = line.split_once(" -> ")?;
+            Some((link.to_string(), path.to_string()))
+        })
+        .collect())
+}
+
+// The closure of paths, or nothing when there are none to query.
+fn closure(paths: &[&str]) -> Result<HashSet<String>, Error> {
+    if paths.is_empty() {
+        return Ok(HashSet::new());
+    }
+    let output = StdCommand::new("nix-store")
+        .arg("-qR")
+        .args(paths)
+        .output()
+        .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    if !output.status.success() {
+        return Err(Error::NixCommandFailed(format!(
+            "nix-store -qR exited with {}: {}",
+            output.status,
+            String::from_utf8_lossy(&output.stderr).trim()
+        )));
+    }
+    Ok(String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .map(str::to_string)
+        .collect())
+}
+
+// The total size of paths, queried a batch at a time to stay within the
+// argument limit. Paths nix can't size count as nothing.
+fn total_size(paths: &[&str]) -> i64 {
+    paths
+        .chunks(500)
+        .filter_map(|chunk| {
+            StdCommand::new("nix")
+                .args(["path-info", "-s"])
+                .args(chunk)
+                .output()
+                .ok()
+        })
+        .map(|output| {
+            String::from_utf8_lossy(&output.stdout)
+                .lines()
+                .filter_map(|line| line.split_whitespace().nth(1)?.parse::<i64>().ok())
+                .sum::<i64>()
+        })
+        .sum()
+}
+
+// Previews garbage collection without changing anything. What would be
+// freed is what is dead already plus the closures of the expiring
+// generations, less whatever the remaining roots still reach.
+fn gc_dry_run(age: &str) -> Result<GcPreview, Error> {
+    let days = parse_age(age)?;
+    let mut generations = Vec::new();
+    let mut expiring = Vec::new();
+    for profile in list_profiles() {
+        for gen in expiring_generations(&profile.path, days)? {
+            expiring.push(link(&profile.path, &gen.id));
+            generations.push(GcGeneration {
+                profile: profile.path.clone(),
+                id: gen.id,
+            });
+        }
+    }
+
+    let roots = gc_roots()?;
+    let kept: Vec<&str> = roots
+        .iter()
+        .filter(|(link, _)| !expiring.contains(link))
+        .map(|(_, path)| path.as_str())
+        .collect();
+    let live = closure(&kept)?;
+
+    let output = StdCommand::new("nix-store")
+        .args(["--gc", "--print-dead"])
+        .output()
+        .map_err(|e| Error::GcFailed(e.to_string()))?;
+    let mut freed: HashSet<String> = String::from_utf8_lossy(&output.stdout)
+        .lines()
+        .map(str::to_string)
+        .collect();
+    let expiring: Vec<&str> = expiring.iter().map(String::as_str).collect();
+    freed.extend(closure(&expiring)?);
+    freed.retain(|path| !live.contains(path));
+
+    let freed: Vec<&str> = freed.iter().map(String::as_str).collect();
+    Ok(GcPreview {
+        generations,
+        paths: freed.len(),
+        bytes: total_size(&freed),
+    })
+}
+
+// Deletes the generations gc-dry-run lists and collects garbage. This is
+// nix-collect-garbage --delete-older-than, except that it leaves pinned
+// generations alone.
+fn collect_garbage(age: &str) -> Result<(), Error> {
+    let days = parse_age(age)?;
+    for profile in list_profiles() {
+        let ids: Vec<String> = expiring_generations(&profile.path, days)?
+            .into_iter()
+            .map(|gen| gen.id)
+            .collect();
+        if ids.is_empty() {
+            continue;
+        }
+        let status = StdCommand::new("nix-env")
+            .args(["--profile", &profile.path, "--delete-generations"])
+            .args(&ids)
+            .status()
+            .map_err(|e| Error::GcFailed(e.to_string()))?;
+        if !status.success() {
+            return Err(Error::GcFailed(format!(
+                "nix-env --delete-generations exited with {}",
+                status
+            )));
+        }
+    }
+
+    let status = StdCommand::new("nix-store")
+        .arg("--gc")
+        .status()
+        .map_err(|e| Error::GcFailed(e.to_string()))?;
+    if !status.success() {
+        return Err(Error::GcFailed(format!(
+            "nix-store --gc exited with {}",
+            status
+        )));
+    }
+    Ok(())
+}
+
+fn cli() -> Command {
+    Command::new("nix-timemach-backend")
+        .version(env!("CARGO_PKG_VERSION"))
+        .about("Nix Time Machine")
+        .subcommand_required(true)
+        .arg(clap::arg!(--store <url> "Nix store to operate on instead of the default"))
+        .arg(
+            clap::arg!(--profile <path> "Profile whose generations to read")
+                .global(true)
+                .default_value(SYSTEM_PROFILE),
+        )
+        .subcommand(Command::new("version").about("Print the backend and protocol version as JSON"))
+        .subcommand(
+            Command::new("list-profiles")
+                .about("List the system, user and Home Manager profiles that exist"),
+        )
+        .subcommand(
+            Command::new("list-generations")
+                .about("List all generations")
+                .arg(clap::arg!(--sizes "Include each generation's closure size")),
+        )
+        .subcommand(
+            Command::new("diff")
+                .about("Show diff between two generations")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("diff-stream")
+                .about("Show the diff between two generations as it is computed, as JSON lines")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("diff-bulk")
+                .about("Show the diffs between several pairs of generations at once")
+                .arg(clap::arg!(<pairs> ... "Generation ID pairs as FROM:TO")),
+        )
+        .subcommand(
+            Command::new("pending-diff")
+                .about("Show what rebuilding the current configuration would change"),
+        )
+        .subcommand(
+            Command::new("diff-stats")
+                .about("Show only the size of the diff between two generations")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("diff-stats-bulk")
+                .about("Show only the sizes of the diffs between several pairs of generations")
+                .arg(clap::arg!(<pairs> ... "Generation ID pairs as FROM:TO")),
+        )
+        .subcommand(
+            Command::new("config-diff")
+                .about("Show configuration changes between two generations")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("lock-diff")
+                .about("Show which flake inputs' pins differ between two generations")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("etc-diff")
+                .about("Show which files under /etc differ between two generations")
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("mark-known-good")
+                .about("Protect a generation from garbage collection and mark it known good")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("rollback")
+                .about("Switch the system to a generation and activate it")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("dry-activate")
+                .about("Show which units activating a generation would stop, start or restart")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("set-boot-default")
+                .about("Make a generation the boot default without activating it")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("delete-generations")
+                .about("Delete generations and their known-good marks")
+                .arg(clap::arg!(<ids> ... "Generation IDs")),
+        )
+        .subcommand(
+            Command::new("trash-generations")
+                .about("Delete generations but keep their systems for a while, to be restored")
+                .arg(clap::arg!(<ids> ... "Generation IDs"))
+                .arg(
+                    clap::arg!(--"keep-days" <days> "Empty trash older than this many days")
+                        .value_parser(clap::value_parser!(i64))
+                        .default_value("7"),
+                ),
+        )
+        .subcommand(Command::new("list-trash").about("List the generations in the trash"))
+        .subcommand(
+            Command::new("restore-generation")
+                .about("Take a generation back out of the trash")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("empty-trash")
+                .about("Let garbage collection have the trashed generations")
+                .arg(
+                    clap::arg!([days] "Only those trashed more than this many days ago")
+                        .value_parser(clap::value_parser!(i64))
+                        .default_value("0"),
+                ),
+        )
+        .subcommand(
+            Command::new("pin")
+                .about("Protect a generation from deletion and garbage collection")
+                .arg(clap::arg!(<id> "Generation ID"))
+                .arg(clap::arg!([name] "Name to remember the pin by")),
+        )
+        .subcommand(
+            Command::new("unpin")
+                .about("Remove a generation's pin")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("gc-dry-run")
+                .about("Show what collecting garbage older than an age would delete and free")
+                .arg(clap::arg!(<age> "Age as nix-collect-garbage takes it, e.g. 30d")),
+        )
+        .subcommand(
+            Command::new("gc")
+                .about("Delete unpinned generations older than an age and collect garbage")
+                .arg(clap::arg!(<age> "Age as nix-collect-garbage takes it, e.g. 30d")),
+        )
+        .subcommand(
+            Command::new("packages")
+                .about("List the package set of a generation, or of the running system")
+                .arg(clap::arg!([id] "Generation ID")),
+        )
+        .subcommand(
+            Command::new("deps-diff")
+                .about("Show how a package's dependencies changed between two generations")
+                .arg(clap::arg!(<pkg> "Store path or name of the package"))
+                .arg(clap::arg!(<from> "From generation ID"))
+                .arg(clap::arg!(<to> "To generation ID")),
+        )
+        .subcommand(
+            Command::new("why-depends")
+                .about("Show the chains of references by which a generation contains a package")
+                .arg(clap::arg!(<id> "Generation ID"))
+                .arg(clap::arg!(<pkg> "Store path or name of the package")),
+        )
+        .subcommand(
+            Command::new("find-package")
+                .about("Show which generations contain a package, and at what version")
+                .arg(clap::arg!(<name> "Package name")),
+        )
+        .subcommand(
+            Command::new("closure-graph")
+                .about("List every path in a generation's closure with its size and references")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("path-sizes")
+                .about("List every path in a generation's closure with its size, largest first")
+                .arg(clap::arg!(<id> "Generation ID")),
+        )
+        .subcommand(
+            Command::new("serve")
+                .about("Answer requests from stdin, one JSON object per line, until EOF"),
+        )
+}
+
+fn to_json<T: Serialize>(value: &T) -> Result<String, Error> {
+    serde_json::to_string(value).map_err(|e| Error::NixOutputParseFailed(e.to_string()))
+}
+
+// Runs one subcommand, returning the JSON it answers with, if any. Changing
+// the system (marks, rollbacks, deletions) and pending diffs always concern
+// the system profile, whatever --profile says.
+fn dispatch(matches: &ArgMatches) -> Result<Option<String>, Error> {
+    let profile = matches
+        .subcommand()
+        .and_then(|(_, matches)| matches.get_one::<String>("profile"))
+        .map(String::as_str)
+        .unwrap_or(SYSTEM_PROFILE);
+    let output = match matches.subcommand() {
+        Some(("version", _)) => Some(to_json(&Version {
+            version: env!("CARGO_PKG_VERSION"),
+            protocol: PROTOCOL_VERSION,
+        })?),
+        Some(("list-profiles", _)) => Some(to_json(&list_profiles())?),
+        Some(("list-generations", matches)) => {
+            let generations = list_generations(profile, matches.get_flag("sizes"))?;
+            Some(to_json(&generations)?)
+        }
+        Some(("diff", matches)) => {
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let diff = get_diff(profile, from, to)?;
+            Some(to_json(&diff)?)
+        }
+        Some(("diff-bulk", matches)) => {
+            let pairs: Vec<String> = matches
+                .get_many::<String>("pairs")
+                .unwrap()
+                .cloned()
+                .collect();
+            let diffs = get_diffs_bulk(profile, &pairs);
+            Some(to_json(&diffs)?)
+        }
+        Some(("diff-stats-bulk", matches)) => {
+            let pairs: Vec<String> = matches
+                .get_many::<String>("pairs")
+                .unwrap()
+                .cloned()
+                .collect();
+            let stats = get_diff_stats_bulk(profile, &pairs);
+            Some(to_json(&stats)?)
+        }
+        Some(("pending-diff", _)) => {
+            let diff = get_pending_diff()?;
+            Some(to_json(&diff)?)
+        }
+        Some(("diff-stats", matches)) => {
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let stats = get_diff_stats(profile, from, to)?;
+            Some(to_json(&stats)?)
+        }
+        Some(("config-diff", matches)) => {
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let diff = get_config_diff(profile, from, to)?;
+            Some(to_json(&diff)?)
+        }
+        Some(("lock-diff", matches)) => {
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let diff = get_lock_diff(profile, from, to);
+            Some(to_json(&diff)?)
+        }
+        Some(("etc-diff", matches)) => {
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let diff = get_etc_diff(profile, from, to)?;
+            Some(to_json(&diff)?)
+        }
+        Some(("mark-known-good", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            mark_known_good(id)?;
+            None
+        }
+        Some(("rollback", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            rollback(id)?;
+            None
+        }
+        Some(("dry-activate", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            let lines = dry_activate(id)?;
+            Some(to_json(&lines)?)
+        }
+        Some(("set-boot-default", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            set_boot_default(id)?;
+            None
+        }
+        Some(("delete-generations", matches)) => {
+            let ids: Vec<String> = matches
+                .get_many::<String>("ids")
+                .unwrap()
+                .cloned()
+                .collect();
+            delete_generations(&ids)?;
+            None
+        }
+        Some(("trash-generations", matches)) => {
+            let ids: Vec<String> = matches
+                .get_many::<String>("ids")
+                .unwrap()
+                .cloned()
+                .collect();
+            trash_generations(&ids, *matches.get_one::<i64>("keep-days").unwrap())?;
+            None
+        }
+        Some(("list-trash", _)) => {
+            let trashed: Vec<TrashedGeneration> = trashed_generations()
+                .into_iter()
+                .map(|(_, gen)| gen)
+                .collect();
+            Some(to_json(&trashed)?)
+        }
+        Some(("restore-generation", matches)) => {
+            restore_generation(matches.get_one::<String>("id").unwrap())?;
+            None
+        }
+        Some(("empty-trash", matches)) => {
+            empty_trash(*matches.get_one::<i64>("days").unwrap())?;
+            None
+        }
+        Some(("pin", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            let name = matches
+                .get_one::<String>("name")
+                .map(String::as_str)
+                .unwrap_or("");
+            pin(id, name)?;
+            None
+        }
+        Some(("unpin", matches)) => {
+            unpin(matches.get_one::<String>("id").unwrap())?;
+            None
+        }
+        Some(("gc-dry-run", matches)) => {
+            let age = matches.get_one::<String>("age").unwrap();
+            Some(to_json(&gc_dry_run(age)?)?)
+        }
+        Some(("gc", matches)) => {
+            collect_garbage(matches.get_one::<String>("age").unwrap())?;
+            None
+        }
+        Some(("packages", matches)) => {
+            let link = match matches.get_one::<String>("id") {
+                Some(id) => link(profile, id),
+                None if profile == SYSTEM_PROFILE => "/run/current-system".to_string(),
+                None => profile.to_string(),
+            };
+            if !Path::new(&link).exists() {
+                return Err(Error::NixCommandFailed(format!("{} does not exist", link)));
+            }
+            let packages = query_store("--references", &link)?;
+            Some(to_json(&packages)?)
+        }
+        Some(("deps-diff", matches)) => {
+            let pkg = matches.get_one::<String>("pkg").unwrap();
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            let diff = get_deps_diff(profile, pkg, from, to)?;
+            Some(to_json(&diff)?)
+        }
+        Some(("find-package", matches)) => {
+            let name = matches.get_one::<String>("name").unwrap();
+            let presence = find_package(profile, name)?;
+            Some(to_json(&presence)?)
+        }
+        Some(("why-depends", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            let pkg = matches.get_one::<String>("pkg").unwrap();
+            Some(to_json(&why_depends(profile, id, pkg)?)?)
+        }
+        Some(("closure-graph", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            Some(to_json(&closure_graph(profile, id)?)?)
+        }
+        Some(("path-sizes", matches)) => {
+            let id = matches.get_one::<String>("id").unwrap();
+            Some(to_json(&path_sizes(profile, id)?)?)
+        }
+        _ => unreachable!(),
+    };
+
+    Ok(output)
+}
+
+#[derive(Deserialize)]
+struct Request {
+    id: u64,
+    method: String,
+    #[serde(default)]
+    params: Vec<String>,
+}
+
+// Answers requests naming a subcommand and its arguments, one JSON object
+// per line, so the frontend can keep a single process instead of starting
+// one per call. Each response carries the request's id and either the
+// subcommand's output as result or an error object with a kind and a
+// message. Progress still goes to
+// stderr, as it does for a single subcommand.
+fn serve() -> Result<(), Error> {
+    let stdin = std::io::stdin();
+    let mut stdout = std::io::stdout();
+    for line in stdin.lock().lines() {
+        let line = line.map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+        if line.trim().is_empty() {
+            continue;
+        }
+        let response = match serde_json::from_str::<Request>(&line) {
+            Err(e) => {
+                serde_json::json!({ "id": null, "error": error_report("usage", &e.to_string()) })
+                    .to_string()
+            }
+            Ok(request) => {
+                let argv = std::iter::once("nix-timemach-backend".to_string())
+                    .chain(std::iter::once(request.method))
+                    .chain(request.params);
+                let result = cli()
+                    .try_get_matches_from(argv)
+                    .map_err(|e| error_report("usage", &e.to_string()))
+                    .and_then(|matches| match matches.subcommand_name() {
+                        Some("serve") => Err(error_report("usage", "serve can't be nested")),
+                        Some("diff-stream") => Err(error_report(
+                            "usage",
+                            "diff-stream writes its own output and can't be served",
+                        )),
+                        _ => dispatch(&matches).map_err(|e| e.report()),
+                    });
+                match result {
+                    Ok(output) => format!(
+                        "{{\"id\":{},\"result\":{}}}",
+                        request.id,
+                        output.unwrap_or_else(|| "null".to_string())
+                    ),
+                    Err(error) => {
+                        serde_json::json!({ "id": request.id, "error": error }).to_string()
+                    }
+                }
+            }
+        };
+        writeln!(stdout, "{}", response).map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+        stdout
+            .flush()
+            .map_err(|e| Error::NixCommandFailed(e.to_string()))?;
+    }
+    Ok(())
+}
+
+// Errors end the process with a JSON error object as the last line of
+// stderr, after whatever the Nix tools printed there.
+fn main() {
+    let matches = cli().get_matches();
+
+    if let Some(store) = matches.get_one::<String>("store") {
+        // Every nix tool the backend runs reads NIX_CONFIG, so this reaches
+        // them all without threading the store through each call.
+        let mut config = std::env::var("NIX_CONFIG").unwrap_or_default();
+        if !config.is_empty() {
+            config.push('\n');
+        }
+        config.push_str(&format!("store = {}", store));
+        std::env::set_var("NIX_CONFIG", config);
+    }
+
+    let result = match matches.subcommand() {
+        Some(("serve", _)) => serve(),
+        Some(("diff-stream", matches)) => {
+            let profile = matches
+                .get_one::<String>("profile")
+                .map(String::as_str)
+                .unwrap_or(SYSTEM_PROFILE);
+            let from = matches.get_one::<String>("from").unwrap();
+            let to = matches.get_one::<String>("to").unwrap();
+            stream_diff(profile, from, to)
+        }
+        _ => dispatch(&matches).map(|output| {
+            if let Some(output) = output {
+                println!("{}", output);
+            }
+        }),
+    };
+    if let Err(e) = result {
+        eprintln!("{}", serde_json::json!({ "error": e.report() }));
+        std::process::exit(1);
+    }
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const bugReportURL = "https://github.com/qxrein/nix-timemach/issues"
+
+// crash is the first panic in the TUI. bubbletea recovers panics in its
+// event loop and in every Cmd goroutine, restoring the terminal; crash only
+// remembers what happened so it can be reported once the program is gone.
+type crash struct {
+	once  sync.Once
+	value any
+	stack []byte
+}
+
+// record notes a panic and lets it go on to bubbletea. It must be deferred.
+func (c *crash) record() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	c.once.Do(func() { c.value, c.stack = r, debug.Stack() })
+	panic(r)
+}
+
+// report tells the user about the crash after the terminal is restored.
+func (c *crash) report(debugLog bool) {
+	if debugLog {
+		log.Printf("panic: %v\n%s", c.value, c.stack)
+	}
+	fmt.Fprintf(os.Stderr, "nix-timemach crashed: %v\n\n", c.value)
+	fmt.Fprintf(os.Stderr, "This is a bug. Please report it at %s and include the trace below.\n\n%s", bugReportURL, c.stack)
+}
+
+// guarded records panics from a model's methods and the Cmds it returns.
+type guarded struct {
+	tea.Model
+	crash *crash
+}
+
+func (g guarded) Init() tea.Cmd {
+	defer g.crash.record()
+	return g.guard(g.Model.Init())
+}
+
+func (g guarded) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer g.crash.record()
+	m, cmd := g.Model.Update(msg)
+	return guarded{m, g.crash}, g.guard(cmd)
+}
+
+func (g guarded) View() string {
+	defer g.crash.record()
+	return g.Model.View()
+}
+
+// guard wraps cmd, and the Cmds of a batch it returns, which bubbletea runs
+// without passing them back through Update.
+func (g guarded) guard(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer g.crash.record()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i, c := range batch {
+				batch[i] = g.guard(c)
+			}
+		}
+		return msg
+	}
+}
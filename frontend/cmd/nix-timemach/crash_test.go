@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicky panics in Update on "update", and in a Cmd on "cmd", which runs
+// in a goroutine of its own.
+type panicky struct{}
+
+func (panicky) Init() tea.Cmd { return nil }
+
+func (m panicky) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg {
+	case "update":
+		panic("boom in update")
+	case "cmd":
+		return m, tea.Batch(func() tea.Msg { panic("boom in cmd") })
+	}
+	return m, nil
+}
+
+func (panicky) View() string { return "" }
+
+func TestGuardedRecordsPanics(t *testing.T) {
+	for _, msg := range []string{"update", "cmd"} {
+		t.Run(msg, func(t *testing.T) {
+			var c crash
+			var out bytes.Buffer
+			p := tea.NewProgram(guarded{panicky{}, &c}, tea.WithInput(nil), tea.WithOutput(&out))
+			go p.Send(msg)
+			p.Run()
+			if want := "boom in " + msg; c.value != want {
+				t.Errorf("recorded %v, want %q", c.value, want)
+			}
+			if len(c.stack) == 0 {
+				t.Error("no stack recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/export"
+	"nix-timemach/internal/models"
+)
+
+// Exit codes for headless commands. A policy failure is distinct from an
+// error so CI can tell "the diff is unacceptable" from "the tool broke".
+const (
+	exitOK     = 0
+	exitPolicy = 1
+	exitError  = 2
+)
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// diffPolicy is a set of conditions a diff must satisfy.
+type diffPolicy struct {
+	failOnRemoved  bool
+	failOnModified bool
+	expectAdded    []string
+}
+
+// violations returns a description of every condition the diff breaks.
+// An upgrade is a modification only, even from a backend that also lists
+// its old and new paths as removed and added.
+func (p diffPolicy) violations(diff models.GenerationDiff) []string {
+	removed := withoutUpgrades(diff.Removed, diff.Modified)
+	added := withoutUpgrades(diff.Added, diff.Modified)
+	var out []string
+	if p.failOnRemoved && len(removed) > 0 {
+		out = append(out, fmt.Sprintf("%d packages removed", len(removed)))
+	}
+	if p.failOnModified && len(diff.Modified) > 0 {
+		out = append(out, fmt.Sprintf("%d packages modified", len(diff.Modified)))
+	}
+	for _, pkg := range p.expectAdded {
+		if !containsPackage(models.Paths(added), pkg) {
+			out = append(out, fmt.Sprintf("expected %s to be added", pkg))
+		}
+	}
+	return out
+}
+
+// withoutUpgrades drops the changes that name a package in modified or
+// either of its paths.
+func withoutUpgrades(changes, modified []models.PackageChange) []models.PackageChange {
+	upgraded := make(map[string]bool, 3*len(modified))
+	for _, m := range modified {
+		upgraded[m.Name], upgraded[m.Path], upgraded[m.NewPath] = true, true, true
+	}
+	delete(upgraded, "")
+	var out []models.PackageChange
+	for _, c := range changes {
+		if !upgraded[c.Name] && !upgraded[c.Path] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func containsPackage(paths []string, pkg string) bool {
+	for _, p := range paths {
+		if _, name, _ := models.ParseStorePath(p); name == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// runDiff prints the diff between two generations and checks it against
+// the policy flags, returning the process exit code.
+func runDiff(client backend.Client, args []string) int {
+	return runPolicyDiff("diff", "<from> <to>", args, func(from, to string) (models.GenerationDiff, error) {
+		return client.GetDiff(context.Background(), from, to)
+	})
+}
+
+// runDiffFiles is runDiff for two exported package sets, computed without
+// the backend, so states captured on different machines can be compared.
+func runDiffFiles(args []string) int {
+	return runPolicyDiff("diff-files", "<a.json> <b.json>", args, func(a, b string) (models.GenerationDiff, error) {
+		files, err := backend.NewFiles([]string{a, b})
+		if err != nil {
+			return models.GenerationDiff{}, err
+		}
+		return files.GetDiff(context.Background(), a, b)
+	})
+}
+
+// runPolicyDiff implements the headless diff commands: it parses the policy
+// flags and two operands, prints the diff get returns and checks it.
+func runPolicyDiff(name, operands string, args []string, get func(a, b string) (models.GenerationDiff, error)) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var policy diffPolicy
+	fs.BoolVar(&policy.failOnRemoved, "fail-on-removed", false, "exit 1 if any package was removed")
+	fs.BoolVar(&policy.failOnModified, "fail-on-modified", false, "exit 1 if any package was modified")
+	fs.Var((*stringList)(&policy.expectAdded), "expect-added", "exit 1 unless `pkg` was added (repeatable)")
+	asJSON := fs.Bool("json", false, "print the diff as a JSON object")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: nix-timemach %s %s [flags]\n", name, operands)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	diff, err := get(positional[0], positional[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *asJSON {
+		err = export.Diff(os.Stdout, export.JSON, "", diff)
+	} else {
+		printDiff(diff)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if violations := policy.violations(diff); len(violations) > 0 {
+		for _, v := range violations {
+			fmt.Fprintf(os.Stderr, "policy: %s\n", v)
+		}
+		return exitPolicy
+	}
+	return exitOK
+}
+
+func printDiff(diff models.GenerationDiff) {
+	for _, item := range diff.Added {
+		fmt.Printf("+ %s\n", item.Path)
+	}
+	for _, item := range diff.Removed {
+		fmt.Printf("- %s\n", item.Path)
+	}
+	for _, item := range diff.Modified {
+		fmt.Printf("~ %s\n", item.Path)
+	}
+}
+
+// runExportDiff writes the diff between two generations as a document, to
+// stdout or a file, returning the process exit code.
+func runExportDiff(client backend.Client, args []string) int {
+	return runExport("export-diff", args, func(w io.Writer, format, from, to string) error {
+		diff, err := client.GetDiff(context.Background(), from, to)
+		if err != nil {
+			return err
+		}
+		return export.Diff(w, format, fmt.Sprintf("Generation %s → %s", from, to), diff)
+	})
+}
+
+// runChangelog writes what every generation from one to another changed,
+// grouped by day, returning the process exit code.
+func runChangelog(client backend.Client, args []string) int {
+	return runExport("changelog", args, func(w io.Writer, format, from, to string) error {
+		ctx := context.Background()
+		generations, err := client.GetGenerations(ctx)
+		if err != nil {
+			return err
+		}
+		in, err := export.Range(generations, from, to)
+		if err != nil {
+			return err
+		}
+		diffs, err := client.GetDiffsBulk(ctx, export.Pairs(in))
+		if err != nil {
+			return err
+		}
+		return export.Changelog(w, format, fmt.Sprintf("Changes from generation %s to %s", from, to), export.Days(in, diffs))
+	})
+}
+
+// runExport implements the headless export commands: it parses the format
+// flags and two generations, and writes what write produces to stdout or
+// the -o file.
+func runExport(name string, args []string, write func(w io.Writer, format, from, to string) error) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", "", "document `format`: "+strings.Join(export.Formats, ", ")+" (default from -o's extension, else md)")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: nix-timemach %s <from> <to> [flags]\n", name)
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return exitError
+	}
+	if *format == "" {
+		*format = export.Markdown
+		if *output != "" {
+			if *format, err = export.FormatOf(*output); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitError
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := write(&b, *format, positional[0], positional[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if *output == "" {
+		fmt.Print(b.String())
+		return exitOK
+	}
+	if err := os.WriteFile(*output, []byte(b.String()), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments, which the flag package alone does not allow.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
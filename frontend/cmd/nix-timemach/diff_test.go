@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+func TestRunDiffFiles(t *testing.T) {
+	before := filepath.Join("testdata", "before.json")
+	after := filepath.Join("testdata", "after.json")
+	upgraded := filepath.Join("testdata", "upgraded.json")
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"diff", []string{before, after}, exitOK},
+		{"same file", []string{before, before, "--fail-on-removed", "--fail-on-modified"}, exitOK},
+		{"removed", []string{before, after, "--fail-on-removed"}, exitPolicy},
+		{"modified", []string{"--fail-on-modified", before, after}, exitPolicy},
+		{"upgrade only", []string{before, upgraded, "--fail-on-removed"}, exitOK},
+		{"upgrade is not an addition", []string{before, upgraded, "--expect-added", "firefox"}, exitPolicy},
+		{"expected addition", []string{before, after, "--expect-added", "ripgrep"}, exitOK},
+		{"missing addition", []string{before, after, "--expect-added", "curl"}, exitPolicy},
+		{"json", []string{before, after, "--json"}, exitOK},
+		{"missing file", []string{before, filepath.Join("testdata", "missing.json")}, exitError},
+		{"one operand", []string{before}, exitError},
+		{"unknown flag", []string{before, after, "--frobnicate"}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runDiffFiles(tt.args); got != tt.want {
+				t.Errorf("runDiffFiles(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffPolicyIgnoresUpgrades(t *testing.T) {
+	const (
+		firefox128 = "/nix/store/jl3v0vqjji50sh8433shdh86i8g81n9i-firefox-128.0.3"
+		firefox129 = "/nix/store/gv2892rbj5qc5j63ckiw585ka2ax3xd8-firefox-129.0.1"
+		htop       = "/nix/store/0r4d2m3wq6f1c5h7j9k2l4n6p8s0v2x4-htop-3.3.0"
+	)
+	// A backend may list an upgrade's paths as removed and added as well.
+	diff := models.GenerationDiff{
+		Added:    []models.PackageChange{{Path: firefox129}},
+		Removed:  []models.PackageChange{{Path: firefox128}, {Path: htop}},
+		Modified: []models.PackageChange{{Path: firefox128, NewPath: firefox129}},
+	}
+	diff.FillNames()
+	policy := diffPolicy{failOnRemoved: true, expectAdded: []string{"firefox"}}
+	want := []string{"1 packages removed", "expected firefox to be added"}
+	if got := policy.violations(diff); !slices.Equal(got, want) {
+		t.Errorf("violations = %q, want %q", got, want)
+	}
+}
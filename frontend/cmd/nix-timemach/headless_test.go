@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/config"
+)
+
+func TestHeadlessExitCodes(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	generations, err := backend.NewDemo().GetGenerations(context.Background())
+	if err != nil || len(generations) < 3 {
+		t.Fatalf("the demo system has %d generations: %v", len(generations), err)
+	}
+	oldest, older, newest := generations[len(generations)-1].ID, generations[len(generations)-2].ID, generations[0].ID
+	out := filepath.Join(t.TempDir(), "diff.md")
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    int
+	}{
+		{"list", "list", nil, exitOK},
+		{"list json", "list", []string{"--json", "--no-sizes"}, exitOK},
+		{"list tsv", "list", []string{"--separator", `\t`, "--no-header"}, exitOK},
+		{"list operand", "list", []string{"extra"}, exitError},
+		{"list unknown flag", "list", []string{"--frobnicate"}, exitError},
+		{"diff", "diff", []string{oldest, newest}, exitOK},
+		{"diff json", "diff", []string{oldest, newest, "--json"}, exitOK},
+		{"diff policy", "diff", []string{oldest, newest, "--expect-added", "no-such-package"}, exitPolicy},
+		{"diff unknown generation", "diff", []string{oldest, "9999"}, exitError},
+		{"diff one operand", "diff", []string{oldest}, exitError},
+		{"export-diff", "export-diff", []string{oldest, newest, "-o", out}, exitOK},
+		{"export-diff bad format", "export-diff", []string{oldest, newest, "--format", "docx"}, exitError},
+		{"changelog", "changelog", []string{oldest, newest}, exitOK},
+		{"changelog unknown generation", "changelog", []string{"9999", newest}, exitError},
+		{"snapshot save", "snapshot", []string{"save", "before", older}, exitOK},
+		{"snapshot list", "snapshot", []string{"list"}, exitOK},
+		{"snapshot bad name", "snapshot", []string{"save", "../escape"}, exitError},
+		{"snapshot no subcommand", "snapshot", nil, exitError},
+		{"rollback", "rollback", []string{older}, exitOK},
+		{"rollback json", "rollback", []string{newest, "--json"}, exitOK},
+		{"rollback unknown generation", "rollback", []string{"9999"}, exitError},
+		{"rollback no operand", "rollback", nil, exitError},
+	}
+	client := backend.NewDemo()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, ok := headless[tt.command]
+			if !ok {
+				t.Fatalf("no headless command %q", tt.command)
+			}
+			if got := run(client, tt.args); got != tt.want {
+				t.Errorf("%s %q = %d, want %d", tt.command, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadlessClient(t *testing.T) {
+	tests := []struct {
+		name, backend string
+		wantErr       bool
+	}{
+		{"demo", "demo", false},
+		{"native", "native", false},
+		{"unknown", "carrier-pigeon", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NIX_TIMEMACH_BACKEND", tt.backend)
+			_, err := headlessClient(config.Config{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("headlessClient with %s: error %v, want error %v", tt.backend, err, tt.wantErr)
+			}
+		})
+	}
+}
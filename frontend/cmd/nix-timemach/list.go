@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// runList prints every generation as a table or as JSON, returning the
+// process exit code.
+func runList(client backend.Client, args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	noHeader := fs.Bool("no-header", false, "omit the column header row")
+	separator := fs.String("separator", "", "join fields with `sep` instead of aligning columns (\\t for TSV)")
+	noSizes := fs.Bool("no-sizes", false, "skip querying closure sizes, which is slow on large stores")
+	asJSON := fs.Bool("json", false, "print the generations as a JSON array")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nix-timemach list [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	get := client.GetGenerationsWithSizes
+	if *noSizes {
+		get = client.GetGenerations
+	}
+	generations, err := get(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *asJSON {
+		if generations == nil {
+			generations = []models.Generation{}
+		}
+		if err := printJSON(generations); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	var rows [][]string
+	if !*noHeader {
+		rows = append(rows, listing.Header)
+	}
+	for _, gen := range generations {
+		rows = append(rows, listing.Row(gen))
+	}
+	if err := listing.Write(os.Stdout, rows, unescapeSeparator(*separator)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// unescapeSeparator lets "\t" be typed without shell quoting tricks.
+func unescapeSeparator(sep string) string {
+	return strings.ReplaceAll(sep, `\t`, "\t")
+}
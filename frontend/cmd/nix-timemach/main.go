@@ -1,25 +1,406 @@
 package main
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"nix-timemach/internal/audit"
 	"nix-timemach/internal/backend"
+	"nix-timemach/internal/cache"
+	"nix-timemach/internal/config"
+	"nix-timemach/internal/i18n"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
 	"nix-timemach/internal/ui"
 )
 
+// missingBackendHint follows a backend.MissingBinaryError from a headless
+// command; the TUI explains the same on its first-run screen.
+const missingBackendHint = "Build it with cargo build --release in the backend directory, install it on $PATH, or set NIX_TIMEMACH_BACKEND_PATH to it; NIX_TIMEMACH_BACKEND=native needs no backend binary."
+
+// defaultTimeout bounds backend calls that only read, long enough for a
+// slow store but short enough that a hung backend doesn't go unnoticed.
+const defaultTimeout = 2 * time.Minute
+
 func main() {
-	client := backend.NewClient("../backend/target/release/nix-timemach-backend")
-	app := ui.NewApp(client)
-	p := tea.NewProgram(
-		app,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
-
-	if err := p.Start(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.DateFormat != "" {
+		listing.TimeLayout = cfg.DateFormat
+	}
+	if cfg.Timezone != "" {
+		if err := setTimezone(cfg.Timezone); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: config timezone: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff-files" {
+		// Compares exported files, so it needs no backend.
+		os.Exit(runDiffFiles(os.Args[2:]))
+	}
+	if len(os.Args) > 1 {
+		if run, ok := headless[os.Args[1]]; ok {
+			client, err := headlessClient(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				var missing *backend.MissingBinaryError
+				if errors.As(err, &missing) {
+					fmt.Fprintln(os.Stderr, missingBackendHint)
+				}
+				os.Exit(1)
+			}
+			os.Exit(run(client, os.Args[2:]))
+		}
+	}
+
+	noConfirmQuit := flag.Bool("no-confirm-quit", false, "quit without asking when actions are pending")
+	pending := flag.Bool("pending", false, "start with the diff of what a rebuild would change")
+	statusInterval := flag.Duration("status-interval", 5*time.Second, "how often the status bar updates")
+	clock := flag.Bool("clock", false, "show the current time in the status bar")
+	dateFormat := flag.String("date-format", listing.TimeLayout, "Go time `layout` timestamps are shown in")
+	timezone := flag.String("timezone", cfg.Timezone, "IANA time `zone` timestamps are shown in, e.g. UTC (default local)")
+	timestamps := flag.String("timestamps", cmp.Or(cfg.Timestamps, ui.TimestampModes[0]), "how the list shows times: "+strings.Join(ui.TimestampModes, ", "))
+	noDayGroups := flag.Bool("no-day-groups", false, "list generations without headings for the day they were made")
+	noColor := flag.Bool("no-color", os.Getenv("NO_COLOR") != "", "draw without colors or other styling (default $NO_COLOR set)")
+	ascii := flag.Bool("ascii", false, "draw with ASCII characters only, for dumb terminals and serial consoles")
+	noMouse := flag.Bool("no-mouse", os.Getenv("NIX_TIMEMACH_NO_MOUSE") != "", "disable mouse reporting")
+	hashLen := flag.Int("hash-len", 8, "store hash characters to show; 0 shows full paths")
+	sudo := flag.Bool("sudo", false, "run system-modifying actions through sudo, prompting for a password (default when not root and sudo or pkexec is installed; --sudo=false turns it off)")
+	sudoProgram := flag.String("sudo-program", "sudo", "escalation program for --sudo: sudo or pkexec")
+	store := flag.String("store", os.Getenv("NIX_TIMEMACH_STORE"), "Nix store `path or URL` to operate on (default $NIX_TIMEMACH_STORE)")
+	backendKind := flag.String("backend", os.Getenv("NIX_TIMEMACH_BACKEND"), "`binary` runs the backend binary, native runs the Nix tools directly, demo makes up a system (default $NIX_TIMEMACH_BACKEND or binary)")
+	demo := flag.Bool("demo", false, "browse a made-up system instead of this one, to try things without Nix; same as --backend demo")
+	host := flag.String("host", os.Getenv("NIX_TIMEMACH_HOST"), "inspect the generations of `user@machine` over ssh (default $NIX_TIMEMACH_HOST)")
+	persistent := flag.Bool("persistent", true, "keep one backend process running instead of starting one per call")
+	timeout := flag.Duration("timeout", defaultTimeout, "give up on backend calls that take longer; 0 waits forever")
+	readOnly := flag.Bool("read-only", false, "disable every action that modifies the system")
+	trashDays := flag.Int("trash-days", models.DefaultTrashDays, "keep deleted generations restorable from the trash for this many days; 0 deletes them outright")
+	noSizes := flag.Bool("no-sizes", false, "don't query closure sizes for the generation list")
+	noDiskCache := flag.Bool("no-disk-cache", false, "keep computed diffs in memory only, not under ~/.cache")
+	noPrefetch := flag.Bool("no-prefetch", false, "don't compute the diffs around the cursor ahead of time")
+	noPreview := flag.Bool("no-preview", false, "start without the preview pane beside the generation list")
+	noWatch := flag.Bool("no-watch", false, "don't reload the list when the profile changes, e.g. after a rebuild elsewhere")
+	lang := flag.String("lang", i18n.Detect(), "language for UI messages")
+	ageBuckets := flag.String("age-buckets", "24h,168h,720h", "comma-separated ages at which timestamp colors change; empty disables")
+	rollbackRank := flag.String("rollback-rank", strings.Join(ui.DefaultRollbackRank, ","), "comma-separated criteria rollback advice ranks by: removed, modified, added, changes, size")
+	themeName := flag.String("theme", cmp.Or(cfg.ThemePreset, "default"), "built-in color theme: "+strings.Join(ui.ThemePresets, ", "))
+	themeFile := flag.String("theme-file", cfg.Theme, "load colors from a JSON theme `file`, over the --theme ones")
+	profile := flag.String("profile", cfg.Profile, "start on the profile with this `name or path`")
+	debugLog := flag.String("debug-log", "", "write debug logging to `file`")
+	flag.Parse()
+	if *demo {
+		*backendKind = "demo"
+	}
+
+	code := exitOK
+	// Deferred ahead of the debug log's Close so that runs first; a crash
+	// in the TUI ends main normally and exits here.
+	defer func() {
+		if code != exitOK {
+			os.Exit(code)
+		}
+	}()
+	if *debugLog != "" {
+		f, err := tea.LogToFile(*debugLog, "nix-timemach")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening debug log: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+	}
+
+	var clientOpts []backend.Option
+	switch {
+	case *sudo:
+		clientOpts = append(clientOpts, backend.WithEscalation(*sudoProgram))
+	case !flagSet("sudo") && !*readOnly && *host == "":
+		// A remote login may be root already, which can't be told from
+		// here, so hosts escalate only when asked to.
+		if program := backend.DetectEscalation(); program != "" {
+			clientOpts = append(clientOpts, backend.WithEscalation(program))
+		}
+	}
+	if *readOnly {
+		clientOpts = append(clientOpts, backend.ReadOnly())
+	}
+	if *persistent {
+		clientOpts = append(clientOpts, backend.Persistent())
+	}
+	clientOpts = append(clientOpts, backend.WithTimeout(*timeout))
+	if *store != "" {
+		// A store on a remote host can only be checked there.
+		if err := backend.ValidateStore(*store); err != nil && *host == "" {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		clientOpts = append(clientOpts, backend.WithStore(*store))
+	}
+	client, err := newClient(*backendKind, cfg.Backend, *host, clientOpts...)
+	var missing *backend.MissingBinaryError
+	switch {
+	case errors.As(err, &missing):
+		// The TUI opens on instructions instead, with the demo one key
+		// away; the client is never called.
+		client = backend.NewProcess(backend.BinaryName, clientOpts...)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: --backend: %v\n", err)
+		os.Exit(1)
+	}
+	cacheDir := ""
+	// The demo's diffs are made up on the spot and not worth keeping.
+	if !*noDiskCache && *backendKind != "demo" {
+		// Without a cache directory diffs are still kept in memory.
+		cacheDir, _ = cache.Dir()
+	}
+	client = cache.Wrap(client, cacheDir)
+
+	// The fleet view is for looking across machines; a single --host
+	// browses just that one, and the demo only its made-up one.
+	var hosts []ui.Host
+	if *host == "" && *backendKind != "demo" {
+		for _, h := range cfg.Hosts {
+			c, err := newClient(*backendKind, cfg.Backend, h, clientOpts...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: host %s: %v\n", h, err)
+				os.Exit(1)
+			}
+			hosts = append(hosts, ui.Host{Name: h, Client: cache.Wrap(c, cacheDir)})
+		}
+	}
+
+	buckets, err := parseDurations(*ageBuckets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --age-buckets: %v\n", err)
+		os.Exit(1)
+	}
+
+	rank, err := ui.ParseRollbackRank(*rollbackRank)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --rollback-rank: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ui.ValidateKeys(cfg.Keys); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	preset, err := ui.PresetTheme(*themeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --theme: %v\n", err)
+		os.Exit(1)
+	}
+	theme, themeTitle := &preset, *themeName
+	if *themeFile != "" {
+		t, err := ui.LoadTheme(*themeFile, preset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; using the %s theme\n", err, *themeName)
+		} else {
+			theme, themeTitle = &t, filepath.Base(*themeFile)
+		}
+	}
+
+	if *noColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	listing.TimeLayout = *dateFormat
+	if err := setTimezone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --timezone: %v\n", err)
+		os.Exit(1)
+	}
+	if !slices.Contains(ui.TimestampModes, *timestamps) {
+		fmt.Fprintf(os.Stderr, "Error: --timestamps: want one of %s, got %q\n", strings.Join(ui.TimestampModes, ", "), *timestamps)
 		os.Exit(1)
 	}
+
+	app := ui.NewApp(client, ui.Options{
+		ConfirmQuit:    !*noConfirmQuit,
+		Pending:        *pending,
+		StatusInterval: *statusInterval,
+		Clock:          *clock,
+		HashLen:        *hashLen,
+		Lang:           *lang,
+		Theme:          theme,
+		ThemeName:      themeTitle,
+		ASCII:          *ascii,
+		Timestamps:     *timestamps,
+		DayGroups:      !*noDayGroups,
+		ReadOnly:       *readOnly,
+		AgeBuckets:     buckets,
+		RollbackRank:   rank,
+		Sizes:          !*noSizes,
+		Preview:        !*noPreview,
+		Prefetch:       !*noPrefetch,
+		Profile:        *profile,
+		Keys:           cfg.Keys,
+		Hosts:          hosts,
+		Reference:      cfg.ReferenceHost,
+		Retention:      cfg.Retention,
+		TrashDays:      max(0, *trashDays),
+		Watch:          !*noWatch,
+		MissingBackend: missing,
+	})
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if !*noMouse && !mouseUnsupported() {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	var c crash
+	p := tea.NewProgram(guarded{app, &c}, opts...)
+
+	_, err = p.Run()
+	switch {
+	case c.value != nil:
+		c.report(*debugLog != "")
+		code = exitError
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+		code = 1
+	}
+}
+
+// headless holds the commands that run without the TUI and ask a backend,
+// by name. Each returns the process exit code.
+var headless = map[string]func(backend.Client, []string) int{
+	"watch": func(client backend.Client, args []string) int {
+		if err := runWatch(client, args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return exitOK
+	},
+	"diff":        runDiff,
+	"export-diff": runExportDiff,
+	"changelog":   runChangelog,
+	"list":        runList,
+	"snapshot":    runSnapshot,
+	"rollback":    runRollback,
+	"serve":       runServe,
+}
+
+// headlessClient is the Client for a headless command. Those take the
+// store, host and timeout from the environment, as they don't share the
+// TUI's flags.
+func headlessClient(cfg config.Config) (backend.Client, error) {
+	var opts []backend.Option
+	if store := os.Getenv("NIX_TIMEMACH_STORE"); store != "" {
+		opts = append(opts, backend.WithStore(store))
+	}
+	timeout := defaultTimeout
+	if s := os.Getenv("NIX_TIMEMACH_TIMEOUT"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NIX_TIMEMACH_TIMEOUT: %w", err)
+		}
+		timeout = d
+	}
+	opts = append(opts, backend.WithTimeout(timeout))
+	host := os.Getenv("NIX_TIMEMACH_HOST")
+	if host == "" {
+		if program := backend.DetectEscalation(); program != "" {
+			opts = append(opts, backend.WithEscalation(program))
+		}
+	}
+	return newClient(os.Getenv("NIX_TIMEMACH_BACKEND"), cfg.Backend, host, opts...)
+}
+
+// newClient returns the Client named by kind, running on host if there is
+// one, with its changes to the system logged to the audit log. The backend
+// binary is looked for only when kind runs it locally; on a host it is the
+// one on the host's $PATH, as local paths mean nothing there.
+func newClient(kind, configured, host string, opts ...backend.Option) (backend.Client, error) {
+	path := backend.BinaryName
+	if host != "" {
+		opts = append(opts, backend.WithHost(host))
+	} else if kind == "" || kind == "binary" {
+		p, err := backend.FindBinary(configured)
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	client, err := backend.New(kind, path, opts...)
+	if err != nil || kind == "demo" {
+		// The demo changes nothing worth logging.
+		return client, err
+	}
+	return audit.Wrap(client), nil
+}
+
+// mouseUnsupported reports terminals known to print mouse reports as stray
+// characters instead of interpreting them.
+func mouseUnsupported() bool {
+	if os.Getenv("INSIDE_EMACS") != "" {
+		return true
+	}
+	term := os.Getenv("TERM")
+	return term == "" || term == "dumb" || term == "linux" || strings.HasPrefix(term, "vt")
+}
+
+// parseDurations parses a comma-separated list of ascending durations.
+func parseDurations(s string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(durations); n > 0 && d <= durations[n-1] {
+			return nil, fmt.Errorf("%s is not greater than %s", d, durations[n-1])
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
+
+// printJSON writes v to stdout as indented JSON, for the headless commands'
+// --json output.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// setTimezone shows timestamps in the IANA time zone name; empty is the
+// local one.
+func setTimezone(name string) error {
+	if name == "" {
+		listing.Location = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	listing.Location = loc
+	return nil
+}
+
+// flagSet reports whether the flag called name was given on the command
+// line, as opposed to left at its default.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
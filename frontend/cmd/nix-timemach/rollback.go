@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"nix-timemach/internal/backend"
+)
+
+// runRollback switches the system to a generation without the TUI, for
+// scripts and sessions without a terminal, returning the process exit code.
+// It doesn't ask first: running it is the confirmation.
+func runRollback(client backend.Client, args []string) int {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the result as a JSON object")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nix-timemach rollback <generation> [flags]")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitError
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return exitError
+	}
+	id := positional[0]
+
+	// sudo asks for a password here, on the terminal, before the rollback
+	// runs it non-interactively.
+	if cmd := client.AuthorizeCommand(); cmd != nil {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v: %v\n", backend.ErrEscalation, err)
+			return exitError
+		}
+	}
+	if err := client.Rollback(context.Background(), id); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *asJSON {
+		err = printJSON(struct {
+			RolledBackTo string `json:"rolled_back_to"`
+		}{id})
+	} else {
+		_, err = fmt.Printf("rolled back to generation %s\n", id)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/snapshot"
+)
+
+const snapshotUsage = `usage:
+  nix-timemach snapshot save <name> [generation]
+  nix-timemach snapshot list
+  nix-timemach snapshot delete <name>
+  nix-timemach snapshot diff <name> <generation>`
+
+var errSnapshotUsage = errors.New("bad usage")
+
+// runSnapshot manages named package-set snapshots, returning the process
+// exit code.
+func runSnapshot(client backend.Client, args []string) int {
+	err := snapshotCommand(client, args)
+	if errors.Is(err, errSnapshotUsage) {
+		fmt.Fprintln(os.Stderr, snapshotUsage)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// snapshotCommand runs one snapshot subcommand. save records the given
+// generation, or the running system when none is given.
+func snapshotCommand(client backend.Client, args []string) error {
+	if len(args) == 0 {
+		return errSnapshotUsage
+	}
+	cmd, args := args[0], args[1:]
+
+	switch {
+	case cmd == "save" && (len(args) == 1 || len(args) == 2):
+		if err := snapshot.ValidName(args[0]); err != nil {
+			return err
+		}
+		var id string
+		if len(args) == 2 {
+			id = args[1]
+		}
+		paths, err := client.GetPackages(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		s := snapshot.Snapshot{Name: args[0], Generation: id, Created: time.Now(), Paths: paths}
+		if err := snapshot.Save(s); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "saved %d packages as %q\n", len(paths), s.Name)
+
+	case cmd == "list" && len(args) == 0:
+		snapshots, err := snapshot.List()
+		if err != nil {
+			return err
+		}
+		for _, s := range snapshots {
+			source := s.Generation
+			if source == "" {
+				source = "current"
+			}
+			fmt.Printf("%s\t%s\t%s\t%d\n", s.Name, s.Created.Format(time.RFC3339), source, len(s.Paths))
+		}
+
+	case cmd == "delete" && len(args) == 1:
+		return snapshot.Delete(args[0])
+
+	case cmd == "diff" && len(args) == 2:
+		diff, err := client.GetDiffAgainstSnapshot(context.Background(), args[1], args[0])
+		if err != nil {
+			return err
+		}
+		printDiff(diff)
+
+	default:
+		return errSnapshotUsage
+	}
+	return nil
+}
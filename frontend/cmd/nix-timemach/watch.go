@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/notify"
+	"nix-timemach/internal/watch"
+)
+
+// profilesDir is where the system profile and its generations' links are.
+const profilesDir = "/nix/var/nix/profiles"
+
+// watcher says what runWatch does with the generations it sees appear.
+type watcher struct {
+	client backend.Client
+	// desktop and webhook are where daemon mode sends its notifications.
+	desktop bool
+	webhook string
+	// threshold is the closure size, in bytes, past which a new generation
+	// is reported; 0 is none.
+	threshold int64
+}
+
+// runWatch polls the backend and prints a line for every generation that
+// appears after the first poll, until interrupted. As a daemon it also
+// wakes as soon as the profile changes, and sends notifications.
+func runWatch(client backend.Client, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", 5*time.Second, "time between polls")
+	daemon := fs.Bool("daemon", false, "run in the background, waking on profile changes, and send notifications")
+	noDesktop := fs.Bool("no-desktop", false, "with --daemon, don't show desktop notifications with notify-send")
+	webhook := fs.String("webhook", "", "with --daemon, `URL` to post each event to as JSON")
+	threshold := fs.String("size-threshold", "", "with --daemon, report a new generation whose closure grows past `SIZE`, e.g. 20G")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", *interval)
+	}
+	w := watcher{client: client}
+	if *daemon {
+		w.desktop, w.webhook = !*noDesktop, *webhook
+		if *threshold != "" {
+			n, err := listing.ParseSize(*threshold)
+			if err != nil {
+				return err
+			}
+			w.threshold = n
+		}
+	} else if *noDesktop || *webhook != "" || *threshold != "" {
+		return errors.New("--no-desktop, --webhook and --size-threshold need --daemon")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	generations, err := w.generations(ctx)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(generations))
+	for _, gen := range generations {
+		seen[gen.ID] = true
+	}
+	last := newest(generations)
+
+	// A daemon on this machine wakes when the profile changes and keeps
+	// polling only to catch what the watch misses. Over ssh there's
+	// nothing to watch.
+	var changes <-chan struct{}
+	if *daemon && client.Host() == "" && client.Store() == "" {
+		changes, err = watch.Changes(ctx, profilesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v; polling instead\n", err)
+		}
+	}
+	if changes != nil {
+		fmt.Fprintf(os.Stderr, "watching %d generations in %s, and polling every %s\n", len(seen), profilesDir, *interval)
+	} else {
+		fmt.Fprintf(os.Stderr, "watching %d generations every %s\n", len(seen), *interval)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+		}
+
+		generations, err := w.generations(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// A transient backend failure shouldn't end a long-running watch.
+			fmt.Fprintf(os.Stderr, "poll failed: %v\n", err)
+			continue
+		}
+		for _, gen := range newGenerations(seen, generations) {
+			seen[gen.ID] = true
+			fmt.Printf("%s\t%s\t%s\n", gen.ID, listing.Timestamp(gen.Timestamp), gen.Description)
+			if *daemon {
+				w.report(ctx, last, gen)
+			}
+			if gen.Timestamp.After(last.Timestamp) {
+				last = gen
+			}
+		}
+	}
+}
+
+// generations lists the profile, with closure sizes when there's a
+// threshold to compare them to.
+func (w watcher) generations(ctx context.Context) ([]models.Generation, error) {
+	if w.threshold > 0 {
+		return w.client.GetGenerationsWithSizes(ctx)
+	}
+	return w.client.GetGenerations(ctx)
+}
+
+// report sends the notifications for gen, which appeared after prev. A
+// closure is reported when it grows past the threshold, not every time
+// it stays there. Failures are printed; the daemon carries on.
+func (w watcher) report(ctx context.Context, prev, gen models.Generation) {
+	events := []notify.Event{{
+		Kind:    notify.KindNewGeneration,
+		Message: fmt.Sprintf("New generation %s: %s", gen.ID, gen.Description),
+	}}
+	if w.threshold > 0 && gen.ClosureSize >= w.threshold && prev.ClosureSize < w.threshold {
+		events = append(events, notify.Event{
+			Kind:        notify.KindSizeThreshold,
+			ClosureSize: gen.ClosureSize,
+			Threshold:   w.threshold,
+			Message: fmt.Sprintf("Generation %s's closure is %s, past %s",
+				gen.ID, listing.HumanSize(gen.ClosureSize), listing.HumanSize(w.threshold)),
+		})
+	}
+
+	title := "nix-timemach"
+	if host := w.client.Host(); host != "" {
+		title += " on " + host
+	}
+	for _, e := range events {
+		e.Host, e.Generation, e.Timestamp, e.Description = w.client.Host(), gen.ID, gen.Timestamp, gen.Description
+		if w.desktop {
+			if err := notify.Desktop(ctx, title, e.Message); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		if w.webhook != "" {
+			if err := notify.Webhook(ctx, w.webhook, e); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+// newest returns the latest of generations, or the zero Generation.
+func newest(generations []models.Generation) models.Generation {
+	var last models.Generation
+	for _, gen := range generations {
+		if gen.Timestamp.After(last.Timestamp) {
+			last = gen
+		}
+	}
+	return last
+}
+
+// newGenerations returns the generations whose IDs are not in seen.
+func newGenerations(seen map[string]bool, generations []models.Generation) []models.Generation {
+	var fresh []models.Generation
+	for _, gen := range generations {
+		if !seen[gen.ID] {
+			fresh = append(fresh, gen)
+		}
+	}
+	return fresh
+}
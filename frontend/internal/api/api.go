@@ -0,0 +1,134 @@
+// Package api serves what a Client knows about generations as a small JSON
+// API over HTTP, for other tools, along with a dashboard in the browser
+// built on it. It only reads: nothing it serves changes the system.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// Details is a generation with what the details view shows besides.
+type Details struct {
+	Generation models.Generation `json:"generation"`
+	Packages   []string          `json:"packages"`
+}
+
+// errNotFound is a generation the profile doesn't have.
+var errNotFound = errors.New("no such generation")
+
+// Handler serves client's generations, and at / a read-only dashboard of
+// them for the browser:
+//
+//	GET /api/generations                list, with ?sizes=true closure sizes
+//	GET /api/generations/{id}           one generation and its packages
+//	GET /api/diff?from=ID&to=ID         what changed between two
+//	GET /api/diff/stats?from=ID&to=ID   how much changed between two
+func Handler(client backend.Client) http.Handler {
+	s := &server{client: client}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/generations", s.generations)
+	mux.HandleFunc("GET /api/generations/{id}", s.details)
+	mux.HandleFunc("GET /api/diff", s.diff)
+	mux.HandleFunc("GET /api/diff/stats", s.diffStats)
+	mux.Handle("GET /", dashboard())
+	return mux
+}
+
+type server struct {
+	client backend.Client
+}
+
+func (s *server) generations(w http.ResponseWriter, r *http.Request) {
+	get := s.client.GetGenerations
+	if r.URL.Query().Get("sizes") == "true" {
+		get = s.client.GetGenerationsWithSizes
+	}
+	generations, err := get(r.Context())
+	if generations == nil {
+		generations = []models.Generation{}
+	}
+	reply(w, generations, err)
+}
+
+func (s *server) details(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	generations, err := s.client.GetGenerations(r.Context())
+	if err != nil {
+		reply(w, nil, err)
+		return
+	}
+	for _, gen := range generations {
+		if gen.ID != id {
+			continue
+		}
+		packages, err := s.client.GetPackages(r.Context(), id)
+		if packages == nil {
+			packages = []string{}
+		}
+		reply(w, Details{Generation: gen, Packages: packages}, err)
+		return
+	}
+	reply(w, nil, errNotFound)
+}
+
+func (s *server) diff(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := pair(w, r)
+	if !ok {
+		return
+	}
+	diff, err := s.client.GetDiff(r.Context(), from, to)
+	reply(w, diff, err)
+}
+
+func (s *server) diffStats(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := pair(w, r)
+	if !ok {
+		return
+	}
+	stats, err := s.client.GetDiffStats(r.Context(), from, to)
+	reply(w, stats, err)
+}
+
+// pair reads the from and to generations of a diff, answering the request
+// itself when either is missing.
+func pair(w http.ResponseWriter, r *http.Request) (from, to string, ok bool) {
+	q := r.URL.Query()
+	from, to = q.Get("from"), q.Get("to")
+	if from == "" || to == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody{"from and to are required"})
+		return "", "", false
+	}
+	return from, to, true
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// reply writes v, or err as {"error": ...} if there was one.
+func reply(w http.ResponseWriter, v any, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		writeJSON(w, http.StatusNotFound, errorBody{err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, errorBody{err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		// The status is out; all that's left is to say why the body
+		// stopped short.
+		log.Printf("failed to write a response: %v", err)
+	}
+}
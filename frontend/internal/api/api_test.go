@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+func get(t *testing.T, h http.Handler, url string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s: Content-Type %q, want application/json", url, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: %v in %s", url, err, rec.Body)
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(backend.NewDemo())
+
+	var generations []models.Generation
+	if code := get(t, h, "/api/generations", &generations); code != http.StatusOK || len(generations) == 0 {
+		t.Fatalf("generations: %d, %d generations", code, len(generations))
+	}
+	var sized []models.Generation
+	if get(t, h, "/api/generations?sizes=true", &sized); len(sized) == 0 || sized[0].ClosureSize == 0 {
+		t.Errorf("generations with sizes have none: %+v", sized)
+	}
+
+	var details Details
+	if code := get(t, h, "/api/generations/12", &details); code != http.StatusOK || details.Generation.ID != "12" || len(details.Packages) == 0 {
+		t.Errorf("details: %d, %+v", code, details)
+	}
+
+	var diff models.GenerationDiff
+	if code := get(t, h, "/api/diff?from=1&to=42", &diff); code != http.StatusOK || len(diff.Modified) == 0 {
+		t.Errorf("diff: %d, %+v", code, models.StatsOf(diff))
+	}
+	var stats models.DiffStats
+	code := get(t, h, "/api/diff/stats?from=1&to=42", &stats)
+	if want := models.StatsOf(diff); code != http.StatusOK || stats.Added != want.Added || stats.Removed != want.Removed || stats.Modified != want.Modified {
+		t.Errorf("stats: %d, %+v, want the counts of %+v", code, stats, want)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := Handler(backend.NewDemo())
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"/api/generations/999", http.StatusNotFound},
+		{"/api/diff?from=1", http.StatusBadRequest},
+		{"/api/diff/stats?to=2", http.StatusBadRequest},
+		{"/api/diff?from=1&to=999", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		var body errorBody
+		if code := get(t, h, tt.url, &body); code != tt.want || body.Error == "" {
+			t.Errorf("GET %s = %d %+v, want %d with an error", tt.url, code, body, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/generations", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/generations = %d, want %d: the API only reads", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
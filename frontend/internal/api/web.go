@@ -0,0 +1,22 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// The dashboard is a page, a script and a style sheet that read the API,
+// built into the program so that serve needs nothing installed beside it.
+//
+//go:embed web
+var web embed.FS
+
+// dashboard serves the dashboard's files.
+func dashboard() http.Handler {
+	files, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
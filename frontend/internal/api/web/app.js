@@ -0,0 +1,130 @@
+// The dashboard only reads from the API next to it, so it works wherever
+// serve is reachable and can't change the system.
+"use strict";
+
+const timeline = document.getElementById("timeline");
+const detail = document.getElementById("detail");
+const summary = document.getElementById("summary");
+
+let generations = [];
+
+async function getJSON(url) {
+  const resp = await fetch(url);
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+// el builds an element; text is set as text, never parsed as HTML, as
+// descriptions and paths come from the system.
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k === "class") e.className = v;
+    else if (k.startsWith("on")) e.addEventListener(k.slice(2), v);
+    else e.setAttribute(k, v);
+  }
+  for (const c of children) {
+    if (c != null) e.append(c);
+  }
+  return e;
+}
+
+function humanSize(n) {
+  const sign = n < 0 ? "-" : "";
+  n = Math.abs(n);
+  if (n < 1024) return sign + n + " B";
+  let exp = 0;
+  while (n >= 1024 && exp < 6) {
+    n /= 1024;
+    exp++;
+  }
+  return sign + n.toFixed(1) + " " + "KMGTPE"[exp - 1] + "iB";
+}
+
+function when(ts) {
+  const d = new Date(ts);
+  if (d.getFullYear() < 1970) return "unknown time";
+  return d.toLocaleString();
+}
+
+function renderTimeline() {
+  const newest = [...generations].reverse();
+  summary.textContent = generations.length + " generations";
+  const list = el("ol");
+  for (const gen of newest) {
+    const tags = [];
+    if (gen.current) tags.push("current");
+    if (gen.booted) tags.push("booted");
+    if (gen.known_good) tags.push("known good");
+    if (gen.pinned) tags.push(gen.pin_name ? "pinned: " + gen.pin_name : "pinned");
+    const item = el("li", { tabindex: "0", "data-id": gen.id, onclick: () => select(gen.id) },
+      el("span", { class: "id" }, "#" + gen.id), " ",
+      el("time", {}, when(gen.timestamp)),
+      ...tags.map((t) => el("span", { class: "tag" }, t)),
+      gen.description ? el("div", {}, gen.description) : null,
+      gen.closure_size > 0 ? el("div", { class: "muted" }, humanSize(gen.closure_size)) : null);
+    item.addEventListener("keydown", (e) => {
+      if (e.key === "Enter") select(gen.id);
+    });
+    list.append(item);
+  }
+  timeline.replaceChildren(el("h2", {}, "Timeline"), list);
+}
+
+function changeTable(title, cls, changes, version) {
+  if (!changes.length) return null;
+  const rows = changes.map((c) => el("tr", {},
+    el("td", {}, el("code", {}, c.name || c.path)),
+    el("td", {}, version(c)),
+    el("td", { class: "size" }, c.size_known ? humanSize(c.size_delta) : "")));
+  return [el("h3", { class: cls }, title + " (" + changes.length + ")"), el("table", {}, ...rows)];
+}
+
+async function select(id) {
+  for (const li of timeline.querySelectorAll("li")) {
+    li.classList.toggle("selected", li.dataset.id === id);
+  }
+  location.hash = id;
+  const i = generations.findIndex((g) => g.id === id);
+  const gen = generations[i];
+  const prev = generations[i - 1];
+  const head = [el("h2", {}, "Generation " + id),
+    el("p", { class: "muted" }, when(gen.timestamp) + (gen.nixos_version ? " · NixOS " + gen.nixos_version : "") +
+      (gen.kernel_version ? " · Linux " + gen.kernel_version : ""))];
+  if (!prev) {
+    detail.replaceChildren(...head, el("p", { class: "muted" }, "This is the oldest generation; there is nothing to compare it to."));
+    return;
+  }
+  detail.replaceChildren(...head, el("p", { class: "muted" }, "Loading the diff from " + prev.id + "…"));
+  try {
+    const diff = await getJSON("api/diff?from=" + encodeURIComponent(prev.id) + "&to=" + encodeURIComponent(id));
+    if (location.hash.slice(1) !== id) return;
+    const parts = [
+      changeTable("Added", "added", diff.added || [], (c) => c.new_version),
+      changeTable("Removed", "removed", diff.removed || [], (c) => c.old_version),
+      changeTable("Modified", "modified", diff.modified || [], (c) => c.old_version + " → " + c.new_version),
+    ].filter(Boolean).flat();
+    detail.replaceChildren(...head, el("p", {}, "Changes since generation " + prev.id + ":"),
+      ...(parts.length ? parts : [el("p", { class: "muted" }, "No packages changed.")]));
+  } catch (err) {
+    detail.replaceChildren(...head, el("p", { class: "error" }, "Failed to load the diff: " + err.message));
+  }
+}
+
+async function load() {
+  try {
+    generations = await getJSON("api/generations?sizes=true");
+  } catch (err) {
+    timeline.replaceChildren(el("p", { class: "error" }, "Failed to load generations: " + err.message));
+    return;
+  }
+  renderTimeline();
+  const wanted = location.hash.slice(1);
+  const start = generations.find((g) => g.id === wanted) || generations.find((g) => g.current) || generations[generations.length - 1];
+  if (start) select(start.id);
+}
+
+load();
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nix-timemach/internal/backend"
+)
+
+func TestDashboard(t *testing.T) {
+	h := Handler(backend.NewDemo())
+	tests := []struct {
+		url, contentType string
+	}{
+		{"/", "text/html"},
+		{"/app.js", "javascript"},
+		{"/style.css", "text/css"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), tt.contentType) || rec.Body.Len() == 0 {
+			t.Errorf("GET %s = %d, %q, %d bytes", tt.url, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Len())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package audit keeps a log of the changes nix-timemach made to systems:
+// what was done, to which generations, by whom and when, and whether it
+// worked. On a machine several people administer, it answers who rolled
+// back or deleted what.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"nix-timemach/internal/xdg"
+)
+
+// Entry is one action, as a line of the log.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// User is who ran nix-timemach; SudoUser is who ran it through sudo,
+	// when it ran as root that way.
+	User     string `json:"user"`
+	SudoUser string `json:"sudo_user,omitempty"`
+	// Host is the machine changed over ssh; empty is this one.
+	Host   string `json:"host,omitempty"`
+	Action string `json:"action"`
+	// Targets are the generations acted on.
+	Targets []string `json:"targets,omitempty"`
+	// Detail is what else the action took, like a pin's name or the age
+	// garbage collection kept.
+	Detail string `json:"detail,omitempty"`
+	// Error is why the action failed; empty means it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// Path is where the log is kept.
+func Path() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.log"), nil
+}
+
+// Append adds e to the log, filling in the time and users.
+func Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.User == "" {
+		e.User = currentUser()
+	}
+	if sudo := os.Getenv("SUDO_USER"); e.SudoUser == "" && sudo != e.User {
+		e.SudoUser = sudo
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+
+	p, err := Path()
+	if err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	// One write per line, so that concurrent sessions' lines don't
+	// interleave.
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	return nil
+}
+
+// Load returns the log, oldest entry first. A missing log is empty, and
+// lines that don't parse, say one cut short by a full disk, are skipped.
+func Load() ([]Entry, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
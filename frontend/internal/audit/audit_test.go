@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "")
+	if entries, err := Load(); entries != nil || err != nil {
+		t.Fatalf("Load with no log = %v, %v, want nothing", entries, err)
+	}
+
+	at := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	if err := Append(Entry{Time: at, User: "alice", Action: "rollback", Targets: []string{"41"}}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	before := time.Now()
+	if err := Append(Entry{Action: "gc", Detail: "30d", Error: "nix-collect-garbage failed"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; !e.Time.Equal(at) || e.User != "alice" || e.Action != "rollback" || len(e.Targets) != 1 || e.Targets[0] != "41" {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Time.Before(before) || e.User == "" || e.Error == "" {
+		t.Errorf("second entry = %+v, want the time and user filled in", e)
+	}
+
+	p, _ := Path()
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("log mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestAppendSudoUser(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "bob")
+	Append(Entry{User: "root", Action: "pin"})
+	Append(Entry{User: "bob", Action: "unpin"})
+	entries, _ := Load()
+	if len(entries) != 2 || entries[0].SudoUser != "bob" || entries[1].SudoUser != "" {
+		t.Errorf("entries = %+v, want bob as the sudo user of root only", entries)
+	}
+}
+
+// A line cut short doesn't hide the rest of the log.
+func TestLoadSkipsBrokenLines(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	Append(Entry{User: "alice", Action: "pin"})
+	p, _ := Path()
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time": "2026-10-14T12:00:00Z", "act` + "\n")
+	f.Close()
+	Append(Entry{User: "alice", Action: "unpin"})
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "pin" || entries[1].Action != "unpin" {
+		t.Errorf("entries = %+v, want pin and unpin", entries)
+	}
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"nix-timemach/internal/backend"
+)
+
+// Client is a backend.Client that logs every change it makes, or fails to
+// make, to the system. Calls refused in read-only mode never reach the
+// system and aren't logged.
+type Client struct {
+	backend.Client
+}
+
+// Wrap returns c with its changes logged.
+func Wrap(c backend.Client) *Client {
+	return &Client{Client: c}
+}
+
+// record logs action after it ran with err. The action's own error wins;
+// one that succeeded but couldn't be logged is reported, as an unlogged
+// change is what the log is there to rule out.
+func (c *Client) record(action string, targets []string, detail string, err error) error {
+	if errors.Is(err, backend.ErrReadOnly) {
+		return err
+	}
+	e := Entry{Host: c.Host(), Action: action, Targets: targets, Detail: detail}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	aerr := Append(e)
+	if err == nil && aerr != nil {
+		return fmt.Errorf("%s succeeded, but %w", action, aerr)
+	}
+	return err
+}
+
+func (c *Client) MarkKnownGood(ctx context.Context, id string) error {
+	return c.record("mark-known-good", []string{id}, "", c.Client.MarkKnownGood(ctx, id))
+}
+
+func (c *Client) Rollback(ctx context.Context, id string) error {
+	return c.record("rollback", []string{id}, "", c.Client.Rollback(ctx, id))
+}
+
+func (c *Client) SetBootDefault(ctx context.Context, id string) error {
+	return c.record("set-boot-default", []string{id}, "", c.Client.SetBootDefault(ctx, id))
+}
+
+func (c *Client) DeleteGenerations(ctx context.Context, ids []string) error {
+	return c.record("delete", ids, "", c.Client.DeleteGenerations(ctx, ids))
+}
+
+func (c *Client) GC(ctx context.Context, age string) error {
+	return c.record("gc", nil, age, c.Client.GC(ctx, age))
+}
+
+func (c *Client) Pin(ctx context.Context, id, name string) error {
+	return c.record("pin", []string{id}, name, c.Client.Pin(ctx, id, name))
+}
+
+func (c *Client) Unpin(ctx context.Context, id string) error {
+	return c.record("unpin", []string{id}, "", c.Client.Unpin(ctx, id))
+}
+
+func (c *Client) TrashGenerations(ctx context.Context, ids []string, keepDays int) error {
+	detail := fmt.Sprintf("%d days", keepDays)
+	return c.record("trash", ids, detail, c.Client.TrashGenerations(ctx, ids, keepDays))
+}
+
+func (c *Client) RestoreGeneration(ctx context.Context, id string) error {
+	return c.record("restore", []string{id}, "", c.Client.RestoreGeneration(ctx, id))
+}
+
+func (c *Client) EmptyTrash(ctx context.Context) error {
+	return c.record("empty-trash", nil, "", c.Client.EmptyTrash(ctx))
+}
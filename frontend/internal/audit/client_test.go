@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nix-timemach/internal/backend"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	demo := backend.NewDemo()
+	c := Wrap(demo)
+
+	if err := c.Pin(ctx, "12", "before-upgrade"); err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+	generations, _ := c.GetGenerations(ctx)
+	var current string
+	for _, gen := range generations {
+		if gen.Current {
+			current = gen.ID
+		}
+	}
+	if err := c.DeleteGenerations(ctx, []string{current}); err == nil {
+		t.Fatal("deleting the current generation succeeded")
+	}
+	// Reads aren't changes.
+	c.GetDiff(ctx, "1", "2")
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("logged %d entries, want 2: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.Action != "pin" || e.Targets[0] != "12" || e.Detail != "before-upgrade" || e.Error != "" {
+		t.Errorf("pin logged as %+v", e)
+	}
+	if e := entries[1]; e.Action != "delete" || e.Targets[0] != current || e.Error == "" {
+		t.Errorf("failed delete logged as %+v", e)
+	}
+}
+
+func TestClientReadOnly(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	c := Wrap(backend.NewDemo(backend.ReadOnly()))
+	if err := c.Rollback(context.Background(), "3"); !errors.Is(err, backend.ErrReadOnly) {
+		t.Fatalf("Rollback error = %v, want ErrReadOnly", err)
+	}
+	if entries, _ := Load(); len(entries) != 0 {
+		t.Errorf("refused rollback logged: %+v", entries)
+	}
+}
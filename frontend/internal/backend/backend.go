@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"nix-timemach/internal/models"
+)
+
+// Client answers questions about generations and changes them. The UI and
+// the headless commands only ever see a Client, so where the answers come
+// from is up to the implementation: Process asks the backend binary, here
+// or over ssh with WithHost; Native runs the Nix tools itself; Demo makes
+// them up. cache.Wrap adds caching to any of them.
+type Client interface {
+	GetGenerations(ctx context.Context) ([]models.Generation, error)
+	GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error)
+	GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error)
+	// StreamDiff is GetDiff that also hands each change to fn as it
+	// arrives, along with the number of changes in all.
+	StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error)
+	GetDiffsBulk(ctx context.Context, pairs [][2]string) ([]models.GenerationDiff, error)
+	GetPendingDiff(ctx context.Context) (models.GenerationDiff, error)
+	GetDiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error)
+	GetDiffStatsBulk(ctx context.Context, pairs [][2]string) ([]models.DiffStats, error)
+	GetConfigDiff(ctx context.Context, fromID, toID string) (models.ConfigDiff, error)
+	GetEtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error)
+	GetLockDiff(ctx context.Context, fromID, toID string) ([]models.InputChange, error)
+	GetPackages(ctx context.Context, id string) ([]string, error)
+	GetDiffAgainstSnapshot(ctx context.Context, id, snapName string) (models.GenerationDiff, error)
+	GetDepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error)
+	FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error)
+	// GetPathSizes lists every path in a generation's closure with its own
+	// size, largest first.
+	GetPathSizes(ctx context.Context, id string) ([]models.PathSize, error)
+	// WhyDepends explains why a generation's closure contains pkg, a store
+	// path or package name. It returns nil if the closure doesn't.
+	WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error)
+	// GetClosureGraph lists every path in a generation's closure with its
+	// own size and references.
+	GetClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error)
+	// GetTrash lists the deleted generations TrashGenerations kept, oldest
+	// first.
+	GetTrash(ctx context.Context) ([]models.TrashedGeneration, error)
+	GetProfiles(ctx context.Context) ([]models.Profile, error)
+	DryActivate(ctx context.Context, id string) (models.ActivationPreview, error)
+	GCDryRun(ctx context.Context, age string) (models.GCPreview, error)
+
+	MarkKnownGood(ctx context.Context, id string) error
+	Rollback(ctx context.Context, id string) error
+	SetBootDefault(ctx context.Context, id string) error
+	DeleteGenerations(ctx context.Context, ids []string) error
+	GC(ctx context.Context, age string) error
+	Pin(ctx context.Context, id, name string) error
+	Unpin(ctx context.Context, id string) error
+	// TrashGenerations deletes generations like DeleteGenerations, but keeps
+	// their systems for keepDays days, during which RestoreGeneration brings
+	// them back. Generations trashed longer ago go for good.
+	TrashGenerations(ctx context.Context, ids []string, keepDays int) error
+	RestoreGeneration(ctx context.Context, id string) error
+	// EmptyTrash leaves every trashed generation to garbage collection.
+	EmptyTrash(ctx context.Context) error
+
+	Store() string
+	Host() string
+	Escalates() bool
+	AuthorizeCommand() *exec.Cmd
+}
+
+// New makes the Client named by kind: "binary" (or "") runs the backend
+// binary, "native" the Nix tools, and "demo" makes up a system. Only the
+// binary can be run on a host set with WithHost, as it is the one thing
+// there is to install.
+func New(kind, binary string, opts ...Option) (Client, error) {
+	remote := newSettings(opts).host != ""
+	switch kind {
+	case "", "binary":
+		return NewProcess(binary, opts...), nil
+	case "native":
+		if remote {
+			return nil, fmt.Errorf("the native backend can't run on a remote host")
+		}
+		return NewNative(opts...), nil
+	case "demo":
+		if remote {
+			return nil, fmt.Errorf("the demo backend can't run on a remote host")
+		}
+		return NewDemo(opts...), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q, want binary, native or demo", kind)
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"nix-timemach/internal/models"
+)
+
+// BulkDiffError reports which pairs of a GetDiffsBulk call failed. Errs
+// lines up with the requested pairs and is nil where the diff succeeded.
+type BulkDiffError struct {
+	Errs []error
+}
+
+func (e *BulkDiffError) Error() string {
+	var failed []error
+	for _, err := range e.Errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return fmt.Sprintf("%d of %d diffs failed, first: %v", len(failed), len(e.Errs), failed[0])
+}
+
+func (e *BulkDiffError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// GetDiffsBulk computes the diff of every from/to pair in one backend run,
+// saving the startup cost of a run per pair. Results are in the order of
+// pairs. A pair that fails leaves a zero diff in its place and is reported
+// through a *BulkDiffError rather than failing the rest; backends without
+// bulk support are asked for each diff in turn.
+func (c *Process) GetDiffsBulk(ctx context.Context, pairs [][2]string) ([]models.GenerationDiff, error) {
+	return bulk(ctx, c, "bulk diff", "diff-bulk", "diff", pairs, func(raw json.RawMessage, diff *models.GenerationDiff) error {
+		return decodeDiff(json.NewDecoder(bytes.NewReader(raw)), diff)
+	}, c.GetDiff)
+}
+
+// GetDiffStatsBulk is GetDiffsBulk for diff stats, which unlike StatsOf
+// include the size delta.
+func (c *Process) GetDiffStatsBulk(ctx context.Context, pairs [][2]string) ([]models.DiffStats, error) {
+	return bulk(ctx, c, "bulk diff stats", "diff-stats-bulk", "stats", pairs, func(raw json.RawMessage, stats *models.DiffStats) error {
+		return json.Unmarshal(raw, stats)
+	}, c.GetDiffStats)
+}
+
+// bulk runs a backend subcommand taking FROM:TO pairs and answering with an
+// array of objects holding either the result under field or an error.
+// Without the subcommand, single is called for each pair instead.
+func bulk[T any](ctx context.Context, c *Process, what, subcommand, field string, pairs [][2]string, decode func(json.RawMessage, *T) error, single func(ctx context.Context, from, to string) (T, error)) ([]T, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	args := []string{subcommand}
+	for _, p := range pairs {
+		args = append(args, p[0]+":"+p[1])
+	}
+
+	var entries []map[string]json.RawMessage
+	err := c.stream(ctx, what, func(dec *json.Decoder) error {
+		return decodeArray(dec, func(e map[string]json.RawMessage) {
+			entries = append(entries, e)
+		})
+	}, args...)
+	if errors.Is(err, ErrUnsupported) {
+		return bulkSequential(ctx, pairs, single)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != len(pairs) {
+		return nil, fmt.Errorf("%s: backend returned %d results for %d pairs", what, len(entries), len(pairs))
+	}
+
+	results := make([]T, len(pairs))
+	errs := make([]error, len(pairs))
+	failed := false
+	for i, e := range entries {
+		from, to := pairs[i][0], pairs[i][1]
+		var msg string
+		if raw, ok := e["error"]; ok {
+			// A null error decodes to "", which is treated as no error.
+			_ = json.Unmarshal(raw, &msg)
+		}
+		raw := e[field]
+		switch {
+		case msg != "":
+			errs[i] = fmt.Errorf("failed to diff %s and %s: %s", from, to, sanitize(msg))
+		case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+			errs[i] = fmt.Errorf("failed to diff %s and %s: backend returned no result", from, to)
+		default:
+			if err := decode(raw, &results[i]); err != nil {
+				errs[i] = fmt.Errorf("failed to decode %s of %s and %s: %w", field, from, to, err)
+			}
+		}
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return results, &BulkDiffError{Errs: errs}
+	}
+	return results, nil
+}
+
+func bulkSequential[T any](ctx context.Context, pairs [][2]string, single func(ctx context.Context, from, to string) (T, error)) ([]T, error) {
+	results := make([]T, len(pairs))
+	errs := make([]error, len(pairs))
+	failed := false
+	for i, p := range pairs {
+		result, err := single(ctx, p[0], p[1])
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to diff %s and %s: %w", p[0], p[1], err)
+			failed = true
+			continue
+		}
+		results[i] = result
+	}
+	if failed {
+		return results, &BulkDiffError{Errs: errs}
+	}
+	return results, nil
+}
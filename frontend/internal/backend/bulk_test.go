@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetDiffsBulk(t *testing.T) {
+	c := newFakeProcess(t)
+	pairs := [][2]string{{"1", "2"}, {"2", "missing"}, {"2", "3"}}
+	diffs, err := c.GetDiffsBulk(context.Background(), pairs)
+
+	var bulkErr *BulkDiffError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("error = %v, want a *BulkDiffError", err)
+	}
+	if len(diffs) != len(pairs) || len(bulkErr.Errs) != len(pairs) {
+		t.Fatalf("got %d diffs and %d errors for %d pairs", len(diffs), len(bulkErr.Errs), len(pairs))
+	}
+	for i, p := range pairs {
+		failed := p[1] == "missing"
+		if (bulkErr.Errs[i] != nil) != failed {
+			t.Errorf("pair %v: error %v", p, bulkErr.Errs[i])
+		}
+		if failed {
+			continue
+		}
+		if want := p[0] + "-" + p[1]; len(diffs[i].Added) != 1 || diffs[i].Added[0].NewVersion != want {
+			t.Errorf("pair %v: got %+v, want the diff %s in its place", p, diffs[i].Added, want)
+		}
+	}
+}
+
+// The bulk call saves a backend start per pair over asking for each diff
+// in turn.
+func BenchmarkDiffs(b *testing.B) {
+	for _, n := range []int{1, 8, 32} {
+		pairs := make([][2]string, n)
+		for i := range pairs {
+			pairs[i] = [2]string{fmt.Sprint(i), fmt.Sprint(i + 1)}
+		}
+		b.Run(fmt.Sprintf("bulk/%d", n), func(b *testing.B) {
+			c := newFakeProcess(b)
+			for range b.N {
+				if _, err := c.GetDiffsBulk(context.Background(), pairs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("sequential/%d", n), func(b *testing.B) {
+			c := newFakeProcess(b)
+			for range b.N {
+				if _, err := bulkSequential(context.Background(), pairs, c.GetDiff); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,77 +1,803 @@
 package backend
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"nix-timemach/internal/models"
+	"nix-timemach/internal/shell"
+	"nix-timemach/internal/snapshot"
+	"os"
 	"os/exec"
-	// "time"
+	"strconv"
+	"strings"
+	"sync"
 )
 
-type Client struct {
+// maxOutputBytes bounds how much backend output a single call may consume.
+// Responses are decoded incrementally, so memory use tracks the decoded
+// entries rather than the raw JSON, but a runaway backend is still cut off.
+const maxOutputBytes = 1 << 30
+
+var errOutputTooLarge = fmt.Errorf("backend output exceeds %d bytes", maxOutputBytes)
+
+// ErrUnsupported is returned when the backend binary predates a subcommand.
+var ErrUnsupported = errors.New("operation not supported by this backend")
+
+// Process is the Client that runs the backend binary for every call.
+type Process struct {
+	settings
 	backendBinary string
+
+	// serverMu guards the persistent server and is held for each call
+	// made through it.
+	serverMu sync.Mutex
+	server   *server
+	noServer bool
+
+	// versionMu guards the protocol check made before the first call
+	// whose output is read.
+	versionMu      sync.Mutex
+	versionChecked bool
+	incompatible   error
+}
+
+func NewProcess(binaryPath string, opts ...Option) *Process {
+	return &Process{settings: newSettings(opts), backendBinary: binaryPath}
+}
+
+// WithStore makes every backend call operate on the given Nix store, a
+// local path or a store URL, instead of the default one.
+func WithStore(store string) Option {
+	return func(s *settings) {
+		s.store = store
+	}
+}
+
+// Store returns the store set with WithStore, or "" for the default.
+func (c *settings) Store() string {
+	return c.store
+}
+
+// ValidateStore checks a --store value: URLs such as ssh://host or daemon
+// are left to Nix, local paths must be existing directories.
+func ValidateStore(store string) error {
+	if strings.Contains(store, "://") || store == "daemon" || store == "auto" || store == "local" {
+		return nil
+	}
+	info, err := os.Stat(store)
+	if err != nil {
+		return fmt.Errorf("invalid store: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid store: %s is not a directory", store)
+	}
+	return nil
 }
 
-func NewClient(binaryPath string) *Client {
-	return &Client{
-		backendBinary: binaryPath,
+// backendArgs prefixes a subcommand's arguments with the options every
+// backend invocation shares.
+func (c *Process) backendArgs(args ...string) []string {
+	if c.store == "" {
+		return args
 	}
+	return append([]string{"--store", c.store}, args...)
+}
+
+func (c *Process) GetGenerations(ctx context.Context) ([]models.Generation, error) {
+	return c.getGenerations(ctx, "list-generations")
+}
+
+// GetGenerationsWithSizes is GetGenerations with closure sizes filled in,
+// which costs the backend a store query per generation.
+func (c *Process) GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error) {
+	return c.getGenerations(ctx, "list-generations", "--sizes")
 }
 
-func (c *Client) GetGenerations() ([]models.Generation, error) {
-	cmd := exec.Command(c.backendBinary, "list-generations")
-	output, err := cmd.Output()
+// GetProfiles lists the profiles that exist. A backend that predates
+// profiles only knows the system's.
+func (c *Process) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	var profiles []models.Profile
+	err := c.stream(ctx, "profiles", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(p models.Profile) {
+			p.Name = sanitize(p.Name)
+			profiles = append(profiles, p)
+		})
+	}, "list-profiles")
+	if errors.Is(err, ErrUnsupported) {
+		return []models.Profile{systemProfile}, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get generations: %w", err)
+		return nil, err
 	}
+	return profiles, nil
+}
 
+func (c *Process) getGenerations(ctx context.Context, args ...string) ([]models.Generation, error) {
 	var generations []models.Generation
-	if err := json.Unmarshal(output, &generations); err != nil {
-		return nil, fmt.Errorf("failed to parse generations: %w", err)
+	err := c.stream(ctx, "generations", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(gen models.Generation) {
+			sanitizeGeneration(&gen)
+			generations = append(generations, gen)
+		})
+	}, args...)
+	if err != nil {
+		return nil, err
 	}
 
 	return generations, nil
 }
 
-func (c *Client) GetDiff(fromID, toID string) (models.GenerationDiff, error) {
-	cmd := exec.Command(c.backendBinary, "diff", fromID, toID)
-	output, err := cmd.Output()
+func (c *Process) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	var diff models.GenerationDiff
+	err := c.stream(ctx, "diff", func(dec *json.Decoder) error {
+		return decodeDiff(dec, &diff)
+	}, "diff", fromID, toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// StreamDiff runs the backend's diff-stream subcommand, which writes a
+// header with the number of changes and then one change per line as each
+// is sized. It always starts its own backend, as the persistent one
+// answers each request in a single line. Backends without diff-stream are
+// served by GetDiff, with every change handed over at the end.
+func (c *Process) StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error) {
+	if err := c.checkVersion(ctx); err != nil {
+		return models.GenerationDiff{}, err
+	}
+	var diff models.GenerationDiff
+	ctx, done := c.bounded(ctx, "diff")
+	err := done(c.spawn(ctx, "diff", func(dec *json.Decoder) error {
+		return decodeDiffStream(dec, &diff, fn)
+	}, profileArgs(ctx, []string{"diff-stream", fromID, toID})...))
+	if errors.Is(err, ErrUnsupported) {
+		if diff, err = c.GetDiff(ctx, fromID, toID); err != nil {
+			return models.GenerationDiff{}, err
+		}
+		emitDiff(diff, fn)
+		return diff, nil
+	}
 	if err != nil {
-		return models.GenerationDiff{}, fmt.Errorf("failed to get diff: %w", err)
+		return models.GenerationDiff{}, err
 	}
 
+	return diff, nil
+}
+
+// emitDiff hands a diff that was computed whole to a StreamDiff callback.
+func emitDiff(diff models.GenerationDiff, fn func(int, models.DiffEntry)) {
+	entries := diff.Entries()
+	for _, e := range entries {
+		fn(len(entries), e)
+	}
+}
+
+// GetPendingDiff builds the current configuration without activating it
+// and diffs the result against the running system. Building can take
+// minutes, so it honours ctx for cancellation.
+func (c *Process) GetPendingDiff(ctx context.Context) (models.GenerationDiff, error) {
 	var diff models.GenerationDiff
-	if err := json.Unmarshal(output, &diff); err != nil {
-		return models.GenerationDiff{}, fmt.Errorf("failed to parse diff: %w", err)
+	err := c.streamContext(ctx, "pending diff", func(dec *json.Decoder) error {
+		return decodeDiff(dec, &diff)
+	}, "pending-diff")
+	if err != nil {
+		return models.GenerationDiff{}, err
 	}
 
 	return diff, nil
 }
 
-/*   for testing
-
-func (c *Client) GetGenerations() ([]models.Generation, error) {
-	return []models.Generation{
-		{
-			ID:          "1",
-			Timestamp:   time.Now().Add(-24 * time.Hour),
-			Description: "Yesterday's system state",
-			Profiles:    []string{"/nix/var/nix/profiles/system-1-link"},
-		},
-		{
-			ID:          "2",
-			Timestamp:   time.Now(),
-			Description: "Current system state",
-			Profiles:    []string{"/nix/var/nix/profiles/system-2-link"},
-		},
-	}, nil
-}
-
-func (c *Client) GetDiff(fromID, toID string) (models.GenerationDiff, error) {
-	return models.GenerationDiff{
-		Added:    []string{"package-1", "package-2"},
-		Removed:  []string{"old-package"},
-		Modified: []string{"modified-package"},
-	}, nil
-}
-*/
+// GetDiffStats returns only the counts and size delta of a diff. Backends
+// without the diff-stats subcommand are served by computing the stats from
+// a full diff instead.
+func (c *Process) GetDiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error) {
+	var stats models.DiffStats
+	err := c.stream(ctx, "diff stats", func(dec *json.Decoder) error {
+		return dec.Decode(&stats)
+	}, "diff-stats", fromID, toID)
+	if errors.Is(err, ErrUnsupported) {
+		diff, err := c.GetDiff(ctx, fromID, toID)
+		if err != nil {
+			return models.DiffStats{}, err
+		}
+		return models.StatsOf(diff), nil
+	}
+	if err != nil {
+		return models.DiffStats{}, err
+	}
+
+	return stats, nil
+}
+
+// GetConfigDiff reports the flake inputs and configuration values that
+// changed between two generations.
+func (c *Process) GetConfigDiff(ctx context.Context, fromID, toID string) (models.ConfigDiff, error) {
+	var diff models.ConfigDiff
+	err := c.stream(ctx, "config diff", func(dec *json.Decoder) error {
+		return dec.Decode(&diff)
+	}, "config-diff", fromID, toID)
+	if err != nil {
+		return models.ConfigDiff{}, err
+	}
+	sanitizeConfigDiff(&diff)
+
+	return diff, nil
+}
+
+// GetLockDiff reports the flake inputs whose pins differ between the lock
+// files of two generations.
+func (c *Process) GetLockDiff(ctx context.Context, fromID, toID string) ([]models.InputChange, error) {
+	var changes []models.InputChange
+	err := c.stream(ctx, "lock diff", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(change models.InputChange) {
+			changes = append(changes, change)
+		})
+	}, "lock-diff", fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+	sanitizeInputChanges(changes)
+
+	return changes, nil
+}
+
+// GetEtcDiff reports the files under /etc that differ between two
+// generations.
+func (c *Process) GetEtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error) {
+	var diff models.FileDiff
+	err := c.stream(ctx, "etc diff", func(dec *json.Decoder) error {
+		return dec.Decode(&diff)
+	}, "etc-diff", fromID, toID)
+	if err != nil {
+		return models.FileDiff{}, err
+	}
+	sanitizeFileDiff(&diff)
+
+	return diff, nil
+}
+
+// GetPackages returns the package set of a generation, or of the running
+// system when id is empty.
+func (c *Process) GetPackages(ctx context.Context, id string) ([]string, error) {
+	args := []string{"packages"}
+	if id != "" {
+		args = append(args, id)
+	}
+	var packages []string
+	err := c.stream(ctx, "packages", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(p string) {
+			packages = append(packages, p)
+		})
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return packages, nil
+}
+
+// DryActivate previews what activating a system generation would do,
+// without doing it.
+func (c *Process) DryActivate(ctx context.Context, id string) (models.ActivationPreview, error) {
+	var lines []string
+	err := c.stream(ctx, "activation preview", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(line string) {
+			lines = append(lines, sanitize(line))
+		})
+	}, "dry-activate", id)
+	if err != nil {
+		return models.ActivationPreview{}, err
+	}
+	return models.ParseDryActivate(lines), nil
+}
+
+// GetDiffAgainstSnapshot diffs a generation against a stored snapshot. The
+// diff is computed here from the two package sets, so it works after the
+// snapshotted generation has been deleted.
+func (c *Process) GetDiffAgainstSnapshot(ctx context.Context, id, snapName string) (models.GenerationDiff, error) {
+	return diffAgainstSnapshot(ctx, c, id, snapName)
+}
+
+func diffAgainstSnapshot(ctx context.Context, c Client, id, snapName string) (models.GenerationDiff, error) {
+	snap, err := snapshot.Load(snapName)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	packages, err := c.GetPackages(ctx, id)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+
+	return models.DiffPaths(snap.Paths, packages), nil
+}
+
+// GetDepsDiff diffs the runtime dependencies of a package between two
+// generations. pkg is the package's store path in either generation.
+func (c *Process) GetDepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error) {
+	var diff models.GenerationDiff
+	err := c.stream(ctx, "dependency diff", func(dec *json.Decoder) error {
+		return decodeDiff(dec, &diff)
+	}, "deps-diff", pkg, fromID, toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// FindPackage reports, for every generation, whether its closure contains
+// the named package and at which version.
+func (c *Process) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	var presence []models.PackagePresence
+	err := c.stream(ctx, "package presence", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(p models.PackagePresence) {
+			p.Version = sanitize(p.Version)
+			presence = append(presence, p)
+		})
+	}, "find-package", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find package %s: %w", name, err)
+	}
+
+	return presence, nil
+}
+
+// GetPathSizes lists every path in a generation's closure with its own
+// size, largest first.
+func (c *Process) GetPathSizes(ctx context.Context, id string) ([]models.PathSize, error) {
+	var sizes []models.PathSize
+	err := c.stream(ctx, "path sizes", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(p models.PathSize) {
+			sizes = append(sizes, p)
+		})
+	}, "path-sizes", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// WhyDepends explains why a generation's closure contains pkg, as the
+// chains of references leading to it.
+func (c *Process) WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error) {
+	var node *models.DependencyNode
+	err := c.stream(ctx, "dependency chain", func(dec *json.Decoder) error {
+		return dec.Decode(&node)
+	}, "why-depends", id, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if node != nil {
+		sanitizeDependencyNode(node)
+	}
+
+	return node, nil
+}
+
+// GetClosureGraph lists every path in a generation's closure with its own
+// size and references.
+func (c *Process) GetClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error) {
+	var nodes []models.StoreNode
+	err := c.stream(ctx, "closure", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(n models.StoreNode) {
+			nodes = append(nodes, n)
+		})
+	}, "closure-graph", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (c *Process) GetTrash(ctx context.Context) ([]models.TrashedGeneration, error) {
+	var trash []models.TrashedGeneration
+	err := c.stream(ctx, "trash", func(dec *json.Decoder) error {
+		return decodeArray(dec, func(gen models.TrashedGeneration) {
+			trash = append(trash, gen)
+		})
+	}, "list-trash")
+	if err != nil {
+		return nil, err
+	}
+
+	return trash, nil
+}
+
+// MarkKnownGood tags a generation as a known-good baseline. The backend keeps
+// a GC root for it, so the mark survives restarts and protects its closure.
+func (c *Process) MarkKnownGood(ctx context.Context, id string) error {
+	if err := c.runPrivileged(ctx, "mark-known-good", id); err != nil {
+		return fmt.Errorf("failed to mark generation %s as known good: %w", id, err)
+	}
+	return nil
+}
+
+// Rollback switches the system profile to a generation and activates it.
+func (c *Process) Rollback(ctx context.Context, id string) error {
+	if err := c.runPrivileged(ctx, "rollback", id); err != nil {
+		return fmt.Errorf("failed to roll back to generation %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetBootDefault makes a generation the one the system boots into next,
+// without activating it.
+func (c *Process) SetBootDefault(ctx context.Context, id string) error {
+	if err := c.runPrivileged(ctx, "set-boot-default", id); err != nil {
+		return fmt.Errorf("failed to make generation %s the boot default: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteGenerations deletes generations from the system profile. The
+// backend refuses to delete the current generation.
+func (c *Process) DeleteGenerations(ctx context.Context, ids []string) error {
+	if err := c.runPrivileged(ctx, append([]string{"delete-generations"}, ids...)...); err != nil {
+		return fmt.Errorf("failed to delete generations %s: %w", strings.Join(ids, ", "), err)
+	}
+	return nil
+}
+
+func (c *Process) TrashGenerations(ctx context.Context, ids []string, keepDays int) error {
+	args := append([]string{"trash-generations", "--keep-days", strconv.Itoa(keepDays)}, ids...)
+	if err := c.runPrivileged(ctx, args...); err != nil {
+		return fmt.Errorf("failed to move generations %s to the trash: %w", strings.Join(ids, ", "), err)
+	}
+	return nil
+}
+
+func (c *Process) RestoreGeneration(ctx context.Context, id string) error {
+	if err := c.runPrivileged(ctx, "restore-generation", id); err != nil {
+		return fmt.Errorf("failed to restore generation %s: %w", id, err)
+	}
+	return nil
+}
+
+func (c *Process) EmptyTrash(ctx context.Context) error {
+	if err := c.runPrivileged(ctx, "empty-trash"); err != nil {
+		return fmt.Errorf("failed to empty the trash: %w", err)
+	}
+	return nil
+}
+
+// GCDryRun previews collecting garbage older than age, e.g. "30d", without
+// deleting anything.
+func (c *Process) GCDryRun(ctx context.Context, age string) (models.GCPreview, error) {
+	var preview models.GCPreview
+	err := c.stream(ctx, "garbage collection preview", func(dec *json.Decoder) error {
+		return dec.Decode(&preview)
+	}, "gc-dry-run", age)
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	sanitizeGCPreview(&preview)
+
+	return preview, nil
+}
+
+// GC deletes the unpinned generations older than age and collects garbage,
+// as nix-collect-garbage --delete-older-than does.
+func (c *Process) GC(ctx context.Context, age string) error {
+	if err := c.runPrivileged(ctx, "gc", age); err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+	return nil
+}
+
+// Pin protects a generation from deletion and garbage collection with a GC
+// root of its own. name is optional and shown in the list.
+func (c *Process) Pin(ctx context.Context, id, name string) error {
+	args := []string{"pin", id}
+	if name != "" {
+		args = append(args, name)
+	}
+	if err := c.runPrivileged(ctx, args...); err != nil {
+		return fmt.Errorf("failed to pin generation %s: %w", id, err)
+	}
+	return nil
+}
+
+// Unpin removes a generation's pin, leaving it to cleanup again.
+func (c *Process) Unpin(ctx context.Context, id string) error {
+	if err := c.runPrivileged(ctx, "unpin", id); err != nil {
+		return fmt.Errorf("failed to unpin generation %s: %w", id, err)
+	}
+	return nil
+}
+
+// runPrivileged runs a backend subcommand that modifies the system.
+func (c *Process) runPrivileged(ctx context.Context, args ...string) error {
+	return c.settings.runPrivileged(ctx, append([]string{c.backendBinary}, c.backendArgs(args...)...)...)
+}
+
+// stream runs the backend with args and hands its stdout to decode as it is
+// produced, instead of buffering the whole response with cmd.Output. It
+// gives up after the configured timeout.
+func (c *Process) stream(ctx context.Context, what string, decode func(*json.Decoder) error, args ...string) error {
+	ctx, done := c.bounded(ctx, what)
+	return done(c.streamContext(ctx, what, decode, args...))
+}
+
+// streamContext is stream without the timeout, for builds that may
+// legitimately take long.
+func (c *Process) streamContext(ctx context.Context, what string, decode func(*json.Decoder) error, args ...string) error {
+	if err := c.checkVersion(ctx); err != nil {
+		return err
+	}
+	return c.run(ctx, what, decode, args...)
+}
+
+// run is streamContext without the protocol check, through the persistent
+// backend if there is one.
+func (c *Process) run(ctx context.Context, what string, decode func(*json.Decoder) error, args ...string) error {
+	args = profileArgs(ctx, args)
+	if c.persistent {
+		result, err := c.call(ctx, what, args...)
+		if !errors.Is(err, errNoServer) {
+			if err != nil {
+				return err
+			}
+			if err := decode(json.NewDecoder(bytes.NewReader(result))); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", what, err)
+			}
+			return nil
+		}
+	}
+	return c.spawn(ctx, what, decode, args...)
+}
+
+// spawn starts a backend process for one call and decodes its stdout as it
+// is written.
+func (c *Process) spawn(ctx context.Context, what string, decode func(*json.Decoder) error, args ...string) error {
+	cmd := c.command(ctx, append([]string{c.backendBinary}, c.backendArgs(args...)...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if fn := progressFrom(ctx); fn != nil {
+		progress := &lineWriter{fn: fn}
+		defer progress.flush()
+		cmd.Stderr = io.MultiWriter(&stderr, progress)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to get %s: %w", what, err)
+	}
+
+	decodeErr := decode(json.NewDecoder(bufio.NewReader(&limitedReader{r: stdout, n: maxOutputBytes})))
+	if decodeErr != nil {
+		// Stop the backend rather than draining output nobody will read.
+		cmd.Process.Kill()
+	}
+
+	// A backend that failed on its own explains more than the parse error
+	// its empty or partial output causes, so report that first.
+	if err := cmd.Wait(); err != nil && !killedBy(err, decodeErr) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isUnsupported(stderr.Bytes()) {
+			return fmt.Errorf("failed to get %s: %w", what, ErrUnsupported)
+		}
+		return newFailure(what, shell.CommandLine(cmd.Args...), err, stderr.Bytes())
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to parse %s: %w", what, decodeErr)
+	}
+
+	return nil
+}
+
+// killedBy reports whether waitErr is just the result of stream killing the
+// backend after decodeErr.
+func killedBy(waitErr, decodeErr error) bool {
+	var exitErr *exec.ExitError
+	if decodeErr == nil || !errors.As(waitErr, &exitErr) {
+		return false
+	}
+	return !exitErr.Exited()
+}
+
+// stderrSummary returns the tail of the backend's stderr, which is where Nix
+// puts the evaluation or build error that caused a failure.
+func stderrSummary(stderr []byte) string {
+	const maxLen = 4096
+	stderr = bytes.TrimSpace(stderr)
+	if len(stderr) > maxLen {
+		stderr = stderr[len(stderr)-maxLen:]
+	}
+	return string(stderr)
+}
+
+// isUnsupported recognises clap's complaint about an unknown subcommand.
+func isUnsupported(stderr []byte) bool {
+	return bytes.Contains(stderr, []byte("unrecognized subcommand"))
+}
+
+// decodeArray decodes a JSON array one element at a time, passing each
+// element to fn so the caller never holds the raw array. A single object in
+// place of the array, a common slip when a backend has only one entry to
+// report, is accepted as a one-element list.
+func decodeArray[T any](dec *json.Decoder, fn func(T)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null decodes to an empty list, as with json.Unmarshal
+	}
+	if d, ok := tok.(json.Delim); ok && d == '{' {
+		v, err := decodeObjectRest[T](dec)
+		if err != nil {
+			return err
+		}
+		fn(v)
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("expected a JSON array or object, got %v", describeToken(tok))
+	}
+	for dec.More() {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		fn(v)
+	}
+	return expectDelim(dec, ']')
+}
+
+// decodeObjectRest decodes an object whose opening brace dec has already
+// consumed. The decoder can't be rewound, so the members are collected and
+// decoded again as a whole.
+func decodeObjectRest[T any](dec *json.Decoder) (T, error) {
+	var v T
+	members := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return v, err
+		}
+		name, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return v, err
+		}
+		members[name] = raw
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return v, err
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// describeToken names a JSON token's type for error messages.
+func describeToken(tok json.Token) string {
+	switch tok := tok.(type) {
+	case json.Delim:
+		return fmt.Sprintf("%q", tok)
+	case string:
+		return fmt.Sprintf("string %q", tok)
+	case float64, json.Number:
+		return fmt.Sprintf("number %v", tok)
+	case bool:
+		return fmt.Sprintf("boolean %v", tok)
+	}
+	return fmt.Sprint(tok)
+}
+
+// decodeDiff decodes a GenerationDiff object, streaming each of its lists.
+// Keys are matched case-insensitively, as encoding/json does.
+func decodeDiff(dec *json.Decoder, diff *models.GenerationDiff) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := tok.(string)
+
+		var list *[]models.PackageChange
+		switch {
+		case strings.EqualFold(name, "explicit_known"):
+			if err := dec.Decode(&diff.ExplicitKnown); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			continue
+		case strings.EqualFold(name, "added"):
+			list = &diff.Added
+		case strings.EqualFold(name, "removed"):
+			list = &diff.Removed
+		case strings.EqualFold(name, "modified"):
+			list = &diff.Modified
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := decodeArray(dec, func(item models.PackageChange) {
+			sanitizeChange(&item)
+			*list = append(*list, item)
+		}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	diff.FillNames()
+	return expectDelim(dec, '}')
+}
+
+// decodeDiffStream decodes diff-stream's output: a header object with the
+// total, then one DiffEntry per line until the end of the output.
+func decodeDiffStream(dec *json.Decoder, diff *models.GenerationDiff, fn func(int, models.DiffEntry)) error {
+	var header struct {
+		Total         int  `json:"total"`
+		ExplicitKnown bool `json:"explicit_known"`
+	}
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	diff.ExplicitKnown = header.ExplicitKnown
+	for {
+		var entry models.DiffEntry
+		if err := dec.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		sanitizeChange(&entry.Change)
+		diff.Add(entry)
+		fn(header.Total, entry)
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// limitedReader is io.LimitReader that reports overflow as an error instead
+// of a silent EOF, so truncated output is never mistaken for a full response.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errOutputTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
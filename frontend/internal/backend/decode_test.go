@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+func TestDecodeArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{"array", `[{"id": "1"}, {"id": "2"}]`, []string{"1", "2"}, ""},
+		{"single object", `{"id": "7"}`, []string{"7"}, ""},
+		{"empty array", `[]`, nil, ""},
+		{"null", `null`, nil, ""},
+		{"string", `"7"`, nil, `got string "7"`},
+		{"number", `7`, nil, "got number 7"},
+		{"truncated", `[{"id": "1"},`, []string{"1"}, "unexpected end"},
+		{"empty", ``, nil, "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			err := decodeArray(json.NewDecoder(strings.NewReader(tt.in)), func(gen models.Generation) {
+				ids = append(ids, gen.ID)
+			})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("decoded %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeDiff(t *testing.T) {
+	tests := []struct {
+		name                     string
+		in                       string
+		added, removed, modified int
+		wantErr                  bool
+	}{
+		{"structured", `{"added": [{"path": "/nix/store/aaaa-htop-3.3.0", "name": "htop", "new_version": "3.3.0"}], "removed": [], "modified": []}`, 1, 0, 0, false},
+		{"bare paths", `{"added": ["/nix/store/aaaa-htop-3.3.0"], "removed": ["/nix/store/bbbb-git-2.45.2", "/nix/store/cccc-vim-9.1"]}`, 1, 2, 0, false},
+		{"case-insensitive keys", `{"Added": ["/nix/store/aaaa-htop-3.3.0"], "MODIFIED": [{"path": "/nix/store/dddd-firefox-128.0", "name": "firefox"}]}`, 1, 0, 1, false},
+		{"unknown keys skipped", `{"total": 3, "added": [], "extra": {"nested": [1, 2]}}`, 0, 0, 0, false},
+		{"single change", `{"added": {"path": "/nix/store/aaaa-htop-3.3.0"}}`, 1, 0, 0, false},
+		{"not an object", `[]`, 0, 0, 0, true},
+		{"truncated", `{"added": [`, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diff models.GenerationDiff
+			err := decodeDiff(json.NewDecoder(strings.NewReader(tt.in)), &diff)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(diff.Added) != tt.added || len(diff.Removed) != tt.removed || len(diff.Modified) != tt.modified {
+				t.Errorf("got %d added, %d removed, %d modified; want %d, %d, %d",
+					len(diff.Added), len(diff.Removed), len(diff.Modified), tt.added, tt.removed, tt.modified)
+			}
+		})
+	}
+}
+
+func TestGetGenerationsShapes(t *testing.T) {
+	tests := []struct {
+		file    string
+		want    []string
+		wantErr bool
+	}{
+		{"generations.json", []string{"41", "42"}, false},
+		{"generation.json", []string{"42"}, false},
+		{"string.json", nil, true},
+		{"truncated.json", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			c := newFakeProcess(t)
+			t.Setenv(fakeOutputEnv, filepath.Join("testdata", tt.file))
+			gens, err := c.GetGenerations(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, gen := range gens {
+				ids = append(ids, gen.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("generations %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetGenerationsSanitizes(t *testing.T) {
+	c := newFakeProcess(t)
+	t.Setenv(fakeOutputEnv, filepath.Join("testdata", "invalid-utf8.json"))
+	gens, err := c.GetGenerations(context.Background())
+	if err != nil {
+		t.Fatalf("GetGenerations: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("got %d generations, want 1", len(gens))
+	}
+	if got, want := gens[0].Description, "laptop �� �[2Jrebuilt"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+	if gens[0].ID != "42" || gens[0].Profile != "/nix/var/nix/profiles/system" {
+		t.Errorf("identifiers changed: %+v", gens[0])
+	}
+}
@@ -0,0 +1,827 @@
+package backend
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nix-timemach/internal/models"
+)
+
+// Demo is the Client behind --demo: a made-up system with a few months of
+// generations, kept in memory, so the TUI can be tried, recorded and
+// tested without Nix. Actions change only the made-up state.
+type Demo struct {
+	settings
+
+	mu          sync.Mutex
+	generations []models.Generation
+	// packages holds each generation's package set, by generation ID.
+	packages map[string][]string
+	// nixpkgs is the nixpkgs revision each generation was built from.
+	nixpkgs map[string]string
+	// trash holds the trashed generations, oldest first, with what
+	// restoring one puts back.
+	trash []demoTrashed
+}
+
+type demoTrashed struct {
+	models.TrashedGeneration
+	generation models.Generation
+	packages   []string
+	nixpkgs    string
+}
+
+// demoPackage is a package the demo system may have, with the versions it
+// moves through and roughly how large it is.
+type demoPackage struct {
+	name     string
+	versions []string
+	mib      int64
+	// optional packages come and go; the rest are always there.
+	optional bool
+	// unit is the systemd unit the package provides, if any.
+	unit string
+}
+
+var demoCatalogue = []demoPackage{
+	{name: "linux", versions: []string{"6.6.30", "6.6.32", "6.6.35", "6.6.41", "6.6.44", "6.6.47"}, mib: 140},
+	{name: "glibc", versions: []string{"2.39-52", "2.40-36"}, mib: 30},
+	{name: "systemd", versions: []string{"255.6", "255.9", "256.2", "256.4"}, mib: 45},
+	{name: "bash", versions: []string{"5.2p26", "5.2p32"}, mib: 8},
+	{name: "coreutils", versions: []string{"9.5"}, mib: 17},
+	{name: "openssh", versions: []string{"9.7p1", "9.8p1"}, mib: 6, unit: "sshd.service"},
+	{name: "nix", versions: []string{"2.18.5", "2.18.7", "2.24.5"}, mib: 28, unit: "nix-daemon.service"},
+	{name: "firefox", versions: []string{"126.0.1", "127.0", "127.0.2", "128.0", "128.0.3", "129.0.1"}, mib: 250},
+	{name: "git", versions: []string{"2.44.1", "2.45.1", "2.45.2", "2.46.0"}, mib: 45},
+	{name: "neovim", versions: []string{"0.9.5", "0.10.0", "0.10.1"}, mib: 30},
+	{name: "python3", versions: []string{"3.11.9", "3.12.4"}, mib: 110},
+	{name: "openssl", versions: []string{"3.0.13", "3.0.14"}, mib: 9},
+	{name: "curl", versions: []string{"8.7.1", "8.8.0", "8.9.1"}, mib: 4},
+	{name: "mesa", versions: []string{"24.0.7", "24.1.1", "24.1.4"}, mib: 320},
+	{name: "networkmanager", versions: []string{"1.46.0", "1.48.2"}, mib: 22, unit: "NetworkManager.service"},
+	{name: "pipewire", versions: []string{"1.0.6", "1.0.7", "1.2.1"}, mib: 15},
+	{name: "gnome-shell", versions: []string{"46.1", "46.2", "46.3"}, mib: 60},
+	{name: "docker", versions: []string{"24.0.9", "25.0.5", "27.1.1"}, mib: 180, optional: true, unit: "docker.service"},
+	{name: "postgresql", versions: []string{"15.7", "16.3"}, mib: 75, optional: true, unit: "postgresql.service"},
+	{name: "nginx", versions: []string{"1.26.0", "1.26.1"}, mib: 5, optional: true, unit: "nginx.service"},
+	{name: "vscode", versions: []string{"1.89.1", "1.90.2", "1.91.1"}, mib: 420, optional: true},
+	{name: "libreoffice", versions: []string{"24.2.3.2", "24.2.5.2"}, mib: 900, optional: true},
+	{name: "steam", versions: []string{"1.0.0.79", "1.0.0.81"}, mib: 35, optional: true},
+	{name: "rustc", versions: []string{"1.77.2", "1.78.0", "1.79.0", "1.80.1"}, mib: 530, optional: true},
+	{name: "go", versions: []string{"1.22.3", "1.22.5", "1.22.6"}, mib: 220, optional: true},
+}
+
+const (
+	demoGenerations = 42
+	demoProfile     = "/nix/var/nix/profiles/system"
+)
+
+// NewDemo makes up the demo system. The same history comes out every time,
+// ending around now.
+func NewDemo(opts ...Option) *Demo {
+	r := rand.New(rand.NewPCG(1, 2))
+	d := &Demo{settings: newSettings(opts), packages: make(map[string][]string), nixpkgs: make(map[string]string)}
+
+	// Each package moves to its next version at generations picked at
+	// random; optional ones are installed or not to begin with, and some
+	// rebuilds add or drop one.
+	bumps := make([][]int, len(demoCatalogue))
+	installed := make([]bool, len(demoCatalogue))
+	for i, p := range demoCatalogue {
+		for _, n := range r.Perm(demoGenerations - 1)[:len(p.versions)-1] {
+			bumps[i] = append(bumps[i], n+2)
+		}
+		installed[i] = !p.optional || r.IntN(2) == 0
+	}
+	when := time.Now()
+	rev := demoRev("nixpkgs", 1)
+	for n := 1; n <= demoGenerations; n++ {
+		id := strconv.Itoa(n)
+		if n > 1 && r.IntN(5) == 0 {
+			if i := r.IntN(len(demoCatalogue)); demoCatalogue[i].optional {
+				installed[i] = !installed[i]
+			}
+		}
+		var packages []string
+		for i, p := range demoCatalogue {
+			if !installed[i] {
+				continue
+			}
+			version := 0
+			for _, b := range bumps[i] {
+				if b <= n {
+					version++
+				}
+				if b == n {
+					// Version bumps come with a nixpkgs update.
+					rev = demoRev("nixpkgs", n)
+				}
+			}
+			packages = append(packages, demoPath(p.name, p.versions[version]))
+		}
+		d.packages[id] = packages
+		d.nixpkgs[id] = rev
+
+		when = when.Add(time.Duration(1+r.IntN(6)*24) * time.Hour).Add(time.Duration(r.IntN(600)) * time.Minute)
+		d.generations = append(d.generations, models.Generation{
+			ID:            id,
+			Timestamp:     when,
+			Profiles:      []string{fmt.Sprintf("%s-%s-link", demoProfile, id)},
+			Profile:       demoProfile,
+			ClosureHash:   demoHash(strings.Join(packages, " ")),
+			StorePath:     demoSystemPath(id),
+			KernelVersion: demoVersion(packages, "linux"),
+			FlakeURL:      "git+file:///etc/nixos",
+			FlakeRevision: demoRev("config", n),
+		})
+	}
+	// The newest generation was built a few hours ago.
+	shift := time.Now().Add(-3 * time.Hour).Truncate(time.Minute).Sub(when)
+	for i := range d.generations {
+		gen := &d.generations[i]
+		gen.Timestamp = gen.Timestamp.Add(shift)
+		gen.NixosVersion = fmt.Sprintf("24.05.%s.%s", gen.Timestamp.Format("20060102"), d.nixpkgs[gen.ID][:7])
+	}
+	last := &d.generations[len(d.generations)-1]
+	last.Current, last.Booted, last.BootDefault = true, true, true
+	last.Description = "(current)"
+	d.generations[len(d.generations)-6].KnownGood = true
+	d.generations[len(d.generations)-12].Pinned = true
+	d.generations[len(d.generations)-12].PinName = "before-upgrade"
+	slices.Reverse(d.generations)
+	return d
+}
+
+// demoHash makes up a store hash, in Nix's base-32 alphabet.
+func demoHash(parts ...any) string {
+	return demoDigits("0123456789abcdfghijklmnpqrsvwxyz", 32, parts)
+}
+
+// demoRev makes up a git revision.
+func demoRev(parts ...any) string {
+	return demoDigits("0123456789abcdef", 40, parts)
+}
+
+// demoDigits makes up n digits from alphabet, the same ones for the same
+// parts.
+func demoDigits(alphabet string, n int, parts []any) string {
+	h := fnv.New64a()
+	fmt.Fprint(h, parts...)
+	r := rand.New(rand.NewPCG(h.Sum64(), 0))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[r.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+func demoPath(name, version string) string {
+	return "/nix/store/" + demoHash(name, version) + "-" + name + "-" + version
+}
+
+func demoSystemPath(id string) string {
+	return "/nix/store/" + demoHash("system", id) + "-nixos-system-demo-24.05"
+}
+
+// demoVersion returns the version of the named package among packages.
+func demoVersion(packages []string, name string) string {
+	for _, p := range packages {
+		if _, pname, version := models.ParseStorePath(p); pname == name {
+			return version
+		}
+	}
+	return ""
+}
+
+// demoSize is a package's own size, its catalogue size give or take a
+// little from version to version.
+func demoSize(path string) int64 {
+	_, name, version := models.ParseStorePath(path)
+	for _, p := range demoCatalogue {
+		if p.name == name {
+			h := fnv.New32a()
+			fmt.Fprint(h, version)
+			return p.mib<<20 + int64(h.Sum32()%(1<<22))
+		}
+	}
+	return 1 << 20
+}
+
+func demoUnit(path string) string {
+	_, name, _ := models.ParseStorePath(path)
+	for _, p := range demoCatalogue {
+		if p.name == name {
+			return p.unit
+		}
+	}
+	return ""
+}
+
+// lookup returns the packages of generation id.
+func (c *Demo) lookup(id string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	packages, ok := c.packages[id]
+	if !ok {
+		return nil, fmt.Errorf("generation %s does not exist", id)
+	}
+	return packages, nil
+}
+
+func (c *Demo) currentID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, gen := range c.generations {
+		if gen.Current {
+			return gen.ID
+		}
+	}
+	return ""
+}
+
+func (c *Demo) GetGenerations(ctx context.Context) ([]models.Generation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.generations), nil
+}
+
+func (c *Demo) GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error) {
+	generations, _ := c.GetGenerations(ctx)
+	for i := range generations {
+		sizes, _ := c.GetPathSizes(ctx, generations[i].ID)
+		for _, p := range sizes {
+			generations[i].ClosureSize += p.Size
+		}
+	}
+	return generations, nil
+}
+
+func (c *Demo) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	from, err := c.lookup(fromID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	to, err := c.lookup(toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	return demoDiff(from, to), nil
+}
+
+func demoDiff(from, to []string) models.GenerationDiff {
+	diff := models.DiffPaths(from, to)
+	sizes := make(map[string]int64)
+	for _, p := range append(slices.Clone(from), to...) {
+		sizes[p] = demoSize(p)
+	}
+	diff.ApplySizes(sizes)
+	return diff
+}
+
+func (c *Demo) StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	emitDiff(diff, fn)
+	return diff, nil
+}
+
+func (c *Demo) GetDiffsBulk(ctx context.Context, pairs [][2]string) ([]models.GenerationDiff, error) {
+	return bulkSequential(ctx, pairs, c.GetDiff)
+}
+
+// GetPendingDiff pretends to build the configuration, which has moved on
+// a little from the current generation.
+func (c *Demo) GetPendingDiff(ctx context.Context) (models.GenerationDiff, error) {
+	current, err := c.lookup(c.currentID())
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	progress := progressFrom(ctx)
+	for _, step := range []string{"evaluating configuration", "building nixos-system-demo-24.05", "done"} {
+		if progress != nil {
+			progress(step)
+		}
+		select {
+		case <-ctx.Done():
+			return models.GenerationDiff{}, ctx.Err()
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+	pending := slices.Clone(current)
+	for i, p := range pending {
+		if _, name, version := models.ParseStorePath(p); name == "firefox" || name == "curl" {
+			pending[i] = demoPath(name, version+".1")
+		}
+	}
+	return demoDiff(current, pending), nil
+}
+
+func (c *Demo) GetDiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.DiffStats{}, err
+	}
+	stats := models.StatsOf(diff)
+	for _, changes := range [][]models.PackageChange{diff.Added, diff.Removed, diff.Modified} {
+		for _, ch := range changes {
+			stats.SizeDelta += ch.SizeDelta
+		}
+	}
+	stats.SizeKnown = true
+	return stats, nil
+}
+
+func (c *Demo) GetDiffStatsBulk(ctx context.Context, pairs [][2]string) ([]models.DiffStats, error) {
+	return bulkSequential(ctx, pairs, c.GetDiffStats)
+}
+
+func (c *Demo) GetConfigDiff(ctx context.Context, fromID, toID string) (models.ConfigDiff, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	diff := models.ConfigDiff{Flake: true}
+	if from, to := c.nixpkgs[fromID], c.nixpkgs[toID]; from != to {
+		diff.Inputs = append(diff.Inputs, models.ConfigChange{Name: "nixpkgs", From: from, To: to})
+	}
+	return diff, nil
+}
+
+func (c *Demo) GetEtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.FileDiff{}, err
+	}
+	var files models.FileDiff
+	for _, ch := range diff.Added {
+		if unit := demoUnit(ch.Path); unit != "" {
+			files.Added = append(files.Added, "/etc/systemd/system/"+unit)
+		}
+	}
+	for _, ch := range diff.Removed {
+		if unit := demoUnit(ch.Path); unit != "" {
+			files.Removed = append(files.Removed, "/etc/systemd/system/"+unit)
+		}
+	}
+	for _, ch := range diff.Modified {
+		if unit := demoUnit(ch.Path); unit != "" {
+			files.Modified = append(files.Modified, "/etc/systemd/system/"+unit)
+		}
+	}
+	return files, nil
+}
+
+func (c *Demo) GetLockDiff(ctx context.Context, fromID, toID string) ([]models.InputChange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, to := c.nixpkgs[fromID], c.nixpkgs[toID]
+	if from == to {
+		return nil, nil
+	}
+	stamp := func(id string) int64 {
+		for _, gen := range c.generations {
+			if gen.ID == id {
+				return gen.Timestamp.Add(-36 * time.Hour).Unix()
+			}
+		}
+		return 0
+	}
+	return []models.InputChange{{
+		Name: "nixpkgs",
+		From: models.LockedInput{Rev: from, LastModified: stamp(fromID)},
+		To:   models.LockedInput{Rev: to, LastModified: stamp(toID)},
+	}}, nil
+}
+
+func (c *Demo) GetPackages(ctx context.Context, id string) ([]string, error) {
+	if id == "" {
+		id = c.currentID()
+	}
+	packages, err := c.lookup(id)
+	return slices.Clone(packages), err
+}
+
+func (c *Demo) GetDiffAgainstSnapshot(ctx context.Context, id, snapName string) (models.GenerationDiff, error) {
+	return diffAgainstSnapshot(ctx, c, id, snapName)
+}
+
+// GetDepsDiff diffs the runtime dependencies of a package, which in the
+// demo are glibc and, for most, openssl.
+func (c *Demo) GetDepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error) {
+	deps := func(id string) ([]string, error) {
+		packages, err := c.lookup(id)
+		if err != nil {
+			return nil, err
+		}
+		var out []string
+		for _, p := range packages {
+			if _, name, _ := models.ParseStorePath(p); name == "glibc" || name == "openssl" {
+				out = append(out, p)
+			}
+		}
+		return out, nil
+	}
+	from, err := deps(fromID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	to, err := deps(toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	return demoDiff(from, to), nil
+}
+
+func (c *Demo) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	generations, _ := c.GetGenerations(ctx)
+	var presence []models.PackagePresence
+	for _, gen := range generations {
+		packages, _ := c.lookup(gen.ID)
+		version := demoVersion(packages, name)
+		presence = append(presence, models.PackagePresence{Generation: gen.ID, Present: version != "", Version: version})
+	}
+	return presence, nil
+}
+
+func (c *Demo) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	return []models.Profile{{Name: "system", Path: demoProfile}}, nil
+}
+
+// DryActivate reports the units of the packages that differ from the
+// running generation as ones that would restart.
+func (c *Demo) DryActivate(ctx context.Context, id string) (models.ActivationPreview, error) {
+	diff, err := c.GetDiff(ctx, c.currentID(), id)
+	if err != nil {
+		return models.ActivationPreview{}, err
+	}
+	var preview models.ActivationPreview
+	for _, ch := range diff.Modified {
+		if unit := demoUnit(ch.Path); unit != "" {
+			preview.Restart = append(preview.Restart, unit)
+		}
+	}
+	for _, ch := range diff.Added {
+		if unit := demoUnit(ch.Path); unit != "" {
+			preview.Start = append(preview.Start, unit)
+		}
+	}
+	for _, ch := range diff.Removed {
+		if unit := demoUnit(ch.Path); unit != "" {
+			preview.Stop = append(preview.Stop, unit)
+		}
+	}
+	return preview, nil
+}
+
+// expiring returns the generations collecting garbage older than age would
+// delete. Call with c.mu held.
+func (c *Demo) expiring(age string) ([]models.Generation, error) {
+	d, err := models.ParseAge(age)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-d)
+	var out []models.Generation
+	for _, gen := range c.generations {
+		if !gen.Current && !gen.Pinned && gen.Timestamp.Before(cutoff) {
+			out = append(out, gen)
+		}
+	}
+	return out, nil
+}
+
+func (c *Demo) GCDryRun(ctx context.Context, age string) (models.GCPreview, error) {
+	c.mu.Lock()
+	doomed, err := c.expiring(age)
+	c.mu.Unlock()
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	var preview models.GCPreview
+	for _, gen := range doomed {
+		preview.Generations = append(preview.Generations, models.GCGeneration{Profile: gen.Profile, ID: gen.ID})
+	}
+	// What the survivors still reference stays.
+	kept := make(map[string]bool)
+	c.mu.Lock()
+	for _, gen := range c.generations {
+		if !slices.ContainsFunc(doomed, func(g models.Generation) bool { return g.ID == gen.ID }) {
+			for _, p := range c.packages[gen.ID] {
+				kept[p] = true
+			}
+		}
+	}
+	freed := make(map[string]bool)
+	for _, gen := range doomed {
+		for _, p := range c.packages[gen.ID] {
+			if !kept[p] && !freed[p] {
+				freed[p] = true
+				preview.Paths++
+				preview.Bytes += demoSize(p)
+			}
+		}
+	}
+	c.mu.Unlock()
+	return preview, nil
+}
+
+// GetPathSizes lists the generation's system, its packages and the paths
+// tying them together.
+func (c *Demo) GetPathSizes(ctx context.Context, id string) ([]models.PathSize, error) {
+	nodes, err := c.GetClosureGraph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]models.PathSize, len(nodes))
+	for i, n := range nodes {
+		sizes[i] = models.PathSize{Path: n.Path, Size: n.Size}
+	}
+	slices.SortFunc(sizes, func(a, b models.PathSize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Path, b.Path))
+	})
+	return sizes, nil
+}
+
+// GetClosureGraph makes the closure up: the system refers to its etc and
+// its system-path, which refers to every package; everything refers to
+// glibc.
+func (c *Demo) GetClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error) {
+	packages, err := c.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	system := demoSystemPath(id)
+	etc := "/nix/store/" + demoHash("etc", id) + "-etc"
+	systemPath := "/nix/store/" + demoHash("system-path", id) + "-system-path"
+	glibc := ""
+	for _, p := range packages {
+		if _, name, _ := models.ParseStorePath(p); name == "glibc" {
+			glibc = p
+		}
+	}
+	withGlibc := func(refs ...string) []string {
+		if glibc != "" {
+			refs = append(refs, glibc)
+		}
+		return refs
+	}
+	nodes := []models.StoreNode{
+		{Path: system, Size: 40 << 10, References: []string{etc, systemPath}},
+		{Path: etc, Size: 2 << 20, References: withGlibc()},
+		{Path: systemPath, Size: 300 << 10, References: slices.Clone(packages)},
+	}
+	for _, p := range packages {
+		node := models.StoreNode{Path: p, Size: demoSize(p)}
+		if p != glibc {
+			node.References = withGlibc()
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (c *Demo) WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error) {
+	nodes, err := c.GetClosureGraph(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]models.StoreNode, len(nodes))
+	target := ""
+	for _, n := range nodes {
+		byPath[n.Path] = n
+		if _, name, _ := models.ParseStorePath(n.Path); n.Path == pkg || name == pkg {
+			target = n.Path
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("%s not found in generation %s", pkg, id)
+	}
+	// Every chain from the system down to the target.
+	var walk func(path string) (models.DependencyNode, bool)
+	walk = func(path string) (models.DependencyNode, bool) {
+		node := models.DependencyNode{Path: path}
+		if path == target {
+			return node, true
+		}
+		for _, ref := range byPath[path].References {
+			if child, ok := walk(ref); ok {
+				node.Children = append(node.Children, child)
+			}
+		}
+		return node, len(node.Children) > 0
+	}
+	root, _ := walk(nodes[0].Path)
+	return &root, nil
+}
+
+func (c *Demo) MarkKnownGood(ctx context.Context, id string) error {
+	return c.update(id, func(gen *models.Generation) {
+		gen.KnownGood = true
+	})
+}
+
+func (c *Demo) Rollback(ctx context.Context, id string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.packages[id]; !ok {
+		return fmt.Errorf("failed to roll back to generation %s: it does not exist", id)
+	}
+	for i := range c.generations {
+		gen := &c.generations[i]
+		gen.Current = gen.ID == id
+		gen.Description = ""
+		if gen.Current {
+			gen.Description = "(current)"
+		}
+	}
+	return nil
+}
+
+func (c *Demo) SetBootDefault(ctx context.Context, id string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.packages[id]; !ok {
+		return fmt.Errorf("failed to make generation %s the boot default: it does not exist", id)
+	}
+	for i := range c.generations {
+		c.generations[i].BootDefault = c.generations[i].ID == id
+	}
+	return nil
+}
+
+func (c *Demo) DeleteGenerations(ctx context.Context, ids []string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.deletable(ids); err != nil {
+		return fmt.Errorf("failed to delete generations %s: %w", strings.Join(ids, ", "), err)
+	}
+	c.remove(ids)
+	return nil
+}
+
+// deletable refuses to delete the current generation and pinned ones, as
+// the backend does. Call with c.mu held.
+func (c *Demo) deletable(ids []string) error {
+	for _, gen := range c.generations {
+		if !slices.Contains(ids, gen.ID) {
+			continue
+		}
+		switch {
+		case gen.Current:
+			return fmt.Errorf("generation %s is current", gen.ID)
+		case gen.Pinned:
+			return fmt.Errorf("generation %s is pinned; unpin it first", gen.ID)
+		}
+	}
+	return nil
+}
+
+func (c *Demo) GetTrash(ctx context.Context) ([]models.TrashedGeneration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var trash []models.TrashedGeneration
+	for _, t := range c.trash {
+		trash = append(trash, t.TrashedGeneration)
+	}
+	return trash, nil
+}
+
+func (c *Demo) TrashGenerations(ctx context.Context, ids []string, keepDays int) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.deletable(ids); err != nil {
+		return fmt.Errorf("failed to move generations %s to the trash: %w", strings.Join(ids, ", "), err)
+	}
+	now := time.Now()
+	for _, gen := range c.generations {
+		if slices.Contains(ids, gen.ID) {
+			c.trash = append(c.trash, demoTrashed{
+				TrashedGeneration: models.TrashedGeneration{ID: gen.ID, StorePath: gen.StorePath, Trashed: now},
+				generation:        gen,
+				packages:          c.packages[gen.ID],
+				nixpkgs:           c.nixpkgs[gen.ID],
+			})
+		}
+	}
+	c.remove(ids)
+	cutoff := now.AddDate(0, 0, -keepDays)
+	c.trash = slices.DeleteFunc(c.trash, func(t demoTrashed) bool {
+		return t.Trashed.Before(cutoff)
+	})
+	return nil
+}
+
+func (c *Demo) RestoreGeneration(ctx context.Context, id string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.IndexFunc(c.trash, func(t demoTrashed) bool { return t.ID == id })
+	switch _, exists := c.packages[id]; {
+	case i < 0:
+		return fmt.Errorf("failed to restore generation %s: it is not in the trash", id)
+	case exists:
+		return fmt.Errorf("failed to restore generation %s: a newer generation took its number", id)
+	}
+	t := c.trash[i]
+	c.trash = slices.Delete(c.trash, i, i+1)
+	c.packages[id], c.nixpkgs[id] = t.packages, t.nixpkgs
+	gen := t.generation
+	gen.KnownGood = false
+	c.generations = append(c.generations, gen)
+	// Newest first, as listed.
+	slices.SortFunc(c.generations, func(a, b models.Generation) int {
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return y - x
+	})
+	return nil
+}
+
+func (c *Demo) EmptyTrash(ctx context.Context) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trash = nil
+	return nil
+}
+
+// remove drops the generations with ids. Call with c.mu held.
+func (c *Demo) remove(ids []string) {
+	c.generations = slices.DeleteFunc(c.generations, func(gen models.Generation) bool {
+		return slices.Contains(ids, gen.ID)
+	})
+	for _, id := range ids {
+		delete(c.packages, id)
+		delete(c.nixpkgs, id)
+	}
+}
+
+func (c *Demo) GC(ctx context.Context, age string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doomed, err := c.expiring(age)
+	if err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+	var ids []string
+	for _, gen := range doomed {
+		ids = append(ids, gen.ID)
+	}
+	c.remove(ids)
+	return nil
+}
+
+func (c *Demo) Pin(ctx context.Context, id, name string) error {
+	return c.update(id, func(gen *models.Generation) {
+		gen.Pinned, gen.PinName = true, name
+	})
+}
+
+func (c *Demo) Unpin(ctx context.Context, id string) error {
+	return c.update(id, func(gen *models.Generation) {
+		gen.Pinned, gen.PinName = false, ""
+	})
+}
+
+// update applies fn to generation id.
+func (c *Demo) update(id string, fn func(gen *models.Generation)) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.generations {
+		if c.generations[i].ID == id {
+			fn(&c.generations[i])
+			return nil
+		}
+	}
+	return fmt.Errorf("generation %s does not exist", id)
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"nix-timemach/internal/xdg"
+)
+
+// BinaryName is the backend binary's file name. On a remote host it is
+// looked up on the host's $PATH.
+const BinaryName = "nix-timemach-backend"
+
+// checkoutPath is where cargo puts the backend when it is built in the
+// source checkout nix-timemach is run from.
+var checkoutPath = filepath.Join("..", "backend", "target", "release", BinaryName)
+
+// MissingBinaryError is returned by FindBinary when there is no backend
+// binary in any of the places it looks.
+type MissingBinaryError struct {
+	// Searched lists the places looked in, in order.
+	Searched []string
+}
+
+func (e *MissingBinaryError) Error() string {
+	return fmt.Sprintf("can't find the backend binary %s; looked in %s", BinaryName, strings.Join(e.Searched, ", "))
+}
+
+// FindBinary returns the backend binary to run: the configured one, the one
+// named by $NIX_TIMEMACH_BACKEND_PATH, the one built into this program, a
+// build in the source checkout, or one installed next to this program, on
+// $PATH or in the XDG data directories, in that order. A configured path
+// or variable that names nothing is an error rather than passed over, as
+// the binary it meant would be missed.
+func FindBinary(configured string) (string, error) {
+	for _, explicit := range []string{configured, os.Getenv("NIX_TIMEMACH_BACKEND_PATH")} {
+		if explicit == "" {
+			continue
+		}
+		if _, err := os.Stat(explicit); err != nil {
+			return "", &MissingBinaryError{Searched: []string{explicit}}
+		}
+		return explicit, nil
+	}
+	if p, err := extractEmbedded(); err != nil || p != "" {
+		return p, err
+	}
+
+	candidates := []string{checkoutPath}
+	if exe, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(exe), BinaryName))
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	if p, err := exec.LookPath(BinaryName); err == nil {
+		return p, nil
+	}
+	searched := append(candidates, "$PATH")
+	for _, dir := range xdg.DataDirs() {
+		p := filepath.Join(dir, BinaryName)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+		searched = append(searched, p)
+	}
+	return "", &MissingBinaryError{Searched: searched}
+}
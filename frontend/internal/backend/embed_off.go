@@ -0,0 +1,7 @@
+//go:build !embedbackend
+
+package backend
+
+// embeddedBinary is empty without the embedbackend tag; the backend is
+// found by FindBinary instead.
+var embeddedBinary []byte
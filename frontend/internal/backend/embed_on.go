@@ -0,0 +1,14 @@
+//go:build embedbackend
+
+package backend
+
+import _ "embed"
+
+// Building with -tags embedbackend puts the backend inside this program, so
+// it ships as a single file. The binary has to be copied here first, which
+// go generate -tags embedbackend ./internal/backend does from a fresh
+// release build.
+//
+//go:generate sh -c "cd ../../../backend && cargo build --release && cp target/release/nix-timemach-backend ../frontend/internal/backend/"
+//go:embed nix-timemach-backend
+var embeddedBinary []byte
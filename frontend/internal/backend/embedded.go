@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"nix-timemach/internal/xdg"
+)
+
+// extractEmbedded writes the backend built into this program, if there is
+// one, to the cache directory and returns its path, or "" if there is none.
+// The copy is named by its hash and checked against it on every start, so
+// a truncated or altered one is replaced and one from another build is
+// never run.
+func extractEmbedded() (string, error) {
+	if len(embeddedBinary) == 0 {
+		return "", nil
+	}
+	sum := sha256.Sum256(embeddedBinary)
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract the embedded backend: %w", err)
+	}
+	path := filepath.Join(dir, "backend", hex.EncodeToString(sum[:8]), BinaryName)
+	if existing, err := os.ReadFile(path); err == nil && sha256.Sum256(existing) == sum {
+		return path, nil
+	}
+	if err := xdg.WriteExecutable(path, embeddedBinary); err != nil {
+		return "", fmt.Errorf("failed to extract the embedded backend: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Failure is a backend call that failed, with what is known about why.
+// Its message is short when the backend reported a structured error; the
+// details are kept for a closer look.
+type Failure struct {
+	// What the call was getting, e.g. "generations", or "" for an action.
+	What string
+	// Command is the command line that ran, or "" for the backend server.
+	Command string
+	// Kind and Message are the backend's own account of the error. Kind
+	// is "" when the backend didn't give one.
+	Kind    string
+	Message string
+	// Stderr is the tail of what the backend printed to stderr, less the
+	// error object it ended with.
+	Stderr string
+	// Err is how the process ended, if it did.
+	Err error
+}
+
+func (f *Failure) Error() string {
+	var msg string
+	switch {
+	case f.Message != "":
+		msg = f.Message
+	case f.Command != "" && f.Err != nil:
+		msg = fmt.Sprintf("%s: %v", f.Command, f.Err)
+	case f.Err != nil:
+		msg = f.Err.Error()
+	default:
+		msg = "backend failed"
+	}
+	if f.Message == "" && f.Stderr != "" {
+		msg += "\n" + f.Stderr
+	}
+	if f.What == "" {
+		return msg
+	}
+	return fmt.Sprintf("failed to get %s: %s", f.What, msg)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// errorReport is how the backend describes an error: the last line of its
+// stderr is {"error": report}, and a server reply carries report itself.
+type errorReport struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// newFailure builds the Failure of a process that exited with err, taking
+// the backend's error object off the end of its stderr if it left one.
+func newFailure(what, command string, err error, stderr []byte) *Failure {
+	f := &Failure{What: what, Command: command, Err: err}
+	stderr = bytes.TrimSpace(stderr)
+	rest, last := []byte(nil), stderr
+	if i := bytes.LastIndexByte(stderr, '\n'); i >= 0 {
+		rest, last = stderr[:i], stderr[i+1:]
+	}
+	var wrapper struct {
+		Error *errorReport `json:"error"`
+	}
+	if json.Unmarshal(last, &wrapper) == nil && wrapper.Error != nil {
+		f.Kind, f.Message = wrapper.Error.Kind, wrapper.Error.Message
+		stderr = rest
+	}
+	f.Stderr = stderrSummary(stderr)
+	return f
+}
+
+// parseReport reads the error of a server reply, which older backends
+// send as a bare string.
+func parseReport(raw json.RawMessage) errorReport {
+	var report errorReport
+	if json.Unmarshal(raw, &report) == nil {
+		return report
+	}
+	var msg string
+	json.Unmarshal(raw, &msg)
+	return errorReport{Message: strings.TrimSpace(msg)}
+}
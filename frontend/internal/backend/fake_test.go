@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The test binary stands in for the backend binary when it is started with
+// fakeBackendEnv set, so the Process client can be tested without Nix.
+const (
+	fakeBackendEnv = "NIX_TIMEMACH_FAKE_BACKEND"
+	// fakeOutputEnv names a file list-generations prints as is.
+	fakeOutputEnv = "NIX_TIMEMACH_FAKE_OUTPUT"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeBackendEnv) != "" {
+		os.Exit(fakeBackend(os.Args[1:], os.Stdout))
+	}
+	os.Exit(m.Run())
+}
+
+// newFakeProcess is a Process whose backend is this test binary.
+func newFakeProcess(t testing.TB, opts ...Option) *Process {
+	t.Helper()
+	t.Setenv(fakeBackendEnv, "1")
+	return NewProcess(os.Args[0], opts...)
+}
+
+// fakeBackend answers the subcommands the tests use, the way the backend
+// binary does, and returns its exit code.
+func fakeBackend(args []string, w io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "error: a subcommand is required")
+		return 2
+	}
+	enc := json.NewEncoder(w)
+	switch args[0] {
+	case "version":
+		enc.Encode(Version{Version: "test", Protocol: Protocol})
+	case "list-generations":
+		data, err := os.ReadFile(os.Getenv(fakeOutputEnv))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w.Write(data)
+	case "diff":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "error: diff takes FROM and TO")
+			return 2
+		}
+		enc.Encode(fakeDiff(args[1], args[2]))
+	case "diff-bulk":
+		type entry struct {
+			Diff  any    `json:"diff"`
+			Error string `json:"error,omitempty"`
+		}
+		entries := []entry{}
+		for _, pair := range args[1:] {
+			from, to, _ := strings.Cut(pair, ":")
+			if to == "missing" {
+				entries = append(entries, entry{Error: "generation missing does not exist"})
+				continue
+			}
+			entries = append(entries, entry{Diff: fakeDiff(from, to)})
+		}
+		enc.Encode(entries)
+	default:
+		fmt.Fprintf(os.Stderr, "error: unrecognized subcommand '%s'\n", args[0])
+		return 2
+	}
+	return 0
+}
+
+// fakeDiff is a diff that tells which generations it is between: the
+// package from-to was added.
+func fakeDiff(from, to string) map[string]any {
+	return map[string]any{
+		"added":    []map[string]string{{"path": "/nix/store/00000000000000000000000000000000-diff-" + from + "-" + to, "name": "diff", "new_version": from + "-" + to}},
+		"removed":  []any{},
+		"modified": []any{},
+	}
+}
@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/snapshot"
+)
+
+// errReadOnly is returned by the actions of a Files client.
+var errReadOnly = errors.New("exported package sets can't be changed")
+
+// Files is the Client over package sets exported to files, as snapshots or
+// arrays of store paths, so states captured on other machines or long ago
+// can be compared without Nix. Each file is a generation whose ID is the
+// path it was loaded from. Diffs are computed here; anything that needs the
+// store is ErrUnsupported, and actions fail.
+type Files struct {
+	settings
+
+	generations []models.Generation
+	// packages holds each file's package set, by generation ID.
+	packages map[string][]string
+}
+
+// NewFiles loads the package sets in paths, oldest first.
+func NewFiles(paths []string, opts ...Option) (*Files, error) {
+	c := &Files{settings: newSettings(opts), packages: make(map[string][]string)}
+	for _, p := range paths {
+		if _, ok := c.packages[p]; ok {
+			continue
+		}
+		snap, err := snapshot.LoadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		gen := models.Generation{ID: p, Description: snap.Name, Timestamp: snap.Created}
+		if snap.Generation != "" {
+			gen.Description = fmt.Sprintf("%s (generation %s)", snap.Name, snap.Generation)
+		}
+		sanitizeGeneration(&gen)
+		c.generations = append(c.generations, gen)
+		c.packages[p] = snap.Paths
+	}
+	return c, nil
+}
+
+func (c *Files) lookup(id string) ([]string, error) {
+	packages, ok := c.packages[id]
+	if !ok {
+		return nil, fmt.Errorf("no package set loaded from %s", id)
+	}
+	return packages, nil
+}
+
+func (c *Files) GetGenerations(ctx context.Context) ([]models.Generation, error) {
+	return slices.Clone(c.generations), nil
+}
+
+func (c *Files) GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error) {
+	return c.GetGenerations(ctx)
+}
+
+func (c *Files) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	from, err := c.lookup(fromID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	to, err := c.lookup(toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	return models.DiffPaths(from, to), nil
+}
+
+func (c *Files) StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	emitDiff(diff, fn)
+	return diff, nil
+}
+
+func (c *Files) GetDiffsBulk(ctx context.Context, pairs [][2]string) ([]models.GenerationDiff, error) {
+	return bulkSequential(ctx, pairs, c.GetDiff)
+}
+
+func (c *Files) GetDiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.DiffStats{}, err
+	}
+	return models.StatsOf(diff), nil
+}
+
+func (c *Files) GetDiffStatsBulk(ctx context.Context, pairs [][2]string) ([]models.DiffStats, error) {
+	return bulkSequential(ctx, pairs, c.GetDiffStats)
+}
+
+func (c *Files) GetPackages(ctx context.Context, id string) ([]string, error) {
+	packages, err := c.lookup(id)
+	return slices.Clone(packages), err
+}
+
+func (c *Files) GetDiffAgainstSnapshot(ctx context.Context, id, snapName string) (models.GenerationDiff, error) {
+	return diffAgainstSnapshot(ctx, c, id, snapName)
+}
+
+func (c *Files) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	var presence []models.PackagePresence
+	for _, gen := range c.generations {
+		p := models.PackagePresence{Generation: gen.ID}
+		for _, path := range c.packages[gen.ID] {
+			if _, pname, version := models.ParseStorePath(path); pname == name {
+				p.Present, p.Version = true, version
+				break
+			}
+		}
+		presence = append(presence, p)
+	}
+	return presence, nil
+}
+
+func (c *Files) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	return nil, nil
+}
+
+func (c *Files) GetPendingDiff(ctx context.Context) (models.GenerationDiff, error) {
+	return models.GenerationDiff{}, ErrUnsupported
+}
+
+func (c *Files) GetConfigDiff(ctx context.Context, fromID, toID string) (models.ConfigDiff, error) {
+	return models.ConfigDiff{}, ErrUnsupported
+}
+
+func (c *Files) GetEtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error) {
+	return models.FileDiff{}, ErrUnsupported
+}
+
+func (c *Files) GetLockDiff(ctx context.Context, fromID, toID string) ([]models.InputChange, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Files) GetDepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error) {
+	return models.GenerationDiff{}, ErrUnsupported
+}
+
+func (c *Files) GetPathSizes(ctx context.Context, id string) ([]models.PathSize, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Files) WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Files) GetClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Files) GetTrash(ctx context.Context) ([]models.TrashedGeneration, error) {
+	return nil, nil
+}
+
+func (c *Files) DryActivate(ctx context.Context, id string) (models.ActivationPreview, error) {
+	return models.ActivationPreview{}, ErrUnsupported
+}
+
+func (c *Files) GCDryRun(ctx context.Context, age string) (models.GCPreview, error) {
+	return models.GCPreview{}, ErrUnsupported
+}
+
+func (c *Files) MarkKnownGood(ctx context.Context, id string) error {
+	return errReadOnly
+}
+
+func (c *Files) Rollback(ctx context.Context, id string) error {
+	return errReadOnly
+}
+
+func (c *Files) SetBootDefault(ctx context.Context, id string) error {
+	return errReadOnly
+}
+
+func (c *Files) DeleteGenerations(ctx context.Context, ids []string) error {
+	return errReadOnly
+}
+
+func (c *Files) GC(ctx context.Context, age string) error {
+	return errReadOnly
+}
+
+func (c *Files) Pin(ctx context.Context, id, name string) error {
+	return errReadOnly
+}
+
+func (c *Files) Unpin(ctx context.Context, id string) error {
+	return errReadOnly
+}
+
+func (c *Files) TrashGenerations(ctx context.Context, ids []string, keepDays int) error {
+	return errReadOnly
+}
+
+func (c *Files) RestoreGeneration(ctx context.Context, id string) error {
+	return errReadOnly
+}
+
+func (c *Files) EmptyTrash(ctx context.Context) error {
+	return errReadOnly
+}
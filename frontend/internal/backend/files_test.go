@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	before := filepath.Join("testdata", "packages.json")
+	after := filepath.Join("testdata", "snapshot.json")
+	c, err := NewFiles([]string{before, after, before})
+	if err != nil {
+		t.Fatalf("NewFiles: %v", err)
+	}
+
+	generations, _ := c.GetGenerations(ctx)
+	if len(generations) != 2 || generations[0].ID != before || generations[1].ID != after {
+		t.Fatalf("generations = %+v, want one per file", generations)
+	}
+	if got, want := generations[1].Description, "after (generation 42)"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+
+	diff, err := c.GetDiff(ctx, before, after)
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+	for _, check := range []struct {
+		kind    string
+		changes []models.PackageChange
+		want    []string
+	}{
+		{"added", diff.Added, []string{"ripgrep"}},
+		{"removed", diff.Removed, []string{"htop"}},
+		{"modified", diff.Modified, []string{"firefox"}},
+	} {
+		var names []string
+		for _, c := range check.changes {
+			names = append(names, c.Name)
+		}
+		if !slices.Equal(names, check.want) {
+			t.Errorf("%s = %v, want %v", check.kind, names, check.want)
+		}
+	}
+
+	presence, _ := c.FindPackage(ctx, "htop")
+	if len(presence) != 2 || !presence[0].Present || presence[1].Present || presence[0].Version != "3.3.0" {
+		t.Errorf("htop presence = %+v, want only in %s", presence, before)
+	}
+	if _, err := c.GetDiff(ctx, before, "other.json"); err == nil {
+		t.Error("GetDiff with a file that wasn't loaded succeeded")
+	}
+	if _, err := c.GetPathSizes(ctx, before); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetPathSizes error = %v, want ErrUnsupported", err)
+	}
+	if err := c.Rollback(ctx, before); err == nil {
+		t.Error("Rollback succeeded")
+	}
+}
+
+func TestNewFilesMissing(t *testing.T) {
+	if _, err := NewFiles([]string{filepath.Join("testdata", "missing.json")}); err == nil {
+		t.Error("NewFiles with a missing file succeeded")
+	}
+}
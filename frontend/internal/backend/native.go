@@ -0,0 +1,430 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/nix"
+)
+
+// Native is the Client that runs the Nix tools itself instead of going
+// through the backend binary.
+type Native struct {
+	settings
+}
+
+func NewNative(opts ...Option) *Native {
+	return &Native{settings: newSettings(opts)}
+}
+
+// nix runs the Nix tools for a call made under ctx, which may name a
+// profile.
+func (c *Native) nix(ctx context.Context) nix.Nix {
+	return nix.Nix{Store: c.store, Profile: profileFrom(ctx)}
+}
+
+func (c *Native) GetGenerations(ctx context.Context) ([]models.Generation, error) {
+	ctx, done := c.bounded(ctx, "generations")
+	generations, err := c.nix(ctx).Generations(ctx, false)
+	if err = done(err); err != nil {
+		return nil, err
+	}
+	for i := range generations {
+		sanitizeGeneration(&generations[i])
+	}
+	return generations, nil
+}
+
+func (c *Native) GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error) {
+	ctx, done := c.bounded(ctx, "generations")
+	generations, err := c.nix(ctx).Generations(ctx, true)
+	if err = done(err); err != nil {
+		return nil, err
+	}
+	for i := range generations {
+		sanitizeGeneration(&generations[i])
+	}
+	return generations, nil
+}
+
+func (c *Native) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	ctx, done := c.bounded(ctx, "diff")
+	diff, err := c.nix(ctx).Diff(ctx, fromID, toID)
+	if err = done(err); err != nil {
+		return models.GenerationDiff{}, err
+	}
+	sanitizeDiff(&diff)
+	return diff, nil
+}
+
+// StreamDiff has nothing to stream from, so it hands over the changes once
+// the whole diff is known.
+func (c *Native) StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error) {
+	diff, err := c.GetDiff(ctx, fromID, toID)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	emitDiff(diff, fn)
+	return diff, nil
+}
+
+// GetDiffsBulk has no process start to save, so it diffs each pair in turn.
+func (c *Native) GetDiffsBulk(ctx context.Context, pairs [][2]string) ([]models.GenerationDiff, error) {
+	return bulkSequential(ctx, pairs, c.GetDiff)
+}
+
+func (c *Native) GetPendingDiff(ctx context.Context) (models.GenerationDiff, error) {
+	var progress io.Writer
+	if fn := progressFrom(ctx); fn != nil {
+		w := &lineWriter{fn: fn}
+		defer w.flush()
+		progress = w
+	}
+	diff, err := c.nix(ctx).PendingDiff(ctx, progress)
+	if err != nil {
+		return models.GenerationDiff{}, err
+	}
+	sanitizeDiff(&diff)
+	return diff, nil
+}
+
+func (c *Native) GetDiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error) {
+	ctx, done := c.bounded(ctx, "diff stats")
+	stats, err := c.nix(ctx).DiffStats(ctx, fromID, toID)
+	return stats, done(err)
+}
+
+func (c *Native) GetDiffStatsBulk(ctx context.Context, pairs [][2]string) ([]models.DiffStats, error) {
+	return bulkSequential(ctx, pairs, c.GetDiffStats)
+}
+
+func (c *Native) GetConfigDiff(ctx context.Context, fromID, toID string) (models.ConfigDiff, error) {
+	ctx, done := c.bounded(ctx, "config diff")
+	diff := c.nix(ctx).ConfigDiff(ctx, fromID, toID)
+	if err := done(ctx.Err()); err != nil {
+		return models.ConfigDiff{}, err
+	}
+	sanitizeConfigDiff(&diff)
+	return diff, nil
+}
+
+func (c *Native) GetLockDiff(ctx context.Context, fromID, toID string) ([]models.InputChange, error) {
+	ctx, done := c.bounded(ctx, "lock diff")
+	changes := c.nix(ctx).LockDiff(ctx, fromID, toID)
+	if err := done(ctx.Err()); err != nil {
+		return nil, err
+	}
+	sanitizeInputChanges(changes)
+	return changes, nil
+}
+
+func (c *Native) GCDryRun(ctx context.Context, age string) (models.GCPreview, error) {
+	ctx, done := c.bounded(ctx, "garbage collection preview")
+	preview, err := c.nix(ctx).GCDryRun(ctx, age)
+	if err = done(err); err != nil {
+		return models.GCPreview{}, err
+	}
+	sanitizeGCPreview(&preview)
+	return preview, nil
+}
+
+func (c *Native) GetEtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error) {
+	ctx, done := c.bounded(ctx, "etc diff")
+	diff, err := c.nix(ctx).EtcDiff(ctx, fromID, toID)
+	if err = done(err); err != nil {
+		return models.FileDiff{}, err
+	}
+	sanitizeFileDiff(&diff)
+	return diff, nil
+}
+
+func (c *Native) GetPackages(ctx context.Context, id string) ([]string, error) {
+	ctx, done := c.bounded(ctx, "packages")
+	packages, err := c.nix(ctx).Packages(ctx, id)
+	if err = done(err); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+func (c *Native) DryActivate(ctx context.Context, id string) (models.ActivationPreview, error) {
+	ctx, done := c.bounded(ctx, "activation preview")
+	lines, err := c.nix(ctx).DryActivate(ctx, id)
+	if err = done(err); err != nil {
+		return models.ActivationPreview{}, err
+	}
+	sanitizeAll(lines)
+	return models.ParseDryActivate(lines), nil
+}
+
+func (c *Native) GetDiffAgainstSnapshot(ctx context.Context, id, snapName string) (models.GenerationDiff, error) {
+	return diffAgainstSnapshot(ctx, c, id, snapName)
+}
+
+func (c *Native) GetDepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error) {
+	ctx, done := c.bounded(ctx, "dependency diff")
+	diff, err := c.nix(ctx).DepsDiff(ctx, pkg, fromID, toID)
+	if err = done(err); err != nil {
+		return models.GenerationDiff{}, err
+	}
+	sanitizeDiff(&diff)
+	return diff, nil
+}
+
+func (c *Native) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	return nix.Profiles(), nil
+}
+
+func (c *Native) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	ctx, done := c.bounded(ctx, "package history")
+	presence, err := c.nix(ctx).FindPackage(ctx, name)
+	if err = done(err); err != nil {
+		return nil, fmt.Errorf("failed to find package %s: %w", name, err)
+	}
+	for i := range presence {
+		presence[i].Version = sanitize(presence[i].Version)
+	}
+	return presence, nil
+}
+
+func (c *Native) GetPathSizes(ctx context.Context, id string) ([]models.PathSize, error) {
+	ctx, done := c.bounded(ctx, "path sizes")
+	sizes, err := c.nix(ctx).PathSizes(ctx, id)
+	return sizes, done(err)
+}
+
+func (c *Native) WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error) {
+	ctx, done := c.bounded(ctx, "dependency chain")
+	node, err := c.nix(ctx).WhyDepends(ctx, id, pkg)
+	if err = done(err); err != nil {
+		return nil, err
+	}
+	if node != nil {
+		sanitizeDependencyNode(node)
+	}
+	return node, nil
+}
+
+func (c *Native) GetClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error) {
+	ctx, done := c.bounded(ctx, "closure")
+	nodes, err := c.nix(ctx).ClosureGraph(ctx, id)
+	return nodes, done(err)
+}
+
+// MarkKnownGood points a GC root at the generation's system, which both
+// protects its closure and records the mark across runs.
+func (c *Native) MarkKnownGood(ctx context.Context, id string) error {
+	target, err := nix.Target(id)
+	if err == nil {
+		err = c.runPrivileged(ctx, "mkdir", "-p", nix.GCRootsDir)
+	}
+	if err == nil {
+		err = c.runPrivileged(ctx, "ln", "-sfn", target, nix.KnownGoodRoot(id))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark generation %s as known good: %w", id, err)
+	}
+	return nil
+}
+
+// Rollback switches the system profile to a generation and activates it,
+// like nixos-rebuild --rollback does for the previous one.
+func (c *Native) Rollback(ctx context.Context, id string) error {
+	err := c.runPrivileged(ctx, c.nix(ctx).Wrap("nix-env", "--profile", nix.SystemProfile, "--switch-generation", id)...)
+	if err == nil {
+		err = c.runPrivileged(ctx, nix.SystemProfile+"/bin/switch-to-configuration", "switch")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll back to generation %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetBootDefault makes a generation the one the system boots into next,
+// like nixos-rebuild boot, without activating it.
+func (c *Native) SetBootDefault(ctx context.Context, id string) error {
+	err := c.runPrivileged(ctx, c.nix(ctx).Wrap("nix-env", "--profile", nix.SystemProfile, "--switch-generation", id)...)
+	if err == nil {
+		err = c.runPrivileged(ctx, nix.SystemProfile+"/bin/switch-to-configuration", "boot")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to make generation %s the boot default: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteGenerations deletes generations and their known-good roots. nix-env
+// refuses to delete the current generation; pinned ones are refused here.
+func (c *Native) DeleteGenerations(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if _, _, ok := nix.FindPin(id); ok {
+			return fmt.Errorf("failed to delete generations %s: generation %s is pinned; unpin it first", strings.Join(ids, ", "), id)
+		}
+	}
+	argv := append([]string{"nix-env", "--profile", nix.SystemProfile, "--delete-generations"}, ids...)
+	err := c.runPrivileged(ctx, c.nix(ctx).Wrap(argv...)...)
+	if err == nil {
+		roots := []string{"rm", "-f"}
+		for _, id := range ids {
+			roots = append(roots, nix.KnownGoodRoot(id))
+		}
+		err = c.runPrivileged(ctx, roots...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete generations %s: %w", strings.Join(ids, ", "), err)
+	}
+	return nil
+}
+
+// GC deletes the unpinned generations older than age, profile by profile,
+// then collects garbage. nix-collect-garbage --delete-older-than would do
+// both but knows nothing of pins.
+func (c *Native) GC(ctx context.Context, age string) error {
+	n := c.nix(ctx)
+	generations, err := n.ExpiringGenerations(ctx, age)
+	if err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+	var profiles []string
+	ids := make(map[string][]string)
+	for _, gen := range generations {
+		if _, ok := ids[gen.Profile]; !ok {
+			profiles = append(profiles, gen.Profile)
+		}
+		ids[gen.Profile] = append(ids[gen.Profile], gen.ID)
+	}
+	for _, profile := range profiles {
+		argv := append([]string{"nix-env", "--profile", profile, "--delete-generations"}, ids[profile]...)
+		if err = c.runPrivileged(ctx, n.Wrap(argv...)...); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = c.runPrivileged(ctx, n.Wrap("nix-store", "--gc")...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to collect garbage: %w", err)
+	}
+	return nil
+}
+
+// Pin points a GC root named after the generation, and the pin's name if
+// it has one, at the generation's system. Pinning again replaces the name.
+func (c *Native) Pin(ctx context.Context, id, name string) error {
+	target, err := nix.Target(id)
+	if err == nil {
+		err = c.runPrivileged(ctx, "mkdir", "-p", nix.GCRootsDir)
+	}
+	if root, _, ok := nix.FindPin(id); ok && err == nil {
+		err = c.runPrivileged(ctx, "rm", "-f", root)
+	}
+	if err == nil {
+		err = c.runPrivileged(ctx, "ln", "-sfn", target, nix.PinRoot(id, name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to pin generation %s: %w", id, err)
+	}
+	return nil
+}
+
+func (c *Native) GetTrash(ctx context.Context) ([]models.TrashedGeneration, error) {
+	var trash []models.TrashedGeneration
+	for _, t := range nix.Trash() {
+		trash = append(trash, t.TrashedGeneration)
+	}
+	return trash, nil
+}
+
+// TrashGenerations points a trash root at each generation's system before
+// deleting it, and takes the roots away again if the deletion fails.
+func (c *Native) TrashGenerations(ctx context.Context, ids []string, keepDays int) error {
+	now := time.Now()
+	err := c.runPrivileged(ctx, "mkdir", "-p", nix.GCRootsDir)
+	var roots []string
+	for _, id := range ids {
+		if err != nil {
+			break
+		}
+		var target string
+		if target, err = nix.Target(id); err == nil {
+			roots = append(roots, nix.TrashRoot(id, now))
+			err = c.runPrivileged(ctx, "ln", "-s", target, nix.TrashRoot(id, now))
+		}
+	}
+	if err == nil {
+		err = c.DeleteGenerations(ctx, ids)
+	}
+	if err != nil {
+		if len(roots) > 0 {
+			c.runPrivileged(ctx, append([]string{"rm", "-f"}, roots...)...)
+		}
+		return fmt.Errorf("failed to move generations %s to the trash: %w", strings.Join(ids, ", "), err)
+	}
+	return c.expireTrash(ctx, now.AddDate(0, 0, -keepDays))
+}
+
+// RestoreGeneration points the generation's link at its system again,
+// which puts it back in the list; the boot menu has it after the next
+// rebuild.
+func (c *Native) RestoreGeneration(ctx context.Context, id string) error {
+	var trashed *nix.Trashed
+	for _, t := range nix.Trash() {
+		if t.ID == id {
+			trashed = &t
+		}
+	}
+	link := nix.Link(id)
+	switch _, err := os.Lstat(link); {
+	case trashed == nil:
+		return fmt.Errorf("failed to restore generation %s: it is not in the trash", id)
+	case err == nil:
+		return fmt.Errorf("failed to restore generation %s: %s exists; a newer generation took its number", id, link)
+	}
+	err := c.runPrivileged(ctx, "ln", "-s", trashed.StorePath, link)
+	if err == nil {
+		err = c.runPrivileged(ctx, "rm", "-f", trashed.Root)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore generation %s: %w", id, err)
+	}
+	return nil
+}
+
+// EmptyTrash removes every trash root.
+func (c *Native) EmptyTrash(ctx context.Context) error {
+	return c.expireTrash(ctx, time.Now())
+}
+
+// expireTrash removes the trash roots of generations trashed before cutoff.
+func (c *Native) expireTrash(ctx context.Context, cutoff time.Time) error {
+	argv := []string{"rm", "-f"}
+	for _, t := range nix.Trash() {
+		if t.Trashed.Before(cutoff) {
+			argv = append(argv, t.Root)
+		}
+	}
+	if len(argv) == 2 {
+		return nil
+	}
+	if err := c.runPrivileged(ctx, argv...); err != nil {
+		return fmt.Errorf("failed to empty the trash: %w", err)
+	}
+	return nil
+}
+
+// Unpin removes the root pinning a generation, if there is one.
+func (c *Native) Unpin(ctx context.Context, id string) error {
+	root, _, ok := nix.FindPin(id)
+	if !ok {
+		return nil
+	}
+	if err := c.runPrivileged(ctx, "rm", "-f", root); err != nil {
+		return fmt.Errorf("failed to unpin generation %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"nix-timemach/internal/shell"
+)
+
+// ErrEscalation is returned when a privileged subcommand could not gain
+// root, as opposed to failing once it had it.
+var ErrEscalation = errors.New("privilege escalation failed")
+
+// ErrNeedsRoot is returned when a subcommand that modifies the system was
+// refused for want of root and there was no escalation to get it.
+var ErrNeedsRoot = errors.New("this needs root; run nix-timemach as root, or with --sudo")
+
+// ErrReadOnly is returned for subcommands that modify the system when the
+// client was created with ReadOnly.
+var ErrReadOnly = errors.New("refusing to modify the system in read-only mode")
+
+// settings are the options every Client implementation shares.
+type settings struct {
+	escalation string
+	readOnly   bool
+	store      string
+	host       string
+	persistent bool
+	timeout    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*settings)
+
+func newSettings(opts []Option) settings {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// WithEscalation runs subcommands that modify the system through program,
+// which must be "sudo" or "pkexec" (or a path to one of them).
+func WithEscalation(program string) Option {
+	return func(s *settings) {
+		s.escalation = program
+	}
+}
+
+// ReadOnly makes every subcommand that modifies the system fail with
+// ErrReadOnly instead of running.
+func ReadOnly() Option {
+	return func(s *settings) {
+		s.readOnly = true
+	}
+}
+
+// DetectEscalation picks the program to gain root with when none was
+// asked for: none when already root, otherwise sudo, or pkexec where there
+// is no sudo. It returns "" when neither is installed.
+func DetectEscalation() string {
+	if os.Geteuid() == 0 {
+		return ""
+	}
+	for _, program := range []string{"sudo", "pkexec"} {
+		if p, err := exec.LookPath(program); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+func (c *settings) escalationKind() string {
+	if c.escalation == "" {
+		return ""
+	}
+	return filepath.Base(c.escalation)
+}
+
+// Escalates reports whether privileged subcommands go through sudo or
+// pkexec.
+func (c *settings) Escalates() bool {
+	return c.escalation != ""
+}
+
+// AuthorizeCommand returns a command that prompts for credentials on the
+// terminal and caches them, so later privileged calls can run without a
+// prompt. It is nil when no terminal prompt is needed: pkexec asks through
+// the desktop's polkit agent on every call instead.
+func (c *settings) AuthorizeCommand() *exec.Cmd {
+	// A remote sudo caches credentials per session, and every call opens
+	// a new one.
+	if c.escalationKind() != "sudo" || c.host != "" {
+		return nil
+	}
+	return exec.Command(c.escalation, "-v")
+}
+
+// escalated builds the command for argv when it needs root. sudo runs
+// non-interactively, relying on AuthorizeCommand having cached credentials,
+// since there is no terminal to prompt on mid-TUI.
+func (c *settings) escalated(ctx context.Context, argv ...string) *exec.Cmd {
+	switch c.escalationKind() {
+	case "":
+		return c.command(ctx, argv...)
+	case "sudo":
+		return c.command(ctx, append([]string{c.escalation, "-n", "--"}, argv...)...)
+	default:
+		return c.command(ctx, append([]string{c.escalation}, argv...)...)
+	}
+}
+
+// runPrivileged runs argv, which modifies the system and produces no
+// output, as root. The mutating calls of Process and Native go through
+// here, which makes it the one place they enforce read-only mode; Demo,
+// which runs nothing, checks for itself.
+func (c *settings) runPrivileged(ctx context.Context, argv ...string) error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	cmd := c.escalated(ctx, argv...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if c.escalationFailed(err, stderr.Bytes()) {
+		return fmt.Errorf("%w: %s", ErrEscalation, stderrSummary(stderr.Bytes()))
+	}
+	if c.escalation == "" && deniedRoot(stderr.Bytes()) {
+		return fmt.Errorf("%w: %s", ErrNeedsRoot, stderrSummary(stderr.Bytes()))
+	}
+	return newFailure("", shell.CommandLine(cmd.Args...), err, stderr.Bytes())
+}
+
+// escalationFailed tells a refused or failed escalation apart from the
+// backend failing after it got root.
+func (c *settings) escalationFailed(err error, stderr []byte) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return c.escalation != ""
+	}
+	switch c.escalationKind() {
+	case "sudo":
+		return bytes.HasPrefix(stderr, []byte("sudo:"))
+	case "pkexec":
+		// pkexec uses 126 when the dialog is dismissed and 127 when
+		// authorization is refused.
+		return exitErr.ExitCode() == 126 || exitErr.ExitCode() == 127
+	}
+	return false
+}
+
+// deniedRoot reports whether stderr is that of a command refused for not
+// running as root, as the profiles and GC roots it writes belong to root.
+func deniedRoot(stderr []byte) bool {
+	for _, sign := range []string{"Permission denied", "Operation not permitted", "must be root"} {
+		if bytes.Contains(stderr, []byte(sign)) {
+			return true
+		}
+	}
+	return false
+}
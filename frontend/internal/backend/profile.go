@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"context"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/nix"
+)
+
+type profileKey struct{}
+
+var systemProfile = models.Profile{Name: "system", Path: nix.SystemProfile}
+
+// ForProfile returns a context under which backend calls read the
+// generations of the profile at path instead of the system's. Calls that
+// change the system, and pending diffs, ignore it.
+func ForProfile(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, profileKey{}, path)
+}
+
+// profileFrom returns the profile set with ForProfile, or "" for the
+// system's.
+func profileFrom(ctx context.Context) string {
+	path, _ := ctx.Value(profileKey{}).(string)
+	if path == nix.SystemProfile {
+		return ""
+	}
+	return path
+}
+
+// ProfileOf returns the path of the profile a context reads, as set with
+// ForProfile.
+func ProfileOf(ctx context.Context) string {
+	if path := profileFrom(ctx); path != "" {
+		return path
+	}
+	return nix.SystemProfile
+}
+
+// profileArgs passes the context's profile on to the backend. It is left
+// out for the system profile so backends that predate --profile still work.
+func profileArgs(ctx context.Context, args []string) []string {
+	path := profileFrom(ctx)
+	if path == "" {
+		return args
+	}
+	return append(args[:len(args):len(args)], "--profile", path)
+}
@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+)
+
+type progressKey struct{}
+
+// WithProgress returns a context under which backend calls pass each line
+// the backend writes to stderr to fn as it is written. fn is called from
+// the goroutine copying the output and must not block for long.
+func WithProgress(ctx context.Context, fn func(line string)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFrom(ctx context.Context) func(string) {
+	fn, _ := ctx.Value(progressKey{}).(func(string))
+	return fn
+}
+
+// lineWriter splits what is written to it into lines. Carriage returns end
+// a line too, since progress meters redraw themselves with them.
+type lineWriter struct {
+	fn      func(string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := w.partial[:i]; len(line) > 0 {
+			w.fn(sanitize(string(line)))
+		}
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes on a final line that had no terminator.
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.fn(sanitize(string(w.partial)))
+		w.partial = nil
+	}
+}
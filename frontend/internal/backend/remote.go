@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"context"
+	"os/exec"
+
+	"nix-timemach/internal/shell"
+)
+
+// sshOptions keep ssh from prompting: there is no terminal to prompt on
+// while the TUI runs, so the host must accept a key or agent.
+var sshOptions = []string{"-o", "BatchMode=yes"}
+
+// WithHost runs every backend command on host, an ssh destination such as
+// user@machine, instead of locally. The backend binary must be installed
+// there. Actions that need root require logging in as root or passwordless
+// sudo on the host, as each command is a new session.
+func WithHost(host string) Option {
+	return func(s *settings) {
+		s.host = host
+	}
+}
+
+// Host returns the host set with WithHost, or "" for this machine.
+func (c *settings) Host() string {
+	return c.host
+}
+
+// command builds the command that runs argv, over ssh when there is a host.
+func (c *settings) command(ctx context.Context, argv ...string) *exec.Cmd {
+	if c.host == "" {
+		return exec.CommandContext(ctx, argv[0], argv[1:]...)
+	}
+	args := append(append([]string{}, sshOptions...), c.host, "--", shell.CommandLine(argv...))
+	return exec.CommandContext(ctx, "ssh", args...)
+}
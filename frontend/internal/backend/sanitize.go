@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"strings"
+	"unicode"
+
+	"nix-timemach/internal/models"
+)
+
+// sanitize makes a backend-supplied string safe to render. encoding/json
+// already replaces invalid UTF-8 in the raw bytes, but a string can still
+// carry escaped control characters (e.g. "\u001b[2J") that would drive the
+// terminal instead of being displayed, so those are replaced too.
+func sanitize(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	if strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '�'
+		}
+		return r
+	}, s)
+}
+
+// Printable is sanitize for the strings that identify something, like
+// generation IDs and store paths, to use where they are rendered. Those are
+// passed on as the backend sent them, since they go back to it in later calls.
+func Printable(s string) string {
+	return sanitize(s)
+}
+
+func sanitizeAll(items []string) {
+	for i := range items {
+		items[i] = sanitize(items[i])
+	}
+}
+
+func sanitizeGeneration(gen *models.Generation) {
+	gen.Description = sanitize(gen.Description)
+	gen.ClosureHash = sanitize(gen.ClosureHash)
+	gen.KernelVersion = sanitize(gen.KernelVersion)
+	gen.NixosVersion = sanitize(gen.NixosVersion)
+	gen.FlakeURL = sanitize(gen.FlakeURL)
+	gen.FlakeRevision = sanitize(gen.FlakeRevision)
+	gen.PinName = sanitize(gen.PinName)
+	sanitizeAll(gen.Specialisations)
+}
+
+func sanitizeChange(c *models.PackageChange) {
+	c.Name = sanitize(c.Name)
+	c.OldVersion = sanitize(c.OldVersion)
+	c.NewVersion = sanitize(c.NewVersion)
+}
+
+func sanitizeDiff(diff *models.GenerationDiff) {
+	for _, changes := range [][]models.PackageChange{diff.Added, diff.Removed, diff.Modified} {
+		for i := range changes {
+			sanitizeChange(&changes[i])
+		}
+	}
+}
+
+func sanitizeConfigDiff(diff *models.ConfigDiff) {
+	for _, changes := range [][]models.ConfigChange{diff.Inputs, diff.Options} {
+		for i := range changes {
+			changes[i].Name = sanitize(changes[i].Name)
+			changes[i].From = sanitize(changes[i].From)
+			changes[i].To = sanitize(changes[i].To)
+		}
+	}
+}
+
+func sanitizeInputChanges(changes []models.InputChange) {
+	for i := range changes {
+		changes[i].Name = sanitize(changes[i].Name)
+		changes[i].From.Rev = sanitize(changes[i].From.Rev)
+		changes[i].To.Rev = sanitize(changes[i].To.Rev)
+	}
+}
+
+func sanitizeGCPreview(preview *models.GCPreview) {
+	for i := range preview.Generations {
+		preview.Generations[i].Profile = sanitize(preview.Generations[i].Profile)
+		preview.Generations[i].ID = sanitize(preview.Generations[i].ID)
+	}
+}
+
+func sanitizeDependencyNode(node *models.DependencyNode) {
+	node.Path = sanitize(node.Path)
+	for i := range node.Children {
+		sanitizeDependencyNode(&node.Children[i])
+	}
+}
+
+func sanitizeFileDiff(diff *models.FileDiff) {
+	sanitizeAll(diff.Added)
+	sanitizeAll(diff.Removed)
+	sanitizeAll(diff.Modified)
+}
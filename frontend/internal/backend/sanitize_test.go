@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "firefox 129.0", "firefox 129.0"},
+		{"unicode", "Größe ✓", "Größe ✓"},
+		{"invalid utf-8", "bad\xff\xfebytes", "bad�bytes"},
+		{"escape sequence", "\x1b[2Jcleared", "�[2Jcleared"},
+		{"newline and tab", "two\nlines\tand a tab", "two�lines�and a tab"},
+		{"c1 control", "csi\u009b31m", "csi�31m"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.in); got != tt.want {
+				t.Errorf("sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeKeepsIdentifiers(t *testing.T) {
+	const path = "/nix/store/abc-odd\x1bname"
+	gen := models.Generation{
+		ID:          "42\x07",
+		Profile:     "/nix/var/nix/profiles/\x1bsystem",
+		StorePath:   path,
+		Profiles:    []string{"/nix/var/nix/profiles/\x1bsystem"},
+		Description: "rebuilt\x1b[2J",
+	}
+	sanitizeGeneration(&gen)
+	if gen.ID != "42\x07" || gen.Profile != "/nix/var/nix/profiles/\x1bsystem" || gen.StorePath != path || gen.Profiles[0] != "/nix/var/nix/profiles/\x1bsystem" {
+		t.Errorf("identifiers changed: %+v", gen)
+	}
+	if gen.Description != "rebuilt�[2J" {
+		t.Errorf("Description = %q, want it sanitized", gen.Description)
+	}
+
+	c := models.PackageChange{Path: path, NewPath: path, Name: "odd\x1bname"}
+	sanitizeChange(&c)
+	if c.Path != path || c.NewPath != path {
+		t.Errorf("paths changed: %q, %q", c.Path, c.NewPath)
+	}
+	if c.Name != "odd�name" {
+		t.Errorf("Name = %q, want it sanitized", c.Name)
+	}
+}
+
+func TestSanitizeDiff(t *testing.T) {
+	diff := models.GenerationDiff{
+		Added:    []models.PackageChange{{Name: "a\x1b", NewVersion: "1\x00"}},
+		Removed:  []models.PackageChange{{Name: "b\xff"}},
+		Modified: []models.PackageChange{{Name: "c", OldVersion: "1\r", NewVersion: "2"}},
+	}
+	sanitizeDiff(&diff)
+	if got := diff.Added[0].Name + diff.Added[0].NewVersion; got != "a�1�" {
+		t.Errorf("added = %q", got)
+	}
+	if got := diff.Removed[0].Name; got != "b�" {
+		t.Errorf("removed = %q", got)
+	}
+	if got := diff.Modified[0].OldVersion; got != "1�" {
+		t.Errorf("modified = %q", got)
+	}
+}
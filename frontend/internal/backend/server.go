@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// errNoServer makes a call fall back to starting a backend process of its
+// own: the backend predates serve, or the server is busy or just died.
+var errNoServer = errors.New("no backend server available")
+
+// Persistent keeps one backend process running and sends it every
+// unprivileged call over stdio, instead of starting a process per call.
+// Calls made while it is busy, such as during a long build, still start
+// their own process.
+func Persistent() Option {
+	return func(s *settings) {
+		s.persistent = true
+	}
+}
+
+// server is a backend running "serve": it reads one JSON request per line
+// and answers each with one line. The process exits when stdin closes,
+// which happens at the latest when the frontend does.
+type server struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *stderrSink
+	nextID uint64
+}
+
+type request struct {
+	ID     uint64   `json:"id"`
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type response struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+func (c *Process) startServer() (*server, error) {
+	// The server outlives any one call, so no call's context bounds it.
+	cmd := c.command(context.Background(), append([]string{c.backendBinary}, c.backendArgs("serve")...)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	sink := &stderrSink{}
+	cmd.Stderr = sink
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &server{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout), stderr: sink}, nil
+}
+
+func (s *server) stop() {
+	s.stdin.Close()
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+}
+
+// call sends one subcommand to the server and returns its result. It
+// returns errNoServer when the call should start a process instead.
+func (c *Process) call(ctx context.Context, what string, args ...string) (json.RawMessage, error) {
+	if !c.serverMu.TryLock() {
+		return nil, errNoServer
+	}
+	defer c.serverMu.Unlock()
+	if c.noServer {
+		return nil, errNoServer
+	}
+	if c.server == nil {
+		srv, err := c.startServer()
+		if err != nil {
+			c.noServer = true
+			return nil, errNoServer
+		}
+		c.server = srv
+	}
+	srv := c.server
+
+	srv.nextID++
+	line, err := json.Marshal(request{ID: srv.nextID, Method: args[0], Params: args[1:]})
+	if err != nil {
+		return nil, err
+	}
+	srv.stderr.begin(progressFrom(ctx))
+
+	type reply struct {
+		line []byte
+		err  error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		if _, err := srv.stdin.Write(append(line, '\n')); err != nil {
+			replies <- reply{err: err}
+			return
+		}
+		line, err := srv.stdout.ReadBytes('\n')
+		replies <- reply{line, err}
+	}()
+
+	var r reply
+	select {
+	case <-ctx.Done():
+		// The request can't be withdrawn, so the server goes with it.
+		srv.stop()
+		srv.stderr.end()
+		c.server = nil
+		return nil, ctx.Err()
+	case r = <-replies:
+	}
+	stderr := srv.stderr.end()
+
+	if r.err != nil {
+		srv.stop()
+		c.server = nil
+		if isUnsupported(stderr) {
+			// This backend has no serve subcommand; don't try again.
+			c.noServer = true
+		}
+		return nil, errNoServer
+	}
+
+	var resp response
+	if err := json.Unmarshal(r.line, &resp); err != nil || resp.ID == nil || *resp.ID != srv.nextID {
+		srv.stop()
+		c.server = nil
+		return nil, fmt.Errorf("failed to get %s: backend server sent an unexpected reply", what)
+	}
+	if len(resp.Error) > 0 && string(resp.Error) != "null" {
+		report := parseReport(resp.Error)
+		if isUnsupported([]byte(report.Message)) {
+			return nil, fmt.Errorf("failed to get %s: %w", what, ErrUnsupported)
+		}
+		return nil, &Failure{What: what, Kind: report.Kind, Message: report.Message, Stderr: stderrSummary(stderr)}
+	}
+	return resp.Result, nil
+}
+
+// stderrSink collects the server's stderr for the call in progress and
+// passes it on as progress when the call asked for that.
+type stderrSink struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	progress *lineWriter
+}
+
+func (s *stderrSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf.Write(p)
+	if s.progress != nil {
+		s.progress.Write(p)
+	}
+	return len(p), nil
+}
+
+func (s *stderrSink) begin(fn func(string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf.Reset()
+	s.progress = nil
+	if fn != nil {
+		s.progress = &lineWriter{fn: fn}
+	}
+}
+
+func (s *stderrSink) end() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress != nil {
+		s.progress.flush()
+		s.progress = nil
+	}
+	return bytes.Clone(s.buf.Bytes())
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+// largeDiff is a diff of n modified packages as the backend writes it.
+func largeDiff(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"added": [], "removed": [], "modified": [`)
+	for i := range n {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"path": "/nix/store/%032d-pkg%d-1.0", "new_path": "/nix/store/%032d-pkg%d-1.1", "name": "pkg%d", "old_version": "1.0", "new_version": "1.1"}`, i, i, i+1, i, i)
+	}
+	b.WriteString(`]}`)
+	return b.Bytes()
+}
+
+func TestDecodeDiffOutputLimit(t *testing.T) {
+	data := largeDiff(100)
+	tests := []struct {
+		limit   int64
+		wantErr error
+	}{
+		{int64(len(data)), nil},
+		{int64(len(data)) - 1, errOutputTooLarge},
+		{64, errOutputTooLarge},
+	}
+	for _, tt := range tests {
+		var diff models.GenerationDiff
+		err := decodeDiff(json.NewDecoder(&limitedReader{r: bytes.NewReader(data), n: tt.limit}), &diff)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("limit %d: error = %v, want %v", tt.limit, err, tt.wantErr)
+		}
+		if tt.wantErr == nil && len(diff.Modified) != 100 {
+			t.Errorf("limit %d: decoded %d changes, want 100", tt.limit, len(diff.Modified))
+		}
+	}
+}
+
+// BenchmarkDecodeDiff measures decoding a large diff as spawn does, a
+// change at a time from the pipe, against reading all of the output first.
+// Buffered allocates the whole raw output, grown as it is read, on top of
+// the decoded changes; streamed only ever holds a small read buffer of it.
+func BenchmarkDecodeDiff(b *testing.B) {
+	for _, n := range []int{1_000, 50_000} {
+		data := largeDiff(n)
+		b.Run(fmt.Sprintf("streamed/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				var diff models.GenerationDiff
+				r := bufio.NewReader(&limitedReader{r: bytes.NewReader(data), n: maxOutputBytes})
+				if err := decodeDiff(json.NewDecoder(r), &diff); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("buffered/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				raw, err := io.ReadAll(bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+				var diff models.GenerationDiff
+				if err := json.Unmarshal(raw, &diff); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when the backend didn't answer within the timeout
+// set with WithTimeout.
+var ErrTimeout = errors.New("backend timed out")
+
+// WithTimeout makes calls that only read fail with ErrTimeout when the
+// backend hasn't answered within d, so a hung backend can't freeze the
+// caller. Zero means no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(s *settings) {
+		s.timeout = d
+	}
+}
+
+// bounded limits ctx to the configured timeout. The returned done releases
+// it and turns the error of a call made under it into ErrTimeout when the
+// timeout is what ended the call.
+func (c *settings) bounded(ctx context.Context, what string) (context.Context, func(error) error) {
+	if c.timeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, c.timeout, ErrTimeout)
+	return ctx, func(err error) error {
+		defer cancel()
+		if err != nil && errors.Is(context.Cause(ctx), ErrTimeout) {
+			return fmt.Errorf("failed to get %s: %w after %s", what, ErrTimeout, c.timeout)
+		}
+		return err
+	}
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Protocol is the version of the backend's output this frontend reads. The
+// backend bumps its own when it changes that output in a way an older
+// frontend would misread; new subcommands and fields don't count, as a
+// missing subcommand is ErrUnsupported and a missing field its zero value.
+const Protocol = 1
+
+// Version is what the backend's version subcommand reports.
+type Version struct {
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+}
+
+// IncompatibleError is returned by every call to a backend that speaks
+// another protocol, instead of whatever its output would parse as.
+type IncompatibleError struct {
+	Binary  string
+	Backend Version
+}
+
+func (e *IncompatibleError) Error() string {
+	if e.Backend.Protocol > Protocol {
+		return fmt.Sprintf("the backend %s (version %s) speaks protocol %d, newer than this nix-timemach's %d; upgrade nix-timemach to match it",
+			e.Binary, e.Backend.Version, e.Backend.Protocol, Protocol)
+	}
+	return fmt.Sprintf("the backend %s (version %s) speaks protocol %d, older than this nix-timemach's %d; upgrade the backend, or build it from the same source as nix-timemach",
+		e.Binary, e.Backend.Version, e.Backend.Protocol, Protocol)
+}
+
+// Version asks the backend for its version. Backends from before the
+// version subcommand speak protocol 1.
+func (c *Process) Version(ctx context.Context) (Version, error) {
+	var v Version
+	ctx, done := c.bounded(ctx, "version")
+	err := done(c.run(ctx, "version", func(dec *json.Decoder) error {
+		return dec.Decode(&v)
+	}, "version"))
+	if errors.Is(err, ErrUnsupported) {
+		return Version{Version: "unknown", Protocol: 1}, nil
+	}
+	return v, err
+}
+
+// checkVersion returns an IncompatibleError if the backend's protocol
+// isn't Protocol. It asks once; a backend that can't be asked, say because
+// it isn't installed, is left to fail the call itself, which explains that
+// better, and is asked again on the next one.
+func (c *Process) checkVersion(ctx context.Context) error {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if c.versionChecked {
+		return c.incompatible
+	}
+	v, err := c.Version(ctx)
+	if err != nil {
+		return nil
+	}
+	c.versionChecked = true
+	if v.Protocol != Protocol {
+		c.incompatible = &IncompatibleError{Binary: c.backendBinary, Backend: v}
+	}
+	return c.incompatible
+}
@@ -0,0 +1,133 @@
+// Package bisect narrows a regression down to the generation that
+// introduced it, by binary search between a good and a bad generation. The
+// session is saved between runs, as testing a generation may take a reboot.
+package bisect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"nix-timemach/internal/xdg"
+)
+
+// Session is a bisection in progress: the newest generation known to be
+// good and the oldest known to be bad, or the other way around when
+// looking for a fix.
+type Session struct {
+	Good    string    `json:"good"`
+	Bad     string    `json:"bad"`
+	Started time.Time `json:"started"`
+}
+
+// Remaining returns the generations among ids still to be tested, those
+// strictly between Good and Bad, oldest first.
+func (s Session) Remaining(ids []string) []string {
+	good, _ := strconv.Atoi(s.Good)
+	bad, _ := strconv.Atoi(s.Bad)
+	lo, hi := min(good, bad), max(good, bad)
+	var remaining []int
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil && n > lo && n < hi {
+			remaining = append(remaining, n)
+		}
+	}
+	slices.Sort(remaining)
+	out := make([]string, len(remaining))
+	for i, n := range remaining {
+		out[i] = strconv.Itoa(n)
+	}
+	return out
+}
+
+// Next returns the generation to test next, the midpoint of the ones
+// remaining, or false once none remain and Bad is the culprit.
+func (s Session) Next(ids []string) (string, bool) {
+	remaining := s.Remaining(ids)
+	if len(remaining) == 0 {
+		return "", false
+	}
+	return remaining[len(remaining)/2], true
+}
+
+// Steps returns how many more generations have to be tested at most when
+// remaining are left: each test halves them.
+func Steps(remaining int) int {
+	steps := 0
+	for n := remaining; n > 0; n /= 2 {
+		steps++
+	}
+	return steps
+}
+
+// Mark records the result of testing id, narrowing the range to one side
+// of it.
+func (s *Session) Mark(id string, good bool) {
+	if good {
+		s.Good = id
+	} else {
+		s.Bad = id
+	}
+}
+
+func path() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bisect.json"), nil
+}
+
+// Load returns the saved session, or nil if there is none.
+func Load() (*Session, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bisect session: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	return &s, nil
+}
+
+// Save stores s, replacing any saved session.
+func Save(s Session) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := xdg.WriteFile(p, data); err != nil {
+		return fmt.Errorf("failed to save bisect session: %w", err)
+	}
+	return nil
+}
+
+// Clear ends the saved session, if there is one.
+func Clear() error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to end bisect session: %w", err)
+	}
+	return nil
+}
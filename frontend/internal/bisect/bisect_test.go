@@ -0,0 +1,92 @@
+package bisect
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRemaining(t *testing.T) {
+	ids := []string{"12", "3", "7", "5", "9", "10", "old", "4"}
+	tests := []struct {
+		name      string
+		good, bad string
+		want      []string
+	}{
+		{"regression", "4", "10", []string{"5", "7", "9"}},
+		{"fix", "10", "4", []string{"5", "7", "9"}},
+		{"adjacent", "9", "10", []string{}},
+		{"outside the list", "1", "20", []string{"3", "4", "5", "7", "9", "10", "12"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Session{Good: tt.good, Bad: tt.bad}.Remaining(ids)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Remaining = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Bisecting a range finds the culprit in at most Steps tests.
+func TestBisect(t *testing.T) {
+	var ids []string
+	for n := 1; n <= 40; n++ {
+		ids = append(ids, strconv.Itoa(n))
+	}
+	for culprit := 2; culprit <= 40; culprit++ {
+		s := Session{Good: "1", Bad: "40"}
+		limit := Steps(len(s.Remaining(ids)))
+		tests := 0
+		for {
+			id, ok := s.Next(ids)
+			if !ok {
+				break
+			}
+			n, _ := strconv.Atoi(id)
+			s.Mark(id, n < culprit)
+			tests++
+		}
+		if s.Bad != strconv.Itoa(culprit) {
+			t.Errorf("culprit %d: bisect ended at %s", culprit, s.Bad)
+		}
+		if tests > limit {
+			t.Errorf("culprit %d: took %d tests, Steps promised %d", culprit, tests, limit)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	for _, tt := range []struct{ remaining, want int }{{0, 0}, {1, 1}, {2, 2}, {3, 2}, {7, 3}, {8, 4}} {
+		if got := Steps(tt.remaining); got != tt.want {
+			t.Errorf("Steps(%d) = %d, want %d", tt.remaining, got, tt.want)
+		}
+	}
+}
+
+func TestSaveLoadClear(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if s, err := Load(); s != nil || err != nil {
+		t.Fatalf("Load with no session = %v, %v, want nil", s, err)
+	}
+
+	want := Session{Good: "3", Bad: "9", Started: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)}
+	if err := Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load()
+	if err != nil || got == nil || *got != want {
+		t.Fatalf("Load = %v, %v, want %v", got, err, want)
+	}
+
+	if err := Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if s, _ := Load(); s != nil {
+		t.Errorf("session still saved after Clear: %v", s)
+	}
+	if err := Clear(); err != nil {
+		t.Errorf("Clear with no session: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+// Package cache memoizes diffs between generations and package searches. A
+// store path never changes, so the diff between two, or whether one's
+// closure has a package, is worth keeping for as long as the paths exist,
+// across runs too.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/xdg"
+)
+
+// Client is a backend.Client whose GetDiff, StreamDiff and FindPackage
+// answer from the cache when they can. Diffs are keyed by the store paths
+// of the two generations, which it learns from the generation lists
+// passing through it; a diff between generations it hasn't seen listed goes
+// to the backend every time.
+type Client struct {
+	backend.Client
+	// dir is where diffs are also kept on disk, or "" for memory only.
+	dir string
+
+	mu       sync.Mutex
+	paths    map[string]string
+	listed   map[string][]string
+	diffs    map[string]models.GenerationDiff
+	packages map[string]map[string]models.PackagePresence
+	inflight map[string]*call
+}
+
+// call is a GetDiff in progress, which later requests for the same diff
+// wait for instead of asking the backend again.
+type call struct {
+	done chan struct{}
+}
+
+// Dir is the default directory for the on-disk cache.
+func Dir() (string, error) {
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "diffs"), nil
+}
+
+// Wrap returns c with its diffs cached in memory and, unless dir is "", in
+// dir.
+func Wrap(c backend.Client, dir string) *Client {
+	return &Client{
+		Client:   c,
+		dir:      dir,
+		paths:    make(map[string]string),
+		listed:   make(map[string][]string),
+		diffs:    make(map[string]models.GenerationDiff),
+		packages: make(map[string]map[string]models.PackagePresence),
+		inflight: make(map[string]*call),
+	}
+}
+
+func (c *Client) GetGenerations(ctx context.Context) ([]models.Generation, error) {
+	generations, err := c.Client.GetGenerations(ctx)
+	c.learn(ctx, generations)
+	return generations, err
+}
+
+func (c *Client) GetGenerationsWithSizes(ctx context.Context) ([]models.Generation, error) {
+	generations, err := c.Client.GetGenerationsWithSizes(ctx)
+	c.learn(ctx, generations)
+	return generations, err
+}
+
+func (c *Client) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	diff, _, err := c.diff(ctx, fromID, toID, func() (models.GenerationDiff, error) {
+		return c.Client.GetDiff(ctx, fromID, toID)
+	})
+	return diff, err
+}
+
+// StreamDiff streams from the backend only when the diff isn't cached; a
+// cached one is handed over all at once.
+func (c *Client) StreamDiff(ctx context.Context, fromID, toID string, fn func(total int, entry models.DiffEntry)) (models.GenerationDiff, error) {
+	diff, cached, err := c.diff(ctx, fromID, toID, func() (models.GenerationDiff, error) {
+		return c.Client.StreamDiff(ctx, fromID, toID, fn)
+	})
+	if cached {
+		entries := diff.Entries()
+		for _, e := range entries {
+			fn(len(entries), e)
+		}
+	}
+	return diff, err
+}
+
+// diff answers from the cache, or from get, reporting which it was.
+func (c *Client) diff(ctx context.Context, fromID, toID string, get func() (models.GenerationDiff, error)) (models.GenerationDiff, bool, error) {
+	key, ok := c.key(ctx, fromID, toID)
+	if !ok {
+		diff, err := get()
+		return diff, false, err
+	}
+	if diff, ok := c.lookup(key); ok {
+		return diff, true, nil
+	}
+	if pending, ok := c.join(key); ok {
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return models.GenerationDiff{}, false, ctx.Err()
+		}
+		if diff, ok := c.lookup(key); ok {
+			return diff, true, nil
+		}
+		// The first request failed, perhaps because it was canceled;
+		// this one asks for itself.
+		diff, err := get()
+		return diff, false, err
+	}
+
+	diff, err := get()
+	if err == nil {
+		c.store(key, diff)
+	}
+	c.finish(key)
+	if err != nil {
+		return diff, false, err
+	}
+	return clone(diff), false, nil
+}
+
+// join returns the request in progress for key, if there is one, and
+// otherwise records that the caller is making it.
+func (c *Client) join(key string) (*call, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pending, ok := c.inflight[key]; ok {
+		return pending, true
+	}
+	c.inflight[key] = &call{done: make(chan struct{})}
+	return nil, false
+}
+
+func (c *Client) finish(key string) {
+	c.mu.Lock()
+	pending := c.inflight[key]
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(pending.done)
+}
+
+// learn records the store path of every generation listed, and which
+// generations the profile has.
+func (c *Client) learn(ctx context.Context, generations []models.Generation) {
+	profile := backend.ProfileOf(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(generations))
+	for _, gen := range generations {
+		if gen.StorePath != "" {
+			c.paths[profile+"\x00"+gen.ID] = gen.StorePath
+		}
+		ids = append(ids, gen.ID)
+	}
+	c.listed[profile] = ids
+}
+
+// key names the diff between two generations by their store paths.
+func (c *Client) key(ctx context.Context, fromID, toID string) (string, bool) {
+	profile := backend.ProfileOf(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from, ok := c.paths[profile+"\x00"+fromID]
+	if !ok {
+		return "", false
+	}
+	to, ok := c.paths[profile+"\x00"+toID]
+	if !ok {
+		return "", false
+	}
+	return from + " " + to, true
+}
+
+func (c *Client) lookup(key string) (models.GenerationDiff, bool) {
+	c.mu.Lock()
+	diff, ok := c.diffs[key]
+	c.mu.Unlock()
+	if ok {
+		return clone(diff), true
+	}
+	if c.dir == "" {
+		return models.GenerationDiff{}, false
+	}
+	data, err := os.ReadFile(c.file(key))
+	if err != nil || json.Unmarshal(data, &diff) != nil {
+		return models.GenerationDiff{}, false
+	}
+	c.mu.Lock()
+	c.diffs[key] = diff
+	c.mu.Unlock()
+	return clone(diff), true
+}
+
+// store keeps diff. Failing to write it to disk only costs a later
+// recomputation, so it isn't reported.
+func (c *Client) store(key string, diff models.GenerationDiff) {
+	c.mu.Lock()
+	c.diffs[key] = clone(diff)
+	c.mu.Unlock()
+	if c.dir == "" {
+		return
+	}
+	if data, err := json.Marshal(diff); err == nil {
+		xdg.WriteFile(c.file(key), data)
+	}
+}
+
+func (c *Client) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// clone copies diff's entries, so callers can't change the cached copy.
+func clone(diff models.GenerationDiff) models.GenerationDiff {
+	diff.Added = slices.Clone(diff.Added)
+	diff.Removed = slices.Clone(diff.Removed)
+	diff.Modified = slices.Clone(diff.Modified)
+	return diff
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// counting is the demo backend, counting the calls the cache should save.
+type counting struct {
+	*backend.Demo
+	diffs, streams, searches atomic.Int32
+	// gate, if set, holds up GetDiff until it is closed.
+	gate chan struct{}
+}
+
+func (c *counting) GetDiff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	c.diffs.Add(1)
+	if c.gate != nil {
+		<-c.gate
+	}
+	return c.Demo.GetDiff(ctx, fromID, toID)
+}
+
+func (c *counting) StreamDiff(ctx context.Context, fromID, toID string, fn func(int, models.DiffEntry)) (models.GenerationDiff, error) {
+	c.streams.Add(1)
+	return c.Demo.StreamDiff(ctx, fromID, toID, fn)
+}
+
+func (c *counting) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	c.searches.Add(1)
+	return c.Demo.FindPackage(ctx, name)
+}
+
+func newCounting() *counting {
+	return &counting{Demo: backend.NewDemo()}
+}
+
+func TestGetDiff(t *testing.T) {
+	ctx := context.Background()
+	b := newCounting()
+	c := Wrap(b, "")
+
+	// Until the generations are listed their store paths are unknown.
+	c.GetDiff(ctx, "40", "41")
+	c.GetDiff(ctx, "40", "41")
+	if n := b.diffs.Load(); n != 2 {
+		t.Fatalf("unlisted diffs went to the backend %d times, want 2", n)
+	}
+
+	c.GetGenerations(ctx)
+	first, err := c.GetDiff(ctx, "40", "41")
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+	first.Modified = nil
+	second, _ := c.GetDiff(ctx, "40", "41")
+	if n := b.diffs.Load(); n != 3 {
+		t.Errorf("listed diff went to the backend %d more times, want 1", n-2)
+	}
+	if len(second.Modified) == 0 {
+		t.Error("changing a returned diff changed the cached one")
+	}
+
+	var streamed int
+	c.StreamDiff(ctx, "40", "41", func(total int, e models.DiffEntry) { streamed++ })
+	if b.streams.Load() != 0 {
+		t.Error("StreamDiff of a cached diff went to the backend")
+	}
+	if want := len(second.Entries()); streamed != want {
+		t.Errorf("streamed %d entries of the cached diff, want %d", streamed, want)
+	}
+}
+
+func TestGetDiffOnDisk(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b := newCounting()
+	c := Wrap(b, dir)
+	c.GetGenerations(ctx)
+	want, _ := c.GetDiff(ctx, "10", "20")
+
+	// A new run finds the diff where the last left it.
+	b = newCounting()
+	c = Wrap(b, dir)
+	c.GetGenerations(ctx)
+	got, err := c.GetDiff(ctx, "10", "20")
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+	if n := b.diffs.Load(); n != 0 {
+		t.Errorf("diff cached on disk went to the backend %d times", n)
+	}
+	if models.StatsOf(got) != models.StatsOf(want) {
+		t.Errorf("diff from disk %+v, want %+v", models.StatsOf(got), models.StatsOf(want))
+	}
+}
+
+func TestGetDiffJoins(t *testing.T) {
+	ctx := context.Background()
+	b := newCounting()
+	b.gate = make(chan struct{})
+	c := Wrap(b, "")
+	c.GetGenerations(ctx)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetDiff(ctx, "1", "42"); err != nil {
+				t.Errorf("GetDiff: %v", err)
+			}
+		}()
+	}
+	// Let the first request reach the backend before the others give up
+	// waiting for it.
+	for b.diffs.Load() == 0 {
+		runtime.Gosched()
+	}
+	close(b.gate)
+	wg.Wait()
+	if n := b.diffs.Load(); n != 1 {
+		t.Errorf("concurrent requests for a diff went to the backend %d times, want 1", n)
+	}
+}
+
+func TestFindPackage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b := newCounting()
+	c := Wrap(b, dir)
+
+	// Without a listing the result can't be keyed.
+	c.FindPackage(ctx, "firefox")
+	c.FindPackage(ctx, "firefox")
+	if n := b.searches.Load(); n != 2 {
+		t.Fatalf("unlisted searches went to the backend %d times, want 2", n)
+	}
+
+	generations, _ := c.GetGenerations(ctx)
+	want, _ := c.FindPackage(ctx, "firefox")
+	b = newCounting()
+	c = Wrap(b, dir)
+	c.GetGenerations(ctx)
+	got, err := c.FindPackage(ctx, "firefox")
+	if err != nil {
+		t.Fatalf("FindPackage: %v", err)
+	}
+	if n := b.searches.Load(); n != 0 {
+		t.Errorf("cached search went to the backend %d times", n)
+	}
+	if len(got) != len(generations) {
+		t.Fatalf("got %d results, want one per generation", len(got))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/xdg"
+)
+
+// FindPackage answers from the cache when every generation of the profile
+// was searched for name before, and asks the backend otherwise. Results are
+// keyed by store path, so they outlive the generation numbers.
+func (c *Client) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	if presence, ok := c.lookupPackage(ctx, name); ok {
+		return presence, nil
+	}
+	presence, err := c.Client.FindPackage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.storePackage(ctx, name, presence)
+	return presence, nil
+}
+
+// lookupPackage returns the cached presence of name in every generation
+// last listed for the profile, if all of them are known.
+func (c *Client) lookupPackage(ctx context.Context, name string) ([]models.PackagePresence, bool) {
+	profile := backend.ProfileOf(ctx)
+	known := c.packageResults(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids, ok := c.listed[profile]
+	if !ok {
+		return nil, false
+	}
+	presence := make([]models.PackagePresence, 0, len(ids))
+	for _, id := range ids {
+		p, ok := known[c.paths[profile+"\x00"+id]]
+		if !ok {
+			return nil, false
+		}
+		p.Generation = id
+		presence = append(presence, p)
+	}
+	return presence, true
+}
+
+// packageResults returns what is known about name, by store path, loading
+// it from disk the first time.
+func (c *Client) packageResults(name string) map[string]models.PackagePresence {
+	c.mu.Lock()
+	known, ok := c.packages[name]
+	c.mu.Unlock()
+	if ok {
+		return known
+	}
+	known = make(map[string]models.PackagePresence)
+	if c.dir != "" {
+		if data, err := os.ReadFile(c.file("package " + name)); err == nil {
+			json.Unmarshal(data, &known)
+		}
+	}
+	c.mu.Lock()
+	c.packages[name] = known
+	c.mu.Unlock()
+	return known
+}
+
+// storePackage adds the presence of name in the generations whose store
+// paths are known. Like diffs, a failed write isn't reported.
+func (c *Client) storePackage(ctx context.Context, name string, presence []models.PackagePresence) {
+	profile := backend.ProfileOf(ctx)
+	known := maps.Clone(c.packageResults(name))
+	c.mu.Lock()
+	for _, p := range presence {
+		if path, ok := c.paths[profile+"\x00"+p.Generation]; ok {
+			p.Generation = ""
+			known[path] = p
+		}
+	}
+	c.packages[name] = known
+	c.mu.Unlock()
+	if c.dir == "" {
+		return
+	}
+	if data, err := json.Marshal(known); err == nil {
+		xdg.WriteFile(c.file("package "+name), data)
+	}
+}
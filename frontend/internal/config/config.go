@@ -0,0 +1,159 @@
+// Package config reads the user's configuration file,
+// ~/.config/nix-timemach/config.toml. Command-line flags and environment
+// variables take precedence over it.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/xdg"
+)
+
+// Config is what the file can set. Empty fields are left to the defaults.
+type Config struct {
+	// Backend is the path of the backend binary.
+	Backend string
+	// Profile is the profile to open, by name or path.
+	Profile string
+	// Theme is a JSON theme file, as for --theme-file.
+	Theme string
+	// ThemePreset is the built-in theme to use, or for Theme to start
+	// from, as for --theme.
+	ThemePreset string
+	// DateFormat is the Go time layout timestamps are shown in.
+	DateFormat string
+	// Timezone is the IANA time zone timestamps are shown in, e.g.
+	// "Europe/Berlin" or "UTC"; empty is the local one.
+	Timezone string
+	// Timestamps is how the list starts showing times, as for
+	// --timestamps.
+	Timestamps string
+	// Hosts are the machines of the fleet view, as ssh destinations.
+	Hosts []string
+	// ReferenceHost is the host the others are compared with; empty uses
+	// the first.
+	ReferenceHost string
+	// Keys rebinds actions by name, from the [keys] table, e.g.
+	// select = ["enter", "l"].
+	Keys map[string][]string
+	// Retention is the cleanup policy, from the [retention] table.
+	Retention models.RetentionPolicy
+}
+
+// Path returns where the configuration file is read from.
+func Path() (string, error) {
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// Load reads the configuration file. A missing file is not an error.
+func Load() (Config, error) {
+	p, err := Path()
+	if err != nil {
+		return Config{}, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{Retention: models.DefaultRetentionPolicy}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	c, err := Parse(string(data))
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	return c, nil
+}
+
+// Parse reads a configuration file's contents. Unknown keys are errors, so
+// a misspelt setting isn't silently ignored.
+func Parse(data string) (Config, error) {
+	doc, err := parseTOML(data)
+	if err != nil {
+		return Config{}, err
+	}
+	for table := range doc {
+		if table != "" && table != "keys" && table != "retention" {
+			return Config{}, fmt.Errorf("unknown table [%s]", table)
+		}
+	}
+
+	c := Config{Retention: models.DefaultRetentionPolicy}
+	fields := map[string]*string{
+		"backend":        &c.Backend,
+		"profile":        &c.Profile,
+		"theme":          &c.Theme,
+		"theme_preset":   &c.ThemePreset,
+		"date_format":    &c.DateFormat,
+		"timezone":       &c.Timezone,
+		"timestamps":     &c.Timestamps,
+		"reference_host": &c.ReferenceHost,
+	}
+	for k, v := range doc[""] {
+		if k == "hosts" {
+			hosts, ok := v.([]string)
+			if !ok {
+				return Config{}, fmt.Errorf("hosts: want a list of hosts")
+			}
+			c.Hosts = hosts
+			continue
+		}
+		field, ok := fields[k]
+		if !ok {
+			return Config{}, fmt.Errorf("unknown key %q", k)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return Config{}, fmt.Errorf("%s: want a string", k)
+		}
+		*field = s
+	}
+	for k, v := range doc["keys"] {
+		if c.Keys == nil {
+			c.Keys = make(map[string][]string)
+		}
+		switch v := v.(type) {
+		case string:
+			c.Keys[k] = []string{v}
+		case []string:
+			c.Keys[k] = v
+		default:
+			return Config{}, fmt.Errorf("keys.%s: want a key or a list of keys", k)
+		}
+	}
+	if err := parseRetention(doc["retention"], &c.Retention); err != nil {
+		return Config{}, err
+	}
+	if c.ReferenceHost != "" && !slices.Contains(c.Hosts, c.ReferenceHost) {
+		return Config{}, fmt.Errorf("reference_host %q is not one of hosts", c.ReferenceHost)
+	}
+	c.Backend = expandHome(c.Backend)
+	c.Theme = expandHome(c.Theme)
+	c.Profile = expandHome(c.Profile)
+	return c, nil
+}
+
+// expandHome replaces a leading ~/ with the home directory, as a shell
+// would.
+func expandHome(p string) string {
+	rest, ok := strings.CutPrefix(p, "~/")
+	if !ok {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, rest)
+}
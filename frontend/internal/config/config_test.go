@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Config
+	}{
+		{name: "empty", want: Config{Retention: models.DefaultRetentionPolicy}},
+		{
+			name: "strings",
+			data: "backend = \"/opt/backend\"\nprofile = 'system'\ndate_format = \"2006-01-02 # not a comment\"\n",
+			want: Config{
+				Backend:    "/opt/backend",
+				Profile:    "system",
+				DateFormat: "2006-01-02 # not a comment",
+				Retention:  models.DefaultRetentionPolicy,
+			},
+		},
+		{
+			name: "escapes",
+			data: `theme_preset = "say \"hi\"\té"`,
+			want: Config{ThemePreset: "say \"hi\"\té", Retention: models.DefaultRetentionPolicy},
+		},
+		{
+			name: "comments",
+			data: "# nix-timemach\ntimezone = \"UTC\" # trailing\n\n# [keys]\n",
+			want: Config{Timezone: "UTC", Retention: models.DefaultRetentionPolicy},
+		},
+		{
+			name: "single-line array",
+			data: `hosts = ["alpha", 'beta']`,
+			want: Config{Hosts: []string{"alpha", "beta"}, Retention: models.DefaultRetentionPolicy},
+		},
+		{
+			name: "multi-line array",
+			data: "hosts = [\n  \"alpha\", # the laptop\n  \"beta\",\n]\nreference_host = \"beta\"\n",
+			want: Config{Hosts: []string{"alpha", "beta"}, ReferenceHost: "beta", Retention: models.DefaultRetentionPolicy},
+		},
+		{
+			name: "empty array",
+			data: "hosts = []",
+			want: Config{Hosts: []string{}, Retention: models.DefaultRetentionPolicy},
+		},
+		{
+			name: "keys",
+			data: "[keys]\nselect = [\n  \"enter\",\n  \"l\",\n]\nquit = \"q\"\n",
+			want: Config{
+				Keys:      map[string][]string{"select": {"enter", "l"}, "quit": {"q"}},
+				Retention: models.DefaultRetentionPolicy,
+			},
+		},
+		{
+			name: "retention",
+			data: "[retention]\nkeep_last = 3\nkeep_pinned = false\n",
+			want: Config{Retention: models.RetentionPolicy{KeepLast: 3, KeepWeekly: 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"unknown key", `colour = "red"`, `unknown key "colour"`},
+		{"unknown table", "[display]\nwidth = 80", "unknown table [display]"},
+		{"nested table", "[keys.extra]\nquit = \"q\"", "unknown table [keys.extra]"},
+		{"wrong type", "profile = 3", "profile: want a string"},
+		{"hosts not strings", "hosts = [1, 2]", "hosts: want a list of hosts"},
+		{"mixed hosts", "hosts = [\n  \"alpha\",\n  2,\n]", "hosts: want a list of hosts"},
+		{"bad key binding", "[keys]\nquit = 1", "keys.quit: want a key or a list of keys"},
+		{"negative count", "[retention]\nkeep_last = -1", "retention.keep_last: want a count"},
+		{"bad flag", "[retention]\nkeep_pinned = 1", "retention.keep_pinned: want true or false"},
+		{"unknown reference", "hosts = [\"alpha\"]\nreference_host = \"beta\"", `reference_host "beta" is not one of hosts`},
+		{"unterminated string", `profile = "system`, "line 1"},
+		{"unterminated array", "hosts = [\n  \"alpha\",\n", "unexpected EOF"},
+		{"duplicate key", "profile = \"a\"\nprofile = \"b\"", "line 2"},
+		{"duplicate table", "[keys]\n[keys]", "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) = %v, want an error containing %q", tt.data, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/xdg"
+)
+
+// parseRetention sets the policy fields the [retention] table sets.
+func parseRetention(table map[string]any, p *models.RetentionPolicy) error {
+	counts := map[string]*int{
+		"keep_last":   &p.KeepLast,
+		"keep_weekly": &p.KeepWeekly,
+	}
+	for k, v := range table {
+		if k == "keep_pinned" {
+			b, ok := v.(bool)
+			if !ok {
+				return fmt.Errorf("retention.%s: want true or false", k)
+			}
+			p.KeepPinned = b
+			continue
+		}
+		field, ok := counts[k]
+		if !ok {
+			return fmt.Errorf("unknown key %q", "retention."+k)
+		}
+		n, ok := v.(int64)
+		if !ok || n < 0 || n > 1<<20 {
+			return fmt.Errorf("retention.%s: want a count", k)
+		}
+		*field = int(n)
+	}
+	return nil
+}
+
+// SaveRetention writes p to the configuration file's [retention] table,
+// replacing the table if there is one. The rest of the file is left as it
+// is; comments inside the old table are lost.
+func SaveRetention(p models.RetentionPolicy) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var lines []string
+	inTable := false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		header := strings.TrimSpace(stripComment(line))
+		if strings.HasPrefix(header, "[") {
+			inTable = strings.TrimSpace(strings.Trim(header, "[]")) == "retention"
+		}
+		if !inTable {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines,
+		"[retention]",
+		fmt.Sprintf("keep_last = %d", p.KeepLast),
+		fmt.Sprintf("keep_weekly = %d", p.KeepWeekly),
+		fmt.Sprintf("keep_pinned = %t", p.KeepPinned),
+	)
+
+	if err := xdg.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	return nil
+}
+
+// stripComment drops a # comment, leaving any # inside a string alone.
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
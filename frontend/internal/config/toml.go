@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+// document is a parsed TOML file: its tables by dotted name, with the
+// top-level keys under "". Arrays of strings are []strings; other values
+// are as the toml package decodes them.
+type document map[string]map[string]any
+
+func parseTOML(data string) (document, error) {
+	var root map[string]any
+	if _, err := toml.Decode(data, &root); err != nil {
+		return nil, err
+	}
+	doc := document{}
+	addTable(doc, "", root)
+	return doc, nil
+}
+
+// addTable adds t to doc as the table name, and the tables inside it under
+// their dotted names.
+func addTable(doc document, name string, t map[string]any) {
+	keys := map[string]any{}
+	doc[name] = keys
+	for k, v := range t {
+		switch v := v.(type) {
+		case map[string]any:
+			if name != "" {
+				k = name + "." + k
+			}
+			addTable(doc, k, v)
+		case []any:
+			keys[k] = stringsOf(v)
+		default:
+			keys[k] = v
+		}
+	}
+}
+
+// stringsOf returns array as a []string if it holds only strings, and
+// unchanged otherwise.
+func stringsOf(array []any) any {
+	out := make([]string, 0, len(array))
+	for _, v := range array {
+		s, ok := v.(string)
+		if !ok {
+			return array
+		}
+		out = append(out, s)
+	}
+	return out
+}
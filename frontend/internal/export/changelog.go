@@ -0,0 +1,175 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// Change is what one generation changed relative to the one before it.
+type Change struct {
+	Generation models.Generation     `json:"generation"`
+	Diff       models.GenerationDiff `json:"diff"`
+}
+
+// Day is the changes made on one calendar day, oldest first.
+type Day struct {
+	Date    string   `json:"date"`
+	Changes []Change `json:"changes"`
+}
+
+// Range returns the generations numbered from fromID to toID, in either
+// order, sorted oldest first.
+func Range(generations []models.Generation, fromID, toID string) ([]models.Generation, error) {
+	from, err := strconv.Atoi(fromID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation %q", fromID)
+	}
+	to, err := strconv.Atoi(toID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation %q", toID)
+	}
+	lo, hi := min(from, to), max(from, to)
+	var in []models.Generation
+	for _, gen := range generations {
+		if n, err := strconv.Atoi(gen.ID); err == nil && n >= lo && n <= hi {
+			in = append(in, gen)
+		}
+	}
+	slices.SortFunc(in, func(a, b models.Generation) int {
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return x - y
+	})
+	if len(in) < 2 {
+		return nil, fmt.Errorf("generations %d to %d span no changes", lo, hi)
+	}
+	return in, nil
+}
+
+// Pairs returns each generation of a range paired with the one before it,
+// for fetching their diffs in bulk.
+func Pairs(generations []models.Generation) [][2]string {
+	var pairs [][2]string
+	for i := 1; i < len(generations); i++ {
+		pairs = append(pairs, [2]string{generations[i-1].ID, generations[i].ID})
+	}
+	return pairs
+}
+
+// Days groups the diffs of Pairs(generations) by the day each later
+// generation was created. Generations that changed no packages are left
+// out.
+func Days(generations []models.Generation, diffs []models.GenerationDiff) []Day {
+	var days []Day
+	for i, diff := range diffs {
+		if len(diff.Added)+len(diff.Removed)+len(diff.Modified) == 0 {
+			continue
+		}
+		gen := generations[i+1]
+		date := gen.Timestamp.Format("2006-01-02")
+		if n := len(days); n == 0 || days[n-1].Date != date {
+			days = append(days, Day{Date: date})
+		}
+		days[len(days)-1].Changes = append(days[len(days)-1].Changes, Change{Generation: gen, Diff: diff})
+	}
+	return days
+}
+
+// changeHeading names a generation in a changelog.
+func changeHeading(gen models.Generation) string {
+	heading := fmt.Sprintf("Generation %s (%s)", gen.ID, listing.Timestamp(gen.Timestamp))
+	if gen.Description != "" {
+		heading += ": " + gen.Description
+	}
+	return heading
+}
+
+// Changelog writes days under title in format.
+func Changelog(w io.Writer, format, title string, days []Day) error {
+	switch format {
+	case Markdown:
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\n", title)
+		if len(days) == 0 {
+			b.WriteString("\nNo package changes.\n")
+		}
+		for _, day := range days {
+			fmt.Fprintf(&b, "\n## %s\n", day.Date)
+			for _, c := range day.Changes {
+				fmt.Fprintf(&b, "\n### %s\n\n", changeHeading(c.Generation))
+				for _, sec := range sections(c.Diff) {
+					for _, line := range sec.Lines {
+						fmt.Fprintf(&b, "- %s: %s\n", sec.Kind, line)
+					}
+				}
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	case HTML:
+		type change struct {
+			Heading  string
+			Sections []section
+		}
+		type day struct {
+			Date    string
+			Changes []change
+		}
+		var data []day
+		for _, d := range days {
+			out := day{Date: d.Date}
+			for _, c := range d.Changes {
+				out.Changes = append(out.Changes, change{changeHeading(c.Generation), sections(c.Diff)})
+			}
+			data = append(data, out)
+		}
+		return changelogPage.Execute(w, struct {
+			Title string
+			Days  []day
+		}{title, data})
+	case JSON:
+		if days == nil {
+			days = []Day{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(days)
+	}
+	return fmt.Errorf("unknown format %q, want one of %s", format, strings.Join(Formats, ", "))
+}
+
+var changelogPage = template.Must(template.New("changelog").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Days}}
+<h2>{{.Date}}</h2>
+{{- range .Changes}}
+<h3>{{.Heading}}</h3>
+{{- range .Sections}}
+<h4>{{.Heading}}</h4>
+<ul>
+{{- range .Lines}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+{{- else}}
+<p>No package changes.</p>
+{{- end}}
+</body>
+</html>
+`))
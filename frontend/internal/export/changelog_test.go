@@ -0,0 +1,94 @@
+package export
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+func TestRange(t *testing.T) {
+	var generations []models.Generation
+	for _, id := range []string{"7", "3", "5", "4", "x", "9"} {
+		generations = append(generations, models.Generation{ID: id})
+	}
+	ids := func(gens []models.Generation) []string {
+		var out []string
+		for _, g := range gens {
+			out = append(out, g.ID)
+		}
+		return out
+	}
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"3", "7", []string{"3", "4", "5", "7"}},
+		{"7", "3", []string{"3", "4", "5", "7"}},
+		{"6", "100", []string{"7", "9"}},
+		{"5", "6", nil},
+		{"3", "x", nil},
+	}
+	for _, tt := range tests {
+		got, err := Range(generations, tt.from, tt.to)
+		if (err != nil) != (tt.want == nil) || !slices.Equal(ids(got), tt.want) {
+			t.Errorf("Range(%s, %s) = %v, %v, want %v", tt.from, tt.to, ids(got), err, tt.want)
+		}
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	defer func(loc *time.Location) { listing.Location = loc }(listing.Location)
+	listing.Location = time.UTC
+	day := func(d, h int) time.Time { return time.Date(2026, 10, d, h, 0, 0, 0, time.UTC) }
+	generations := []models.Generation{
+		{ID: "1", Timestamp: day(12, 9)},
+		{ID: "2", Timestamp: day(12, 18), Description: "kernel update"},
+		{ID: "3", Timestamp: day(13, 9)},
+		{ID: "4", Timestamp: day(14, 9)},
+	}
+	pairs := Pairs(generations)
+	if want := [][2]string{{"1", "2"}, {"2", "3"}, {"3", "4"}}; !slices.Equal(pairs, want) {
+		t.Fatalf("Pairs = %v, want %v", pairs, want)
+	}
+	diffs := []models.GenerationDiff{
+		models.DiffPaths([]string{firefox128}, []string{firefox129}),
+		{},
+		models.DiffPaths(nil, []string{ripgrep}),
+	}
+	days := Days(generations, diffs)
+	if len(days) != 2 || days[0].Date != "2026-10-12" || days[1].Date != "2026-10-14" {
+		t.Fatalf("Days = %+v, want the 12th and 14th, leaving out the rebuild that changed nothing", days)
+	}
+	if c := days[0].Changes; len(c) != 1 || c[0].Generation.ID != "2" {
+		t.Errorf("changes of the 12th = %+v, want generation 2", c)
+	}
+
+	var b strings.Builder
+	if err := Changelog(&b, Markdown, "Changes", days); err != nil {
+		t.Fatalf("Changelog: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# Changes\n",
+		"\n## 2026-10-12\n",
+		"\n### Generation 2 (" + listing.Timestamp(day(12, 18)) + "): kernel update\n\n- modified: firefox 128.0.3 → 129.0.1\n",
+		"\n### Generation 4 (" + listing.Timestamp(day(14, 9)) + ")\n\n- added: ripgrep 14.1.0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("changelog lacks %q:\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	if err := Changelog(&b, JSON, "Changes", nil); err != nil || strings.TrimSpace(b.String()) != "[]" {
+		t.Errorf("empty JSON changelog = %q, %v, want []", b.String(), err)
+	}
+	b.Reset()
+	if err := Changelog(&b, HTML, "Changes", days); err != nil || !strings.Contains(b.String(), "<h3>Generation 2 (") {
+		t.Errorf("HTML changelog = %v:\n%s", err, b.String())
+	}
+}
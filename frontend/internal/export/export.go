@@ -0,0 +1,150 @@
+// Package export writes diffs and changelogs as documents to keep or
+// share: Markdown, a standalone HTML page, or JSON for scripts. The
+// interactive exports and the headless commands produce the same files.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// The formats a diff can be exported in.
+const (
+	Markdown = "md"
+	HTML     = "html"
+	JSON     = "json"
+)
+
+// Formats lists the format names, for usage messages.
+var Formats = []string{Markdown, HTML, JSON}
+
+// FormatOf picks the format from a file name's extension.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".html", ".htm":
+		return HTML, nil
+	case ".json":
+		return JSON, nil
+	}
+	return "", fmt.Errorf("can't tell the format of %s; use .md, .html or .json", path)
+}
+
+// Diff writes diff under title in format. JSON leaves the title out and
+// gives empty lists instead of nulls, as scripts would otherwise have to
+// check for them.
+func Diff(w io.Writer, format, title string, diff models.GenerationDiff) error {
+	switch format {
+	case Markdown:
+		return markdown(w, title, diff)
+	case HTML:
+		return page.Execute(w, struct {
+			Title    string
+			Sections []section
+		}{title, sections(diff)})
+	case JSON:
+		for _, changes := range []*[]models.PackageChange{&diff.Added, &diff.Removed, &diff.Modified} {
+			if *changes == nil {
+				*changes = []models.PackageChange{}
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	return fmt.Errorf("unknown format %q, want one of %s", format, strings.Join(Formats, ", "))
+}
+
+// section is one kind of change, with a line per package.
+type section struct {
+	Kind    string
+	Heading string
+	Lines   []string
+}
+
+func sections(diff models.GenerationDiff) []section {
+	var out []section
+	for _, s := range []struct {
+		heading string
+		changes []models.PackageChange
+	}{
+		{"Added", diff.Added},
+		{"Removed", diff.Removed},
+		{"Modified", diff.Modified},
+	} {
+		if len(s.changes) == 0 {
+			continue
+		}
+		sec := section{Kind: strings.ToLower(s.heading), Heading: fmt.Sprintf("%s (%d)", s.heading, len(s.changes))}
+		for _, c := range s.changes {
+			sec.Lines = append(sec.Lines, describe(c))
+		}
+		out = append(out, sec)
+	}
+	return out
+}
+
+// describe names a change by package and version, "firefox 121 → 122" for
+// a modified one, falling back to the store path's own name.
+func describe(c models.PackageChange) string {
+	name, version := c.Name, c.NewVersion
+	if name == "" {
+		_, name, version = models.ParseStorePath(c.Path)
+	}
+	if version == "" {
+		version = c.OldVersion
+	}
+	switch {
+	case c.NewPath != "" && c.OldVersion != "" && c.NewVersion != "" && c.OldVersion != c.NewVersion:
+		return fmt.Sprintf("%s %s → %s", name, c.OldVersion, c.NewVersion)
+	case version != "":
+		return name + " " + version
+	}
+	return name
+}
+
+func markdown(w io.Writer, title string, diff models.GenerationDiff) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	secs := sections(diff)
+	if len(secs) == 0 {
+		b.WriteString("\nNo package changes.\n")
+	}
+	for _, sec := range secs {
+		fmt.Fprintf(&b, "\n## %s\n\n", sec.Heading)
+		for _, line := range sec.Lines {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var page = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Sections}}
+<h2>{{.Heading}}</h2>
+<ul>
+{{- range .Lines}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No package changes.</p>
+{{- end}}
+</body>
+</html>
+`))
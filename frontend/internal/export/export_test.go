@@ -0,0 +1,105 @@
+package export
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+const (
+	firefox128 = "/nix/store/jl3v0vqjji50sh8433shdh86i8g81n9i-firefox-128.0.3"
+	firefox129 = "/nix/store/gv2892rbj5qc5j63ckiw585ka2ax3xd8-firefox-129.0.1"
+	htop       = "/nix/store/0r4d2m3wq6f1c5h7j9k2l4n6p8s0v2x4-htop-3.3.0"
+	ripgrep    = "/nix/store/5c7d9f1h3j5l7n9p1r3t5v7x9z1b3d5f-ripgrep-14.1.0"
+)
+
+func testDiff() models.GenerationDiff {
+	return models.DiffPaths([]string{firefox128, htop}, []string{firefox129, ripgrep})
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"diff.md", Markdown},
+		{"notes.MARKDOWN", Markdown},
+		{"out/diff.html", HTML},
+		{"diff.htm", HTML},
+		{"diff.json", JSON},
+		{"diff.txt", ""},
+		{"diff", ""},
+	}
+	for _, tt := range tests {
+		got, err := FormatOf(tt.path)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestDiffMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := Diff(&b, Markdown, "Generation 41 → 42", testDiff()); err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := `# Generation 41 → 42
+
+## Added (1)
+
+- ripgrep 14.1.0
+
+## Removed (1)
+
+- htop 3.3.0
+
+## Modified (1)
+
+- firefox 128.0.3 → 129.0.1
+`
+	if b.String() != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	Diff(&b, Markdown, "Nothing", models.GenerationDiff{})
+	if !strings.Contains(b.String(), "No package changes.") {
+		t.Errorf("empty diff =\n%s", b.String())
+	}
+}
+
+func TestDiffHTML(t *testing.T) {
+	var b strings.Builder
+	if err := Diff(&b, HTML, "<b>41</b> → 42", testDiff()); err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{"<title>&lt;b&gt;41&lt;/b&gt; → 42</title>", "<h2>Modified (1)</h2>", "<li>firefox 128.0.3 → 129.0.1</li>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	var b strings.Builder
+	diff := models.DiffPaths([]string{htop}, nil)
+	if err := Diff(&b, JSON, "ignored", diff); err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("not JSON: %v\n%s", err, b.String())
+	}
+	if added, ok := got["added"].([]any); !ok || len(added) != 0 {
+		t.Errorf("added = %#v, want an empty list", got["added"])
+	}
+	if removed, _ := got["removed"].([]any); len(removed) != 1 {
+		t.Errorf("removed = %#v, want htop", got["removed"])
+	}
+}
+
+func TestDiffUnknownFormat(t *testing.T) {
+	if err := Diff(&strings.Builder{}, "pdf", "t", testDiff()); err == nil {
+		t.Error("Diff in an unknown format succeeded")
+	}
+}
@@ -0,0 +1,102 @@
+// Package groups persists named sets of generations for repeated
+// comparisons.
+package groups
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"nix-timemach/internal/xdg"
+)
+
+type Group struct {
+	Name    string    `json:"name"`
+	IDs     []string  `json:"ids"`
+	Created time.Time `json:"created"`
+}
+
+func path() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "groups.json"), nil
+}
+
+// Load returns the saved groups sorted by name. A missing file is not an
+// error.
+func Load() ([]Group, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read groups: %w", err)
+	}
+
+	var groups []Group
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// Put saves g, replacing any existing group with the same name.
+func Put(g Group) error {
+	groups, err := Load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range groups {
+		if groups[i].Name == g.Name {
+			groups[i] = g
+			replaced = true
+		}
+	}
+	if !replaced {
+		groups = append(groups, g)
+	}
+	return save(groups)
+}
+
+// Delete removes the group called name, if it exists.
+func Delete(name string) error {
+	groups, err := Load()
+	if err != nil {
+		return err
+	}
+	kept := groups[:0]
+	for _, g := range groups {
+		if g.Name != name {
+			kept = append(kept, g)
+		}
+	}
+	return save(kept)
+}
+
+func save(groups []Group) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := xdg.WriteFile(p, data); err != nil {
+		return fmt.Errorf("failed to save groups: %w", err)
+	}
+	return nil
+}
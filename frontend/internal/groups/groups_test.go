@@ -0,0 +1,77 @@
+package groups
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func names(groups []Group) []string {
+	var out []string
+	for _, g := range groups {
+		out = append(out, g.Name)
+	}
+	return out
+}
+
+func TestPutLoadDelete(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if groups, err := Load(); groups != nil || err != nil {
+		t.Fatalf("Load with no file = %v, %v, want nothing", groups, err)
+	}
+
+	created := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for _, g := range []Group{
+		{Name: "kernels", IDs: []string{"3", "7"}, Created: created},
+		{Name: "before-upgrade", IDs: []string{"12"}, Created: created},
+	} {
+		if err := Put(g); err != nil {
+			t.Fatalf("Put(%s): %v", g.Name, err)
+		}
+	}
+	groups, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, want := names(groups), []string{"before-upgrade", "kernels"}; !slices.Equal(got, want) {
+		t.Fatalf("groups = %v, want %v sorted by name", got, want)
+	}
+	if g := groups[1]; !slices.Equal(g.IDs, []string{"3", "7"}) || !g.Created.Equal(created) {
+		t.Errorf("kernels = %+v", g)
+	}
+
+	if err := Put(Group{Name: "kernels", IDs: []string{"3", "7", "9"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	groups, _ = Load()
+	if len(groups) != 2 || len(groups[1].IDs) != 3 {
+		t.Errorf("after replacing kernels, groups = %+v", groups)
+	}
+
+	if err := Delete("before-upgrade"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := Delete("missing"); err != nil {
+		t.Fatalf("Delete of a missing group: %v", err)
+	}
+	groups, _ = Load()
+	if got := names(groups); !slices.Equal(got, []string{"kernels"}) {
+		t.Errorf("after Delete, groups = %v", got)
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	Put(Group{Name: "kernels"})
+	p, _ := path()
+	if err := os.WriteFile(p, []byte(`{"name": "kernels"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Load of a malformed file succeeded")
+	}
+	if err := Put(Group{Name: "other"}); err == nil {
+		t.Error("Put over a malformed file succeeded, losing it")
+	}
+}
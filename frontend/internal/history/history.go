@@ -0,0 +1,79 @@
+// Package history keeps what was done before: short lists of recently
+// entered strings, such as list filters, persisted across sessions, and
+// the in-memory undo history of a session.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nix-timemach/internal/xdg"
+)
+
+// MaxEntries caps how many entries a history keeps.
+const MaxEntries = 50
+
+func path(name string) (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+"-history"), nil
+}
+
+// Load returns the named history, oldest entry first. A missing file is not
+// an error.
+func Load(name string) ([]string, error) {
+	p, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s history: %w", name, err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, nil
+}
+
+// Add appends entry as the newest item, dropping an earlier copy of it and
+// the oldest entries beyond MaxEntries.
+func Add(entries []string, entry string) []string {
+	kept := make([]string, 0, len(entries)+1)
+	for _, e := range entries {
+		if e != entry {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, entry)
+	if len(kept) > MaxEntries {
+		kept = kept[len(kept)-MaxEntries:]
+	}
+	return kept
+}
+
+// Save replaces the named history with entries.
+func Save(name string, entries []string) error {
+	p, err := path(name)
+	if err != nil {
+		return err
+	}
+	data := strings.Join(entries, "\n") + "\n"
+	if err := xdg.WriteFile(p, []byte(data)); err != nil {
+		return fmt.Errorf("failed to save %s history: %w", name, err)
+	}
+	return nil
+}
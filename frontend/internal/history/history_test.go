@@ -0,0 +1,54 @@
+package history
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		entry   string
+		want    []string
+	}{
+		{"first", nil, "firefox", []string{"firefox"}},
+		{"newest last", []string{"git"}, "firefox", []string{"git", "firefox"}},
+		{"repeat moves to the end", []string{"firefox", "git"}, "firefox", []string{"git", "firefox"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Add(tt.entries, tt.entry); !slices.Equal(got, tt.want) {
+				t.Errorf("Add(%v, %q) = %v, want %v", tt.entries, tt.entry, got, tt.want)
+			}
+		})
+	}
+
+	var entries []string
+	for i := range MaxEntries + 5 {
+		entries = Add(entries, strconv.Itoa(i))
+	}
+	if len(entries) != MaxEntries || entries[0] != "5" || entries[MaxEntries-1] != strconv.Itoa(MaxEntries+4) {
+		t.Errorf("after %d entries kept %d, %s to %s; want the newest %d", MaxEntries+5, len(entries), entries[0], entries[len(entries)-1], MaxEntries)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if entries, err := Load("filter"); entries != nil || err != nil {
+		t.Fatalf("Load with no history = %v, %v, want nothing", entries, err)
+	}
+
+	want := []string{"firefox", "kernel 6.6", "git"}
+	if err := Save("filter", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load("filter")
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("Load = %v, %v, want %v", got, err, want)
+	}
+	if other, _ := Load("search"); other != nil {
+		t.Errorf("another history shares the file: %v", other)
+	}
+}
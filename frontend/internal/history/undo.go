@@ -0,0 +1,59 @@
+package history
+
+// Undo is an undo history: the steps done, newest last, and the ones undone
+// since, which can be redone until another step is done. Steps are applied
+// by the caller, which moves them with Undo and Redo once it has.
+type Undo[T any] struct {
+	done   []T
+	undone []T
+}
+
+// Do records step as the newest done, forgetting the undone steps and the
+// oldest done ones beyond MaxEntries.
+func (u *Undo[T]) Do(step T) {
+	u.done = append(u.done, step)
+	if len(u.done) > MaxEntries {
+		u.done = u.done[len(u.done)-MaxEntries:]
+	}
+	u.undone = nil
+}
+
+// Last returns the step Undo would undo.
+func (u *Undo[T]) Last() (T, bool) {
+	if len(u.done) == 0 {
+		var zero T
+		return zero, false
+	}
+	return u.done[len(u.done)-1], true
+}
+
+// Next returns the step Redo would redo.
+func (u *Undo[T]) Next() (T, bool) {
+	if len(u.undone) == 0 {
+		var zero T
+		return zero, false
+	}
+	return u.undone[len(u.undone)-1], true
+}
+
+// Undo moves the last done step to the undone ones.
+func (u *Undo[T]) Undo() {
+	if step, ok := u.Last(); ok {
+		u.done = u.done[:len(u.done)-1]
+		u.undone = append(u.undone, step)
+	}
+}
+
+// Redo moves the next undone step back to the done ones.
+func (u *Undo[T]) Redo() {
+	if step, ok := u.Next(); ok {
+		u.undone = u.undone[:len(u.undone)-1]
+		u.done = append(u.done, step)
+	}
+}
+
+// Clear forgets every step.
+func (u *Undo[T]) Clear() {
+	u.done = nil
+	u.undone = nil
+}
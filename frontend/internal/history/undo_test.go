@@ -0,0 +1,51 @@
+package history
+
+import "testing"
+
+func TestUndo(t *testing.T) {
+	var u Undo[int]
+	if _, ok := u.Last(); ok {
+		t.Fatal("empty history has a step to undo")
+	}
+	u.Undo()
+	u.Redo()
+
+	u.Do(1)
+	u.Do(2)
+	u.Undo()
+	if last, _ := u.Last(); last != 1 {
+		t.Errorf("after undo, Last = %d, want 1", last)
+	}
+	if next, ok := u.Next(); !ok || next != 2 {
+		t.Errorf("after undo, Next = %d, %v, want 2", next, ok)
+	}
+	u.Redo()
+	if last, _ := u.Last(); last != 2 {
+		t.Errorf("after redo, Last = %d, want 2", last)
+	}
+
+	u.Undo()
+	u.Do(3)
+	if _, ok := u.Next(); ok {
+		t.Error("a new step left an undone one to redo")
+	}
+
+	for i := range MaxEntries + 1 {
+		u.Do(i)
+	}
+	undone := 0
+	for ; ; undone++ {
+		if _, ok := u.Last(); !ok {
+			break
+		}
+		u.Undo()
+	}
+	if undone != MaxEntries {
+		t.Errorf("could undo %d steps, want %d", undone, MaxEntries)
+	}
+
+	u.Clear()
+	if _, ok := u.Next(); ok {
+		t.Error("Clear left a step to redo")
+	}
+}
@@ -0,0 +1,422 @@
+package i18n
+
+// English is the default catalog; every key used by the UI must exist here.
+var English = map[string]string{
+	"app.initializing": "Initializing...",
+	"app.loading":      "Loading...",
+	"app.error":        "Error: %v\n\nPress 'r' to retry or 'q' to quit",
+
+	"error.detailsHint": "Press 'd' for details",
+	"error.hideHint":    "Press 'd' to hide details",
+	"error.command":     "command",
+	"error.kind":        "kind",
+	"error.status":      "status",
+	"error.message":     "message:",
+	"error.stderr":      "stderr:",
+
+	"help.up":            "up",
+	"help.down":          "down",
+	"help.pageUp":        "page up",
+	"help.pageDown":      "page down",
+	"help.select":        "select",
+	"help.from":          "mark diff start",
+	"help.diffFrom":      "diff from mark",
+	"help.back":          "back",
+	"help.quit":          "quit",
+	"help.reload":        "reload",
+	"help.knownGood":     "mark known good",
+	"help.details":       "details",
+	"help.collapse":      "collapse identical",
+	"help.pending":       "pending rebuild",
+	"help.mark":          "mark",
+	"help.saveGroup":     "save marked as group",
+	"help.groups":        "groups",
+	"help.hashes":        "abbreviate hashes",
+	"help.copyCommand":   "copy command",
+	"help.findPackage":   "find package",
+	"help.filter":        "filter",
+	"help.snapshot":      "diff against snapshot",
+	"help.explicit":      "explicit packages only",
+	"help.files":         "packages / files in /etc",
+	"help.groupPrefixes": "group by prefix",
+	"help.log":           "backend log",
+	"help.presets":       "compare over time",
+	"help.profile":       "switch profile",
+	"help.preview":       "preview pane",
+	"help.attrPaths":     "group by attribute path",
+	"help.advise":        "rollback advice",
+	"help.rollback":      "roll back",
+	"help.boot":          "make boot default",
+	"help.delete":        "delete",
+	"help.gc":            "collect garbage",
+	"help.pin":           "pin / unpin",
+	"help.retention":     "retention policy",
+	"help.trash":         "trash",
+	"help.audit":         "audit log",
+	"help.undo":          "undo",
+	"help.theme":         "next theme",
+	"help.timestamps":    "relative/absolute times",
+	"help.foldDay":       "fold/unfold day",
+	"help.redo":          "redo",
+	"help.bisect":        "bisect a regression",
+	"help.timeline":      "package timeline",
+	"help.changelog":     "changelog from mark",
+	"help.sizeHistory":   "closure size history",
+	"help.usage":         "largest paths",
+	"help.why":           "why is it here",
+	"help.whyPackage":    "why a package",
+	"help.closureTree":   "closure tree",
+	"help.export":        "export diff",
+	"help.more":          "more keys",
+
+	"help.deleteGroup": "delete group",
+
+	"groups.title":         "Comparison groups",
+	"groups.empty":         "No saved groups. Mark generations with space and save them with S.",
+	"groups.missing":       "(%d missing)",
+	"groups.prompt":        "Name for the %d marked generations",
+	"groups.saved":         "saved group %q",
+	"groups.loaded":        "loaded group %q",
+	"groups.loadedMissing": "loaded group %q; %d members no longer exist: %s",
+
+	"list.identical":   "(+%d identical)",
+	"list.profile":     "profile: %s",
+	"list.bootDefault": "⏻ boot default",
+	"list.booted":      "booted",
+	"list.from":        "◆ from",
+	"list.to":          "◇ to",
+	"list.nixos":       "NixOS %s",
+	"list.kernel":      "Linux %s",
+	"list.rev":         "rev %s",
+	"list.pinned":      "⚑ pinned",
+	"list.pinnedAs":    "⚑ %s",
+
+	"pane.changes": "Changes since generation %s:",
+	"pane.oldest":  "The oldest generation; nothing came before it.",
+
+	"compare.marked":  "diffing from generation %s; move to the other end and press d",
+	"compare.cleared": "diff start cleared",
+	"compare.noMark":  "mark the generation to diff from with m first",
+
+	"filter.count": "%d of %d",
+	"filter.none":  "No generations match %q.",
+
+	"fleet.title":     "Fleet",
+	"fleet.host":      "generation %s, rebuilt %s",
+	"fleet.error":     "error: %s",
+	"fleet.reference": "(reference)",
+	"fleet.inSync":    "in sync",
+
+	"search.prompt":   "Package name and optional version, e.g. openssl 1.1 (empty to clear)",
+	"search.found":    "%s: in %d generations, first %s, last %s (esc to show all)",
+	"search.notFound": "%s: not in any generation",
+
+	"help.copyPath":     "copy path",
+	"help.diffPrevious": "diff from mark or previous",
+	"help.pager":        "open in pager",
+
+	"diff.loading":         "Loading diff...",
+	"diff.streaming":       "Loading diff... %d of %d changes",
+	"diff.title":           "Diff: %s → %s",
+	"diff.spanOne":         "(spanning 1 intermediate generation)",
+	"diff.span":            "(spanning %d intermediate generations)",
+	"diff.reversed":        "(reversed: going back in time)",
+	"diff.sizeDelta":       "(closure %s)",
+	"diff.snapshotTitle":   "Diff: snapshot %s → %s",
+	"diff.pendingTitle":    "Diff: current system → pending rebuild",
+	"diff.none":            "No package changes.",
+	"diff.noExplicit":      "No changes to explicitly configured packages (e to show all).",
+	"diff.explicitUnknown": "this backend doesn't report which packages are explicit",
+	"diff.prefixGroup":     "%s-* (%d packages)",
+	"diff.attrUnknown":     "this backend doesn't report attribute paths",
+	"diff.attrNone":        "(no attribute path)",
+	"diff.added":           "Added:",
+	"diff.removed":         "Removed:",
+	"diff.modified":        "Modified:",
+
+	"files.title":       "Files in /etc:",
+	"files.loading":     "Loading changes to /etc...",
+	"files.none":        "No files in /etc changed.",
+	"files.failed":      "Couldn't compare /etc: %s",
+	"files.unavailable": "file changes are only available between two generations",
+
+	"units.title": "Systemd units:",
+
+	"deps.title":       "Dependencies of %s: %s → %s",
+	"deps.loading":     "Loading dependency changes...",
+	"deps.none":        "No dependency changes.",
+	"deps.pending":     "dependency changes are only available between two generations",
+	"deps.unsupported": "this backend can't show dependency changes",
+
+	"snapshot.prompt": "Snapshot to diff against (%s)",
+	"snapshot.none":   "no snapshots; save one with: nix-timemach snapshot save <name>",
+
+	"log.empty": "(no backend output yet)",
+
+	"time.now":     "just now",
+	"time.minute":  "1 minute ago",
+	"time.minutes": "%d minutes ago",
+	"time.hour":    "1 hour ago",
+	"time.hours":   "%d hours ago",
+	"time.day":     "1 day ago",
+	"time.days":    "%d days ago",
+	"time.week":    "1 week ago",
+	"time.weeks":   "%d weeks ago",
+	"time.month":   "1 month ago",
+	"time.months":  "%d months ago",
+	"time.year":    "1 year ago",
+	"time.years":   "%d years ago",
+
+	"days.today":     "Today",
+	"days.yesterday": "Yesterday",
+	"days.week":      "Earlier this week",
+	"days.lastWeek":  "Last week",
+	"days.unknown":   "Unknown date",
+	"days.folded":    "(%d generations)",
+	"days.foldedOne": "(1 generation)",
+
+	"timestamps.both":     "time and how long ago",
+	"timestamps.relative": "how long ago",
+	"timestamps.absolute": "time",
+
+	"presets.title":      "Compare the newest generation with…",
+	"presets.day":        "1 day ago",
+	"presets.week":       "1 week ago",
+	"presets.month":      "1 month ago",
+	"presets.boot":       "the last boot",
+	"presets.noneBefore": "no generation existed at %s",
+	"presets.unchanged":  "generation %s was already in effect at %s; nothing to compare",
+
+	"preview.stop":    "would stop:",
+	"preview.restart": "would restart:",
+	"preview.reload":  "would reload:",
+	"preview.start":   "would start:",
+	"preview.keep":    "would leave running:",
+	"preview.more":    "(+%d more)",
+	"preview.none":    "No units would change.",
+	"preview.failed":  "Couldn't preview the activation: %s",
+
+	"gc.prompt":      "Delete generations older than (days, e.g. 30d)",
+	"gc.title":       "Garbage collection: generations older than %s",
+	"gc.loading":     "Working out what would be deleted...",
+	"gc.running":     "Collecting garbage...",
+	"gc.failed":      "Couldn't preview garbage collection: %s",
+	"gc.nothing":     "Nothing to delete or collect.",
+	"gc.generations": "Would delete %d generations:",
+	"gc.frees":       "Would free %s in %d store paths.",
+	"gc.choices":     "[enter] collect garbage    [esc] back",
+	"gc.done":        "deleted %d generations and freed about %s",
+
+	"pin.prompt": "Pin generation %s as (optional name)",
+
+	"export.prompt": "Export diff to (.md, .html or .json)",
+	"export.done":   "diff written to %s",
+	"export.failed": "couldn't export the diff: %v",
+
+	"timeline.prompt":  "Package to trace",
+	"timeline.title":   "History of %s",
+	"timeline.absent":  "(absent)",
+	"timeline.since":   "since generation %s",
+	"timeline.one":     "in generation %s",
+	"timeline.range":   "in generations %s–%s",
+	"timeline.choices": "[enter] diff across the change    [esc] back",
+
+	"changelog.title":        "Changes from generation %s to %s",
+	"changelog.generation":   "Generation %s",
+	"changelog.empty":        "No package changes.",
+	"changelog.exportPrompt": "Export changelog to (.md, .html or .json)",
+	"changelog.exportDone":   "changelog written to %s",
+	"changelog.exportFailed": "couldn't export the changelog: %v",
+	"changelog.choices":      "[↑/↓] scroll    [E] export    [esc] back",
+
+	"sizes.title":   "Closure size over time",
+	"sizes.unknown": "No closure sizes are known.",
+	"sizes.summary": "%s to %s · %s from generation %s to %s",
+	"sizes.choices": "[↑/↓] scroll    [esc] back",
+
+	"usage.title":       "Largest paths in generation %s",
+	"usage.empty":       "The closure couldn't be sized.",
+	"usage.unsupported": "this backend can't list path sizes",
+	"usage.summary":     "largest %d of %d paths · %s in all",
+	"usage.name":        "Package",
+	"usage.size":        "Size",
+	"usage.share":       "Share",
+	"usage.choices":     "[s] sort    [y] copy path    [esc] back",
+
+	"why.prompt":      "Why does generation %s contain (name or store path)",
+	"why.title":       "Why generation %s contains %s",
+	"why.none":        "Generation %s doesn't depend on %s.",
+	"why.pending":     "dependency chains are only available for generations",
+	"why.unsupported": "this backend can't explain dependencies",
+
+	"closure.title":       "Closure of generation %s",
+	"closure.sizes":       "%s · %s with dependencies",
+	"closure.empty":       "The closure is empty.",
+	"closure.unsupported": "this backend can't list the closure",
+
+	"trash.title":       "Trash",
+	"trash.empty":       "The trash is empty.",
+	"trash.expires":     "expires in %s",
+	"trash.expiresDays": "expires in %dd",
+	"trash.unsupported": "this backend can't keep deleted generations",
+	"trash.choices":     "[enter] restore    [x] empty trash    [esc] back",
+
+	"watch.new":     "new generation %s detected",
+	"watch.newMany": "new generations %s detected",
+
+	"audit.title":     "Audit log",
+	"audit.empty":     "Nothing has been changed yet.",
+	"audit.summary":   "%d actions, newest first · %s",
+	"audit.time":      "Time",
+	"audit.user":      "User",
+	"audit.host":      "Host",
+	"audit.localhost": "this machine",
+	"audit.action":    "Action",
+	"audit.targets":   "Generations",
+	"audit.detail":    "Detail",
+	"audit.result":    "Result",
+	"audit.sudo":      "%s as %s",
+	"audit.ok":        "ok",
+	"audit.failed":    "failed: %s",
+	"audit.choices":   "[enter] show error    [esc] back",
+
+	"undo.nothing":       "nothing to undo",
+	"undo.nothingToRedo": "nothing to redo",
+	"undo.gone":          "that view can't be shown again",
+	"undo.marks":         "marking",
+	"undo.boot":          "booting generation %s by default",
+	"undo.rollback":      "rolling back to generation %s",
+	"undo.pin":           "pinning generation %s",
+	"undo.unpin":         "unpinning generation %s",
+	"undo.trash":         "moving generation %s to the trash",
+	"undo.trashBatch":    "moving generations %s to the trash",
+	"undo.restore":       "restoring generation %s",
+
+	"setup.title":   "nix-timemach needs its backend",
+	"setup.missing": "The backend program, %s, isn't installed. It was looked for in:",
+	"setup.fix": "To provide it, do one of:\n\n" +
+		"  • build it with cargo build --release in the backend directory of the source\n" +
+		"  • install it on $PATH or next to nix-timemach\n" +
+		"  • point NIX_TIMEMACH_BACKEND_PATH, or backend in the config file, at it\n" +
+		"  • run nix-timemach --backend native, which uses the Nix tools directly",
+	"setup.choices": "[d] try the demo instead    [q] quit",
+
+	"tree.choices": "[→] expand    [←] collapse    [esc] back",
+
+	"bisect.title":       "Bisect: good %s, bad %s",
+	"bisect.markedBad":   "bisect: generation %s is bad; press I on a good one to start",
+	"bisect.cleared":     "bisect: bad mark cleared",
+	"bisect.ended":       "bisect ended",
+	"bisect.missing":     "that generation no longer exists",
+	"bisect.left":        "%d generations left to test, at most %d more steps.",
+	"bisect.test":        "Test generation %s:",
+	"bisect.running":     "(running now)",
+	"bisect.found":       "Generation %s is the first bad one; generation %s next to it is good.",
+	"bisect.good":        "✓ good",
+	"bisect.bad":         "✗ bad",
+	"bisect.next":        "? testing",
+	"bisect.choices":     "[s] switch to it now    [o] boot it next    [g] good    [b] bad    [d] diff from good    [e] end bisect    [esc] back",
+	"bisect.doneChoices": "[d] diff the two    [e] end bisect    [esc] back",
+
+	"retention.title":         "Retention policy",
+	"retention.last":          "Keep the newest %d generations",
+	"retention.weekly":        "Keep one generation a week for %d weeks",
+	"retention.pinned":        "Keep pinned generations: %s",
+	"retention.yes":           "yes",
+	"retention.no":            "no",
+	"retention.editChoices":   "[↑/↓] choose    [←/→] change    [enter] save and review    [esc] back",
+	"retention.doomed":        "Applying the policy would delete %d generations:",
+	"retention.unpins":        "⚑ pinned; will be unpinned",
+	"retention.nothing":       "The policy keeps every generation.",
+	"retention.backChoice":    "[esc] back to the policy",
+	"retention.reviewChoices": "[enter] delete them    [esc] back to the policy",
+	"retention.running":       "Deleting generations...",
+	"retention.done":          "retention policy applied: deleted %d generations",
+
+	"rollback.none":      "no generation older than the current one to roll back to",
+	"rollback.failed":    "couldn't compare any generation with the current one",
+	"rollback.recommend": "safest rollback: generation %s (%s)",
+	"rollback.safest":    "⟲ safest rollback",
+
+	"pending.building": "Building configuration... %s (esc to cancel)",
+
+	"batch.knownGood": "Marking generations as known good",
+	"batch.progress":  "%d/%d done",
+	"batch.current":   "generation %s",
+	"batch.canceling": "Cancelling after the current item...",
+	"batch.summary":   "%s: %d succeeded, %d failed",
+	"batch.skipped":   "(%d skipped)",
+	"batch.failedIDs": "(%s)",
+
+	"status.copied":       "copied: %s",
+	"status.noReproducer": "this diff has no command-line equivalent",
+	"status.rolledBack":   "rolled back to generation %s",
+	"status.bootDefault":  "generation %s will be booted by default",
+	"status.deleted":      "deleted %d generations",
+	"status.deletedOne":   "deleted generation %s",
+	"status.trashed":      "moved %d generations to the trash; restore them with J",
+	"status.trashedOne":   "moved generation %s to the trash; restore it with J",
+	"status.restored":     "restored generation %s",
+	"status.trashEmptied": "emptied the trash",
+	"status.undone":       "undid %s",
+	"status.redone":       "redid %s",
+	"status.theme":        "theme: %s",
+	"status.timestamps":   "timestamps: %s",
+	"status.keepCurrent":  "generation %s is the running system and can't be deleted",
+	"status.keepPinned":   "generation %s is pinned; unpin it with K first",
+	"status.pinned":       "pinned generation %s",
+	"status.unpinned":     "unpinned generation %s",
+	"status.readOnly":     "read-only mode: this action is disabled",
+	"status.systemOnly":   "this action only applies to the system profile",
+	"status.profile":      "showing the %s profile",
+	"status.host":         "host: %s",
+	"status.store":        "store: %s",
+	"status.refreshed":    "refreshed %s ago",
+
+	"details.title":           "Generation %s",
+	"details.created":         "Created",
+	"details.description":     "Description",
+	"details.storePath":       "Store path",
+	"details.closureSize":     "Closure size",
+	"details.nixos":           "NixOS",
+	"details.kernel":          "Kernel",
+	"details.specialisations": "Specialisations",
+	"details.flakeUrl":        "Flake",
+	"details.flakeRevision":   "Flake revision",
+	"details.dirty":           "(uncommitted changes)",
+	"details.pin":             "Pinned",
+	"details.pinnedUnnamed":   "yes",
+	"details.pinnedAs":        "as %q",
+	"details.boot":            "Boot",
+	"details.booted":          "booted",
+	"details.bootDefault":     "boot default",
+	"details.notBooted":       "not booted, not the boot default",
+	"details.profiles":        "Profiles:",
+
+	"config.title": "Configuration:",
+	"config.input": "input %s",
+	"config.none":  "(none)",
+
+	"locks.title": "Flake inputs:",
+	"locks.day":   "(%+d day)",
+	"locks.days":  "(%+d days)",
+
+	"confirm.choices":        "[y] yes    [n] no",
+	"confirm.knownGood":      "Mark generation %s as known good?\nIts closure will be kept as a GC root.",
+	"confirm.knownGoodBatch": "Mark %d generations as known good?\nTheir closures will be kept as GC roots.",
+	"confirm.rollback":       "Roll the system back to generation %s?\nIt will be activated immediately.",
+	"confirm.boot":           "Boot generation %s by default?\nThe running system is left as it is.",
+	"confirm.delete":         "Delete generation %s?\nThis can't be undone.",
+	"confirm.deleteBatch":    "Delete %d generations (%s)?\nThis can't be undone.",
+	"confirm.trash":          "Move generation %s to the trash?\nIt can be restored for %d days.",
+	"confirm.trashBatch":     "Move %d generations (%s) to the trash?\nThey can be restored for %d days.",
+	"confirm.restore":        "Restore generation %s?\nIt will be listed again, but not activated.",
+	"confirm.emptyTrash":     "Empty the trash of %d generations?\nThis can't be undone.",
+	"confirm.undo":           "Undo %s?",
+	"confirm.redo":           "Redo %s?",
+	"confirm.deleteGroup":    "Delete group %q?",
+	"confirm.unpin":          "Unpin generation %s?\nGarbage collection may delete it again.",
+	"confirm.quit":           "%s — quit anyway?",
+	"quit.marked":            "%d generations are marked for a batch action",
+	"quit.markedOne":         "1 generation is marked for a batch action",
+}
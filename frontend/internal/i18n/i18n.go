@@ -0,0 +1,76 @@
+// Package i18n holds the message catalogs for user-facing UI strings.
+//
+// To add a translation, create a file with a map like English keyed by the
+// same identifiers and register it in catalogs. Keys missing from a
+// translation fall back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var catalogs = map[string]map[string]string{
+	"en": English,
+}
+
+// Catalog looks up messages for one language.
+type Catalog struct {
+	lang     string
+	messages map[string]string
+}
+
+// New returns the catalog for lang, accepting locale forms such as
+// "de_DE.UTF-8". Unknown languages get the English catalog.
+func New(lang string) *Catalog {
+	lang = normalize(lang)
+	messages, ok := catalogs[lang]
+	if !ok {
+		lang, messages = "en", English
+	}
+	return &Catalog{lang: lang, messages: messages}
+}
+
+// Detect returns the language requested by the environment, following the
+// usual LC_ALL > LC_MESSAGES > LANG precedence.
+func Detect() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return "en"
+}
+
+// Lang is the language the catalog actually serves.
+func (c *Catalog) Lang() string {
+	return c.lang
+}
+
+// T returns the message for key, formatted with args if any are given.
+// Unknown keys are returned verbatim so a missing entry is visible but
+// never fatal.
+func (c *Catalog) T(key string, args ...any) string {
+	msg, ok := c.messages[key]
+	if !ok {
+		if msg, ok = English[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func normalize(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_.@-"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "c" || lang == "posix" {
+		return "en"
+	}
+	return lang
+}
@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct{ lang, want string }{
+		{"", "en"},
+		{"en", "en"},
+		{"en_GB.UTF-8", "en"},
+		{"C", "en"},
+		{"POSIX", "en"},
+		{"xx_YY", "en"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.lang).Lang(); got != tt.want {
+			t.Errorf("New(%q).Lang() = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "")
+	if got := Detect(); got != "en" {
+		t.Errorf("Detect with nothing set = %q, want en", got)
+	}
+	t.Setenv("LANG", "de_DE.UTF-8")
+	t.Setenv("LC_MESSAGES", "fr_FR.UTF-8")
+	if got := Detect(); got != "fr_FR.UTF-8" {
+		t.Errorf("Detect = %q, want LC_MESSAGES over LANG", got)
+	}
+	t.Setenv("LC_ALL", "C")
+	if got := Detect(); got != "C" {
+		t.Errorf("Detect = %q, want LC_ALL over the rest", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	c := &Catalog{lang: "xx", messages: map[string]string{"confirm.quit": "%s — wirklich?"}}
+	if got := c.T("confirm.quit", "x"); got != "x — wirklich?" {
+		t.Errorf("translated message = %q", got)
+	}
+	if got, want := c.T("confirm.choices"), English["confirm.choices"]; got != want {
+		t.Errorf("untranslated message = %q, want the English %q", got, want)
+	}
+	if got := c.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key = %q, want it verbatim", got)
+	}
+}
+
+// Every message the UI asks for by a literal key is in the English catalog.
+func TestEnglishHasUIKeys(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "ui", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no UI sources: %v", err)
+	}
+	key := regexp.MustCompile(`\bt\("([A-Za-z0-9_.]+)"[,)]`)
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range key.FindAllSubmatch(src, -1) {
+			if _, ok := English[string(m[1])]; !ok {
+				t.Errorf("%s: no English message %q", filepath.Base(f), m[1])
+			}
+		}
+	}
+}
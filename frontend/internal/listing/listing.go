@@ -0,0 +1,126 @@
+// Package listing formats generations the same way for the interactive
+// list and the headless list command.
+package listing
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"nix-timemach/internal/models"
+)
+
+// DefaultTimeLayout is how generation timestamps are shown unless the
+// configuration says otherwise.
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// TimeLayout is how generation timestamps are shown everywhere. It is set
+// once at startup.
+var TimeLayout = DefaultTimeLayout
+
+// Header names the columns returned by Row.
+var Header = []string{"ID", "TIMESTAMP", "DESCRIPTION", "SIZE", "CURRENT", "NIXOS", "KERNEL", "REV"}
+
+// Location is the time zone timestamps are shown in. It is set once at
+// startup.
+var Location = time.Local
+
+// unknownTime stands in for a timestamp the backend reported in a form that
+// couldn't be read, e.g. "????-??-?? ??:??:??". It is as wide as TimeLayout
+// so columns stay aligned.
+func unknownTime() string {
+	sample := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(TimeLayout)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return '?'
+		}
+		return r
+	}, sample)
+}
+
+// Timestamp formats t with TimeLayout in Location, or returns unknownTime
+// for the zero time.
+func Timestamp(t time.Time) string {
+	if t.IsZero() {
+		return unknownTime()
+	}
+	return t.In(Location).Format(TimeLayout)
+}
+
+// Row returns gen's columns in Header order. Unknown sizes and versions are
+// shown as "-".
+func Row(gen models.Generation) []string {
+	size := "-"
+	if gen.ClosureSize > 0 {
+		size = HumanSize(gen.ClosureSize)
+	}
+	current := ""
+	if gen.Current {
+		current = "*"
+	}
+	return []string{gen.ID, Timestamp(gen.Timestamp), gen.Description, size, current, orDash(gen.NixosVersion), orDash(gen.KernelVersion), orDash(gen.ShortRevision())}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// HumanSize formats a byte count with binary units, e.g. "12.3 MiB".
+func HumanSize(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit || m <= -unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseSize reads a byte count like "512M", "20G", "1.5 GiB" or "4096".
+// Units are binary, with or without "iB" or "B": "20G" is 20 GiB.
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	mult := int64(1)
+	if i := strings.IndexAny(t, "KMGTPE"); i >= 0 && i == len(t)-1 {
+		mult = int64(1) << (10 * (strings.IndexByte("KMGTPE", t[i]) + 1))
+		t = t[:i]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+	if err != nil || n < 0 || n*float64(mult) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// Write prints rows to w. With an empty sep the columns are padded to line
+// up; otherwise fields are joined with sep as-is, for scripts.
+func Write(w io.Writer, rows [][]string, sep string) error {
+	if sep != "" {
+		for _, row := range rows {
+			if _, err := fmt.Fprintln(w, strings.Join(row, sep)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
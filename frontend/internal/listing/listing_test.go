@@ -0,0 +1,28 @@
+package listing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	defer func(layout string, loc *time.Location) { TimeLayout, Location = layout, loc }(TimeLayout, Location)
+	Location = time.UTC
+	at := time.Date(2024, 7, 1, 9, 30, 5, 0, time.UTC)
+	tests := []struct {
+		layout string
+		t      time.Time
+		want   string
+	}{
+		{DefaultTimeLayout, at, "2024-07-01 09:30:05"},
+		{DefaultTimeLayout, time.Time{}, "????-??-?? ??:??:??"},
+		{"Jan 2 15:04", at, "Jul 1 09:30"},
+		{"Jan 2 15:04", time.Time{}, "??? ? ??:??"},
+	}
+	for _, tt := range tests {
+		TimeLayout = tt.layout
+		if got := Timestamp(tt.t); got != tt.want {
+			t.Errorf("Timestamp(%v) with %q = %q, want %q", tt.t, tt.layout, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ActivationPreview is what activating a generation would do to the
+// running system's units, as switch-to-configuration dry-activate reports.
+type ActivationPreview struct {
+	Stop    []string
+	Restart []string
+	Reload  []string
+	Start   []string
+	// Keep are changed units that would be left running.
+	Keep []string
+	// Notes are the other things it reports, e.g. that systemd itself
+	// would be restarted.
+	Notes []string
+}
+
+var unitLine = regexp.MustCompile(`^would (stop|NOT stop|restart|reload|start) the following (?:changed )?units: (.*)$`)
+
+// ParseDryActivate reads the lines switch-to-configuration dry-activate
+// printed.
+func ParseDryActivate(lines []string) ActivationPreview {
+	var p ActivationPreview
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := unitLine.FindStringSubmatch(line)
+		if m == nil {
+			p.Notes = append(p.Notes, line)
+			continue
+		}
+		units := strings.Split(m[2], ", ")
+		switch m[1] {
+		case "stop":
+			p.Stop = append(p.Stop, units...)
+		case "NOT stop":
+			p.Keep = append(p.Keep, units...)
+		case "restart":
+			p.Restart = append(p.Restart, units...)
+		case "reload":
+			p.Reload = append(p.Reload, units...)
+		case "start":
+			p.Start = append(p.Start, units...)
+		}
+	}
+	return p
+}
+
+// Empty reports whether no unit would change.
+func (p ActivationPreview) Empty() bool {
+	return len(p.Stop)+len(p.Restart)+len(p.Reload)+len(p.Start)+len(p.Keep) == 0
+}
@@ -0,0 +1,59 @@
+package models
+
+import "encoding/json"
+
+// PackageChange is one entry of a GenerationDiff. An added package has only
+// a new version and a removed one only an old one.
+type PackageChange struct {
+	// Path is the package's store path; for a modified package, its old
+	// one, with NewPath the path it became.
+	Path    string `json:"path"`
+	NewPath string `json:"new_path"`
+
+	Name       string `json:"name"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+	// SizeDelta is how much the package itself grew, in bytes. It is only
+	// meaningful when SizeKnown is set.
+	SizeDelta int64 `json:"size_delta"`
+	SizeKnown bool  `json:"size_known"`
+
+	// Explicit marks a package the configuration asks for directly, as
+	// opposed to one pulled in as a dependency. Only meaningful when the
+	// diff's ExplicitKnown is set.
+	Explicit bool `json:"explicit"`
+	// AttrPath is the configuration attribute the package comes from, e.g.
+	// environment.systemPackages.firefox, when the backend can tell.
+	AttrPath string `json:"attr_path"`
+}
+
+// UnmarshalJSON also accepts a bare store path, which is how backends that
+// predate structured entries report changes.
+func (c *PackageChange) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*c = PackageChange{Path: path}
+		return nil
+	}
+	type plain PackageChange
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// Bump compares a modified package's versions: positive for an upgrade,
+// negative for a downgrade and zero when the version didn't change or
+// isn't known.
+func (c PackageChange) Bump() int {
+	if c.OldVersion == "" || c.NewVersion == "" {
+		return 0
+	}
+	return CompareVersions(c.NewVersion, c.OldVersion)
+}
+
+// Paths returns the store paths of changes.
+func Paths(changes []PackageChange) []string {
+	paths := make([]string, len(changes))
+	for i, c := range changes {
+		paths[i] = c.Path
+	}
+	return paths
+}
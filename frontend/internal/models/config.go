@@ -0,0 +1,21 @@
+package models
+
+// ConfigChange is a single configuration value that differs between two
+// generations, such as a flake input revision.
+type ConfigChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ConfigDiff describes the configuration changes that produced a
+// generation, as opposed to the package changes that resulted from them.
+type ConfigDiff struct {
+	Flake   bool           `json:"flake"`
+	Inputs  []ConfigChange `json:"inputs"`
+	Options []ConfigChange `json:"options"`
+}
+
+func (d ConfigDiff) Empty() bool {
+	return len(d.Inputs) == 0 && len(d.Options) == 0
+}
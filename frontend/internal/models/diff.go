@@ -5,3 +5,86 @@ type Diff struct {
 	Removed  []string
 	Modified []string
 }
+
+// DiffStats summarises a diff by magnitude only.
+type DiffStats struct {
+	Added     int   `json:"added"`
+	Removed   int   `json:"removed"`
+	Modified  int   `json:"modified"`
+	SizeDelta int64 `json:"size_delta"`
+	SizeKnown bool  `json:"size_known"`
+}
+
+// StatsOf derives stats from a full diff. The size delta is unknown.
+func StatsOf(diff GenerationDiff) DiffStats {
+	return DiffStats{
+		Added:    len(diff.Added),
+		Removed:  len(diff.Removed),
+		Modified: len(diff.Modified),
+	}
+}
+
+// DiffPaths compares two package sets the way the backend's diff does,
+// which is nvd's: a path only in from is modified when a path only in to
+// has the same name, and removed otherwise; a path only in to that replaced
+// nothing is added. Each new path replaces at most one old one.
+func DiffPaths(from, to []string) GenerationDiff {
+	inFrom := make(map[string]bool, len(from))
+	for _, p := range from {
+		inFrom[p] = true
+	}
+	inTo := make(map[string]bool, len(to))
+	replacements := make(map[string][]string)
+	for _, p := range to {
+		inTo[p] = true
+		if !inFrom[p] {
+			_, name, _ := ParseStorePath(p)
+			replacements[name] = append(replacements[name], p)
+		}
+	}
+
+	var diff GenerationDiff
+	replaced := make(map[string]bool)
+	for _, p := range from {
+		if inTo[p] {
+			continue
+		}
+		_, name, _ := ParseStorePath(p)
+		if q := replacements[name]; len(q) > 0 {
+			replacements[name] = q[1:]
+			replaced[q[0]] = true
+			diff.Modified = append(diff.Modified, PackageChange{Path: p, NewPath: q[0]})
+		} else {
+			diff.Removed = append(diff.Removed, PackageChange{Path: p})
+		}
+	}
+	for _, p := range to {
+		if !inFrom[p] && !replaced[p] {
+			diff.Added = append(diff.Added, PackageChange{Path: p})
+		}
+	}
+	diff.FillNames()
+	return diff
+}
+
+// ApplySizes sets the size delta of every change whose packages have a
+// size in sizes, which maps store paths to their own size in bytes.
+func (d *GenerationDiff) ApplySizes(sizes map[string]int64) {
+	for i := range d.Added {
+		c := &d.Added[i]
+		c.SizeDelta, c.SizeKnown = sizes[c.Path]
+	}
+	for i := range d.Removed {
+		c := &d.Removed[i]
+		size, ok := sizes[c.Path]
+		c.SizeDelta, c.SizeKnown = -size, ok
+	}
+	for i := range d.Modified {
+		c := &d.Modified[i]
+		old, okOld := sizes[c.Path]
+		new, okNew := sizes[c.NewPath]
+		if okOld && okNew {
+			c.SizeDelta, c.SizeKnown = new-old, true
+		}
+	}
+}
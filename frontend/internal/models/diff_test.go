@@ -0,0 +1,58 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffPaths(t *testing.T) {
+	const (
+		firefox128 = "/nix/store/jl3v0vqjji50sh8433shdh86i8g81n9i-firefox-128.0.3"
+		firefox129 = "/nix/store/gv2892rbj5qc5j63ckiw585ka2ax3xd8-firefox-129.0.1"
+		firefox130 = "/nix/store/q4d1m8x2c6v0b3n7k5j9h1g3f5d7s9a1-firefox-130.0"
+		git        = "/nix/store/9b2mvn1c1a5k1lz0c8x2f4b7m1q0w3hx-git-2.45.2"
+		htop       = "/nix/store/0r4d2m3wq6f1c5h7j9k2l4n6p8s0v2x4-htop-3.3.0"
+	)
+	tests := []struct {
+		name                     string
+		from, to                 []string
+		added, removed, modified []string
+	}{
+		{name: "identical", from: []string{git}, to: []string{git}},
+		{name: "both empty"},
+		{name: "added", from: []string{git}, to: []string{git, htop}, added: []string{htop}},
+		{name: "removed", from: []string{git, htop}, to: []string{git}, removed: []string{htop}},
+		{name: "upgraded", from: []string{firefox128, git}, to: []string{firefox129, git}, modified: []string{firefox128}},
+		{
+			name: "upgraded and removed", from: []string{firefox128, git, htop}, to: []string{firefox129, git},
+			removed: []string{htop}, modified: []string{firefox128},
+		},
+		{
+			name: "second version added", from: []string{firefox128}, to: []string{firefox128, firefox129},
+			added: []string{firefox129},
+		},
+		{
+			name: "one of two versions replaced", from: []string{firefox128, firefox129}, to: []string{firefox130},
+			removed: []string{firefox129}, modified: []string{firefox128},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffPaths(tt.from, tt.to)
+			check := func(kind string, got []PackageChange, want []string) {
+				t.Helper()
+				if paths := Paths(got); !slices.Equal(paths, want) && len(paths)+len(want) > 0 {
+					t.Errorf("%s = %v, want %v", kind, paths, want)
+				}
+			}
+			check("added", diff.Added, tt.added)
+			check("removed", diff.Removed, tt.removed)
+			check("modified", diff.Modified, tt.modified)
+		})
+	}
+
+	diff := DiffPaths([]string{firefox128}, []string{firefox129})
+	if m := diff.Modified[0]; m.NewPath != firefox129 || m.Name != "firefox" || m.OldVersion != "128.0.3" || m.NewVersion != "129.0.1" {
+		t.Errorf("modified firefox = %+v, want 128.0.3 -> 129.0.1 at %s", m, firefox129)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "strings"
+
+// FileDiff lists the files under /etc that differ between two generations,
+// as absolute paths like /etc/ssh/sshd_config.
+type FileDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+func (d FileDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// unitDir is where NixOS puts the system's systemd units.
+const unitDir = "/etc/systemd/system/"
+
+// Units narrows d to systemd units, named relative to the unit directory,
+// e.g. sshd.service or multi-user.target.wants/sshd.service.
+func (d FileDiff) Units() FileDiff {
+	units := func(paths []string) []string {
+		var kept []string
+		for _, p := range paths {
+			if name, ok := strings.CutPrefix(p, unitDir); ok {
+				kept = append(kept, name)
+			}
+		}
+		return kept
+	}
+	return FileDiff{Added: units(d.Added), Removed: units(d.Removed), Modified: units(d.Modified)}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GCPreview is what collecting garbage older than some age would do: the
+// generations it would delete and the store paths it would then free.
+type GCPreview struct {
+	Generations []GCGeneration `json:"generations"`
+	Paths       int            `json:"paths"`
+	Bytes       int64          `json:"bytes"`
+}
+
+// GCGeneration is a generation of one of the profiles garbage collection
+// covers.
+type GCGeneration struct {
+	Profile string `json:"profile"`
+	ID      string `json:"id"`
+}
+
+// ParseAge parses an age as nix-collect-garbage --delete-older-than takes
+// it, a number of days like "30d".
+func ParseAge(age string) (time.Duration, error) {
+	days, err := strconv.ParseUint(strings.TrimSuffix(age, "d"), 10, 32)
+	if err != nil || !strings.HasSuffix(age, "d") {
+		return 0, fmt.Errorf("invalid age %q, want days like 30d", age)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
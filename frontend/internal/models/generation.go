@@ -7,11 +7,133 @@ type Generation struct {
 	Timestamp   time.Time `json:"timestamp"`
 	Description string    `json:"description"`
 	Profiles    []string  `json:"profiles"`
-	Selected    bool      `json:"-"`
+	// Profile is the path of the profile the generation belongs to.
+	Profile     string `json:"profile"`
+	KnownGood   bool   `json:"known_good"`
+	ClosureHash string `json:"closure_hash"`
+	Current     bool   `json:"current"`
+	// ClosureSize is only reported when asked for; zero means unknown.
+	ClosureSize int64 `json:"closure_size"`
+	// Booted is the generation the machine started with; BootDefault the
+	// one it will start with next. Both are system generations only.
+	Booted      bool `json:"booted"`
+	BootDefault bool `json:"boot_default"`
+	// KernelVersion and NixosVersion are empty when unknown, as they are
+	// for profiles other than the system's.
+	KernelVersion string `json:"kernel_version"`
+	NixosVersion  string `json:"nixos_version"`
+	// StorePath is the system or profile store path the generation's link
+	// points to.
+	StorePath       string   `json:"store_path"`
+	Specialisations []string `json:"specialisations"`
+	// FlakeURL, FlakeRevision and FlakeDirty describe the flake the
+	// configuration was built from, for systems built from one. The URL
+	// is only known when the configuration records it.
+	FlakeURL      string `json:"flake_url"`
+	FlakeRevision string `json:"flake_revision"`
+	FlakeDirty    bool   `json:"flake_dirty"`
+	// Pinned generations have a GC root of their own and are never
+	// deleted by cleanup; PinName is what the pin was called, if anything.
+	Pinned   bool   `json:"pinned"`
+	PinName  string `json:"pin_name"`
+	Selected bool   `json:"-"`
+}
+
+// ShortRevision abbreviates the flake revision as git does, marking a
+// build from a dirty tree, or returns "" for a system not built from a
+// flake.
+func (g Generation) ShortRevision() string {
+	rev := g.FlakeRevision
+	if rev == "" {
+		return ""
+	}
+	if len(rev) > 7 {
+		rev = rev[:7]
+	}
+	if g.FlakeDirty {
+		rev += "-dirty"
+	}
+	return rev
 }
 
 type GenerationDiff struct {
-	Added    []string
-	Removed  []string
-	Modified []string
+	Added    []PackageChange `json:"added"`
+	Removed  []PackageChange `json:"removed"`
+	Modified []PackageChange `json:"modified"`
+	// ExplicitKnown is set when the backend tagged which changes are
+	// explicitly configured packages.
+	ExplicitKnown bool `json:"explicit_known"`
+}
+
+// FillNames sets the name and versions of changes a backend reported as
+// bare store paths.
+func (d *GenerationDiff) FillNames() {
+	fill := func(changes []PackageChange, added bool) {
+		for i := range changes {
+			fillName(&changes[i], added)
+		}
+	}
+	fill(d.Added, true)
+	fill(d.Removed, false)
+	fill(d.Modified, false)
+}
+
+func fillName(c *PackageChange, added bool) {
+	if c.Name != "" {
+		return
+	}
+	_, name, version := ParseStorePath(c.Path)
+	c.Name = name
+	if added {
+		c.NewVersion = version
+		return
+	}
+	c.OldVersion = version
+	if c.NewPath != "" {
+		_, _, c.NewVersion = ParseStorePath(c.NewPath)
+	}
+}
+
+func (d GenerationDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffEntry is one change of a diff that is streamed rather than returned
+// whole; Kind is "added", "removed" or "modified".
+type DiffEntry struct {
+	Kind   string        `json:"kind"`
+	Change PackageChange `json:"change"`
+}
+
+// Add appends the entry's change to the list its kind names, filling in its
+// name as FillNames would. Entries of an unknown kind are dropped.
+func (d *GenerationDiff) Add(e DiffEntry) {
+	var list *[]PackageChange
+	switch e.Kind {
+	case "added":
+		list = &d.Added
+	case "removed":
+		list = &d.Removed
+	case "modified":
+		list = &d.Modified
+	default:
+		return
+	}
+	fillName(&e.Change, e.Kind == "added")
+	*list = append(*list, e.Change)
+}
+
+// Entries returns the diff's changes as the entries a stream of it would
+// carry, in the order the backend sends them.
+func (d GenerationDiff) Entries() []DiffEntry {
+	entries := make([]DiffEntry, 0, len(d.Added)+len(d.Removed)+len(d.Modified))
+	for _, kind := range []struct {
+		name    string
+		changes []PackageChange
+	}{{"added", d.Added}, {"removed", d.Removed}, {"modified", d.Modified}} {
+		for _, c := range kind.changes {
+			entries = append(entries, DiffEntry{Kind: kind.name, Change: c})
+		}
+	}
+	return entries
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+)
+
+// LockedInput is a flake input as a lock file pins it. LastModified is the
+// commit date in seconds since the epoch, zero when the lock doesn't say.
+type LockedInput struct {
+	Rev          string `json:"rev"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// InputChange is a direct input of the configuration's flake whose pin
+// differs between two generations. An input only one side has is zero on
+// the other.
+type InputChange struct {
+	Name string      `json:"name"`
+	From LockedInput `json:"from"`
+	To   LockedInput `json:"to"`
+}
+
+// Moved is how far the input's commit date moved, or false when either
+// date is unknown.
+func (c InputChange) Moved() (time.Duration, bool) {
+	if c.From.LastModified == 0 || c.To.LastModified == 0 {
+		return 0, false
+	}
+	return time.Duration(c.To.LastModified-c.From.LastModified) * time.Second, true
+}
+
+// ParseFlakeLock returns the direct inputs a flake.lock pins, by name.
+// Inputs that follow another are left out, as they share its pin.
+func ParseFlakeLock(data []byte) (map[string]LockedInput, error) {
+	var lock struct {
+		Root  string `json:"root"`
+		Nodes map[string]struct {
+			Inputs map[string]json.RawMessage `json:"inputs"`
+			Locked struct {
+				Rev          string `json:"rev"`
+				LastModified int64  `json:"lastModified"`
+			} `json:"locked"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	if lock.Root == "" {
+		lock.Root = "root"
+	}
+	inputs := make(map[string]LockedInput)
+	for name, ref := range lock.Nodes[lock.Root].Inputs {
+		var node string
+		if json.Unmarshal(ref, &node) != nil {
+			continue
+		}
+		locked := lock.Nodes[node].Locked
+		inputs[name] = LockedInput{Rev: locked.Rev, LastModified: locked.LastModified}
+	}
+	return inputs, nil
+}
+
+// DiffLocks lists the inputs whose pins differ between two locks, by name.
+func DiffLocks(from, to map[string]LockedInput) []InputChange {
+	all := maps.Clone(from)
+	maps.Copy(all, to)
+	var changes []InputChange
+	for _, name := range slices.Sorted(maps.Keys(all)) {
+		if from[name] != to[name] {
+			changes = append(changes, InputChange{Name: name, From: from[name], To: to[name]})
+		}
+	}
+	return changes
+}
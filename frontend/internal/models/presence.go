@@ -0,0 +1,64 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PackagePresence records whether one generation's closure contains a
+// package, and at which version.
+type PackagePresence struct {
+	Generation string `json:"generation"`
+	Present    bool   `json:"present"`
+	Version    string `json:"version"`
+}
+
+// ParsePackageQuery splits a search like "openssl 1.1" into the package
+// name and the version wanted, which may be "" for any.
+func ParsePackageQuery(q string) (name, version string) {
+	name, version, _ = strings.Cut(strings.TrimSpace(q), " ")
+	return name, strings.TrimSpace(version)
+}
+
+// VersionMatches reports whether version is want or a release within it:
+// "1.1" matches 1.1, 1.1.1w and 1.1-rc1, but not 1.10. An empty want
+// matches any version.
+func VersionMatches(version, want string) bool {
+	rest, ok := strings.CutPrefix(version, want)
+	if !ok {
+		return false
+	}
+	return rest == "" || want == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+// VersionSpan is a run of consecutive generations that all have a package
+// at the same version, or all lack it.
+type VersionSpan struct {
+	Present bool
+	Version string
+	// First and Last are the oldest and newest generation of the run.
+	First, Last string
+	Count       int
+}
+
+// PackageHistory turns the presence of a package in every generation into
+// the runs where its version stayed the same, oldest first.
+func PackageHistory(presence []PackagePresence) []VersionSpan {
+	presence = slices.Clone(presence)
+	slices.SortStableFunc(presence, func(a, b PackagePresence) int {
+		x, _ := strconv.Atoi(a.Generation)
+		y, _ := strconv.Atoi(b.Generation)
+		return x - y
+	})
+	var spans []VersionSpan
+	for _, p := range presence {
+		if n := len(spans); n > 0 && spans[n-1].Present == p.Present && spans[n-1].Version == p.Version {
+			spans[n-1].Last = p.Generation
+			spans[n-1].Count++
+			continue
+		}
+		spans = append(spans, VersionSpan{Present: p.Present, Version: p.Version, First: p.Generation, Last: p.Generation, Count: 1})
+	}
+	return spans
+}
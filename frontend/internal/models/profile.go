@@ -0,0 +1,14 @@
+package models
+
+// Profile is a Nix profile whose generations can be browsed: the system's,
+// the user's own or Home Manager's.
+type Profile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// System reports whether p is the system profile, the only one the
+// system-changing actions apply to.
+func (p Profile) System() bool {
+	return p.Name == "system"
+}
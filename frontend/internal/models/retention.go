@@ -0,0 +1,57 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// RetentionPolicy says which generations to keep when cleaning up; the
+// rest may be deleted. The current generation is always kept, as is any
+// generation whose time is unknown, since its age can't be judged.
+type RetentionPolicy struct {
+	// KeepLast keeps the newest generations, this many of them.
+	KeepLast int
+	// KeepWeekly keeps the newest generation of each of the last this
+	// many weeks.
+	KeepWeekly int
+	// KeepPinned keeps pinned generations whatever their age.
+	KeepPinned bool
+}
+
+// DefaultRetentionPolicy is the policy until the user sets one.
+var DefaultRetentionPolicy = RetentionPolicy{KeepLast: 10, KeepWeekly: 4, KeepPinned: true}
+
+// Doomed returns the generations p would delete as of now, newest first.
+func (p RetentionPolicy) Doomed(generations []Generation, now time.Time) []Generation {
+	generations = slices.Clone(generations)
+	slices.SortStableFunc(generations, func(a, b Generation) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	keep := make(map[string]bool)
+	for i, gen := range generations {
+		if i < p.KeepLast || gen.Current || gen.Timestamp.IsZero() || (p.KeepPinned && gen.Pinned) {
+			keep[gen.ID] = true
+		}
+	}
+	// Newest first, so the first generation met in a week is the one the
+	// week ended with.
+	kept := make(map[int]bool)
+	for _, gen := range generations {
+		if gen.Timestamp.IsZero() {
+			continue
+		}
+		week := int(now.Sub(gen.Timestamp) / (7 * 24 * time.Hour))
+		if week < p.KeepWeekly && !kept[week] {
+			kept[week] = true
+			keep[gen.ID] = true
+		}
+	}
+
+	var doomed []Generation
+	for _, gen := range generations {
+		if !keep[gen.ID] {
+			doomed = append(doomed, gen)
+		}
+	}
+	return doomed
+}
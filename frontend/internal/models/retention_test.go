@@ -0,0 +1,75 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetentionDoomed(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	// gens returns a generation per age in days, numbered from the oldest.
+	gens := func(ages ...int) []Generation {
+		out := make([]Generation, len(ages))
+		for i, age := range ages {
+			out[i] = Generation{ID: strconv.Itoa(len(ages) - i), Timestamp: now.Add(-time.Duration(age) * 24 * time.Hour)}
+		}
+		return out
+	}
+	with := func(list []Generation, id string, f func(*Generation)) []Generation {
+		for i := range list {
+			if list[i].ID == id {
+				f(&list[i])
+			}
+		}
+		return list
+	}
+
+	tests := []struct {
+		name        string
+		policy      RetentionPolicy
+		generations []Generation
+		want        []string
+	}{
+		{name: "nothing", policy: DefaultRetentionPolicy},
+		{name: "keep last", policy: RetentionPolicy{KeepLast: 2}, generations: gens(0, 1, 2, 3, 4), want: []string{"3", "2", "1"}},
+		{name: "keep all", policy: RetentionPolicy{KeepLast: 10}, generations: gens(0, 1, 2)},
+		{name: "oldest first", policy: RetentionPolicy{KeepLast: 1}, generations: gens(2, 1, 0), want: []string{"2", "3"}},
+		{
+			name: "keep weekly", policy: RetentionPolicy{KeepWeekly: 3},
+			generations: gens(0, 2, 8, 9, 15, 30), want: []string{"5", "3", "1"},
+		},
+		{
+			name: "last and weekly", policy: RetentionPolicy{KeepLast: 2, KeepWeekly: 2},
+			generations: gens(0, 1, 2, 8, 9, 30), want: []string{"4", "2", "1"},
+		},
+		{
+			name: "pinned", policy: RetentionPolicy{KeepLast: 1, KeepPinned: true},
+			generations: with(gens(0, 10, 20), "1", func(g *Generation) { g.Pinned = true }), want: []string{"2"},
+		},
+		{
+			name: "pinned without keep pinned", policy: RetentionPolicy{KeepLast: 1},
+			generations: with(gens(0, 10, 20), "1", func(g *Generation) { g.Pinned = true }), want: []string{"2", "1"},
+		},
+		{
+			name: "current", policy: RetentionPolicy{},
+			generations: with(gens(0, 10, 20), "2", func(g *Generation) { g.Current = true }), want: []string{"3", "1"},
+		},
+		{
+			name: "unknown time", policy: RetentionPolicy{KeepLast: 1, KeepWeekly: 1},
+			generations: with(gens(0, 1, 2), "1", func(g *Generation) { g.Timestamp = time.Time{} }), want: []string{"2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, gen := range tt.policy.Doomed(tt.generations, now) {
+				ids = append(ids, gen.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Doomed = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
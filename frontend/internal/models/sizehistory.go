@@ -0,0 +1,37 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// SizePoint is one generation's closure size, and how much it grew from
+// the generation before it whose size is known.
+type SizePoint struct {
+	Generation string    `json:"generation"`
+	Timestamp  time.Time `json:"timestamp"`
+	Size       int64     `json:"size"`
+	Delta      int64     `json:"delta"`
+}
+
+// SizeHistory returns the closure sizes of generations, oldest first.
+// Generations whose size is unknown are left out; the first point's Delta
+// is zero.
+func SizeHistory(generations []Generation) []SizePoint {
+	var points []SizePoint
+	for _, gen := range generations {
+		if gen.ClosureSize > 0 {
+			points = append(points, SizePoint{Generation: gen.ID, Timestamp: gen.Timestamp, Size: gen.ClosureSize})
+		}
+	}
+	slices.SortFunc(points, func(a, b SizePoint) int {
+		x, _ := strconv.Atoi(a.Generation)
+		y, _ := strconv.Atoi(b.Generation)
+		return x - y
+	})
+	for i := 1; i < len(points); i++ {
+		points[i].Delta = points[i].Size - points[i-1].Size
+	}
+	return points
+}
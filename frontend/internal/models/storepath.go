@@ -0,0 +1,24 @@
+package models
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// ParseStorePath splits a store path like
+// /nix/store/<hash>-firefox-121.0 into its hash, package name and version.
+// Like Nix's own parseDrvName, the version starts at the first dash that is
+// followed by a digit. Parts that can't be found are returned empty.
+func ParseStorePath(p string) (hash, name, version string) {
+	base := path.Base(p)
+	if i := strings.IndexByte(base, '-'); i >= 0 && strings.HasPrefix(p, "/") {
+		hash, base = base[:i], base[i+1:]
+	}
+	for i := 0; i < len(base)-1; i++ {
+		if base[i] == '-' && unicode.IsDigit(rune(base[i+1])) {
+			return hash, base[:i], base[i+1:]
+		}
+	}
+	return hash, base, ""
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// timestampLayouts are the string forms accepted for a generation's
+// timestamp, tried in order. Layouts without a zone are read as local time,
+// which is what nix-env prints.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// UnmarshalJSON tolerates timestamps as RFC 3339, as a local date and time
+// or as Unix seconds. A timestamp in none of those forms leaves Timestamp
+// zero instead of failing, so one bad field doesn't hide every generation.
+func (g *Generation) UnmarshalJSON(data []byte) error {
+	type plain Generation
+	var raw struct {
+		*plain
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	raw.plain = (*plain)(g)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	g.Timestamp = parseTimestamp(raw.Timestamp)
+	return nil
+}
+
+// parseTimestamp returns the zero time for anything it can't read.
+func parseTimestamp(data json.RawMessage) time.Time {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Not a string: a bare number is taken as Unix seconds.
+		var n json.Number
+		if json.Unmarshal(data, &n) != nil {
+			return time.Time{}
+		}
+		s = n.String()
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9))
+	}
+	return time.Time{}
+}
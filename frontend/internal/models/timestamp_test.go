@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGenerationTimestamp(t *testing.T) {
+	local := time.Date(2024, 7, 1, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name      string
+		timestamp string
+		want      time.Time
+	}{
+		{"rfc3339", `"2024-07-01T09:30:00Z"`, time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)},
+		{"rfc3339 with offset", `"2024-07-01T11:30:00+02:00"`, time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)},
+		{"rfc3339 fractional", `"2024-07-01T09:30:00.5Z"`, time.Date(2024, 7, 1, 9, 30, 0, 5e8, time.UTC)},
+		{"local date and time", `"2024-07-01 09:30:00"`, local},
+		{"local with T", `"2024-07-01T09:30:00"`, local},
+		{"unix seconds", `1719826200`, time.Unix(1719826200, 0)},
+		{"unix seconds as string", `"1719826200"`, time.Unix(1719826200, 0)},
+		{"unix fractional", `1719826200.25`, time.Unix(1719826200, 25e7)},
+		{"malformed", `"yesterday"`, time.Time{}},
+		{"empty", `""`, time.Time{}},
+		{"null", `null`, time.Time{}},
+		{"wrong type", `{"at": 1}`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gen Generation
+			data := `{"id": "42", "description": "kept", "timestamp": ` + tt.timestamp + `}`
+			if err := json.Unmarshal([]byte(data), &gen); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if !gen.Timestamp.Equal(tt.want) {
+				t.Errorf("Timestamp = %v, want %v", gen.Timestamp, tt.want)
+			}
+			if gen.ID != "42" || gen.Description != "kept" {
+				t.Errorf("other fields lost: %+v", gen)
+			}
+		})
+	}
+}
+
+func TestGenerationsSurviveBadTimestamp(t *testing.T) {
+	var gens []Generation
+	data := `[{"id": "1", "timestamp": "2024-07-01T09:30:00Z"}, {"id": "2", "timestamp": "garbage"}, {"id": "3", "timestamp": 1719826200}]`
+	if err := json.Unmarshal([]byte(data), &gens); err != nil {
+		t.Fatalf("one bad timestamp failed the list: %v", err)
+	}
+	if len(gens) != 3 {
+		t.Fatalf("decoded %d generations, want 3", len(gens))
+	}
+	if !gens[1].Timestamp.IsZero() || gens[0].Timestamp.IsZero() || gens[2].Timestamp.IsZero() {
+		t.Errorf("timestamps = %v, %v, %v; want only the second zero", gens[0].Timestamp, gens[1].Timestamp, gens[2].Timestamp)
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TrashedGeneration is a deleted system generation whose system is kept
+// until the trash is emptied, so it can still be restored.
+type TrashedGeneration struct {
+	ID        string    `json:"id"`
+	StorePath string    `json:"store_path"`
+	Trashed   time.Time `json:"trashed"`
+}
+
+// DefaultTrashDays is how long deleted generations stay restorable unless
+// configured otherwise.
+const DefaultTrashDays = 7
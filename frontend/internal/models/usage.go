@@ -0,0 +1,61 @@
+package models
+
+// PathSize is a store path in a closure and the space it takes itself,
+// not counting its dependencies.
+type PathSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// StoreNode is a store path in a closure with its own size and the paths
+// it references, not counting itself.
+type StoreNode struct {
+	Path       string   `json:"path"`
+	Size       int64    `json:"size"`
+	References []string `json:"references"`
+}
+
+// ClosureGraph indexes the paths of a closure for walking its references.
+type ClosureGraph struct {
+	nodes map[string]StoreNode
+	sizes map[string]int64
+}
+
+// NewClosureGraph indexes nodes by path.
+func NewClosureGraph(nodes []StoreNode) *ClosureGraph {
+	g := &ClosureGraph{nodes: make(map[string]StoreNode, len(nodes)), sizes: make(map[string]int64)}
+	for _, n := range nodes {
+		g.nodes[n.Path] = n
+	}
+	return g
+}
+
+// Node returns the path's node, or false if it isn't in the closure.
+func (g *ClosureGraph) Node(path string) (StoreNode, bool) {
+	n, ok := g.nodes[path]
+	return n, ok
+}
+
+// ClosureSize is the size of path and everything it references, each path
+// counted once.
+func (g *ClosureGraph) ClosureSize(path string) int64 {
+	if size, ok := g.sizes[path]; ok {
+		return size
+	}
+	seen := map[string]bool{path: true}
+	stack := []string{path}
+	var size int64
+	for len(stack) > 0 {
+		n := g.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		size += n.Size
+		for _, ref := range n.References {
+			if !seen[ref] {
+				seen[ref] = true
+				stack = append(stack, ref)
+			}
+		}
+	}
+	g.sizes[path] = size
+	return size
+}
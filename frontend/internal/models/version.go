@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions orders two versions the way nix-env does: component by
+// component, where components are runs of digits or of letters and dots
+// and dashes only separate them. Numbers compare numerically and sort after
+// words, except that "pre" sorts before everything and a missing component
+// before a number. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	ca, cb := versionComponents(a), versionComponents(b)
+	for i := 0; i < len(ca) || i < len(cb); i++ {
+		var x, y string
+		if i < len(ca) {
+			x = ca[i]
+		}
+		if i < len(cb) {
+			y = cb[i]
+		}
+		if componentLess(x, y) {
+			return -1
+		}
+		if componentLess(y, x) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionComponents(v string) []string {
+	var components []string
+	for len(v) > 0 {
+		if v[0] == '.' || v[0] == '-' {
+			v = v[1:]
+			continue
+		}
+		digit := unicode.IsDigit(rune(v[0]))
+		end := strings.IndexFunc(v, func(r rune) bool {
+			return r == '.' || r == '-' || unicode.IsDigit(r) != digit
+		})
+		if end < 0 {
+			end = len(v)
+		}
+		components = append(components, v[:end])
+		v = v[end:]
+	}
+	return components
+}
+
+// componentLess is Nix's componentsLT.
+func componentLess(x, y string) bool {
+	nx, errX := strconv.ParseUint(x, 10, 64)
+	ny, errY := strconv.ParseUint(y, 10, 64)
+	switch {
+	case errX == nil && errY == nil:
+		return nx < ny
+	case x == "" && errY == nil:
+		return true
+	case x == "pre" && y != "pre":
+		return true
+	case y == "pre":
+		return false
+	case errX == nil:
+		return false
+	case errY == nil:
+		return true
+	default:
+		return x < y
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DependencyNode is a store path in the answer to why a closure contains a
+// package: the root is the generation, the leaves the package, and every
+// chain from one to the other a path of references.
+type DependencyNode struct {
+	Path     string           `json:"path"`
+	Children []DependencyNode `json:"children"`
+}
+
+// ParseWhyDepends builds the tree nix why-depends --all draws, where each
+// level indents a path by four columns of box-drawing characters. It
+// returns nil when there is no tree, as the closure doesn't contain the
+// package.
+func ParseWhyDepends(output string) *DependencyNode {
+	type level struct {
+		depth int
+		node  DependencyNode
+	}
+	var stack []level
+	// attach moves the innermost node under its parent.
+	attach := func() {
+		last := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		parent := &stack[len(stack)-1].node
+		parent.Children = append(parent.Children, last.node)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		start := strings.IndexByte(line, '/')
+		if start < 0 {
+			continue
+		}
+		depth := utf8.RuneCountInString(line[:start]) / 4
+		for len(stack) > 1 && stack[len(stack)-1].depth >= depth {
+			attach()
+		}
+		stack = append(stack, level{depth, DependencyNode{Path: strings.Fields(line[start:])[0]}})
+	}
+	if len(stack) == 0 {
+		return nil
+	}
+	for len(stack) > 1 {
+		attach()
+	}
+	return &stack[0].node
+}
@@ -0,0 +1,30 @@
+package nix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DryActivate runs system generation id's switch-to-configuration
+// dry-activate and returns what it printed. Nothing is activated.
+func (n Nix) DryActivate(ctx context.Context, id string) ([]string, error) {
+	cmd := n.command(ctx, Link(id)+"/bin/switch-to-configuration", "dry-activate")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to preview activation: %w", commandError(cmd, err, stderr.Bytes()))
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out)+"\n"+stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,121 @@
+package nix
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// ConfigDiff reports the flake inputs and configuration values that
+// changed between two generations. Values that can't be read compare as
+// empty.
+func (n Nix) ConfigDiff(ctx context.Context, fromID, toID string) models.ConfigDiff {
+	from, to := n.link(fromID), n.link(toID)
+	fromInfo, toInfo := n.versionInfo(ctx, from), n.versionInfo(ctx, to)
+
+	var diff models.ConfigDiff
+	_, fromFlake := fromInfo["configurationRevision"]
+	_, toFlake := toInfo["configurationRevision"]
+	diff.Flake = fromFlake || toFlake || hasFile(from, "etc/flake.lock") || hasFile(to, "etc/flake.lock")
+
+	// An input only one side has was added or removed, and is empty on the
+	// other.
+	if diff.Flake {
+		old, new := inputRevisions(n.lockedInputs(fromID), fromInfo), inputRevisions(n.lockedInputs(toID), toInfo)
+		all := maps.Clone(old)
+		maps.Copy(all, new)
+		for _, name := range slices.Sorted(maps.Keys(all)) {
+			if old[name] != new[name] {
+				diff.Inputs = append(diff.Inputs, models.ConfigChange{Name: name, From: old[name], To: new[name]})
+			}
+		}
+	}
+
+	for _, name := range []string{"nixos-version", "kernel-params", "configuration-name"} {
+		old, new := readTrimmed(from, name), readTrimmed(to, name)
+		if old != new {
+			diff.Options = append(diff.Options, models.ConfigChange{Name: name, From: old, To: new})
+		}
+	}
+	return diff
+}
+
+// inputRevisions is the revision of every direct input of a generation's
+// flake, by name, from the flake.lock it carries. nixos-version fills in
+// nixpkgs and self, the configuration's own revision, when the lock doesn't
+// name them or there is none.
+func inputRevisions(lock map[string]models.LockedInput, info map[string]any) map[string]string {
+	revs := make(map[string]string, len(lock)+2)
+	for name, input := range lock {
+		revs[name] = input.Rev
+	}
+	for _, input := range []struct{ key, name string }{
+		{"nixpkgsRevision", "nixpkgs"},
+		{"configurationRevision", "self"},
+	} {
+		if rev, ok := info[input.key].(string); ok && revs[input.name] == "" {
+			revs[input.name] = rev
+		}
+	}
+	return revs
+}
+
+// hasFile reports whether system carries the file name, e.g. etc/flake.lock.
+func hasFile(system, name string) bool {
+	_, err := os.Stat(filepath.Join(system, name))
+	return err == nil
+}
+
+// versionInfo is what nixos-version --json reports for a system. Flake-based
+// systems carry a configurationRevision; channel-based ones do not.
+func (n Nix) versionInfo(ctx context.Context, system string) map[string]any {
+	out, err := n.output(ctx, filepath.Join(system, "sw/bin/nixos-version"), "--json")
+	if err != nil {
+		return nil
+	}
+	var info map[string]any
+	if json.Unmarshal(out, &info) != nil {
+		return nil
+	}
+	return info
+}
+
+// flakeMetadata returns the flake URL, revision and dirty flag of the
+// configuration system was built from. nixos-version --json only has the
+// revision, which ends in "-dirty" when the tree had uncommitted changes;
+// a configuration can record the rest in etc/nixos-version.json, which
+// takes precedence.
+func (n Nix) flakeMetadata(ctx context.Context, system string) (url, rev string, dirty bool) {
+	info := n.versionInfo(ctx, system)
+	if data, err := os.ReadFile(filepath.Join(system, "etc/nixos-version.json")); err == nil {
+		var recorded map[string]any
+		if json.Unmarshal(data, &recorded) == nil {
+			if info == nil {
+				info = recorded
+			} else {
+				maps.Copy(info, recorded)
+			}
+		}
+	}
+	url, _ = info["flakeUrl"].(string)
+	rev, _ = info["configurationRevision"].(string)
+	dirty, _ = info["dirty"].(bool)
+	if trimmed, ok := strings.CutSuffix(rev, "-dirty"); ok {
+		rev, dirty = trimmed, true
+	}
+	return url, rev, dirty
+}
+
+func readTrimmed(system, name string) string {
+	data, err := os.ReadFile(filepath.Join(system, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,70 @@
+package nix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"nix-timemach/internal/models"
+)
+
+// fakeGeneration makes generation id of profile carry testdata/lock as its
+// etc/flake.lock, or no lock when lock is "".
+func fakeGeneration(t *testing.T, profile, id, lock string) {
+	t.Helper()
+	etc := filepath.Join(profile+"-"+id+"-link", "etc")
+	if err := os.MkdirAll(etc, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if lock == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", lock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(etc, "flake.lock"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigDiffInputs(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "profile")
+	fakeGeneration(t, profile, "1", "flake-1.lock")
+	fakeGeneration(t, profile, "2", "flake-2.lock")
+	fakeGeneration(t, profile, "3", "")
+	n := Nix{Profile: profile}
+
+	tests := []struct {
+		name      string
+		from, to  string
+		wantFlake bool
+		want      []models.ConfigChange
+	}{
+		{"changed, added and removed", "1", "2", true, []models.ConfigChange{
+			{Name: "disko", From: "", To: "disko1"},
+			{Name: "nixpkgs", From: "np1", To: "np2"},
+			{Name: "sops", From: "sops1", To: ""},
+		}},
+		{"same lock", "2", "2", true, nil},
+		{"lock dropped", "2", "3", true, []models.ConfigChange{
+			{Name: "disko", From: "disko1", To: ""},
+			{Name: "home-manager", From: "hm1", To: ""},
+			{Name: "nixpkgs", From: "np2", To: ""},
+		}},
+		{"no flake", "3", "3", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := n.ConfigDiff(context.Background(), tt.from, tt.to)
+			if diff.Flake != tt.wantFlake {
+				t.Errorf("Flake = %v, want %v", diff.Flake, tt.wantFlake)
+			}
+			if !slices.Equal(diff.Inputs, tt.want) {
+				t.Errorf("Inputs = %+v, want %+v", diff.Inputs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package nix
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// Diff compares the packages two generations reference directly.
+func (n Nix) Diff(ctx context.Context, fromID, toID string) (models.GenerationDiff, error) {
+	return n.diffSystems(ctx, n.link(fromID), n.link(toID))
+}
+
+func (n Nix) diffSystems(ctx context.Context, from, to string) (models.GenerationDiff, error) {
+	fromRefs, err := n.query(ctx, "--references", from)
+	if err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to diff: %w", err)
+	}
+	toRefs, err := n.query(ctx, "--references", to)
+	if err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to diff: %w", err)
+	}
+
+	diff := models.DiffPaths(fromRefs, toRefs)
+	if err := n.markExplicit(ctx, &diff, from, to); err != nil {
+		return models.GenerationDiff{}, err
+	}
+	diff.ApplySizes(n.sizes(ctx, diff))
+	return diff, nil
+}
+
+// markExplicit tags the packages listed in environment.systemPackages: the
+// system-path derivation behind a system's sw link references exactly those.
+// Other profiles have no such link and are left untagged.
+func (n Nix) markExplicit(ctx context.Context, diff *models.GenerationDiff, systems ...string) error {
+	for _, system := range systems {
+		if _, err := os.Stat(system + "/sw"); err != nil {
+			return nil
+		}
+	}
+	var explicit []string
+	for _, system := range systems {
+		refs, err := n.query(ctx, "--references", system+"/sw")
+		if err != nil {
+			return fmt.Errorf("failed to find explicit packages: %w", err)
+		}
+		explicit = append(explicit, refs...)
+	}
+	for _, changes := range [][]models.PackageChange{diff.Added, diff.Removed, diff.Modified} {
+		for i := range changes {
+			changes[i].Explicit = slices.Contains(explicit, changes[i].Path)
+		}
+	}
+	diff.ExplicitKnown = true
+	return nil
+}
+
+// PendingDiff builds the current configuration without activating it and
+// diffs the result against the running system. The build's progress is
+// written to progress as it happens.
+func (n Nix) PendingDiff(ctx context.Context, progress io.Writer) (models.GenerationDiff, error) {
+	dir, err := os.MkdirTemp("", "nix-timemach-")
+	if err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to build configuration: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := n.run(ctx, progress, dir, "nixos-rebuild", "build"); err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to build configuration: %w", err)
+	}
+	return n.diffSystems(ctx, currentSystem, filepath.Join(dir, "result"))
+}
+
+// DiffStats summarises the diff of two generations along with the change
+// in closure size.
+func (n Nix) DiffStats(ctx context.Context, fromID, toID string) (models.DiffStats, error) {
+	diff, err := n.Diff(ctx, fromID, toID)
+	if err != nil {
+		return models.DiffStats{}, err
+	}
+	stats := models.StatsOf(diff)
+	from, okFrom := n.closureSize(ctx, n.link(fromID))
+	to, okTo := n.closureSize(ctx, n.link(toID))
+	if okFrom && okTo {
+		stats.SizeDelta, stats.SizeKnown = to-from, true
+	}
+	return stats, nil
+}
+
+// Packages lists the package set of a generation, or of the running system
+// or current generation when id is empty.
+func (n Nix) Packages(ctx context.Context, id string) ([]string, error) {
+	system := n.current()
+	if id != "" {
+		system = n.link(id)
+	}
+	refs, err := n.query(ctx, "--references", system)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	return refs, nil
+}
+
+// DepsDiff diffs the runtime closures of one package as it appears in two
+// generations, which shows the dependencies behind a modified entry.
+func (n Nix) DepsDiff(ctx context.Context, pkg, fromID, toID string) (models.GenerationDiff, error) {
+	_, name, _ := models.ParseStorePath(pkg)
+	find := func(id string) ([]string, error) {
+		requisites, err := n.query(ctx, "--requisites", n.link(id))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range requisites {
+			if _, pname, _ := models.ParseStorePath(p); pname == name {
+				return n.query(ctx, "--requisites", p)
+			}
+		}
+		return nil, fmt.Errorf("%s not found in generation %s", name, id)
+	}
+
+	from, err := find(fromID)
+	if err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to diff dependencies: %w", err)
+	}
+	to, err := find(toID)
+	if err != nil {
+		return models.GenerationDiff{}, fmt.Errorf("failed to diff dependencies: %w", err)
+	}
+	return models.DiffPaths(from, to), nil
+}
+
+// FindPackage reports, for every generation, whether its closure contains
+// the named package and at which version. A generation whose closure can't
+// be queried, e.g. because it was garbage collected, doesn't contain it.
+func (n Nix) FindPackage(ctx context.Context, name string) ([]models.PackagePresence, error) {
+	generations, err := n.Generations(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var presence []models.PackagePresence
+	for _, gen := range generations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := models.PackagePresence{Generation: gen.ID}
+		requisites, _ := n.query(ctx, "--requisites", gen.Profiles[0])
+		for _, path := range requisites {
+			if _, pname, version := models.ParseStorePath(path); pname == name {
+				p.Present, p.Version = true, version
+				break
+			}
+		}
+		presence = append(presence, p)
+	}
+	return presence, nil
+}
+
+// WhyDepends explains why a generation's closure contains pkg, a store path
+// or package name, as nix why-depends --all draws it.
+func (n Nix) WhyDepends(ctx context.Context, id, pkg string) (*models.DependencyNode, error) {
+	link := n.link(id)
+	path := pkg
+	if !strings.HasPrefix(pkg, "/") {
+		requisites, err := n.query(ctx, "--requisites", link)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain dependency: %w", err)
+		}
+		i := slices.IndexFunc(requisites, func(p string) bool {
+			_, name, _ := models.ParseStorePath(p)
+			return name == pkg
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("%s not found in generation %s", pkg, id)
+		}
+		path = requisites[i]
+	}
+	out, err := n.output(ctx, "nix", "why-depends", "--all", link, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain dependency: %w", err)
+	}
+	return models.ParseWhyDepends(string(out)), nil
+}
+
+// ClosureGraph lists every path in a generation's closure with its own
+// size and the paths it references.
+func (n Nix) ClosureGraph(ctx context.Context, id string) ([]models.StoreNode, error) {
+	out, err := n.output(ctx, "nix", "path-info", "-r", "--json", n.link(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query closure: %w", err)
+	}
+	type info struct {
+		Path       string   `json:"path"`
+		NarSize    int64    `json:"narSize"`
+		References []string `json:"references"`
+	}
+	// Nix 2.19 and later key the paths' info by path; older versions list
+	// it with the path inside.
+	var infos []info
+	if err := json.Unmarshal(out, &infos); err != nil {
+		var byPath map[string]info
+		if err := json.Unmarshal(out, &byPath); err != nil {
+			return nil, fmt.Errorf("failed to parse closure: %w", err)
+		}
+		for path, i := range byPath {
+			i.Path = path
+			infos = append(infos, i)
+		}
+	}
+	nodes := make([]models.StoreNode, len(infos))
+	for k, i := range infos {
+		refs := slices.DeleteFunc(i.References, func(r string) bool { return r == i.Path })
+		nodes[k] = models.StoreNode{Path: i.Path, Size: i.NarSize, References: refs}
+	}
+	return nodes, nil
+}
+
+// PathSizes lists every path in a generation's closure with its own size,
+// largest first.
+func (n Nix) PathSizes(ctx context.Context, id string) ([]models.PathSize, error) {
+	out, err := n.output(ctx, "nix", "path-info", "-rs", n.link(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to size closure: %w", err)
+	}
+	var sizes []models.PathSize
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			sizes = append(sizes, models.PathSize{Path: fields[0], Size: size})
+		}
+	}
+	slices.SortFunc(sizes, func(a, b models.PathSize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Path, b.Path))
+	})
+	return sizes, nil
+}
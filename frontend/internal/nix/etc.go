@@ -0,0 +1,92 @@
+package nix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"nix-timemach/internal/models"
+)
+
+// EtcDiff compares the /etc trees of two generations, which shows
+// configuration changes that don't change any package, like an edited
+// sshd_config.
+func (n Nix) EtcDiff(ctx context.Context, fromID, toID string) (models.FileDiff, error) {
+	var trees [2]map[string]string
+	for i, id := range []string{fromID, toID} {
+		etc := filepath.Join(n.link(id), "etc")
+		if _, err := os.Stat(etc); err != nil {
+			return models.FileDiff{}, fmt.Errorf("failed to read generation %s: %w", id, err)
+		}
+		trees[i] = make(map[string]string)
+		if err := etcFiles(ctx, etc, "/etc", trees[i]); err != nil {
+			return models.FileDiff{}, err
+		}
+	}
+	from, to := trees[0], trees[1]
+
+	var diff models.FileDiff
+	for _, name := range slices.Sorted(maps.Keys(from)) {
+		other, ok := to[name]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, name)
+		case !sameFile(from[name], other):
+			diff.Modified = append(diff.Modified, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(to)) {
+		if _, ok := from[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	return diff, nil
+}
+
+// etcFiles maps every file under dir to the store file it resolves to.
+// NixOS's etc tree is mostly symlinks into the store.
+func etcFiles(ctx context.Context, dir, prefix string, files map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		name := prefix + "/" + entry.Name()
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if err := etcFiles(ctx, path, name, files); err != nil {
+				return err
+			}
+			continue
+		}
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			if target, err = os.Readlink(path); err != nil {
+				target = path
+			}
+		}
+		files[name] = target
+	}
+	return nil
+}
+
+// sameFile reports whether two files resolve to the same store file or,
+// failing that, have the same contents.
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	x, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	y, err := os.ReadFile(b)
+	return err == nil && bytes.Equal(x, y)
+}
@@ -0,0 +1,151 @@
+package nix
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"nix-timemach/internal/models"
+)
+
+// GCDryRun previews collecting garbage older than age without changing
+// anything. What would be freed is what is dead already plus the closures
+// of the expiring generations, less whatever the remaining roots still
+// reach.
+func (n Nix) GCDryRun(ctx context.Context, age string) (models.GCPreview, error) {
+	generations, err := n.ExpiringGenerations(ctx, age)
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	preview := models.GCPreview{Generations: generations}
+	expiring := make(map[string]bool)
+	for _, gen := range generations {
+		expiring[Nix{Store: n.Store, Profile: gen.Profile}.link(gen.ID)] = true
+	}
+
+	out, err := n.output(ctx, "nix-store", "--gc", "--print-roots")
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	var kept []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if link, path, ok := strings.Cut(line, " -> "); ok && !expiring[link] {
+			kept = append(kept, path)
+		}
+	}
+	live, err := n.closure(ctx, kept)
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+
+	out, err = n.output(ctx, "nix-store", "--gc", "--print-dead")
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	freed := make(map[string]bool)
+	for _, path := range strings.Fields(string(out)) {
+		freed[path] = true
+	}
+	doomed, err := n.closure(ctx, slices.Collect(maps.Keys(expiring)))
+	if err != nil {
+		return models.GCPreview{}, err
+	}
+	for path := range doomed {
+		freed[path] = true
+	}
+	for path := range live {
+		delete(freed, path)
+	}
+
+	preview.Paths = len(freed)
+	preview.Bytes = n.totalSize(ctx, slices.Collect(maps.Keys(freed)))
+	return preview, nil
+}
+
+// ExpiringGenerations returns the generations of every profile Profiles
+// lists that are older than age and not pinned.
+func (n Nix) ExpiringGenerations(ctx context.Context, age string) ([]models.GCGeneration, error) {
+	maxAge, err := models.ParseAge(age)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+
+	var expiring []models.GCGeneration
+	for _, p := range Profiles() {
+		profile := Nix{Store: n.Store, Profile: p.Path}
+		generations, err := profile.Generations(ctx, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, gen := range expiringGenerations(generations, cutoff) {
+			expiring = append(expiring, models.GCGeneration{Profile: p.Path, ID: gen.ID})
+		}
+	}
+	return expiring, nil
+}
+
+// expiringGenerations returns the generations --delete-older-than would
+// delete, less the pinned ones. Like nix-env, it keeps the current
+// generation and the newest one older than the cutoff, which was the one in
+// use at the cutoff.
+func expiringGenerations(generations []models.Generation, cutoff time.Time) []models.Generation {
+	generations = slices.Clone(generations)
+	slices.SortFunc(generations, func(a, b models.Generation) int {
+		x, _ := strconv.Atoi(a.ID)
+		y, _ := strconv.Atoi(b.ID)
+		return y - x
+	})
+	var expiring []models.Generation
+	expired := false
+	for _, gen := range generations {
+		if expired && !gen.Current && !gen.Pinned {
+			expiring = append(expiring, gen)
+		}
+		if gen.Timestamp.Before(cutoff) {
+			expired = true
+		}
+	}
+	return expiring
+}
+
+// closure returns the closure of paths, or nothing when there are none.
+func (n Nix) closure(ctx context.Context, paths []string) (map[string]bool, error) {
+	closure := make(map[string]bool)
+	if len(paths) == 0 {
+		return closure, nil
+	}
+	out, err := n.output(ctx, "nix-store", append([]string{"-qR"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Fields(string(out)) {
+		closure[path] = true
+	}
+	return closure, nil
+}
+
+// totalSize adds up the own sizes of paths, queried a batch at a time to
+// stay within the argument limit. Paths Nix can't size count as nothing.
+func (n Nix) totalSize(ctx context.Context, paths []string) int64 {
+	var total int64
+	for chunk := range slices.Chunk(paths, 500) {
+		out, err := n.output(ctx, "nix", append([]string{"path-info", "-s"}, chunk...)...)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			if size, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				total += size
+			}
+		}
+	}
+	return total
+}
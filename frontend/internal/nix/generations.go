@@ -0,0 +1,168 @@
+package nix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// Generations lists the system profile's generations, newest first, by
+// reading its links: nix-env dates a generation by its link's mtime. Closure
+// sizes cost a query per generation, so they are only filled in when asked
+// for.
+func (n Nix) Generations(ctx context.Context, withSizes bool) ([]models.Generation, error) {
+	profile := n.profile()
+	dir, base := filepath.Split(profile)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list generations: %w", err)
+	}
+	current, _ := os.Readlink(profile)
+
+	var generations []models.Generation
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), base+"-")
+		if !ok {
+			continue
+		}
+		id, ok = strings.CutSuffix(id, "-link")
+		if _, err := strconv.Atoi(id); !ok || err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		link := n.link(id)
+		gen := models.Generation{
+			ID:          id,
+			Timestamp:   info.ModTime(),
+			Profiles:    []string{link},
+			Profile:     profile,
+			ClosureHash: closureHash(link),
+			Current:     current == entry.Name(),
+		}
+		if gen.Current {
+			gen.Description = "(current)"
+		}
+		if _, err := os.Lstat(KnownGoodRoot(id)); err == nil && n.system() {
+			gen.KnownGood = true
+		}
+		if n.system() {
+			_, gen.PinName, gen.Pinned = FindPin(id)
+		}
+		if withSizes {
+			gen.ClosureSize, _ = n.closureSize(ctx, link)
+		}
+		gen.StorePath, _ = os.Readlink(link)
+		if n.system() {
+			gen.KernelVersion = kernelVersion(link)
+			gen.NixosVersion = readTrimmed(link, "nixos-version")
+			gen.Specialisations = specialisations(link)
+			gen.FlakeURL, gen.FlakeRevision, gen.FlakeDirty = n.flakeMetadata(ctx, link)
+		}
+		generations = append(generations, gen)
+	}
+
+	sort.Slice(generations, func(i, j int) bool {
+		a, _ := strconv.Atoi(generations[i].ID)
+		b, _ := strconv.Atoi(generations[j].ID)
+		return a > b
+	})
+	if n.system() {
+		markBoot(generations)
+	}
+	return generations, nil
+}
+
+// markBoot flags the generation the machine booted into and the one it
+// will boot next. systemd-boot names its default entry in loader.conf;
+// otherwise the boot loader was last installed for the current generation,
+// which is what nixos-rebuild switch and boot both do.
+func markBoot(generations []models.Generation) {
+	booted, _ := filepath.EvalSymlinks(bootedSystem)
+	def, ok := systemdBootDefault()
+	for i := range generations {
+		gen := &generations[i]
+		if !ok && gen.Current {
+			def = gen.ID
+		}
+		if booted != "" {
+			target, _ := filepath.EvalSymlinks(gen.Profiles[0])
+			gen.Booted = target == booted
+		}
+	}
+	for i := range generations {
+		generations[i].BootDefault = generations[i].ID == def
+	}
+}
+
+var bootEntry = regexp.MustCompile(`^default\s+nixos-generation-(\d+)\b`)
+
+// systemdBootDefault reads a line like "default nixos-generation-42.conf"
+// from loader.conf.
+func systemdBootDefault() (string, bool) {
+	data, err := os.ReadFile(systemdBootConf)
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := bootEntry.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// closureHash returns the store hash of the system a link points to. Two
+// generations with the same system store path have identical closures.
+func closureHash(link string) string {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return ""
+	}
+	hash, _, _ := models.ParseStorePath(target)
+	return hash
+}
+
+// kernelVersion returns the version of the kernel a system boots, from the
+// store path its kernel link points into, e.g.
+// /nix/store/<hash>-linux-6.6.1/bzImage.
+func kernelVersion(system string) string {
+	kernel, err := filepath.EvalSymlinks(filepath.Join(system, "kernel"))
+	if err != nil {
+		return ""
+	}
+	_, _, version := models.ParseStorePath(filepath.Dir(kernel))
+	return version
+}
+
+// specialisations returns the names of a system's specialisations, sorted.
+func specialisations(system string) []string {
+	entries, err := os.ReadDir(filepath.Join(system, "specialisation"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// Target returns the store path generation id's link points to.
+func Target(id string) (string, error) {
+	target, err := os.Readlink(Link(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to read generation %s: %w", id, err)
+	}
+	return target, nil
+}
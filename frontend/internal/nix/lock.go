@@ -0,0 +1,27 @@
+package nix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"nix-timemach/internal/models"
+)
+
+// LockDiff compares the flake.lock files two generations carry as
+// etc/flake.lock, which a configuration provides with
+// environment.etc."flake.lock".source = ./flake.lock. A generation without
+// one, or with one that can't be read, pins nothing.
+func (n Nix) LockDiff(ctx context.Context, fromID, toID string) []models.InputChange {
+	return models.DiffLocks(n.lockedInputs(fromID), n.lockedInputs(toID))
+}
+
+// lockedInputs reads the direct inputs of generation id's etc/flake.lock.
+func (n Nix) lockedInputs(id string) map[string]models.LockedInput {
+	data, err := os.ReadFile(filepath.Join(n.link(id), "etc/flake.lock"))
+	if err != nil {
+		return nil
+	}
+	inputs, _ := models.ParseFlakeLock(data)
+	return inputs
+}
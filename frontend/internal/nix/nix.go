@@ -0,0 +1,301 @@
+// Package nix answers the backend's questions by running the Nix
+// command-line tools directly, so the frontend works without the separately
+// built backend binary.
+package nix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/shell"
+)
+
+const (
+	// SystemProfile is the profile whose generations are listed unless
+	// another is asked for, and the one system-changing actions act on.
+	SystemProfile = "/nix/var/nix/profiles/system"
+	// GCRootsDir holds the roots that record known-good generations.
+	GCRootsDir = "/nix/var/nix/gcroots/nix-timemach"
+	// currentSystem is the running system, which a pending rebuild is
+	// compared with.
+	currentSystem = "/run/current-system"
+	// bootedSystem is the system the machine booted into.
+	bootedSystem = "/run/booted-system"
+	// systemdBootConf names systemd-boot's default entry.
+	systemdBootConf = "/boot/loader/loader.conf"
+)
+
+// Nix runs Nix tools against one store and reads one profile.
+type Nix struct {
+	// Store is a store path or URL, or "" for the default store.
+	Store string
+	// Profile is the profile whose generations are read, or "" for the
+	// system's.
+	Profile string
+}
+
+// Link returns the link of system generation id.
+func Link(id string) string {
+	return fmt.Sprintf("%s-%s-link", SystemProfile, id)
+}
+
+func (n Nix) profile() string {
+	if n.Profile == "" {
+		return SystemProfile
+	}
+	return n.Profile
+}
+
+func (n Nix) system() bool {
+	return n.profile() == SystemProfile
+}
+
+// link returns the link of generation id of n's profile.
+func (n Nix) link(id string) string {
+	return fmt.Sprintf("%s-%s-link", n.profile(), id)
+}
+
+// current is what a generation is compared with when none is named: the
+// running system, or a profile's current generation.
+func (n Nix) current() string {
+	if n.system() {
+		return currentSystem
+	}
+	return n.profile()
+}
+
+// KnownGoodRoot returns the GC root that marks generation id known good.
+func KnownGoodRoot(id string) string {
+	return filepath.Join(GCRootsDir, "known-good-"+id)
+}
+
+// PinRoot returns the GC root that pins generation id: pin-<id>, or
+// pin-<id>-<name> when the pin has a name, so the name needs no separate
+// record. Characters that don't belong in a file name become underscores.
+func PinRoot(id, name string) string {
+	if name == "" {
+		return filepath.Join(GCRootsDir, "pin-"+id)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r)) {
+			return r
+		}
+		return '_'
+	}, name)
+	return filepath.Join(GCRootsDir, "pin-"+id+"-"+name)
+}
+
+// FindPin returns the root pinning generation id and the pin's name, or
+// false if it isn't pinned.
+func FindPin(id string) (root, name string, ok bool) {
+	entries, err := os.ReadDir(GCRootsDir)
+	if err != nil {
+		return "", "", false
+	}
+	prefix := "pin-" + id
+	for _, entry := range entries {
+		rest, found := strings.CutPrefix(entry.Name(), prefix)
+		if !found {
+			continue
+		}
+		if rest == "" {
+			return filepath.Join(GCRootsDir, entry.Name()), "", true
+		}
+		if name, found := strings.CutPrefix(rest, "-"); found {
+			return filepath.Join(GCRootsDir, entry.Name()), name, true
+		}
+	}
+	return "", "", false
+}
+
+// TrashRoot returns the GC root that keeps generation id in the trash:
+// trash-<id>-<seconds since the epoch>, which records when it was trashed
+// without a separate record, like PinRoot.
+func TrashRoot(id string, trashed time.Time) string {
+	return filepath.Join(GCRootsDir, fmt.Sprintf("trash-%s-%d", id, trashed.Unix()))
+}
+
+// Trashed is a generation in the trash and the root that keeps it.
+type Trashed struct {
+	models.TrashedGeneration
+	Root string
+}
+
+// Trash lists the generations in the trash, oldest first.
+func Trash() []Trashed {
+	entries, err := os.ReadDir(GCRootsDir)
+	if err != nil {
+		return nil
+	}
+	var trash []Trashed
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry.Name(), "trash-")
+		if !ok {
+			continue
+		}
+		id, seconds, ok := strings.Cut(rest, "-")
+		unix, err := strconv.ParseInt(seconds, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		root := filepath.Join(GCRootsDir, entry.Name())
+		target, err := os.Readlink(root)
+		if err != nil {
+			continue
+		}
+		trash = append(trash, Trashed{
+			TrashedGeneration: models.TrashedGeneration{ID: id, StorePath: target, Trashed: time.Unix(unix, 0)},
+			Root:              root,
+		})
+	}
+	slices.SortFunc(trash, func(a, b Trashed) int {
+		return a.Trashed.Compare(b.Trashed)
+	})
+	return trash
+}
+
+// Wrap prefixes argv so it runs against n's store even when started through
+// sudo, which doesn't pass the environment on.
+func (n Nix) Wrap(argv ...string) []string {
+	if n.Store == "" {
+		return argv
+	}
+	return append([]string{"env", "NIX_CONFIG=" + n.config()}, argv...)
+}
+
+// config is NIX_CONFIG with the store setting added. Every Nix tool reads
+// it, so the store reaches them all without a flag per tool.
+func (n Nix) config() string {
+	config := os.Getenv("NIX_CONFIG")
+	if config != "" {
+		config += "\n"
+	}
+	return config + "store = " + n.Store
+}
+
+func (n Nix) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if n.Store != "" {
+		cmd.Env = append(os.Environ(), "NIX_CONFIG="+n.config())
+	}
+	return cmd
+}
+
+// output runs a tool and returns its stdout, or an error naming the command
+// and carrying its stderr.
+func (n Nix) output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := n.command(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, commandError(cmd, err, stderr.Bytes())
+	}
+	return out, nil
+}
+
+// run runs a tool in dir with its stderr going to w, for long builds whose
+// progress is worth showing.
+func (n Nix) run(ctx context.Context, w io.Writer, dir, name string, args ...string) error {
+	cmd := n.command(ctx, name, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if w != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, w)
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return commandError(cmd, err, stderr.Bytes())
+	}
+	return nil
+}
+
+func commandError(cmd *exec.Cmd, err error, stderr []byte) error {
+	const maxLen = 4096
+	stderr = bytes.TrimSpace(stderr)
+	if len(stderr) > maxLen {
+		stderr = stderr[len(stderr)-maxLen:]
+	}
+	if len(stderr) == 0 {
+		return fmt.Errorf("%s: %w", shell.CommandLine(cmd.Args...), err)
+	}
+	return fmt.Errorf("%s: %w\n%s", shell.CommandLine(cmd.Args...), err, stderr)
+}
+
+// query lists the store paths nix-store -q reports for path, e.g. its
+// references or requisites.
+func (n Nix) query(ctx context.Context, flag, path string) ([]string, error) {
+	out, err := n.output(ctx, "nix-store", "-q", flag, path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// sizes returns the own size of every package in diff that Nix knows,
+// looked up in one call. Sizes are a nicety, so failure leaves them out.
+func (n Nix) sizes(ctx context.Context, diff models.GenerationDiff) map[string]int64 {
+	var paths []string
+	for _, changes := range [][]models.PackageChange{diff.Added, diff.Removed, diff.Modified} {
+		for _, c := range changes {
+			paths = append(paths, c.Path)
+			if c.NewPath != "" {
+				paths = append(paths, c.NewPath)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	out, err := n.output(ctx, "nix", append([]string{"path-info", "-s"}, paths...)...)
+	if err != nil {
+		return nil
+	}
+	sizes := make(map[string]int64, len(paths))
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			sizes[fields[0]] = size
+		}
+	}
+	return sizes
+}
+
+// closureSize returns the closure size of path, or false when Nix can't
+// tell.
+func (n Nix) closureSize(ctx context.Context, path string) (int64, bool) {
+	out, err := n.output(ctx, "nix", "path-info", "-S", path)
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	var size int64
+	if _, err := fmt.Sscan(fields[1], &size); err != nil {
+		return 0, false
+	}
+	return size, true
+}
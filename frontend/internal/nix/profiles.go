@@ -0,0 +1,37 @@
+package nix
+
+import (
+	"os"
+	"path/filepath"
+
+	"nix-timemach/internal/models"
+)
+
+// Profiles lists the profiles worth browsing that exist: the system's, the
+// user's own and Home Manager's. With use-xdg-base-directories user
+// profiles live under ~/.local/state rather than per-user, so both places
+// are tried.
+func Profiles() []models.Profile {
+	home, _ := os.UserHomeDir()
+	perUser := filepath.Join("/nix/var/nix/profiles/per-user", os.Getenv("USER"))
+	state := filepath.Join(home, ".local/state/nix/profiles")
+	candidates := []struct {
+		name  string
+		paths []string
+	}{
+		{"system", []string{SystemProfile}},
+		{"user", []string{filepath.Join(state, "profile"), filepath.Join(perUser, "profile")}},
+		{"home-manager", []string{filepath.Join(state, "home-manager"), filepath.Join(perUser, "home-manager")}},
+	}
+
+	var profiles []models.Profile
+	for _, c := range candidates {
+		for _, path := range c.paths {
+			if _, err := os.Lstat(path); err == nil {
+				profiles = append(profiles, models.Profile{Name: c.name, Path: path})
+				break
+			}
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,78 @@
+// Package notify tells someone away from the terminal what the watch
+// daemon saw: on the desktop with notify-send, or by posting it to a
+// webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Event kinds.
+const (
+	KindNewGeneration = "new_generation"
+	KindSizeThreshold = "size_threshold"
+)
+
+// Event is what a webhook receives, as JSON.
+type Event struct {
+	Kind string `json:"kind"`
+	// Host is the machine watched over ssh; empty is this one.
+	Host        string    `json:"host,omitempty"`
+	Generation  string    `json:"generation"`
+	Timestamp   time.Time `json:"timestamp"`
+	Description string    `json:"description,omitempty"`
+	// ClosureSize and Threshold are in bytes, and only set for a
+	// KindSizeThreshold event.
+	ClosureSize int64 `json:"closure_size,omitempty"`
+	Threshold   int64 `json:"threshold,omitempty"`
+	// Message is the event in words, as the desktop shows it.
+	Message string `json:"message"`
+}
+
+// webhookTimeout bounds a post, so a webhook that hangs can't hold up the
+// events after it.
+const webhookTimeout = 10 * time.Second
+
+// Desktop shows a notification with notify-send.
+func Desktop(ctx context.Context, title, body string) error {
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=nix-timemach", "--", title, body).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("failed to show a desktop notification: %w", err)
+	}
+	return nil
+}
+
+// Webhook posts e to url as JSON. Any status other than 2xx is an error.
+func Webhook(ctx context.Context, url string, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to post to the webhook: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post to the webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nix-timemach")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to the webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to post to the webhook: %s", resp.Status)
+	}
+	return nil
+}
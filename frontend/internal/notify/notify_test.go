@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	want := Event{
+		Kind:        KindNewGeneration,
+		Generation:  "42",
+		Timestamp:   time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		Description: "NixOS 24.05",
+		Message:     "generation 42 appeared",
+	}
+	var got Event
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := Webhook(context.Background(), srv.URL, want); err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if got != want {
+		t.Errorf("webhook received %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	err := Webhook(context.Background(), srv.URL, Event{Kind: KindNewGeneration})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Webhook to a failing server = %v, want its status", err)
+	}
+}
+
+// fakeNotifySend puts a notify-send on $PATH that runs script.
+func fakeNotifySend(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notify-send"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestDesktop(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args")
+	fakeNotifySend(t, `printf '%s\n' "$@" > `+args+"\n")
+	if err := Desktop(context.Background(), "nix-timemach", "-generation 42 appeared"); err != nil {
+		t.Fatalf("Desktop: %v", err)
+	}
+	data, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "--app-name=nix-timemach\n--\nnix-timemach\n-generation 42 appeared\n"; got != want {
+		t.Errorf("notify-send ran with %q, want %q", got, want)
+	}
+
+	fakeNotifySend(t, "echo 'cannot connect to the bus' >&2\nexit 1\n")
+	if err := Desktop(context.Background(), "t", "b"); err == nil || !strings.Contains(err.Error(), "cannot connect to the bus") {
+		t.Errorf("Desktop with a failing notify-send = %v, want its output", err)
+	}
+}
@@ -0,0 +1,30 @@
+// Package shell renders commands as text that can be pasted into a POSIX
+// shell.
+package shell
+
+import "strings"
+
+// Quote returns arg quoted for a POSIX shell, leaving it bare when that is
+// already safe.
+func Quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:=@%+,", r))
+	}) < 0
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// CommandLine joins a command and its arguments into a single shell line.
+func CommandLine(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
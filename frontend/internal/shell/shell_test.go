@@ -0,0 +1,44 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct{ arg, want string }{
+		{"", "''"},
+		{"nix-timemach", "nix-timemach"},
+		{"/nix/store/abc-firefox-129.0", "/nix/store/abc-firefox-129.0"},
+		{"--since=2026-10-14", "--since=2026-10-14"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a;b", "'a;b'"},
+		{"*", "'*'"},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.arg); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// The shell reads a command line back as the arguments it was made from.
+func TestCommandLine(t *testing.T) {
+	args := []string{"", "plain", "two words", "it's", `"$(rm -rf /)"`, "back\\slash", "new\nline", "é"}
+	out, err := exec.Command("sh", "-c", "printf '%s\\0' "+CommandLine(args...)).Output()
+	if err != nil {
+		t.Skipf("no POSIX shell: %v", err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(got) != len(args) {
+		t.Fatalf("shell read %q, want %q", got, args)
+	}
+	for i := range args {
+		if got[i] != args[i] {
+			t.Errorf("argument %d read as %q, want %q", i, got[i], args[i])
+		}
+	}
+}
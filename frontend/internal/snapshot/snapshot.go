@@ -0,0 +1,155 @@
+// Package snapshot stores the package sets of generations under a name, so
+// they can be diffed against long after the generation itself is gone.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"nix-timemach/internal/xdg"
+)
+
+type Snapshot struct {
+	Name string `json:"name"`
+	// Generation is the generation the package set was taken from, or
+	// empty for the running system.
+	Generation string    `json:"generation"`
+	Created    time.Time `json:"created"`
+	Paths      []string  `json:"paths"`
+}
+
+func dir() (string, error) {
+	data, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "snapshots"), nil
+}
+
+// ValidName reports whether name can be used as a snapshot file name.
+func ValidName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
+
+func path(name string) (string, error) {
+	if err := ValidName(name); err != nil {
+		return "", err
+	}
+	d, err := dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, name+".json"), nil
+}
+
+// Load reads the snapshot called name.
+func Load(name string) (Snapshot, error) {
+	p, err := path(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Snapshot{}, fmt.Errorf("no snapshot named %q", name)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	return s, nil
+}
+
+// LoadFile reads a package set exported to an arbitrary file: either a
+// snapshot, or a plain JSON array of store paths as the backend's packages
+// subcommand prints.
+func LoadFile(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read package set: %w", err)
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err == nil {
+		return Snapshot{Name: filepath.Base(path), Paths: paths}, nil
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to parse %s: expected a snapshot or an array of store paths: %w", path, err)
+	}
+	return s, nil
+}
+
+// List returns every snapshot, newest first. A missing directory is not an
+// error.
+func List() ([]Snapshot, error) {
+	d, err := dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(d)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || ValidName(name) != nil {
+			continue
+		}
+		s, err := Load(name)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Created.After(snapshots[j].Created) })
+	return snapshots, nil
+}
+
+// Save stores s, replacing any snapshot with the same name.
+func Save(s Snapshot) error {
+	p, err := path(s.Name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := xdg.WriteFile(p, data); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the snapshot called name.
+func Delete(name string) error {
+	p, err := path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no snapshot named %q", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
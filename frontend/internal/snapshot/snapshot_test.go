@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		file     string
+		name     string
+		gen      string
+		numPaths int
+		wantErr  bool
+	}{
+		{file: "paths.json", name: "paths.json", numPaths: 3},
+		{file: "snapshot.json", name: "after", gen: "42", numPaths: 3},
+		{file: "invalid.json", wantErr: true},
+		{file: "missing.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			s, err := LoadFile(filepath.Join("testdata", tt.file))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadFile(%s) = %+v, want an error", tt.file, s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile(%s): %v", tt.file, err)
+			}
+			if s.Name != tt.name || s.Generation != tt.gen || len(s.Paths) != tt.numPaths {
+				t.Errorf("LoadFile(%s) = name %q, generation %q, %d paths; want %q, %q, %d",
+					tt.file, s.Name, s.Generation, len(s.Paths), tt.name, tt.gen, tt.numPaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+)
+
+// previewUnits is how many units of each kind the preview names before
+// summarising the rest.
+const previewUnits = 4
+
+type previewMsg struct {
+	id      string
+	preview models.ActivationPreview
+	err     error
+}
+
+// previewRollback asks what activating generation id would do, so the
+// rollback confirmation can say which units would stop and restart.
+func (a *App) previewRollback(id string) tea.Cmd {
+	a.loading = true
+	return func() tea.Msg {
+		preview, err := a.client.DryActivate(context.Background(), id)
+		return previewMsg{id: id, preview: preview, err: err}
+	}
+}
+
+// confirmRollback asks to roll back with the preview in the prompt. A
+// preview that failed, e.g. for lack of permission, doesn't stop the
+// rollback; the prompt says it is missing.
+func (a *App) confirmRollback(msg previewMsg) {
+	a.loading = false
+	if a.state != stateGenerations {
+		return
+	}
+	action := a.rollbackTo(msg.id)
+	if i := a.current(); i >= 0 && a.generations[i].Current && a.generations[i].ID != msg.id {
+		prev := a.generations[i].ID
+		action = a.undoable(action, systemStep(a.t("undo.rollback", msg.id),
+			a.rollbackTo(prev), a.rollbackTo(msg.id)))
+	}
+	a.askConfirm(
+		a.t("confirm.rollback", msg.id)+"\n\n"+a.renderPreview(msg),
+		a.privileged(action),
+	)
+}
+
+func (a *App) renderPreview(msg previewMsg) string {
+	if msg.err != nil {
+		first, _, _ := strings.Cut(msg.err.Error(), "\n")
+		return a.t("preview.failed", first)
+	}
+	p := msg.preview
+	var lines []string
+	for _, section := range []struct {
+		label string
+		units []string
+	}{
+		{"preview.stop", p.Stop},
+		{"preview.restart", p.Restart},
+		{"preview.reload", p.Reload},
+		{"preview.start", p.Start},
+		{"preview.keep", p.Keep},
+	} {
+		if len(section.units) > 0 {
+			lines = append(lines, a.t(section.label)+" "+a.unitList(section.units))
+		}
+	}
+	if p.Empty() {
+		lines = append(lines, a.t("preview.none"))
+	}
+	lines = append(lines, p.Notes...)
+	return strings.Join(lines, "\n")
+}
+
+func (a *App) unitList(units []string) string {
+	if len(units) <= previewUnits {
+		return strings.Join(units, ", ")
+	}
+	return strings.Join(units[:previewUnits], ", ") + " " + a.t("preview.more", len(units)-previewUnits)
+}
@@ -1,17 +1,26 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"nix-timemach/internal/backend"
+	"nix-timemach/internal/bisect"
+	"nix-timemach/internal/groups"
+	"nix-timemach/internal/history"
+	"nix-timemach/internal/i18n"
+	"nix-timemach/internal/listing"
 	"nix-timemach/internal/models"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 type state int
@@ -19,156 +28,1173 @@ type state int
 const (
 	stateGenerations state = iota
 	stateDiff
+	stateConfirm
+	stateDetails
+	statePrompt
+	stateGroups
+	stateBatch
+	stateDeps
+	statePresets
+	stateFleet
+	stateGC
+	stateRetention
+	stateBisect
+	stateTimeline
+	stateChangelog
+	stateSizeHistory
+	stateUsage
+	stateWhy
+	stateClosure
+	stateTrash
+	stateAudit
 )
 
 type keyMap struct {
-	Up     key.Binding
-	Down   key.Binding
-	Select key.Binding
-	Back   key.Binding
-	Quit   key.Binding
-	Reload key.Binding
+	Up          key.Binding
+	Down        key.Binding
+	PageUp      key.Binding
+	PageDown    key.Binding
+	Select      key.Binding
+	From        key.Binding
+	DiffFrom    key.Binding
+	Back        key.Binding
+	Quit        key.Binding
+	Reload      key.Binding
+	Good        key.Binding
+	Details     key.Binding
+	Collapse    key.Binding
+	Pending     key.Binding
+	Mark        key.Binding
+	SaveGroup   key.Binding
+	Groups      key.Binding
+	Help        key.Binding
+	Hashes      key.Binding
+	CopyCmd     key.Binding
+	Find        key.Binding
+	Filter      key.Binding
+	Snapshot    key.Binding
+	Explicit    key.Binding
+	Files       key.Binding
+	Rollback    key.Binding
+	Boot        key.Binding
+	Delete      key.Binding
+	Advise      key.Binding
+	AttrPaths   key.Binding
+	Group       key.Binding
+	Log         key.Binding
+	Presets     key.Binding
+	Profile     key.Binding
+	Preview     key.Binding
+	GC          key.Binding
+	Pin         key.Binding
+	Retention   key.Binding
+	Bisect      key.Binding
+	Timeline    key.Binding
+	Export      key.Binding
+	Changelog   key.Binding
+	SizeHistory key.Binding
+	Usage       key.Binding
+	Trash       key.Binding
+	Audit       key.Binding
+	Undo        key.Binding
+	Redo        key.Binding
+	Why         key.Binding
+	Theme       key.Binding
+	Timestamps  key.Binding
+	FoldDay     key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Down, k.Select, k.Back, k.Quit}
+	return []key.Binding{k.Up, k.Down, k.Select, k.Back, k.Quit, k.Help}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.Select},
-		{k.Back, k.Reload, k.Quit},
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Select, k.From, k.DiffFrom},
+		{k.Details, k.Preview, k.Profile, k.Good, k.Rollback, k.Boot, k.Delete, k.GC, k.Pin, k.Retention, k.Trash, k.Audit, k.Bisect, k.Collapse, k.FoldDay, k.Pending},
+		{k.Filter, k.Find, k.Timeline, k.Changelog, k.SizeHistory, k.Usage, k.Presets, k.Advise, k.Snapshot, k.Mark, k.SaveGroup, k.Groups},
+		{k.Hashes, k.Explicit, k.Export, k.Why, k.Files, k.Group, k.AttrPaths, k.CopyCmd, k.Log, k.Undo, k.Redo, k.Theme, k.Timestamps, k.Back, k.Reload, k.Quit, k.Help},
 	}
 }
 
+// Options holds the user-configurable behaviour of the App.
+type Options struct {
+	// ConfirmQuit asks before quitting while destructive work is pending.
+	ConfirmQuit bool
+	// Pending opens straight into the diff of the pending rebuild.
+	Pending bool
+	// StatusInterval is how often the status bar's relative times update.
+	StatusInterval time.Duration
+	// Clock shows the wall-clock time in the status bar.
+	Clock bool
+	// HashLen is how many characters of store hashes to show when
+	// abbreviation is on; 0 starts with full paths.
+	HashLen int
+	// Lang selects the message catalog, e.g. "en" or "de_DE.UTF-8".
+	Lang string
+	// Theme replaces the default colors when set.
+	Theme *Theme
+	// ThemeName is what Theme is called when switching themes: a preset's
+	// name, or the file it was loaded from.
+	ThemeName string
+	// DayGroups lists generations under headings for the day they were
+	// made, which can be folded.
+	DayGroups bool
+	// Timestamps is the TimestampModes entry the list starts in; empty is
+	// the first.
+	Timestamps string
+	// ASCII draws with ASCII characters only, for terminals and consoles
+	// without Unicode.
+	ASCII bool
+	// ReadOnly disables every action that modifies the system.
+	ReadOnly bool
+	// AgeBuckets are the ascending age thresholds at which row timestamps
+	// move on to the theme's next age color. Empty disables age coloring.
+	AgeBuckets []time.Duration
+	// RollbackRank orders the criteria rollback advice ranks candidates
+	// by, most important first; empty uses DefaultRollbackRank.
+	RollbackRank []string
+	// Sizes fetches each generation's closure size after the list loads.
+	Sizes bool
+	// Preview starts with the list split beside a preview of the
+	// generation at the cursor, in terminals wide enough for both.
+	Preview bool
+	// Prefetch computes the diffs around the cursor while it rests, for a
+	// client that caches them.
+	Prefetch bool
+	// Profile is the profile to open, by name or path; empty opens the
+	// system profile.
+	Profile string
+	// Keys replaces the keys of the bindings it names, e.g. "select".
+	Keys map[string][]string
+	// Hosts opens a dashboard of these machines instead of the list.
+	Hosts []Host
+	// Reference names the host the others' drift is measured against;
+	// empty uses the first.
+	Reference string
+	// Retention is the cleanup policy the policy editor starts with.
+	Retention models.RetentionPolicy
+	// TrashDays is how many days deleted generations can be restored for;
+	// 0 deletes them outright.
+	TrashDays int
+	// Watch reloads the list when the profile's directory changes.
+	Watch bool
+	// MissingBackend opens on how to install the backend binary instead of
+	// the list, when it couldn't be found.
+	MissingBackend *backend.MissingBinaryError
+}
+
 type App struct {
-	keys        keyMap
-	help        help.Model
-	viewport    viewport.Model
-	spinner     spinner.Model
-	client      *backend.Client // Add this
-	state       state
-	generations []models.Generation
-	cursor      int
-	selected    *models.Generation
-	diff        *models.GenerationDiff
-	err         error
-	ready       bool
-	loading     bool
-	width       int
-	height      int
-}
-
-func NewApp(client *backend.Client) *App {
-	keys := keyMap{
+	keys               keyMap
+	detailsKeys        detailsKeys
+	groupKeys          groupKeys
+	help               help.Model
+	viewport           viewport.Model
+	spinner            spinner.Model
+	client             backend.Client
+	opts               Options
+	msgs               *i18n.Catalog
+	state              state
+	generations        []models.Generation
+	cursor             int
+	profileCursor      int
+	collapseDuplicates bool
+	abbreviate         bool
+	pending            bool
+	pendingStarted     time.Time
+	cancelPending      context.CancelFunc
+	cancelDiff         context.CancelFunc
+	cancelWatch        context.CancelFunc
+	notice             string
+	stream             *diffStream
+	gc                 *gcScreen
+	retention          *retentionScreen
+	bisect             *bisect.Session
+	bisectBad          string
+	timeline           *timeline
+	changelog          *changelog
+	sizeHistory        *sizeHistory
+	usage              *usage
+	why                *whyView
+	closure            *closureView
+	trash              *trashView
+	audit              *auditView
+	undo               history.Undo[undoStep]
+	themes             []themeChoice
+	timestamps         int
+	foldedDays         map[string]bool
+	themeIndex         int
+	view               viewStop
+	fleet              *fleet
+	profiles           []models.Profile
+	profileIndex       int
+	lastRefresh        time.Time
+	now                time.Time
+	focusID            string
+	selected           *models.Generation
+	diff               *models.GenerationDiff
+	configDiff         *models.ConfigDiff
+	lockDiff           []models.InputChange
+	showFiles          bool
+	fileDiff           *models.FileDiff
+	fileDiffErr        error
+	stats              map[string]models.DiffStats
+	pane               previewPane
+	prefetch           prefetcher
+	confirm            *confirmation
+	prompt             *prompt
+	marked             map[string]bool
+	search             *packageFoundMsg
+	modifiedCursor     int
+	deps               *depsView
+	filter             listFilter
+	snapshot           string
+	explicitOnly       bool
+	groupPrefixes      bool
+	byAttrPath         bool
+	rollback           rollbackMsg
+	showLog            bool
+	presetCursor       int
+	logLines           []string
+	groupList          []groups.Group
+	groupCursor        int
+	batch              *batch
+	status             string
+	err                error
+	errDetail          bool
+	ready              bool
+	loading            bool
+	width              int
+	height             int
+}
+
+func newKeyMap(t func(string, ...any) string) keyMap {
+	return keyMap{
 		Up: key.NewBinding(
 			key.WithKeys("up", "k"),
-			key.WithHelp("↑/k", "up"),
+			key.WithHelp("↑/k", t("help.up")),
 		),
 		Down: key.NewBinding(
 			key.WithKeys("down", "j"),
-			key.WithHelp("↓/j", "down"),
+			key.WithHelp("↓/j", t("help.down")),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", t("help.pageUp")),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdn", t("help.pageDown")),
 		),
 		Select: key.NewBinding(
 			key.WithKeys("enter"),
-			key.WithHelp("enter", "select"),
+			key.WithHelp("enter", t("help.select")),
+		),
+		From: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", t("help.from")),
+		),
+		DiffFrom: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", t("help.diffFrom")),
 		),
 		Back: key.NewBinding(
 			key.WithKeys("esc"),
-			key.WithHelp("esc", "back"),
+			key.WithHelp("esc", t("help.back")),
 		),
 		Quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
-			key.WithHelp("q", "quit"),
+			key.WithHelp("q", t("help.quit")),
 		),
 		Reload: key.NewBinding(
 			key.WithKeys("r"),
-			key.WithHelp("r", "reload"),
+			key.WithHelp("r", t("help.reload")),
+		),
+		Good: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", t("help.knownGood")),
+		),
+		Details: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", t("help.details")),
+		),
+		Collapse: key.NewBinding(
+			key.WithKeys("="),
+			key.WithHelp("=", t("help.collapse")),
+		),
+		Pending: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", t("help.pending")),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", t("help.mark")),
+		),
+		SaveGroup: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", t("help.saveGroup")),
+		),
+		Groups: key.NewBinding(
+			key.WithKeys("G"),
+			key.WithHelp("G", t("help.groups")),
+		),
+		Hashes: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", t("help.hashes")),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", t("help.filter")),
+		),
+		Presets: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", t("help.presets")),
+		),
+		GC: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", t("help.gc")),
+		),
+		Pin: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", t("help.pin")),
+		),
+		Retention: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", t("help.retention")),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", t("help.undo")),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", t("help.redo")),
+		),
+		Theme: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", t("help.theme")),
+		),
+		FoldDay: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", t("help.foldDay")),
+		),
+		Timestamps: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", t("help.timestamps")),
 		),
+		Audit: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", t("help.audit")),
+		),
+		Trash: key.NewBinding(
+			key.WithKeys("J"),
+			key.WithHelp("J", t("help.trash")),
+		),
+		Bisect: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", t("help.bisect")),
+		),
+		Timeline: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", t("help.timeline")),
+		),
+		Changelog: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", t("help.changelog")),
+		),
+		SizeHistory: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", t("help.sizeHistory")),
+		),
+		Usage: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", t("help.usage")),
+		),
+		Why: key.NewBinding(
+			key.WithKeys("Y"),
+			key.WithHelp("Y", t("help.why")),
+		),
+		Preview: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", t("help.preview")),
+		),
+		Profile: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", t("help.profile")),
+		),
+		Advise: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", t("help.advise")),
+		),
+		Rollback: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", t("help.rollback")),
+		),
+		Boot: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", t("help.boot")),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", t("help.delete")),
+		),
+		Log: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", t("help.log")),
+		),
+		Group: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", t("help.groupPrefixes")),
+		),
+		Explicit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", t("help.explicit")),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", t("help.export")),
+		),
+		Files: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", t("help.files")),
+		),
+		AttrPaths: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", t("help.attrPaths")),
+		),
+		Snapshot: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", t("help.snapshot")),
+		),
+		Find: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", t("help.findPackage")),
+		),
+		CopyCmd: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", t("help.copyCommand")),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", t("help.more")),
+		),
+	}
+}
+
+func NewApp(client backend.Client, opts Options) *App {
+	msgs := i18n.New(opts.Lang)
+	if opts.Theme != nil {
+		applyTheme(*opts.Theme)
+	}
+	keys := newKeyMap(msgs.T)
+	// Options.Keys has been checked with ValidateKeys.
+	keys.remap(opts.Keys)
+
+	if opts.ReadOnly {
+		keys.disableMutating()
 	}
 
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
-	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	if opts.ASCII {
+		sp.Spinner = spinner.Line
+	}
+	sp.Style = spinnerStyle
 
-	return &App{
-		keys:    keys,
-		help:    help.New(),
-		spinner: sp,
-		client:  client, // Pass the client here
-		state:   stateGenerations,
+	app := &App{
+		keys:        keys,
+		detailsKeys: newDetailsKeys(msgs.T),
+		groupKeys:   newGroupKeys(msgs.T),
+		help:        help.New(),
+		spinner:     sp,
+		client:      client,
+		opts:        opts,
+		msgs:        msgs,
+		stats:       make(map[string]models.DiffStats),
+		marked:      make(map[string]bool),
+		foldedDays:  make(map[string]bool),
+		filter:      newListFilter(),
+		now:         time.Now(),
+		abbreviate:  opts.HashLen > 0,
+		state:       stateGenerations,
+		fleet:       newFleet(opts),
+		pane:        previewPane{on: opts.Preview},
+	}
+	app.themes, app.themeIndex = themeChoices(opts)
+	app.timestamps = max(0, slices.Index(TimestampModes, opts.Timestamps))
+	if app.fleet != nil {
+		app.state = stateFleet
 	}
+	return app
 }
 
 func (a *App) Init() tea.Cmd {
+	cmds := []tea.Cmd{a.spinner.Tick, a.statusTick(), a.loadFilterHistory}
+	if a.opts.MissingBackend != nil {
+		return tea.Batch(cmds...)
+	}
+	if a.fleet != nil {
+		return tea.Batch(append(cmds, a.loadFleet())...)
+	}
+	cmds = append(cmds, a.fetchGenerations(), a.loadProfiles, a.rewatch())
+	if a.opts.Pending {
+		cmds = append(cmds, a.startPendingDiff())
+	}
+	return tea.Batch(cmds...)
+}
+
+func (a *App) t(key string, args ...any) string {
+	return a.msgs.T(key, args...)
+}
+
+// pendingWork describes queued destructive work that quitting would discard,
+// or returns "" if there is none: generations marked for a batch action.
+// A batch already running holds on to the keyboard until it finishes or is
+// cancelled, so it can't be quit from.
+func (a *App) pendingWork() string {
+	switch {
+	case len(a.marked) == 1:
+		return a.t("quit.markedOne")
+	case len(a.marked) > 1:
+		return a.t("quit.marked", len(a.marked))
+	}
+	return ""
+}
+
+// quit quits, first asking whether to if that would discard pending work.
+// Quitting while already asked, as with a second ctrl+c, doesn't ask again.
+func (a *App) quit() tea.Cmd {
+	pending := a.pendingWork()
+	if !a.opts.ConfirmQuit || pending == "" || (a.confirm != nil && a.confirm.quit) {
+		return tea.Quit
+	}
+	if a.confirm != nil {
+		// ctrl+c gives up on whatever else was being asked.
+		a.state, a.confirm = a.confirm.prev, nil
+	}
+	a.askConfirm(a.t("confirm.quit", pending), tea.Quit)
+	a.confirm.quit = true
+	return nil
+}
+
+// fetchGenerations loads the active profile's generations. The profile is
+// read now, as the Cmd runs outside Update.
+func (a *App) fetchGenerations() tea.Cmd {
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		generations, err := a.client.GetGenerations(ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+		return generationsMsg(generations)
+	}
+}
+
+// fetchConfigDiff never reports an error: the configuration section is
+// supplementary and simply stays hidden when the backend can't provide it.
+func (a *App) fetchConfigDiff(ctx context.Context, from, to string) tea.Msg {
+	diff, err := a.client.GetConfigDiff(ctx, from, to)
+	if err != nil {
+		return configDiffMsg{}
+	}
+	return configDiffMsg(diff)
+}
+
+// loadDiff fetches the diff between two generations along with their
+// configuration, flake input and /etc changes. Going back cancels them all.
+func (a *App) loadDiff(from, to string) tea.Cmd {
+	ctx := a.diffContext()
+	a.resetFiles()
 	return tea.Batch(
-		a.spinner.Tick,
-		a.fetchGenerations,
+		a.streamDiff(ctx, from, to),
+		func() tea.Msg { return a.fetchConfigDiff(ctx, from, to) },
+		func() tea.Msg { return a.fetchLockDiff(ctx, from, to) },
+		func() tea.Msg { return a.fetchFileDiff(ctx, from, to) },
 	)
 }
 
-func (a *App) fetchGenerations() tea.Msg {
-	generations, err := a.client.GetGenerations()
-	if err != nil {
-		return errMsg{err}
+// diffContext returns the context for the diff view's backend calls, which
+// stopDiff cancels.
+func (a *App) diffContext() context.Context {
+	a.stopDiff()
+	ctx, cancel := context.WithCancel(a.profileContext())
+	a.cancelDiff = cancel
+	return ctx
+}
+
+// stopDiff cancels an in-flight diff, if any.
+func (a *App) stopDiff() {
+	if a.cancelDiff != nil {
+		a.cancelDiff()
+		a.cancelDiff = nil
 	}
-	return generationsMsg(generations)
+	a.stream = nil
 }
 
-func (a *App) fetchDiff(from, to string) tea.Msg {
-	diff, err := a.client.GetDiff(from, to)
-	if err != nil {
-		return errMsg{err}
+// closeDiff leaves the diff view for the list, dropping the diff and
+// whatever is still loading for it.
+func (a *App) closeDiff() {
+	a.stopPendingDiff()
+	a.stopDiff()
+	a.state = stateGenerations
+	a.clearFrom()
+	a.diff = nil
+	a.configDiff = nil
+	a.lockDiff = nil
+	a.resetFiles()
+	a.snapshot = ""
+}
+
+// predecessor returns the index of the generation created just before
+// generations[i], or -1 if it is the oldest.
+func (a *App) predecessor(i int) int {
+	prev := -1
+	for j, gen := range a.generations {
+		if gen.Timestamp.Before(a.generations[i].Timestamp) &&
+			(prev < 0 || gen.Timestamp.After(a.generations[prev].Timestamp)) {
+			prev = j
+		}
+	}
+	return prev
+}
+
+// showDiff opens the diff from generations[from] to the cursor's
+// generation.
+func (a *App) showDiff(from int) tea.Cmd {
+	a.setFrom(from)
+	a.state = stateDiff
+	return a.loadDiff(a.selected.ID, a.generations[a.cursor].ID)
+}
+
+// between counts the generations created strictly between a and b, in
+// either order.
+func (a *App) between(from, to models.Generation) int {
+	lo, hi := from.Timestamp, to.Timestamp
+	if hi.Before(lo) {
+		lo, hi = hi, lo
+	}
+	n := 0
+	for _, gen := range a.generations {
+		if gen.Timestamp.After(lo) && gen.Timestamp.Before(hi) {
+			n++
+		}
+	}
+	return n
+}
+
+func statsKey(from, to string) string {
+	return from + "→" + to
+}
+
+// requestStats fetches the stats annotation for the cursor's generation
+// against its predecessor, unless it is already known.
+func (a *App) requestStats() tea.Cmd {
+	if a.state != stateGenerations || a.cursor >= len(a.generations) {
+		return nil
+	}
+	prev := a.predecessor(a.cursor)
+	if prev < 0 {
+		return nil
+	}
+	from, to := a.generations[prev].ID, a.generations[a.cursor].ID
+	if _, ok := a.stats[statsKey(from, to)]; ok {
+		return nil
+	}
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		stats, err := a.client.GetDiffStats(ctx, from, to)
+		if err != nil {
+			// Annotations are best-effort; the row just stays unannotated.
+			return nil
+		}
+		return statsMsg{from: from, to: to, stats: stats}
+	}
+}
+
+// bootDefault returns the ID of the generation booted by default, or "" if
+// the backend doesn't say.
+func (a *App) bootDefault() string {
+	for _, gen := range a.generations {
+		if gen.BootDefault {
+			return gen.ID
+		}
+	}
+	return ""
+}
+
+func (a *App) setBootDefault(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.SetBootDefault(context.Background(), id); err != nil {
+			return errMsg{err}
+		}
+		return actionDoneMsg{status: a.t("status.bootDefault", id), focus: id}
+	}
+}
+
+func (a *App) rollbackTo(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.Rollback(context.Background(), id); err != nil {
+			return errMsg{err}
+		}
+		return actionDoneMsg{status: a.t("status.rolledBack", id), focus: id}
+	}
+}
+
+func (a *App) markKnownGood(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.MarkKnownGood(context.Background(), id); err != nil {
+			return errMsg{err}
+		}
+		return actionDoneMsg{focus: id}
 	}
-	return diffMsg(diff)
 }
 
 type generationsMsg []models.Generation
 type diffMsg models.GenerationDiff
+type configDiffMsg models.ConfigDiff
+type statsMsg struct {
+	from, to string
+	stats    models.DiffStats
+}
+
+// actionDoneMsg reports that a mutating backend action finished. focus is
+// the generation the cursor should land on once the list is reloaded.
+type actionDoneMsg struct {
+	status string
+	focus  string
+}
+type batchStartMsg struct {
+	label string
+	ids   []string
+	run   func(id string) error
+}
 type errMsg struct{ error }
 
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
+	defer a.recordView()
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		a.status = ""
+		a.notice = ""
+		if msg.String() == "ctrl+c" {
+			return a, a.quit()
+		}
+		if a.opts.MissingBackend != nil && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateSetup(msg)
+		}
+		if a.err != nil && a.updateError(msg) {
+			return a, nil
+		}
+		if a.state == statePrompt {
+			return a, a.updatePrompt(msg)
+		}
+		if a.state == stateConfirm {
+			return a, a.updateConfirm(msg)
+		}
+		if key.Matches(msg, a.keys.Undo, a.keys.Redo) && a.navigable(a.state) && !a.filter.editing {
+			return a, a.replay(key.Matches(msg, a.keys.Redo))
+		}
+		if key.Matches(msg, a.keys.Theme) && !a.filter.editing {
+			a.nextTheme()
+			return a, nil
+		}
+		if a.state == stateDetails && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateDetails(msg)
+		}
+		if a.state == stateFleet && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateFleet(msg)
+		}
+		if a.state == stateGroups && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateGroups(msg)
+		}
+		if a.state == statePresets && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updatePresets(msg)
+		}
+		if a.state == stateGC && (a.gc.running || !key.Matches(msg, a.keys.Quit)) {
+			return a, a.updateGC(msg)
+		}
+		if a.state == stateRetention && (a.retention.running || !key.Matches(msg, a.keys.Quit)) {
+			return a, a.updateRetention(msg)
+		}
+		if a.state == stateBisect && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateBisect(msg)
+		}
+		if a.state == stateTimeline && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateTimeline(msg)
+		}
+		if a.state == stateChangelog && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateChangelog(msg)
+		}
+		if a.state == stateSizeHistory && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateSizeHistory(msg)
+		}
+		if a.state == stateUsage && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateUsage(msg)
+		}
+		if a.state == stateWhy && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateWhy(msg)
+		}
+		if a.state == stateTrash && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateTrash(msg)
+		}
+		if a.state == stateAudit && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateAudit(msg)
+		}
+		if a.state == stateClosure && !key.Matches(msg, a.keys.Quit) {
+			return a, a.updateClosure(msg)
+		}
+		if a.state == stateGenerations && a.filter.editing {
+			return a, a.updateFilter(msg)
+		}
+		if a.opts.ReadOnly && a.keys.mutates(msg) {
+			a.setStatus(a.t("status.readOnly"))
+			return a, nil
+		}
+		if !a.onSystemProfile() && a.keys.mutates(msg) {
+			a.setStatus(a.t("status.systemOnly"))
+			return a, nil
+		}
+		if a.state == stateGenerations && a.noMatches() &&
+			(key.Matches(msg, a.keys.Select, a.keys.Details, a.keys.Mark) || a.keys.mutates(msg)) {
+			// The cursor is on a row the filter hides.
+			return a, nil
+		}
+		if a.state == stateBatch {
+			// Quitting mid-batch would abandon it half done; Back cancels
+			// the remaining items instead.
+			if key.Matches(msg, a.keys.Back) {
+				a.batch.canceled = true
+			}
+			return a, nil
+		}
+
 		switch {
 		case key.Matches(msg, a.keys.Quit):
-			return a, tea.Quit
+			return a, a.quit()
 
 		case key.Matches(msg, a.keys.Back):
+			if a.state == stateGenerations && a.selected != nil {
+				a.clearFrom()
+				break
+			}
+			if a.state == stateGenerations && !a.filterActive() && a.search != nil {
+				a.search = nil
+				break
+			}
+			if a.state == stateGenerations && !a.filterActive() && a.fleet != nil {
+				a.state = stateFleet
+				break
+			}
 			if a.state == stateDiff {
-				a.state = stateGenerations
-				a.selected = nil
-				a.diff = nil
+				a.closeDiff()
+			}
+			if a.state == stateDeps {
+				a.state = stateDiff
+				a.deps = nil
+			}
+			if a.state == stateGenerations && a.filterActive() {
+				a.clearFilter()
 			}
 
 		case key.Matches(msg, a.keys.Up):
-			if a.state == stateGenerations && a.cursor > 0 {
-				a.cursor--
+			if a.state == stateGenerations && a.moveCursor(-1) {
+				cmds = append(cmds, a.requestStats())
+			}
+			if a.state == stateDiff && a.modifiedCursor > 0 {
+				a.modifiedCursor--
 			}
 
 		case key.Matches(msg, a.keys.Down):
-			if a.state == stateGenerations && a.cursor < len(a.generations)-1 {
-				a.cursor++
+			if a.state == stateGenerations && a.moveCursor(1) {
+				cmds = append(cmds, a.requestStats())
+			}
+			if a.state == stateDiff && a.diff != nil && a.modifiedCursor < len(a.visibleDiff(*a.diff).Modified)-1 {
+				a.modifiedCursor++
+			}
+
+		case key.Matches(msg, a.keys.PageUp):
+			if a.state == stateGenerations && a.moveCursor(-a.pageSize()) {
+				cmds = append(cmds, a.requestStats())
+			}
+
+		case key.Matches(msg, a.keys.PageDown):
+			if a.state == stateGenerations && a.moveCursor(a.pageSize()) {
+				cmds = append(cmds, a.requestStats())
 			}
 
 		case key.Matches(msg, a.keys.Select):
 			if a.state == stateGenerations {
 				if a.selected == nil {
-					a.selected = &a.generations[a.cursor]
-					a.generations[a.cursor].Selected = true
+					a.setFrom(a.cursor)
 				} else {
 					a.state = stateDiff
-					cmds = append(cmds, func() tea.Msg {
-						return a.fetchDiff(a.selected.ID, a.generations[a.cursor].ID)
-					})
+					cmds = append(cmds, a.loadDiff(a.selected.ID, a.generations[a.cursor].ID))
+				}
+			} else if a.state == stateDiff && !a.showFiles {
+				cmds = append(cmds, a.openDeps())
+			}
+
+		case key.Matches(msg, a.keys.From):
+			if a.state == stateGenerations {
+				a.toggleFrom()
+			}
+
+		case key.Matches(msg, a.keys.DiffFrom):
+			if a.state == stateGenerations {
+				if a.selected == nil {
+					a.setStatus(a.t("compare.noMark"))
+					break
 				}
+				a.state = stateDiff
+				cmds = append(cmds, a.loadDiff(a.selected.ID, a.generations[a.cursor].ID))
+			}
+
+		case key.Matches(msg, a.keys.Preview):
+			if a.state == stateGenerations {
+				a.pane.on = !a.pane.on
+			}
+
+		case key.Matches(msg, a.keys.Details):
+			if a.state == stateGenerations {
+				a.openDetails()
+			}
+
+		case key.Matches(msg, a.keys.Collapse):
+			if a.state == stateGenerations {
+				a.collapseDuplicates = !a.collapseDuplicates
+				a.clampCursor()
+			}
+
+		case key.Matches(msg, a.keys.FoldDay):
+			if a.state == stateGenerations && a.opts.DayGroups {
+				a.toggleDay()
+			}
+
+		case key.Matches(msg, a.keys.Help):
+			a.help.ShowAll = !a.help.ShowAll
+
+		case key.Matches(msg, a.keys.Hashes):
+			a.abbreviate = !a.abbreviate
+
+		case key.Matches(msg, a.keys.Explicit):
+			if a.state == stateDiff && a.diff != nil {
+				a.toggleExplicitOnly()
+			}
+
+		case key.Matches(msg, a.keys.Export):
+			if a.state == stateDiff && a.diff != nil {
+				a.askExport()
+			}
+
+		case key.Matches(msg, a.keys.Why):
+			if a.state == stateDiff && a.diff != nil {
+				cmds = append(cmds, a.whyModified())
+			}
+
+		case key.Matches(msg, a.keys.Files):
+			if a.state == stateDiff && a.diff != nil {
+				a.toggleFiles()
+			}
+
+		case key.Matches(msg, a.keys.AttrPaths):
+			if a.state == stateDiff && a.diff != nil {
+				a.toggleAttrPaths()
+			}
+
+		case key.Matches(msg, a.keys.Profile):
+			if a.state == stateGenerations {
+				cmds = append(cmds, a.switchProfile())
+			}
+
+		case key.Matches(msg, a.keys.Presets):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				a.state = statePresets
+			}
+
+		case key.Matches(msg, a.keys.Advise):
+			if a.state == stateGenerations && !a.loading {
+				cmds = append(cmds, a.adviseRollback())
+			}
+
+		case key.Matches(msg, a.keys.Log):
+			a.showLog = !a.showLog
+
+		case key.Matches(msg, a.keys.Group):
+			if a.state == stateDiff || a.state == stateDeps {
+				a.groupPrefixes = !a.groupPrefixes
+			}
+
+		case key.Matches(msg, a.keys.CopyCmd):
+			if a.state == stateDiff {
+				if line, ok := a.reproducer(); ok {
+					a.copyToClipboard(line)
+				} else {
+					a.setStatus(a.t("status.noReproducer"))
+				}
+			}
+
+		case key.Matches(msg, a.keys.Mark):
+			if a.state == stateGenerations {
+				before := maps.Clone(a.marked)
+				a.toggleMark()
+				a.recordMarks(before)
+			}
+
+		case key.Matches(msg, a.keys.Filter):
+			if a.state == stateGenerations {
+				a.startFilter()
+			}
+
+		case key.Matches(msg, a.keys.Snapshot):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				cmds = append(cmds, a.loadSnapshots)
+			}
+
+		case key.Matches(msg, a.keys.Find):
+			if a.state == stateGenerations {
+				a.askFindPackage()
+			}
+
+		case key.Matches(msg, a.keys.Timeline):
+			if a.state == stateGenerations {
+				a.askTimeline()
+			}
+
+		case key.Matches(msg, a.keys.Changelog):
+			if a.state == stateGenerations {
+				cmds = append(cmds, a.showChangelog())
+			}
+
+		case key.Matches(msg, a.keys.SizeHistory):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				cmds = append(cmds, a.showSizeHistory())
+			}
+
+		case key.Matches(msg, a.keys.Usage):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				cmds = append(cmds, a.showUsage())
+			}
+
+		case key.Matches(msg, a.keys.SaveGroup):
+			if a.state == stateGenerations && len(a.marked) > 0 {
+				a.askPrompt(a.t("groups.prompt", len(a.marked)), "", a.saveGroup)
+			}
+
+		case key.Matches(msg, a.keys.Groups):
+			if a.state == stateGenerations {
+				a.state = stateGroups
+				a.groupCursor = 0
+				cmds = append(cmds, a.loadGroups)
+			}
+
+		case key.Matches(msg, a.keys.Pending):
+			if a.state == stateGenerations {
+				cmds = append(cmds, a.startPendingDiff())
+			}
+
+		case key.Matches(msg, a.keys.Good):
+			if a.state == stateGenerations && len(a.marked) > 0 {
+				ids := a.markedIDs()
+				a.askConfirm(a.t("confirm.knownGoodBatch", len(ids)), a.privileged(func() tea.Msg {
+					return batchStartMsg{a.t("batch.knownGood"), ids, func(id string) error {
+						return a.client.MarkKnownGood(context.Background(), id)
+					}}
+				}))
+			} else if a.state == stateGenerations && len(a.generations) > 0 {
+				gen := a.generations[a.cursor]
+				a.askConfirm(
+					a.t("confirm.knownGood", gen.ID),
+					a.privileged(a.markKnownGood(gen.ID)),
+				)
+			}
+
+		case key.Matches(msg, a.keys.Rollback):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				cmds = append(cmds, a.previewRollback(a.generations[a.cursor].ID))
+			}
+
+		case key.Matches(msg, a.keys.Boot):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				gen := a.generations[a.cursor]
+				action := a.setBootDefault(gen.ID)
+				if prev := a.bootDefault(); prev != "" && prev != gen.ID {
+					action = a.undoable(action, systemStep(a.t("undo.boot", gen.ID),
+						a.setBootDefault(prev), a.setBootDefault(gen.ID)))
+				}
+				a.askConfirm(a.t("confirm.boot", gen.ID), a.privileged(action))
+			}
+
+		case key.Matches(msg, a.keys.Delete):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				a.askDelete()
+			}
+
+		case key.Matches(msg, a.keys.GC):
+			if a.state == stateGenerations {
+				a.askGC()
+			}
+
+		case key.Matches(msg, a.keys.Pin):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				a.askPin(a.generations[a.cursor])
+			}
+
+		case key.Matches(msg, a.keys.Retention):
+			if a.state == stateGenerations {
+				a.openRetention()
+			}
+
+		case key.Matches(msg, a.keys.Timestamps):
+			if a.state == stateGenerations {
+				a.timestamps = (a.timestamps + 1) % len(TimestampModes)
+				a.setStatus(a.t("status.timestamps", a.t("timestamps."+TimestampModes[a.timestamps])))
+			}
+
+		case key.Matches(msg, a.keys.Audit):
+			if a.state == stateGenerations {
+				cmds = append(cmds, a.showAudit())
+			}
+
+		case key.Matches(msg, a.keys.Trash):
+			if a.state == stateGenerations {
+				cmds = append(cmds, a.showTrash())
+			}
+
+		case key.Matches(msg, a.keys.Bisect):
+			if a.state == stateGenerations && len(a.generations) > 0 {
+				a.startBisect()
 			}
 
 		case key.Matches(msg, a.keys.Reload):
+			a.err = nil
+			a.errDetail = false
 			a.loading = true
-			cmds = append(cmds, a.fetchGenerations)
+			cmds = append(cmds, a.fetchGenerations())
+		}
+
+	case tea.MouseMsg:
+		cmds = append(cmds, a.updateMouse(msg))
+
+	case previewMsg:
+		a.confirmRollback(msg)
+
+	case prefetchTickMsg:
+		cmds = append(cmds, a.startPrefetch(msg))
+
+	case paneTickMsg:
+		cmds = append(cmds, a.fetchPane(msg))
+
+	case paneDiffMsg:
+		a.applyPaneDiff(msg)
+
+	case fleetHostMsg:
+		a.applyFleetHost(msg)
+
+	case hostGenerationsMsg:
+		if msg.client == a.client && a.state != stateFleet {
+			return a.Update(generationsMsg(msg.generations))
 		}
 
 	case tea.WindowSizeMsg:
@@ -179,34 +1205,210 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.ready = true
 
 	case generationsMsg:
+		if a.staleGenerations(msg) {
+			break
+		}
 		a.loading = false
+		from := a.selected
 		a.generations = msg
-		a.cursor = 0
+		if from != nil {
+			a.restoreFrom(from)
+		}
+		a.rollback = nil
+		a.now = time.Now()
+		a.lastRefresh = a.now
+		a.restoreFocus()
+		a.clampCursor()
+		cmds = append(cmds, a.requestStats())
+		if a.opts.Sizes {
+			cmds = append(cmds, a.fetchSizes())
+		}
+
+	case sizesMsg:
+		a.applySizes(msg)
+
+	case profilesMsg:
+		cmds = append(cmds, a.applyProfiles(msg))
+
+	case statsMsg:
+		a.stats[statsKey(msg.from, msg.to)] = msg.stats
+
+	case rollbackMsg:
+		a.applyRollbackAdvice(msg)
+
+	case deletedMsg:
+		cmds = append(cmds, a.applyDeleted(msg))
+
+	case undoableMsg:
+		a.undo.Do(msg.step)
+		cmds = append(cmds, func() tea.Msg { return msg.msg })
+
+	case replayedMsg:
+		cmds = append(cmds, a.applyReplayed(msg))
+
+	case profileChangedMsg:
+		cmds = append(cmds, a.reloadChanged(msg))
+
+	case watchedMsg:
+		cmds = append(cmds, a.applyWatched(msg))
+
+	case auditMsg:
+		a.applyAudit(msg)
+
+	case trashMsg:
+		a.applyTrash(msg)
+
+	case restoredMsg:
+		cmds = append(cmds, a.applyRestored(msg))
+
+	case trashEmptiedMsg:
+		a.setStatus(a.t("status.trashEmptied"))
+		cmds = append(cmds, a.showTrash())
+
+	case gcPreviewMsg:
+		a.applyGCPreview(msg)
+
+	case gcDoneMsg:
+		cmds = append(cmds, a.applyGCDone(msg))
+
+	case timelineMsg:
+		a.applyTimeline(msg)
+
+	case changelogMsg:
+		a.applyChangelog(msg)
+
+	case sizeHistoryMsg:
+		a.applySizeHistory(msg)
+
+	case usageMsg:
+		a.applyUsage(msg)
+
+	case whyMsg:
+		a.applyWhy(msg)
+
+	case closureMsg:
+		a.applyClosure(msg)
+
+	case retentionDoneMsg:
+		cmds = append(cmds, a.applyRetentionDone(msg))
+
+	case actionDoneMsg:
+		a.setStatus(msg.status)
+		cmds = append(cmds, a.refreshAfterAction(msg.focus))
+
+	case authorizedMsg:
+		cmds = append(cmds, msg.action)
+
+	case batchStartMsg:
+		cmds = append(cmds, a.startBatch(msg.label, msg.ids, msg.run))
+
+	case batchStepMsg:
+		cmds = append(cmds, a.updateBatch(msg))
+
+	case pendingCanceledMsg:
+		// Already back in the list; nothing to show.
+
+	case pendingFailedMsg:
+		// Leave the diff view so retrying returns to the list rather than
+		// to a build that is no longer running.
+		a.stopPendingDiff()
+		a.state = stateGenerations
+		a.err = msg.err
 
 	case diffMsg:
 		a.loading = false
+		a.cancelPending = nil
+		a.stream = nil
 		a.diff = (*models.GenerationDiff)(&msg)
+		a.modifiedCursor = 0
+
+	case diffChunkMsg:
+		cmds = append(cmds, a.applyDiffChunk(msg))
+
+	case depsMsg:
+		if a.deps != nil && a.deps.pkg == msg.pkg {
+			a.deps.diff = &msg.diff
+		}
+
+	case depsUnsupportedMsg:
+		a.state = stateDiff
+		a.deps = nil
+		a.setStatus(a.t("deps.unsupported"))
+
+	case snapshotsLoadedMsg:
+		a.askSnapshot(msg)
+
+	case logLineMsg:
+		cmds = append(cmds, a.appendLog(msg))
+
+	case filterDebounceMsg:
+		a.applyDebounced(msg)
+
+	case filterHistoryMsg:
+		a.filter.history = msg
+		a.filter.recall = len(msg)
+
+	case packageFoundMsg:
+		a.applySearch(msg)
+
+	case groupsLoadedMsg:
+		a.groupList = msg
+		if a.groupCursor >= len(a.groupList) {
+			a.groupCursor = 0
+		}
+
+	case groupSavedMsg:
+		a.setStatus(a.t("groups.saved", string(msg)))
+
+	case pagerDoneMsg:
+		if msg.err != nil {
+			a.err = msg.err
+		}
+
+	case configDiffMsg:
+		a.configDiff = (*models.ConfigDiff)(&msg)
+
+	case lockDiffMsg:
+		a.lockDiff = msg
+
+	case fileDiffMsg:
+		a.applyFileDiff(msg)
 
 	case errMsg:
 		a.err = msg.error
 		a.loading = false
 
+	case statusTickMsg:
+		a.now = time.Time(msg)
+		cmds = append(cmds, a.statusTick())
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		a.spinner, cmd = a.spinner.Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
+	cmds = append(cmds, a.schedulePane(), a.schedulePrefetch())
 	return a, tea.Batch(cmds...)
 }
 
 func (a *App) View() string {
+	if a.opts.ASCII {
+		return toASCII(a.screen())
+	}
+	return a.screen()
+}
+
+func (a *App) screen() string {
 	if !a.ready {
-		return "Initializing..."
+		return a.t("app.initializing")
 	}
 
+	if a.opts.MissingBackend != nil {
+		return a.renderSetup()
+	}
 	if a.err != nil {
-		return fmt.Sprintf("Error: %v\n\nPress 'r' to retry or 'q' to quit", a.err)
+		return a.renderError()
 	}
 
 	var content string
@@ -214,27 +1416,158 @@ func (a *App) View() string {
 	switch a.state {
 	case stateGenerations:
 		content = a.renderGenerations()
+		if a.splitView() {
+			content = a.joinPane(content)
+		}
 	case stateDiff:
 		content = a.renderDiff()
+	case stateConfirm:
+		content = a.renderConfirm()
+	case stateDetails:
+		content = a.renderDetails()
+	case statePrompt:
+		content = a.renderPrompt()
+	case stateGroups:
+		content = a.renderGroups()
+	case stateBatch:
+		content = a.renderBatch()
+	case stateDeps:
+		content = a.renderDeps()
+	case statePresets:
+		content = a.renderPresets()
+	case stateFleet:
+		content = a.renderFleet()
+	case stateGC:
+		content = a.renderGC()
+	case stateRetention:
+		content = a.renderRetention()
+	case stateBisect:
+		content = a.renderBisect()
+	case stateTimeline:
+		content = a.renderTimeline()
+	case stateChangelog:
+		content = a.renderChangelog()
+	case stateSizeHistory:
+		content = a.renderSizeHistory()
+	case stateUsage:
+		content = a.renderUsage()
+	case stateWhy:
+		content = a.renderWhy()
+	case stateClosure:
+		content = a.renderClosure()
+	case stateTrash:
+		content = a.renderTrash()
+	case stateAudit:
+		content = a.renderAudit()
 	}
 
 	if a.loading {
-		content = fmt.Sprintf("%s Loading...", a.spinner.View())
+		content = fmt.Sprintf("%s %s", a.spinner.View(), a.t("app.loading"))
+	}
+
+	var keys help.KeyMap = a.keys
+	switch a.state {
+	case stateDetails:
+		keys = detailsHelp{a.keys, a.detailsKeys}
+	case stateGroups:
+		keys = groupsHelp{a.keys, a.groupKeys}
+	case stateFleet:
+		keys = fleetHelp{a.keys}
+	}
+
+	if a.showLog {
+		content += "\n" + a.renderLogPanel()
 	}
 
-	return fmt.Sprintf("%s\n\n%s", content, a.help.View(a.keys))
+	if a.notice != "" {
+		content += "\n" + noticeStyle.Render(a.notice)
+	}
+	if status := a.renderStatusBar(); status != "" {
+		content += "\n" + status
+	}
+
+	return fmt.Sprintf("%s\n\n%s", content, a.help.View(keys))
 }
 
 func (a *App) renderGenerations() string {
 	var b strings.Builder
 
 	b.WriteString(titleStyle.Render("nix-timemach"))
+	b.WriteString(a.renderProfile())
+	if a.filterActive() {
+		b.WriteString(a.renderFilter())
+	}
 	b.WriteString("\n\n")
+	if a.noMatches() {
+		if a.filter.applied != "" {
+			b.WriteString("  " + a.t("filter.none", a.filter.applied) + "\n")
+		} else {
+			b.WriteString("  " + a.searchSummary() + "\n")
+		}
+	}
 
+	relWidth := 0
+	for _, gen := range a.generations {
+		relWidth = max(relWidth, utf8.RuneCountInString(a.relativeTime(gen.Timestamp)))
+	}
+	var rows []string
+	cursorRow := 0
 	for i, gen := range a.generations {
-		item := fmt.Sprintf("%s - %s", gen.Timestamp.Format("2006-01-02 15:04:05"), gen.Description)
+		if a.hidden(i) {
+			continue
+		}
+		heading := a.opts.DayGroups && a.groupLead(i)
+		if heading {
+			rows = append(rows, a.renderDayHeading(i))
+			if a.foldedDays[a.dayKey(i)] {
+				if i == a.cursor {
+					cursorRow = len(rows) - 1
+				}
+				continue
+			}
+		}
+		if i == a.cursor {
+			cursorRow = len(rows)
+			if heading && len(rows) == 1 {
+				// Keeps the first heading in view at the top of the list.
+				cursorRow = 0
+			}
+		}
+		var row strings.Builder
+
+		timestamp := a.stamp(gen.Timestamp, relWidth)
+		item := fmt.Sprintf("%s - %s", timestamp, gen.Description)
+		if a.collapseDuplicates {
+			if n := a.duplicateRun(i); n > 0 {
+				item += " " + a.t("list.identical", n)
+			}
+		}
+
+		mark := "  "
+		if a.marked[gen.ID] {
+			mark = "● "
+		}
 
 		style := itemStyle
+		switch {
+		case gen.KnownGood:
+			item = "✔ " + item
+			style = knownGoodStyle
+		case a.duplicateOfPrevious(i):
+			item = "≡ " + item
+			style = duplicateStyle
+		case gen.Selected:
+			item = "  " + item
+		default:
+			// Only plain rows get age colors: an inner style would reset
+			// the row's own color partway through.
+			if age, ok := a.ageStyle(gen.Timestamp); ok {
+				item = strings.Replace(item, timestamp, age.Render(timestamp), 1)
+			}
+			item = "  " + item
+		}
+
+		item = mark + item
 		if i == a.cursor {
 			item = "> " + item
 		} else {
@@ -245,51 +1578,172 @@ func (a *App) renderGenerations() string {
 			style = selectedItemStyle
 		}
 
-		b.WriteString(style.Render(item))
-		b.WriteString("\n")
+		row.WriteString(style.Render(item))
+		row.WriteString(a.renderEndpoint(i))
+		row.WriteString(a.renderPin(gen))
+		row.WriteString(a.renderBisectMark(gen))
+		row.WriteString(renderClosureSize(gen))
+		row.WriteString(a.renderVersions(i))
+		if gen.BootDefault {
+			row.WriteString("  " + presenceStyle.Render(a.t("list.bootDefault")))
+		}
+		if gen.Booted {
+			row.WriteString("  " + statsStyle.Render(a.t("list.booted")))
+		}
+		if prev := a.predecessor(i); prev >= 0 {
+			if stats, ok := a.stats[statsKey(a.generations[prev].ID, gen.ID)]; ok {
+				row.WriteString("  " + statsStyle.Render(formatStats(stats)))
+			}
+		}
+		if note, ok := a.rollbackAnnotation(gen.ID); ok {
+			row.WriteString("  " + note)
+		}
+		if p, ok := a.search.presence(gen.ID); ok && p.Present {
+			row.WriteString("  " + presenceStyle.Render(strings.TrimSpace(a.search.name+" "+p.Version)))
+		}
+		rows = append(rows, row.String())
 	}
+	b.WriteString(a.scrollRows(rows, cursorRow))
 
 	return b.String()
 }
 
 func (a *App) renderDiff() string {
 	if a.diff == nil {
-		return "Loading diff..."
+		if a.pending {
+			return a.renderPendingProgress()
+		}
+		if a.stream != nil && a.stream.total > 0 {
+			return a.renderStream()
+		}
+		return a.t("diff.loading")
 	}
 
 	var b strings.Builder
 
-	fromTime := a.selected.Timestamp.Format("2006-01-02 15:04:05")
-	toTime := a.generations[a.cursor].Timestamp.Format("2006-01-02 15:04:05")
-
-	b.WriteString(titleStyle.Render(fmt.Sprintf("Diff: %s → %s", fromTime, toTime)))
+	b.WriteString(titleStyle.Render(a.diffTitle()))
 	b.WriteString("\n\n")
 
-	if len(a.diff.Added) > 0 {
-		b.WriteString(lipgloss.NewStyle().Foreground(special).Render("Added:"))
+	if a.configDiff != nil && !a.configDiff.Empty() {
+		b.WriteString(a.renderConfigDiff())
+	}
+	if len(a.lockDiff) > 0 {
+		b.WriteString(a.renderLockDiff())
+	}
+
+	if a.showFiles {
+		b.WriteString(a.renderFileDiff())
+		return b.String()
+	}
+	b.WriteString(a.renderUnitDiff())
+
+	switch visible := a.visibleDiff(*a.diff); {
+	case a.diff.Empty():
+		b.WriteString(a.t("diff.none"))
+	case visible.Empty():
+		b.WriteString(a.t("diff.noExplicit"))
+	default:
+		b.WriteString(a.renderChanges(visible, a.modifiedCursor))
+	}
+
+	return b.String()
+}
+
+// renderChanges lists a diff's entries by kind. The modified entry at focus
+// is marked with a cursor; pass -1 for none.
+func (a *App) renderChanges(diff models.GenerationDiff, focus int) string {
+	if a.byAttrPath && hasAttrPaths(diff) {
+		return a.renderAttrTree(diff, focus)
+	}
+
+	var b strings.Builder
+
+	if len(diff.Added) > 0 {
+		b.WriteString(addedStyle.Render(a.t("diff.added")))
 		b.WriteString("\n")
-		for _, item := range a.diff.Added {
-			b.WriteString(fmt.Sprintf("  + %s\n", item))
-		}
+		b.WriteString(a.renderChangeList(diff, "+", diff.Added))
 		b.WriteString("\n")
 	}
 
-	if len(a.diff.Removed) > 0 {
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("Removed:"))
+	if len(diff.Removed) > 0 {
+		b.WriteString(removedStyle.Render(a.t("diff.removed")))
 		b.WriteString("\n")
-		for _, item := range a.diff.Removed {
-			b.WriteString(fmt.Sprintf("  - %s\n", item))
-		}
+		b.WriteString(a.renderChangeList(diff, "-", diff.Removed))
 		b.WriteString("\n")
 	}
 
-	if len(a.diff.Modified) > 0 {
-		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("Modified:"))
+	if len(diff.Modified) > 0 {
+		b.WriteString(modifiedStyle.Render(a.t("diff.modified")))
 		b.WriteString("\n")
-		for _, item := range a.diff.Modified {
-			b.WriteString(fmt.Sprintf("  ~ %s\n", item))
+		for i, item := range diff.Modified {
+			cursor := "  "
+			if i == focus {
+				cursor = "> "
+			}
+			b.WriteString(fmt.Sprintf("%s~ %s\n", cursor, a.renderChange(diff, item)))
 		}
 	}
 
 	return b.String()
 }
+
+func (a *App) diffTitle() string {
+	if a.pending {
+		return a.t("diff.pendingTitle")
+	}
+	if a.snapshot != "" {
+		return a.t("diff.snapshotTitle", a.snapshot, listing.Timestamp(a.generations[a.cursor].Timestamp))
+	}
+	from, to := *a.selected, a.generations[a.cursor]
+	title := a.t("diff.title", listing.Timestamp(from.Timestamp), listing.Timestamp(to.Timestamp))
+	switch n := a.between(from, to); {
+	case n == 1:
+		title += " " + a.t("diff.spanOne")
+	case n > 1:
+		title += " " + a.t("diff.span", n)
+	}
+	if to.Timestamp.Before(from.Timestamp) {
+		title += " " + a.t("diff.reversed")
+	}
+	if delta, ok := a.netSize(from, to); ok {
+		title += " " + a.t("diff.sizeDelta", signedSize(delta))
+	}
+	return title
+}
+
+func (a *App) renderConfigDiff() string {
+	var b strings.Builder
+
+	b.WriteString(headingStyle.Render(a.t("config.title")))
+	b.WriteString("\n")
+	for _, c := range a.configDiff.Inputs {
+		b.WriteString(fmt.Sprintf("  %s: %s → %s\n", a.t("config.input", c.Name), a.orNone(c.From), a.orNone(c.To)))
+	}
+	for _, c := range a.configDiff.Options {
+		b.WriteString(fmt.Sprintf("  %s: %s → %s\n", c.Name, a.orNone(c.From), a.orNone(c.To)))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func (a *App) orNone(s string) string {
+	if s == "" {
+		return a.t("config.none")
+	}
+	return s
+}
+
+// displayPath is how store paths are shown; copies and exports always use
+// the full path.
+func (a *App) displayPath(p string) string {
+	p = backend.Printable(p)
+	if !a.abbreviate {
+		return p
+	}
+	hashLen := a.opts.HashLen
+	if hashLen <= 0 {
+		hashLen = defaultHashLen
+	}
+	return abbreviateStorePath(p, hashLen)
+}
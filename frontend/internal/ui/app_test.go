@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// newTestApp is an App on the demo system with its list loaded, in a
+// terminal of 100 by 40.
+func newTestApp(t *testing.T, opts Options) *App {
+	t.Helper()
+	a := NewApp(backend.NewDemo(), opts)
+	a.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	a.Update(a.fetchGenerations()())
+	if len(a.generations) == 0 {
+		t.Fatal("the demo system has no generations")
+	}
+	return a
+}
+
+// press sends the key named s, as bubbletea would, and returns the command
+// the App answered with.
+func press(a *App, s string) tea.Cmd {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	switch s {
+	case " ":
+		msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	case "up":
+		msg = tea.KeyMsg{Type: tea.KeyUp}
+	case "ctrl+c":
+		msg = tea.KeyMsg{Type: tea.KeyCtrlC}
+	}
+	_, cmd := a.Update(msg)
+	return cmd
+}
+
+// quits reports whether cmd quits the program.
+func quits(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestQuitConfirmsMarkedGenerations(t *testing.T) {
+	tests := []struct {
+		name        string
+		confirmQuit bool
+		marks       int
+		wantConfirm bool
+	}{
+		{"nothing marked", true, 0, false},
+		{"one marked", true, 1, true},
+		{"several marked", true, 3, true},
+		{"confirmation off", false, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, Options{ConfirmQuit: tt.confirmQuit})
+			for range tt.marks {
+				press(a, " ")
+				press(a, "down")
+			}
+			if len(a.marked) != tt.marks {
+				t.Fatalf("marked %d generations, want %d", len(a.marked), tt.marks)
+			}
+
+			cmd := press(a, "q")
+			if tt.wantConfirm {
+				if a.state != stateConfirm || quits(cmd) {
+					t.Fatalf("q quit without asking, state %v", a.state)
+				}
+				if quits(press(a, "n")) || a.state != stateGenerations || len(a.marked) != tt.marks {
+					t.Fatalf("n didn't cancel quitting with the marks kept")
+				}
+				press(a, "q")
+				if !quits(press(a, "y")) {
+					t.Fatal("y didn't quit")
+				}
+				return
+			}
+			if a.state == stateConfirm || !quits(cmd) {
+				t.Fatalf("q asked first, want it to quit")
+			}
+		})
+	}
+}
+
+func TestCtrlCConfirmsMarkedGenerations(t *testing.T) {
+	a := newTestApp(t, Options{ConfirmQuit: true})
+	if !quits(press(a, "ctrl+c")) {
+		t.Fatal("ctrl+c with nothing marked didn't quit")
+	}
+
+	press(a, " ")
+	if quits(press(a, "ctrl+c")) || a.state != stateConfirm {
+		t.Fatalf("ctrl+c quit without asking, state %v", a.state)
+	}
+	if quits(press(a, "n")) || a.state != stateGenerations || len(a.marked) != 1 {
+		t.Fatal("n didn't cancel quitting with the mark kept")
+	}
+	press(a, "ctrl+c")
+	if !quits(press(a, "ctrl+c")) {
+		t.Fatal("a second ctrl+c didn't quit")
+	}
+}
+
+// apply runs cmd and hands its message to the App, returning the command
+// the App answered with.
+func apply(a *App, cmd tea.Cmd) tea.Cmd {
+	_, next := a.Update(cmd())
+	return next
+}
+
+func TestListReloadsAfterActions(t *testing.T) {
+	tests := []struct {
+		name string
+		// refocus moves the cursor away first, as the action has to bring
+		// it back to the generation it acted on.
+		refocus bool
+		// act runs the action on generation id, which starts at index pos.
+		act func(a *App, id string) tea.Cmd
+		// check inspects the reloaded list.
+		check func(t *testing.T, a *App, id string, pos int)
+	}{
+		{
+			name: "delete",
+			act: func(a *App, id string) tea.Cmd {
+				return apply(a, a.deleteGenerations([]string{id}))
+			},
+			check: func(t *testing.T, a *App, id string, pos int) {
+				for _, gen := range a.generations {
+					if gen.ID == id {
+						t.Fatalf("generation %s is still listed", id)
+					}
+				}
+				if a.cursor != pos {
+					t.Errorf("cursor at %d, want %d on the deleted row's neighbour", a.cursor, pos)
+				}
+			},
+		},
+		{
+			name:    "pin",
+			refocus: true,
+			act: func(a *App, id string) tea.Cmd {
+				return a.pin(id, "keep")
+			},
+			check: func(t *testing.T, a *App, id string, pos int) {
+				gen := a.generations[a.cursor]
+				if gen.ID != id {
+					t.Fatalf("cursor on %s, want the pinned %s", gen.ID, id)
+				}
+				if !gen.Pinned || gen.PinName != "keep" {
+					t.Errorf("generation %s not shown pinned: %+v", id, gen)
+				}
+			},
+		},
+		{
+			name:    "rollback",
+			refocus: true,
+			act: func(a *App, id string) tea.Cmd {
+				return a.rollbackTo(id)
+			},
+			check: func(t *testing.T, a *App, id string, pos int) {
+				gen := a.generations[a.cursor]
+				if gen.ID != id {
+					t.Fatalf("cursor on %s, want %s rolled back to", gen.ID, id)
+				}
+				if !gen.Current {
+					t.Errorf("generation %s not shown current", id)
+				}
+				for _, other := range a.generations {
+					if other.Current && other.ID != id {
+						t.Errorf("generation %s still shown current", other.ID)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, Options{})
+			pos := -1
+			for i, gen := range a.generations {
+				if i > 0 && !gen.Current && !gen.Pinned {
+					pos = i
+					break
+				}
+			}
+			if pos < 0 {
+				t.Fatal("the demo system has no generation to act on")
+			}
+			id := a.generations[pos].ID
+			a.cursor = pos
+			if tt.refocus {
+				a.cursor = 0
+			}
+			n := len(a.generations)
+
+			apply(a, tt.act(a, id))
+			if !a.loading {
+				t.Fatal("the list wasn't reloaded after the action")
+			}
+			a.Update(a.fetchGenerations()())
+			if a.loading {
+				t.Fatal("the reloaded list wasn't shown")
+			}
+			if tt.refocus && len(a.generations) != n {
+				t.Errorf("%d generations listed, want %d", len(a.generations), n)
+			}
+			tt.check(t, a, id, pos)
+		})
+	}
+}
+
+// The profile to load is read when the Cmd is made: it runs on its own
+// goroutine while Update goes on, here switching profiles. Run with -race.
+func TestFetchGenerationsReadsProfileFirst(t *testing.T) {
+	a := newTestApp(t, Options{})
+	a.profiles = []models.Profile{{Name: "system", Path: "/nix/var/nix/profiles/system"}, {Name: "user", Path: "/home/user/.nix-profile"}}
+	a.profileIndex = 0
+	cmd := a.fetchGenerations()
+	done := make(chan tea.Msg)
+	go func() { done <- cmd() }()
+	a.profileIndex = 1
+	if _, ok := (<-done).(generationsMsg); !ok {
+		t.Error("fetchGenerations didn't load the generations")
+	}
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// asciiGlyphs are the UI's own symbols, arrows, borders and chart blocks
+// by their nearest ASCII look-alike, one character for one so that columns
+// stay aligned.
+var asciiGlyphs = strings.NewReplacer(
+	"→", ">", "←", "<", "↑", "^", "↓", "v",
+	"▸", ">", "▾", "v", "▼", "v",
+	"·", "-", "•", "*", "●", "*", "★", "*", "◆", "*", "◇", "o",
+	"✔", "+", "✓", "+", "✗", "x", "≡", "=", "⚑", "!", "⏻", "B", "⟲", "@",
+	"…", ".", "–", "-", "—", "-", "�", "?",
+	"─", "-", "━", "-", "│", "|", "┃", "|",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+",
+	"├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+",
+	"▁", "_", "▂", ".", "▃", "-", "▄", "=", "▅", "+", "▆", "*", "▇", "#", "█", "#", "░", ":",
+)
+
+// toASCII returns s with nothing outside ASCII left in it: the UI's glyphs
+// are swapped for asciiGlyphs, and anything else, like a description
+// written in another script, becomes '?'.
+func toASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '?'
+		}
+		return r
+	}, asciiGlyphs.Replace(s))
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// attrNode is one segment of an attribute path in the diff tree, e.g.
+// "systemPackages" in environment.systemPackages.firefox.
+type attrNode struct {
+	name     string
+	children map[string]*attrNode
+	changes  []attrChange
+}
+
+// attrChange is a diff entry placed in the attribute tree, with the sign of
+// the list it came from.
+type attrChange struct {
+	sign   string
+	change models.PackageChange
+	focus  bool
+}
+
+// hasAttrPaths reports whether the backend attributed any entry of diff to
+// an attribute path.
+func hasAttrPaths(diff models.GenerationDiff) bool {
+	for _, changes := range [][]models.PackageChange{diff.Added, diff.Removed, diff.Modified} {
+		for _, c := range changes {
+			if c.AttrPath != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *App) toggleAttrPaths() {
+	if !hasAttrPaths(*a.diff) {
+		a.setStatus(a.t("diff.attrUnknown"))
+		return
+	}
+	a.byAttrPath = !a.byAttrPath
+}
+
+// attrTree arranges diff's entries by attribute path. Entries the backend
+// couldn't attribute are collected under a node of their own.
+func (a *App) attrTree(diff models.GenerationDiff, focus int) *attrNode {
+	root := &attrNode{}
+	add := func(sign string, c models.PackageChange, focus bool) {
+		path := c.AttrPath
+		if path == "" {
+			path = a.t("diff.attrNone")
+		}
+		node := root
+		for _, seg := range strings.Split(path, ".") {
+			if node.children == nil {
+				node.children = make(map[string]*attrNode)
+			}
+			child, ok := node.children[seg]
+			if !ok {
+				child = &attrNode{name: seg}
+				node.children[seg] = child
+			}
+			node = child
+		}
+		node.changes = append(node.changes, attrChange{sign: sign, change: c, focus: focus})
+	}
+
+	for _, c := range diff.Added {
+		add("+", c, false)
+	}
+	for _, c := range diff.Removed {
+		add("-", c, false)
+	}
+	for i, c := range diff.Modified {
+		add("~", c, i == focus)
+	}
+	return root
+}
+
+// renderAttrTree draws diff as a tree of attribute paths. A segment with a
+// single child and no entries of its own is joined with that child, so
+// long chains stay on one line.
+func (a *App) renderAttrTree(diff models.GenerationDiff, focus int) string {
+	var b strings.Builder
+	var walk func(node *attrNode, depth int)
+	walk = func(node *attrNode, depth int) {
+		names := make([]string, 0, len(node.children))
+		for name := range node.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			child := node.children[name]
+			label := child.name
+			for len(child.changes) == 0 && len(child.children) == 1 {
+				for _, only := range child.children {
+					child = only
+				}
+				label += "." + child.name
+			}
+
+			indent := strings.Repeat("  ", depth+1)
+			b.WriteString(indent + headingStyle.Render(label) + "\n")
+			for _, c := range child.changes {
+				cursor := "  "
+				if c.focus {
+					cursor = "> "
+				}
+				b.WriteString(indent + cursor + c.sign + " " + a.renderChange(diff, c.change) + "\n")
+			}
+			walk(child, depth+1)
+		}
+	}
+	walk(a.attrTree(diff, focus), 0)
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/audit"
+	"nix-timemach/internal/listing"
+)
+
+// auditView is the audit log, newest entry first.
+type auditView struct {
+	entries []audit.Entry
+	table   *table
+}
+
+type auditMsg []audit.Entry
+
+// showAudit loads the audit log.
+func (a *App) showAudit() tea.Cmd {
+	a.loading = true
+	return func() tea.Msg {
+		entries, err := audit.Load()
+		if err != nil {
+			return errMsg{err}
+		}
+		return auditMsg(entries)
+	}
+}
+
+func (a *App) applyAudit(msg auditMsg) {
+	a.loading = false
+	entries := slices.Clone(msg)
+	slices.Reverse(entries)
+
+	hosts := slices.ContainsFunc(entries, func(e audit.Entry) bool { return e.Host != "" })
+	cells := make([][]string, len(entries))
+	for i, e := range entries {
+		user := e.User
+		if e.SudoUser != "" {
+			user = a.t("audit.sudo", e.SudoUser, e.User)
+		}
+		result := addedStyle.Render(a.t("audit.ok"))
+		if e.Error != "" {
+			first, _, _ := strings.Cut(e.Error, "\n")
+			result = removedStyle.Render(a.t("audit.failed", first))
+		}
+		row := []string{listing.Timestamp(e.Time), user}
+		if hosts {
+			row = append(row, cmp.Or(e.Host, a.t("audit.localhost")))
+		}
+		cells[i] = append(row, e.Action, strings.Join(e.Targets, ", "), e.Detail, result)
+	}
+
+	columns := []tableColumn{
+		{title: a.t("audit.time"), width: len(listing.Timestamp(a.now))},
+		{title: a.t("audit.user"), width: 16},
+	}
+	if hosts {
+		columns = append(columns, tableColumn{title: a.t("audit.host"), width: 16})
+	}
+	columns = append(columns,
+		tableColumn{title: a.t("audit.action"), width: 16},
+		tableColumn{title: a.t("audit.targets"), width: 16},
+		tableColumn{title: a.t("audit.detail"), width: 12},
+		tableColumn{title: a.t("audit.result"), width: 40},
+	)
+	a.audit = &auditView{entries: entries, table: newTable(columns, cells, -1)}
+	a.state = stateAudit
+}
+
+func (a *App) updateAudit(msg tea.KeyMsg) tea.Cmd {
+	t := a.audit.table
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		t.move(-1, a.usageHeight()-1)
+	case key.Matches(msg, a.keys.Down):
+		t.move(1, a.usageHeight()-1)
+	case key.Matches(msg, a.keys.PageUp):
+		t.move(-a.pageSize(), a.usageHeight()-1)
+	case key.Matches(msg, a.keys.PageDown):
+		t.move(a.pageSize(), a.usageHeight()-1)
+	case key.Matches(msg, a.keys.Select):
+		// The table cuts errors short; the status line has room for
+		// the first line of one.
+		if len(t.order) > 0 {
+			if e := a.audit.entries[t.selected()]; e.Error != "" {
+				first, _, _ := strings.Cut(e.Error, "\n")
+				a.setStatus(first)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *App) renderAudit() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("audit.title")) + "\n\n")
+	if len(a.audit.entries) == 0 {
+		b.WriteString(itemStyle.Render(a.t("audit.empty")) + "\n")
+		return b.String()
+	}
+	if p, err := audit.Path(); err == nil {
+		b.WriteString("  " + statsStyle.Render(a.t("audit.summary", len(a.audit.entries), p)) + "\n\n")
+	}
+	b.WriteString(a.audit.table.render(a.usageHeight()))
+	b.WriteString("\n" + statsStyle.Render(a.t("audit.choices")))
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// batch runs one backend action per generation, one at a time, so progress
+// can be shown and the remainder cancelled.
+type batch struct {
+	label    string
+	ids      []string
+	run      func(id string) error
+	next     int
+	failed   []string
+	canceled bool
+	bar      progress.Model
+}
+
+type batchStepMsg struct {
+	id  string
+	err error
+}
+
+// startBatch switches to the progress view and runs the first step.
+func (a *App) startBatch(label string, ids []string, run func(id string) error) tea.Cmd {
+	bar := progress.New(progress.WithDefaultGradient())
+	if a.width > 8 {
+		bar.Width = a.width - 8
+	}
+	a.batch = &batch{label: label, ids: ids, run: run, bar: bar}
+	a.state = stateBatch
+	return a.batchStep()
+}
+
+func (a *App) batchStep() tea.Cmd {
+	b := a.batch
+	id := b.ids[b.next]
+	return func() tea.Msg {
+		return batchStepMsg{id: id, err: b.run(id)}
+	}
+}
+
+// updateBatch records a finished step and either starts the next one or
+// wraps up with a summary.
+func (a *App) updateBatch(msg batchStepMsg) tea.Cmd {
+	b := a.batch
+	if b == nil {
+		return nil
+	}
+	b.next++
+	if msg.err != nil {
+		b.failed = append(b.failed, msg.id)
+	}
+
+	if !b.canceled && b.next < len(b.ids) {
+		return a.batchStep()
+	}
+
+	succeeded := b.next - len(b.failed)
+	summary := a.t("batch.summary", b.label, succeeded, len(b.failed))
+	if skipped := len(b.ids) - b.next; skipped > 0 {
+		summary += " " + a.t("batch.skipped", skipped)
+	}
+	if len(b.failed) > 0 {
+		summary += " " + a.t("batch.failedIDs", strings.Join(b.failed, ", "))
+	}
+
+	var focus string
+	if a.cursor < len(a.generations) {
+		focus = a.generations[a.cursor].ID
+	}
+
+	a.batch = nil
+	a.state = stateGenerations
+	marked := a.marked
+	a.marked = make(map[string]bool)
+	a.recordMarks(marked)
+	return func() tea.Msg { return actionDoneMsg{status: summary, focus: focus} }
+}
+
+func (a *App) renderBatch() string {
+	b := a.batch
+	var s strings.Builder
+
+	s.WriteString(titleStyle.Render(b.label))
+	s.WriteString("\n\n  ")
+	s.WriteString(b.bar.ViewAs(float64(b.next) / float64(len(b.ids))))
+	s.WriteString("\n\n")
+	s.WriteString(itemStyle.Render(a.t("batch.progress", b.next, len(b.ids))))
+	s.WriteString("\n")
+	if b.next < len(b.ids) {
+		s.WriteString(itemStyle.Render(fmt.Sprintf("%s %s", a.spinner.View(), a.t("batch.current", b.ids[b.next]))))
+		s.WriteString("\n")
+	}
+	if b.canceled {
+		s.WriteString(itemStyle.Render(a.t("batch.canceling")))
+		s.WriteString("\n")
+	}
+
+	return s.String()
+}
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/bisect"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+var (
+	bisectGood   = key.NewBinding(key.WithKeys("g"))
+	bisectBad    = key.NewBinding(key.WithKeys("b"))
+	bisectSwitch = key.NewBinding(key.WithKeys("s"))
+	bisectBoot   = key.NewBinding(key.WithKeys("o"))
+	bisectDiff   = key.NewBinding(key.WithKeys("d"))
+	bisectEnd    = key.NewBinding(key.WithKeys("e"))
+)
+
+// startBisect opens the bisection in progress, or sets one up: the first
+// press marks the focused generation bad, the second marks another good
+// and starts. Pressing it again on the bad one cancels.
+func (a *App) startBisect() {
+	if a.bisect == nil {
+		s, err := bisect.Load()
+		if err != nil {
+			a.setStatus(err.Error())
+			return
+		}
+		a.bisect = s
+	}
+	if a.bisect != nil {
+		a.state = stateBisect
+		return
+	}
+
+	gen := a.generations[a.cursor]
+	switch a.bisectBad {
+	case "":
+		a.bisectBad = gen.ID
+		a.setStatus(a.t("bisect.markedBad", gen.ID))
+	case gen.ID:
+		a.bisectBad = ""
+		a.setStatus(a.t("bisect.cleared"))
+	default:
+		s := bisect.Session{Good: gen.ID, Bad: a.bisectBad, Started: time.Now()}
+		if err := bisect.Save(s); err != nil {
+			a.setStatus(err.Error())
+			return
+		}
+		a.bisect = &s
+		a.bisectBad = ""
+		a.state = stateBisect
+	}
+}
+
+func (a *App) generationIDs() []string {
+	ids := make([]string, len(a.generations))
+	for i, gen := range a.generations {
+		ids[i] = gen.ID
+	}
+	return ids
+}
+
+func (a *App) updateBisect(msg tea.KeyMsg) tea.Cmd {
+	s := a.bisect
+	next, testing := s.Next(a.generationIDs())
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+
+	case key.Matches(msg, bisectEnd):
+		if err := bisect.Clear(); err != nil {
+			a.setStatus(err.Error())
+			return nil
+		}
+		a.bisect = nil
+		a.state = stateGenerations
+		a.setStatus(a.t("bisect.ended"))
+
+	case key.Matches(msg, bisectDiff):
+		if testing {
+			return a.bisectDiff(s.Good, next)
+		}
+		return a.bisectDiff(s.Good, s.Bad)
+
+	case !testing:
+		// Nothing left to test; only the keys above apply.
+
+	case key.Matches(msg, bisectGood, bisectBad):
+		s.Mark(next, key.Matches(msg, bisectGood))
+		if err := bisect.Save(*s); err != nil {
+			a.setStatus(err.Error())
+		}
+
+	case key.Matches(msg, bisectSwitch, bisectBoot):
+		if a.opts.ReadOnly {
+			a.setStatus(a.t("status.readOnly"))
+			return nil
+		}
+		if key.Matches(msg, bisectSwitch) {
+			a.askConfirm(a.t("confirm.rollback", next), a.privileged(a.rollbackTo(next)))
+		} else {
+			a.askConfirm(a.t("confirm.boot", next), a.privileged(a.setBootDefault(next)))
+		}
+	}
+	return nil
+}
+
+// bisectDiff opens the diff from one generation of the bisection to
+// another, as if the first had been marked with m.
+func (a *App) bisectDiff(from, to string) tea.Cmd {
+	i, j := a.generationIndex(from), a.generationIndex(to)
+	if i < 0 || j < 0 {
+		a.setStatus(a.t("bisect.missing"))
+		return nil
+	}
+	a.cursor = j
+	return a.showDiff(i)
+}
+
+// generationIndex returns the index of the generation with id, or -1.
+func (a *App) generationIndex(id string) int {
+	for i, gen := range a.generations {
+		if gen.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderBisectMark labels the generations a bisection is between, and the
+// one it is testing, in the list.
+func (a *App) renderBisectMark(gen models.Generation) string {
+	s := a.bisect
+	switch {
+	case s == nil && gen.ID == a.bisectBad:
+		return "  " + removedStyle.Render(a.t("bisect.bad"))
+	case s == nil:
+		return ""
+	case gen.ID == s.Good:
+		return "  " + addedStyle.Render(a.t("bisect.good"))
+	case gen.ID == s.Bad:
+		return "  " + removedStyle.Render(a.t("bisect.bad"))
+	}
+	if next, ok := s.Next(a.generationIDs()); ok && next == gen.ID {
+		return "  " + presenceStyle.Render(a.t("bisect.next"))
+	}
+	return ""
+}
+
+func (a *App) renderBisect() string {
+	s := a.bisect
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("bisect.title", s.Good, s.Bad)) + "\n\n")
+
+	next, ok := s.Next(a.generationIDs())
+	if !ok {
+		b.WriteString(a.t("bisect.found", s.Bad, s.Good) + "\n\n")
+		b.WriteString(statsStyle.Render(a.t("bisect.doneChoices")))
+		return b.String()
+	}
+
+	left := len(s.Remaining(a.generationIDs()))
+	b.WriteString(a.t("bisect.left", left, bisect.Steps(left)) + "\n\n")
+	gen := a.generations[a.generationIndex(next)]
+	line := a.t("bisect.test", gen.ID) + " " + fmt.Sprintf("%s - %s", listing.Timestamp(gen.Timestamp), gen.Description)
+	if gen.Current || gen.Booted {
+		line += "  " + presenceStyle.Render(a.t("bisect.running"))
+	}
+	b.WriteString(headingStyle.Render(line) + "\n\n")
+	b.WriteString(statsStyle.Render(a.t("bisect.choices")))
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/export"
+	"nix-timemach/internal/models"
+)
+
+// changelog is what every generation between two changed, grouped by day.
+type changelog struct {
+	from, to string
+	days     []export.Day
+	top      int
+}
+
+type changelogMsg struct {
+	from, to string
+	days     []export.Day
+}
+
+// showChangelog loads the changelog from the marked generation to the
+// focused one.
+func (a *App) showChangelog() tea.Cmd {
+	if a.selected == nil {
+		a.setStatus(a.t("compare.noMark"))
+		return nil
+	}
+	in, err := export.Range(a.generations, a.selected.ID, a.generations[a.cursor].ID)
+	if err != nil {
+		a.setStatus(err.Error())
+		return nil
+	}
+	a.loading = true
+	ctx := a.profileContext()
+	from, to := in[0].ID, in[len(in)-1].ID
+	return func() tea.Msg {
+		diffs, err := a.client.GetDiffsBulk(ctx, export.Pairs(in))
+		if err != nil {
+			return errMsg{err}
+		}
+		return changelogMsg{from: from, to: to, days: export.Days(in, diffs)}
+	}
+}
+
+func (a *App) applyChangelog(msg changelogMsg) {
+	a.loading = false
+	a.changelog = &changelog{from: msg.from, to: msg.to, days: msg.days}
+	a.state = stateChangelog
+}
+
+func (a *App) updateChangelog(msg tea.KeyMsg) tea.Cmd {
+	c := a.changelog
+	last := max(0, len(a.changelogLines())-a.listHeight())
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		c.top = max(0, c.top-1)
+	case key.Matches(msg, a.keys.Down):
+		c.top = min(last, c.top+1)
+	case key.Matches(msg, a.keys.PageUp):
+		c.top = max(0, c.top-a.pageSize())
+	case key.Matches(msg, a.keys.PageDown):
+		c.top = min(last, c.top+a.pageSize())
+	case key.Matches(msg, a.keys.Export):
+		a.askChangelogExport()
+	}
+	return nil
+}
+
+func (a *App) changelogTitle() string {
+	return a.t("changelog.title", a.changelog.from, a.changelog.to)
+}
+
+// askChangelogExport asks where to write the changelog, picking the format
+// from the file's extension.
+func (a *App) askChangelogExport() {
+	c := a.changelog
+	name := fmt.Sprintf("nix-timemach-changelog-%s-%s.md", c.from, c.to)
+	a.askPrompt(a.t("changelog.exportPrompt"), name, func(path string) tea.Cmd {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+		format, err := export.FormatOf(path)
+		if err == nil {
+			var b strings.Builder
+			err = export.Changelog(&b, format, a.changelogTitle(), c.days)
+			if err == nil {
+				err = os.WriteFile(path, []byte(b.String()), 0o644)
+			}
+		}
+		if err != nil {
+			a.setStatus(a.t("changelog.exportFailed", err))
+			return nil
+		}
+		a.setStatus(a.t("changelog.exportDone", path))
+		return nil
+	})
+}
+
+// changelogLines lays the changelog out a line at a time, for scrolling.
+func (a *App) changelogLines() []string {
+	var lines []string
+	for i, day := range a.changelog.days {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, headingStyle.Render(day.Date))
+		for _, c := range day.Changes {
+			gen := c.Generation
+			lines = append(lines, "  "+a.t("changelog.generation", gen.ID)+" "+statsStyle.Render(gen.Description))
+			for _, s := range []struct {
+				sign    string
+				changes []models.PackageChange
+			}{
+				{addedStyle.Render("+"), c.Diff.Added},
+				{removedStyle.Render("-"), c.Diff.Removed},
+				{modifiedStyle.Render("~"), c.Diff.Modified},
+			} {
+				for _, change := range s.changes {
+					lines = append(lines, "    "+s.sign+" "+a.renderChange(c.Diff, change))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+func (a *App) renderChangelog() string {
+	c := a.changelog
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.changelogTitle()) + "\n\n")
+	lines := a.changelogLines()
+	if len(lines) == 0 {
+		b.WriteString(a.t("changelog.empty") + "\n")
+	}
+	end := min(len(lines), c.top+a.listHeight())
+	for _, line := range lines[min(c.top, end):end] {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("changelog.choices")))
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package ui
+
+import "strings"
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a one-line chart at most width runes wide.
+// With more values than fit, each rune shows the largest of the values it
+// covers. Heights are scaled between the smallest and largest value, as
+// closure sizes tend to differ by little next to their total.
+func sparkline(values []int64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+	if len(values) > width {
+		buckets := make([]int64, width)
+		for i, v := range values {
+			j := i * width / len(values)
+			buckets[j] = max(buckets[j], v)
+		}
+		values = buckets
+	}
+	lo, hi := chartRange(values)
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteRune(sparkTicks[scale(v, lo, hi, len(sparkTicks)-1)])
+	}
+	return b.String()
+}
+
+// bar draws value as a horizontal bar out of width runes, scaled between
+// lo and hi. It is never empty, so the smallest value still shows.
+func bar(value, lo, hi int64, width int) string {
+	n := 1 + scale(value, lo, hi, width-1)
+	return strings.Repeat("█", n) + strings.Repeat("░", max(0, width-n))
+}
+
+func chartRange(values []int64) (lo, hi int64) {
+	lo, hi = values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, hi
+}
+
+// scale maps v from lo..hi onto 0..steps.
+func scale(v, lo, hi int64, steps int) int {
+	if hi <= lo || steps <= 0 {
+		return steps
+	}
+	return int((v - lo) * int64(steps) / (hi - lo))
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"github.com/muesli/termenv"
+	"nix-timemach/internal/shell"
+)
+
+// copyToClipboard copies text via the terminal's OSC 52 support, which also
+// works over SSH, and confirms it in the status bar.
+func (a *App) copyToClipboard(text string) {
+	termenv.Copy(text)
+	a.setStatus(a.t("status.copied", text))
+}
+
+// reproducer returns the headless command that prints the diff currently
+// on screen, for bug reports and scripts.
+func (a *App) reproducer() (string, bool) {
+	if a.pending || a.cursor >= len(a.generations) {
+		return "", false
+	}
+	var args []string
+	switch {
+	case a.snapshot != "":
+		args = []string{"nix-timemach", "snapshot", "diff", a.snapshot, a.generations[a.cursor].ID}
+	case a.selected != nil:
+		args = []string{"nix-timemach", "diff", a.selected.ID, a.generations[a.cursor].ID}
+	default:
+		return "", false
+	}
+	line := shell.CommandLine(args...)
+	if store := a.client.Store(); store != "" {
+		line = "NIX_TIMEMACH_STORE=" + shell.Quote(store) + " " + line
+	}
+	if host := a.client.Host(); host != "" {
+		line = "NIX_TIMEMACH_HOST=" + shell.Quote(host) + " " + line
+	}
+	return line, true
+}
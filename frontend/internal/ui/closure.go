@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// closureView is a generation's runtime closure as a tree of references,
+// opened a level at a time.
+type closureView struct {
+	id    string
+	graph *models.ClosureGraph
+	tree  *tree
+}
+
+type closureMsg struct {
+	id    string
+	nodes []models.StoreNode
+}
+
+// showClosure loads the closure of the focused generation.
+func (a *App) showClosure() tea.Cmd {
+	id := a.generations[a.cursor].ID
+	a.loading = true
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		nodes, err := a.client.GetClosureGraph(ctx, id)
+		if errors.Is(err, backend.ErrUnsupported) {
+			return errMsg{errors.New(a.t("closure.unsupported"))}
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return closureMsg{id: id, nodes: nodes}
+	}
+}
+
+func (a *App) applyClosure(msg closureMsg) {
+	a.loading = false
+	c := &closureView{id: msg.id, graph: models.NewClosureGraph(msg.nodes), tree: &tree{}}
+	// The generation is the path nothing else in its closure refers to.
+	referenced := make(map[string]bool)
+	for _, n := range msg.nodes {
+		for _, ref := range n.References {
+			referenced[ref] = true
+		}
+	}
+	for _, n := range msg.nodes {
+		if !referenced[n.Path] {
+			c.tree.root = a.closureItem(c.graph, n.Path)
+			c.tree.root.children, c.tree.root.more = c.tree.root.more(), nil
+			c.tree.root.open = true
+			break
+		}
+	}
+	a.closure = c
+	a.state = stateClosure
+}
+
+// closureItem is path in the tree, labelled with its own size and its
+// closure's. Its references follow when it is opened, largest closure
+// first.
+func (a *App) closureItem(g *models.ClosureGraph, path string) *treeItem {
+	n, _ := g.Node(path)
+	_, name, version := models.ParseStorePath(path)
+	label := backend.Printable(strings.TrimSpace(name+" "+version)) + "  " +
+		statsStyle.Render(a.t("closure.sizes", listing.HumanSize(n.Size), listing.HumanSize(g.ClosureSize(path))))
+	item := &treeItem{label: label}
+	if len(n.References) > 0 {
+		item.more = func() []*treeItem {
+			refs := slices.Clone(n.References)
+			slices.SortFunc(refs, func(x, y string) int {
+				return cmp.Or(cmp.Compare(g.ClosureSize(y), g.ClosureSize(x)), strings.Compare(x, y))
+			})
+			children := make([]*treeItem, len(refs))
+			for i, ref := range refs {
+				children[i] = a.closureItem(g, ref)
+			}
+			return children
+		}
+	}
+	return item
+}
+
+func (a *App) updateClosure(msg tea.KeyMsg) tea.Cmd {
+	t := a.closure.tree
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateDetails
+	case t.root == nil:
+	case key.Matches(msg, a.keys.Up):
+		t.move(-1, a.listHeight())
+	case key.Matches(msg, a.keys.Down):
+		t.move(1, a.listHeight())
+	case key.Matches(msg, a.keys.PageUp):
+		t.move(-a.pageSize(), a.listHeight())
+	case key.Matches(msg, a.keys.PageDown):
+		t.move(a.pageSize(), a.listHeight())
+	case key.Matches(msg, treeExpand, a.keys.Select):
+		t.expand(a.listHeight())
+	case key.Matches(msg, treeCollapse):
+		t.collapse(a.listHeight())
+	}
+	return nil
+}
+
+func (a *App) renderClosure() string {
+	c := a.closure
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("closure.title", c.id)) + "\n\n")
+	if c.tree.root == nil {
+		b.WriteString(a.t("closure.empty") + "\n")
+	} else {
+		b.WriteString(c.tree.render(a.listHeight()))
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("tree.choices")))
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package ui
+
+import "nix-timemach/internal/models"
+
+// setFrom makes generations[i] the start of the next diff.
+func (a *App) setFrom(i int) {
+	a.clearFrom()
+	a.selected = &a.generations[i]
+	a.generations[i].Selected = true
+}
+
+// clearFrom forgets the start of the next diff.
+func (a *App) clearFrom() {
+	for i := range a.generations {
+		a.generations[i].Selected = false
+	}
+	a.selected = nil
+}
+
+// restoreFrom keeps the start of the next diff across a reload of the
+// list. If the generation is gone the mark is dropped, unless a diff from
+// it is open; that diff keeps its old copy.
+func (a *App) restoreFrom(from *models.Generation) {
+	for i, gen := range a.generations {
+		if gen.ID == from.ID {
+			a.setFrom(i)
+			return
+		}
+	}
+	if a.state != stateDiff && a.state != stateDeps {
+		a.selected = nil
+	}
+}
+
+// toggleFrom marks the cursor's generation as the start of the next diff,
+// or unmarks it if it already is.
+func (a *App) toggleFrom() {
+	gen := a.generations[a.cursor]
+	if a.selected != nil && a.selected.ID == gen.ID {
+		a.clearFrom()
+		a.setStatus(a.t("compare.cleared"))
+		return
+	}
+	a.setFrom(a.cursor)
+	a.setStatus(a.t("compare.marked", gen.ID))
+}
+
+// renderEndpoint labels the two ends of the diff being set up: the marked
+// generation and, once one is marked, the cursor's.
+func (a *App) renderEndpoint(i int) string {
+	switch {
+	case a.selected == nil:
+		return ""
+	case a.generations[i].Selected:
+		return "  " + presenceStyle.Render(a.t("list.from"))
+	case i == a.cursor:
+		return "  " + presenceStyle.Render(a.t("list.to"))
+	}
+	return ""
+}
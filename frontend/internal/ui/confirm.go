@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// confirmation is a pending action waiting for the user to accept it.
+type confirmation struct {
+	prompt string
+	action tea.Cmd
+	prev   state
+	// quit is set on the confirmation of quitting.
+	quit bool
+}
+
+var (
+	confirmYes = key.NewBinding(key.WithKeys("y", "Y", "enter"))
+	confirmNo  = key.NewBinding(key.WithKeys("n", "N", "esc"))
+)
+
+// askConfirm switches to the confirmation modal; action runs only if the
+// user accepts.
+func (a *App) askConfirm(prompt string, action tea.Cmd) {
+	a.confirm = &confirmation{prompt: prompt, action: action, prev: a.state}
+	a.state = stateConfirm
+}
+
+func (a *App) updateConfirm(msg tea.KeyMsg) tea.Cmd {
+	c := a.confirm
+	switch {
+	case key.Matches(msg, confirmYes):
+		a.state = c.prev
+		a.confirm = nil
+		return c.action
+	case key.Matches(msg, confirmNo):
+		a.state = c.prev
+		a.confirm = nil
+	}
+	return nil
+}
+
+func (a *App) renderConfirm() string {
+	body := fmt.Sprintf("%s\n\n%s", a.confirm.prompt, a.t("confirm.choices"))
+	box := confirmStyle.Render(body)
+	if a.width == 0 {
+		return box
+	}
+	return lipgloss.Place(a.width, a.height-4, lipgloss.Center, lipgloss.Center, box)
+}
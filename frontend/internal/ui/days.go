@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"math"
+	"time"
+
+	"nix-timemach/internal/listing"
+)
+
+// dayGroup is the heading generations made at t are listed under. key
+// names the heading for remembering which ones are folded; title is what
+// it says, like "Yesterday" or "September 2026".
+func (a *App) dayGroup(t time.Time) (key, title string) {
+	if t.IsZero() {
+		return "unknown", a.t("days.unknown")
+	}
+	now, t := a.now.In(listing.Location), t.In(listing.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, listing.Location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, listing.Location)
+	// Weeks start on Monday. Days are rounded, as one with a daylight
+	// saving change isn't 24 hours long.
+	weekday := (int(now.Weekday()) + 6) % 7
+	switch days := int(math.Round(today.Sub(day).Hours() / 24)); {
+	case days <= 0:
+		return "today", a.t("days.today")
+	case days == 1:
+		return "yesterday", a.t("days.yesterday")
+	case days <= weekday:
+		return "week", a.t("days.week")
+	case days <= weekday+7:
+		return "lastWeek", a.t("days.lastWeek")
+	}
+	return day.Format("2006-01"), day.Format("January 2006")
+}
+
+// dayKey is the key of the heading generations[i] is listed under.
+func (a *App) dayKey(i int) string {
+	key, _ := a.dayGroup(a.generations[i].Timestamp)
+	return key
+}
+
+// groupLead reports whether generations[i] is the first row shown under
+// its heading: the one the heading goes above, and the one that stands
+// for the whole group when it is folded.
+func (a *App) groupLead(i int) bool {
+	key := a.dayKey(i)
+	for j := i - 1; j >= 0 && a.dayKey(j) == key; j-- {
+		if !a.filteredOut(j) {
+			return false
+		}
+	}
+	return true
+}
+
+// folded reports whether generations[i] is hidden in a folded group.
+func (a *App) folded(i int) bool {
+	return a.opts.DayGroups && a.foldedDays[a.dayKey(i)] && !a.groupLead(i)
+}
+
+// groupSize counts the rows shown under the heading generations[lead]
+// starts, folded or not.
+func (a *App) groupSize(lead int) int {
+	key, n := a.dayKey(lead), 0
+	for i := lead; i < len(a.generations) && a.dayKey(i) == key; i++ {
+		if !a.filteredOut(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// toggleDay folds the group the cursor is in, leaving the cursor on its
+// heading, or unfolds it.
+func (a *App) toggleDay() {
+	if len(a.generations) == 0 {
+		return
+	}
+	key := a.dayKey(a.cursor)
+	if a.foldedDays[key] {
+		delete(a.foldedDays, key)
+		return
+	}
+	for !a.groupLead(a.cursor) {
+		a.cursor--
+	}
+	a.foldedDays[key] = true
+}
+
+// renderDayHeading is the heading above generations[lead]'s group. A
+// folded group is only its heading, which then takes the cursor.
+func (a *App) renderDayHeading(lead int) string {
+	key, title := a.dayGroup(a.generations[lead].Timestamp)
+	if !a.foldedDays[key] {
+		return headingStyle.Render("  ▾ " + title)
+	}
+	count := a.t("days.folded", a.groupSize(lead))
+	if a.groupSize(lead) == 1 {
+		count = a.t("days.foldedOne")
+	}
+	line := "▸ " + title + " " + statsStyle.Render(count)
+	if lead == a.cursor {
+		return selectedItemStyle.Render("> " + line)
+	}
+	return headingStyle.Render("  " + line)
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// askDelete confirms deleting the marked generations, or the focused one
+// when none are marked, moving them to the trash unless that is turned
+// off. The running system's generation and pinned ones are
+// refused up front rather than left to fail in the backend.
+func (a *App) askDelete() {
+	ids := a.markedIDs()
+	if len(ids) == 0 {
+		ids = []string{a.generations[a.cursor].ID}
+	}
+	for _, gen := range a.generations {
+		if gen.Current && slices.Contains(ids, gen.ID) {
+			a.setStatus(a.t("status.keepCurrent", gen.ID))
+			return
+		}
+		if gen.Pinned && slices.Contains(ids, gen.ID) {
+			a.setStatus(a.t("status.keepPinned", gen.ID))
+			return
+		}
+	}
+
+	var prompt string
+	switch {
+	case a.opts.TrashDays > 0 && len(ids) > 1:
+		prompt = a.t("confirm.trashBatch", len(ids), strings.Join(ids, ", "), a.opts.TrashDays)
+	case a.opts.TrashDays > 0:
+		prompt = a.t("confirm.trash", ids[0], a.opts.TrashDays)
+	case len(ids) > 1:
+		prompt = a.t("confirm.deleteBatch", len(ids), strings.Join(ids, ", "))
+	default:
+		prompt = a.t("confirm.delete", ids[0])
+	}
+	action := a.deleteGenerations(ids)
+	if days := a.opts.TrashDays; days > 0 {
+		what := a.t("undo.trash", ids[0])
+		if len(ids) > 1 {
+			what = a.t("undo.trashBatch", strings.Join(ids, ", "))
+		}
+		action = a.undoable(action, systemStep(what, a.restoreGenerations(ids), a.trashGenerations(ids, days)))
+	}
+	a.askConfirm(prompt, a.privileged(action))
+}
+
+func (a *App) deleteGenerations(ids []string) tea.Cmd {
+	days := a.opts.TrashDays
+	return func() tea.Msg {
+		var err error
+		if days > 0 {
+			err = a.client.TrashGenerations(context.Background(), ids, days)
+		} else {
+			err = a.client.DeleteGenerations(context.Background(), ids)
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return deletedMsg{ids: ids, trashed: days > 0}
+	}
+}
+
+type deletedMsg struct {
+	ids     []string
+	trashed bool
+}
+
+// applyDeleted forgets the deleted generations' marks and reports like any
+// other finished action.
+func (a *App) applyDeleted(msg deletedMsg) tea.Cmd {
+	for _, id := range msg.ids {
+		delete(a.marked, id)
+	}
+	var status string
+	switch {
+	case msg.trashed && len(msg.ids) == 1:
+		status = a.t("status.trashedOne", msg.ids[0])
+	case msg.trashed:
+		status = a.t("status.trashed", len(msg.ids))
+	case len(msg.ids) == 1:
+		status = a.t("status.deletedOne", msg.ids[0])
+	default:
+		status = a.t("status.deleted", len(msg.ids))
+	}
+	return func() tea.Msg { return actionDoneMsg{status: status} }
+}
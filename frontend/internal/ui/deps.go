@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// depsView drills into one modified package of the current diff, showing
+// how its own dependencies changed.
+type depsView struct {
+	pkg      string
+	from, to models.Generation
+	diff     *models.GenerationDiff
+}
+
+type depsMsg struct {
+	pkg  string
+	diff models.GenerationDiff
+}
+
+type depsUnsupportedMsg struct{}
+
+// openDeps opens the dependency diff of the focused modified entry.
+func (a *App) openDeps() tea.Cmd {
+	if a.diff == nil {
+		return nil
+	}
+	modified := a.visibleDiff(*a.diff).Modified
+	if a.modifiedCursor >= len(modified) {
+		return nil
+	}
+	if a.pending || a.snapshot != "" {
+		a.setStatus(a.t("deps.pending"))
+		return nil
+	}
+
+	d := &depsView{
+		pkg:  modified[a.modifiedCursor].Path,
+		from: *a.selected,
+		to:   a.generations[a.cursor],
+	}
+	a.deps = d
+	a.state = stateDeps
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		diff, err := a.client.GetDepsDiff(ctx, d.pkg, d.from.ID, d.to.ID)
+		if errors.Is(err, backend.ErrUnsupported) {
+			return depsUnsupportedMsg{}
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return depsMsg{pkg: d.pkg, diff: diff}
+	}
+}
+
+func (a *App) renderDeps() string {
+	var b strings.Builder
+
+	_, name, _ := models.ParseStorePath(a.deps.pkg)
+	b.WriteString(titleStyle.Render(a.t("deps.title", name, a.deps.from.ID, a.deps.to.ID)))
+	b.WriteString("\n\n")
+
+	switch {
+	case a.deps.diff == nil:
+		b.WriteString(a.t("deps.loading"))
+	case a.deps.diff.Empty():
+		b.WriteString(a.t("deps.none"))
+	default:
+		b.WriteString(a.renderChanges(*a.deps.diff, -1))
+	}
+
+	return b.String()
+}
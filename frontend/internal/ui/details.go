@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/shell"
+)
+
+// detailsKeys act on the focused profile in stateDetails; up/down move
+// between profiles rather than generations.
+type detailsKeys struct {
+	Copy  key.Binding
+	Diff  key.Binding
+	Pager key.Binding
+	Why   key.Binding
+	Tree  key.Binding
+}
+
+func newDetailsKeys(t func(string, ...any) string) detailsKeys {
+	return detailsKeys{
+		Copy: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", t("help.copyPath")),
+		),
+		Diff: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", t("help.diffPrevious")),
+		),
+		Pager: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", t("help.pager")),
+		),
+		Why: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", t("help.whyPackage")),
+		),
+		Tree: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", t("help.closureTree")),
+		),
+	}
+}
+
+// detailsHelp shows the profile actions alongside navigation.
+type detailsHelp struct {
+	nav     keyMap
+	actions detailsKeys
+}
+
+func (h detailsHelp) ShortHelp() []key.Binding {
+	return []key.Binding{h.nav.Up, h.nav.Down, h.actions.Copy, h.actions.Diff, h.actions.Pager, h.actions.Why, h.actions.Tree, h.nav.Back}
+}
+
+func (h detailsHelp) FullHelp() [][]key.Binding {
+	return [][]key.Binding{h.ShortHelp()}
+}
+
+type pagerDoneMsg struct{ err error }
+
+func (a *App) openDetails() {
+	if len(a.generations) == 0 {
+		return
+	}
+	a.state = stateDetails
+	a.profileCursor = 0
+}
+
+func (a *App) updateDetails(msg tea.KeyMsg) tea.Cmd {
+	gen := a.generations[a.cursor]
+
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+
+	case key.Matches(msg, a.keys.Up):
+		if a.profileCursor > 0 {
+			a.profileCursor--
+		}
+
+	case key.Matches(msg, a.keys.Down):
+		if a.profileCursor < len(gen.Profiles)-1 {
+			a.profileCursor++
+		}
+
+	case key.Matches(msg, a.detailsKeys.Copy):
+		if path, ok := a.focusedProfile(); ok {
+			a.copyToClipboard(path)
+		}
+
+	case key.Matches(msg, a.detailsKeys.Pager):
+		if path, ok := a.focusedProfile(); ok {
+			cmd := exec.Command("sh", "-c", `nix-store -q --tree "$1" | ${PAGER:-less}`, "sh", path)
+			if host := a.client.Host(); host != "" {
+				// The path is on the host; only the pager runs here.
+				cmd = exec.Command("sh", "-c", `ssh -- "$2" "nix-store -q --tree $1" | ${PAGER:-less}`, "sh", shell.Quote(path), host)
+			}
+			return tea.ExecProcess(cmd, func(err error) tea.Msg {
+				return pagerDoneMsg{err}
+			})
+		}
+
+	case key.Matches(msg, a.detailsKeys.Why):
+		a.askWhy()
+
+	case key.Matches(msg, a.detailsKeys.Tree):
+		return a.showClosure()
+
+	case key.Matches(msg, a.detailsKeys.Diff):
+		from := a.detailsDiffFrom()
+		if from < 0 {
+			return nil
+		}
+		return a.showDiff(from)
+	}
+
+	return nil
+}
+
+// renderFields lists what is known about gen, one field per line. Fields
+// the backend didn't report are left out.
+func (a *App) renderFields(gen models.Generation) string {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(fmt.Sprintf("  %-16s %s\n", a.t(name), value))
+		}
+	}
+	field("details.created", listing.Timestamp(gen.Timestamp)+" ("+a.relativeTime(gen.Timestamp)+")")
+	field("details.description", gen.Description)
+	field("details.storePath", a.displayPath(gen.StorePath))
+	if gen.ClosureSize > 0 {
+		field("details.closureSize", listing.HumanSize(gen.ClosureSize))
+	}
+	field("details.nixos", gen.NixosVersion)
+	field("details.kernel", gen.KernelVersion)
+	field("details.specialisations", strings.Join(gen.Specialisations, ", "))
+	field("details.flakeUrl", gen.FlakeURL)
+	rev := gen.FlakeRevision
+	if rev != "" && gen.FlakeDirty {
+		rev += " " + a.t("details.dirty")
+	}
+	field("details.flakeRevision", rev)
+	if gen.Pinned {
+		pin := a.t("details.pinnedUnnamed")
+		if gen.PinName != "" {
+			pin = a.t("details.pinnedAs", gen.PinName)
+		}
+		field("details.pin", pin)
+	}
+	field("details.boot", a.bootStatus(gen))
+	return b.String()
+}
+
+// bootStatus says whether gen is the booted system and the boot default,
+// or "" for a profile other than the system's, which is neither.
+func (a *App) bootStatus(gen models.Generation) string {
+	var status []string
+	if gen.Booted {
+		status = append(status, a.t("details.booted"))
+	}
+	if gen.BootDefault {
+		status = append(status, a.t("details.bootDefault"))
+	}
+	if len(status) == 0 && a.onSystemProfile() {
+		status = append(status, a.t("details.notBooted"))
+	}
+	return strings.Join(status, ", ")
+}
+
+// detailsDiffFrom is where diffing from the details view starts: the
+// generation marked as the start of the next diff, or else the focused
+// profile's generation before the cursor's. It is -1 when there is neither.
+func (a *App) detailsDiffFrom() int {
+	gen := a.generations[a.cursor]
+	if a.selected != nil && a.selected.ID != gen.ID {
+		for i := range a.generations {
+			if a.generations[i].ID == a.selected.ID {
+				return i
+			}
+		}
+	}
+	focused, ok := a.focusedProfile()
+	if !ok {
+		return -1
+	}
+	profile := linkProfile(focused)
+	prev := -1
+	for i, other := range a.generations {
+		if !other.Timestamp.Before(gen.Timestamp) || (prev >= 0 && !other.Timestamp.After(a.generations[prev].Timestamp)) {
+			continue
+		}
+		if slices.ContainsFunc(other.Profiles, func(p string) bool { return linkProfile(p) == profile }) {
+			prev = i
+		}
+	}
+	return prev
+}
+
+// linkProfile is the profile a generation link like
+// /nix/var/nix/profiles/system-42-link belongs to. Other paths are their
+// own profile.
+func linkProfile(link string) string {
+	rest, ok := strings.CutSuffix(link, "-link")
+	i := strings.LastIndexByte(rest, '-')
+	if !ok || i < 0 {
+		return link
+	}
+	if _, err := strconv.Atoi(rest[i+1:]); err != nil {
+		return link
+	}
+	return rest[:i]
+}
+
+func (a *App) focusedProfile() (string, bool) {
+	profiles := a.generations[a.cursor].Profiles
+	if a.profileCursor >= len(profiles) {
+		return "", false
+	}
+	return profiles[a.profileCursor], true
+}
+
+func (a *App) renderDetails() string {
+	var b strings.Builder
+	gen := a.generations[a.cursor]
+
+	b.WriteString(titleStyle.Render(a.t("details.title", gen.ID)))
+	b.WriteString("\n\n")
+	b.WriteString(a.renderFields(gen))
+	b.WriteString("\n")
+	b.WriteString(itemStyle.Render(a.t("details.profiles")))
+	b.WriteString("\n")
+
+	for i, profile := range gen.Profiles {
+		profile = a.displayPath(profile)
+		style := itemStyle
+		if i == a.profileCursor {
+			profile = "> " + profile
+			style = selectedItemStyle
+		} else {
+			profile = "  " + profile
+		}
+		b.WriteString(style.Render(profile))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
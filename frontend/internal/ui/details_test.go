@@ -0,0 +1,45 @@
+package ui
+
+import "testing"
+
+func TestLinkProfile(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/nix/var/nix/profiles/system-42-link", "/nix/var/nix/profiles/system"},
+		{"/home/me/.local/state/nix/profiles/home-manager-7-link", "/home/me/.local/state/nix/profiles/home-manager"},
+		{"/nix/store/abc-nixos-system-24.05/specialisation/gaming", "/nix/store/abc-nixos-system-24.05/specialisation/gaming"},
+		{"no-number-link", "no-number-link"},
+		{"nolink-link", "nolink-link"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := linkProfile(tt.in); got != tt.want {
+			t.Errorf("linkProfile(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetailsDiffFrom(t *testing.T) {
+	a := newTestApp(t, Options{})
+	a.openDetails()
+	prev := a.predecessor(a.cursor)
+	if prev < 0 {
+		t.Fatal("the demo's first generation has no predecessor")
+	}
+	if got := a.detailsDiffFrom(); got != prev {
+		t.Fatalf("diffs from %d, want the predecessor %d", got, prev)
+	}
+
+	// A generation of another profile in between is skipped.
+	want := a.predecessor(prev)
+	a.generations[prev].Profiles = []string{"/nix/var/nix/profiles/per-user/me/profile-3-link"}
+	if got := a.detailsDiffFrom(); got != want {
+		t.Errorf("diffs from %d, want %d past the other profile's generation", got, want)
+	}
+
+	// A marked generation wins.
+	last := len(a.generations) - 1
+	a.setFrom(last)
+	if got := a.detailsDiffFrom(); got != last {
+		t.Errorf("diffs from %d, want the marked %d", got, last)
+	}
+}
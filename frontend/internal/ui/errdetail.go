@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+)
+
+// errorDetailKey toggles the details of a backend failure on the error
+// screen, which has no help bar of its own.
+var errorDetailKey = key.NewBinding(key.WithKeys("d"))
+
+// updateError handles the error screen's own key, reporting whether it
+// did.
+func (a *App) updateError(msg tea.KeyMsg) bool {
+	var f *backend.Failure
+	if !key.Matches(msg, errorDetailKey) || !errors.As(a.err, &f) {
+		return false
+	}
+	a.errDetail = !a.errDetail
+	return true
+}
+
+// renderError shows the first line of the error. A backend failure's
+// command, stderr and exit status are one key away, as they are what
+// explains a permission or path problem.
+func (a *App) renderError() string {
+	var f *backend.Failure
+	if !errors.As(a.err, &f) {
+		return a.t("app.error", a.err)
+	}
+	summary, _, more := strings.Cut(a.err.Error(), "\n")
+	if !a.errDetail {
+		if more || f.Command != "" || f.Stderr != "" {
+			summary += "\n\n" + a.t("error.detailsHint")
+		}
+		return a.t("app.error", summary)
+	}
+
+	var b strings.Builder
+	b.WriteString(summary + "\n")
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "\n  %-10s %s", a.t(name), value)
+		}
+	}
+	field("error.command", f.Command)
+	field("error.kind", f.Kind)
+	if f.Err != nil {
+		field("error.status", f.Err.Error())
+	}
+	block := func(name, text string) {
+		fmt.Fprintf(&b, "\n\n  %s\n", a.t(name))
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+	// A one-line message is the summary already.
+	if strings.Contains(f.Message, "\n") {
+		block("error.message", f.Message)
+	}
+	if f.Stderr != "" {
+		block("error.stderr", f.Stderr)
+	}
+	b.WriteString("\n" + a.t("error.hideHint"))
+	return a.t("app.error", b.String())
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+
+	"nix-timemach/internal/models"
+)
+
+// explicitMarker flags packages the configuration asks for directly.
+const explicitMarker = "★ "
+
+// visibleDiff returns diff with dependency changes dropped when only
+// explicit packages are shown. Without explicit tags from the backend it is
+// returned unchanged.
+func (a *App) visibleDiff(diff models.GenerationDiff) models.GenerationDiff {
+	if !a.explicitOnly || !diff.ExplicitKnown {
+		return diff
+	}
+	explicit := func(changes []models.PackageChange) []models.PackageChange {
+		var kept []models.PackageChange
+		for _, c := range changes {
+			if c.Explicit {
+				kept = append(kept, c)
+			}
+		}
+		return kept
+	}
+	return models.GenerationDiff{
+		Added:         explicit(diff.Added),
+		Removed:       explicit(diff.Removed),
+		Modified:      explicit(diff.Modified),
+		ExplicitKnown: true,
+	}
+}
+
+func (a *App) toggleExplicitOnly() {
+	if !a.diff.ExplicitKnown {
+		a.setStatus(a.t("diff.explicitUnknown"))
+		return
+	}
+	a.explicitOnly = !a.explicitOnly
+	a.modifiedCursor = 0
+}
+
+// renderChange draws one diff entry, marking explicit packages when the
+// backend tags them. A modified package whose version changed shows as
+// "firefox 121 → 122", colored by whether it is an upgrade or a downgrade.
+func (a *App) renderChange(diff models.GenerationDiff, c models.PackageChange) string {
+	text := a.displayPath(c.Path)
+	if c.NewPath != "" && c.OldVersion != "" && c.NewVersion != "" && c.OldVersion != c.NewVersion {
+		text = fmt.Sprintf("%s %s → %s", c.Name, c.OldVersion, c.NewVersion)
+		switch bump := c.Bump(); {
+		case bump > 0:
+			text = addedStyle.Render(text)
+		case bump < 0:
+			text = removedStyle.Render(text)
+		}
+	}
+	if c.SizeKnown && c.SizeDelta != 0 {
+		text += " " + statsStyle.Render(signedSize(c.SizeDelta))
+	}
+	if !diff.ExplicitKnown {
+		return text
+	}
+	if c.Explicit {
+		return explicitMarker + text
+	}
+	return "  " + text
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/export"
+)
+
+// askExport asks where to write the diff on screen, picking the format
+// from the file's extension. What is exported is what is shown, so with
+// only explicit packages on, dependencies are left out.
+func (a *App) askExport() {
+	a.askPrompt(a.t("export.prompt"), a.exportName(), func(path string) tea.Cmd {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil
+		}
+		format, err := export.FormatOf(path)
+		if err == nil {
+			var b strings.Builder
+			err = export.Diff(&b, format, a.diffTitle(), a.visibleDiff(*a.diff))
+			if err == nil {
+				err = os.WriteFile(path, []byte(b.String()), 0o644)
+			}
+		}
+		if err != nil {
+			a.setStatus(a.t("export.failed", err))
+			return nil
+		}
+		a.setStatus(a.t("export.done", path))
+		return nil
+	})
+}
+
+// exportName suggests a file name after the two ends of the diff.
+func (a *App) exportName() string {
+	switch {
+	case a.pending:
+		return "nix-timemach-pending.md"
+	case a.snapshot != "":
+		return fmt.Sprintf("nix-timemach-%s-%s.md", a.snapshot, a.generations[a.cursor].ID)
+	}
+	return fmt.Sprintf("nix-timemach-%s-%s.md", a.selected.ID, a.generations[a.cursor].ID)
+}
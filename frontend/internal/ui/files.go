@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+)
+
+// fileDiffMsg is the /etc diff between two generations, or why it couldn't
+// be had.
+type fileDiffMsg struct {
+	from, to string
+	diff     models.FileDiff
+	err      error
+}
+
+// fetchFileDiff gets the /etc diff that loadDiff fetches alongside the
+// package diff, for the unit section and the file view.
+func (a *App) fetchFileDiff(ctx context.Context, from, to string) tea.Msg {
+	diff, err := a.client.GetEtcDiff(ctx, from, to)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return fileDiffMsg{from: from, to: to, diff: diff, err: err}
+}
+
+// toggleFiles switches the diff view between package changes and the
+// changes to /etc. Only a diff between two generations has both trees to
+// compare.
+func (a *App) toggleFiles() {
+	if a.pending || a.snapshot != "" || a.selected == nil {
+		a.setStatus(a.t("files.unavailable"))
+		return
+	}
+	a.showFiles = !a.showFiles
+}
+
+// applyFileDiff keeps the /etc diff if the view it was fetched for is
+// still open.
+func (a *App) applyFileDiff(msg fileDiffMsg) {
+	if a.state != stateDiff || a.selected == nil || a.selected.ID != msg.from || a.generations[a.cursor].ID != msg.to {
+		return
+	}
+	if msg.err != nil {
+		a.fileDiffErr = msg.err
+		return
+	}
+	a.fileDiff = &msg.diff
+}
+
+// resetFiles returns the diff view to package changes.
+func (a *App) resetFiles() {
+	a.showFiles = false
+	a.fileDiff = nil
+	a.fileDiffErr = nil
+}
+
+func (a *App) renderFileDiff() string {
+	switch {
+	case a.fileDiffErr != nil:
+		first, _, _ := strings.Cut(a.fileDiffErr.Error(), "\n")
+		return a.t("files.failed", first)
+	case a.fileDiff == nil:
+		return a.t("files.loading")
+	case a.fileDiff.Empty():
+		return a.t("files.none")
+	}
+
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(a.t("files.title")) + "\n")
+	for _, section := range []struct {
+		heading string
+		style   func(...string) string
+		sign    string
+		paths   []string
+	}{
+		{"diff.added", addedStyle.Render, "+", a.fileDiff.Added},
+		{"diff.removed", removedStyle.Render, "-", a.fileDiff.Removed},
+		{"diff.modified", modifiedStyle.Render, "~", a.fileDiff.Modified},
+	} {
+		if len(section.paths) == 0 {
+			continue
+		}
+		b.WriteString(section.style(a.t(section.heading)) + "\n")
+		for _, p := range section.paths {
+			b.WriteString("  " + section.sign + " " + p + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderUnitDiff lists the systemd units a diff adds, removes or changes,
+// or nothing if there are none or they couldn't be compared.
+func (a *App) renderUnitDiff() string {
+	if a.fileDiff == nil {
+		return ""
+	}
+	units := a.fileDiff.Units()
+	if units.Empty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(a.t("units.title")) + "\n")
+	for _, u := range units.Added {
+		b.WriteString("  " + addedStyle.Render("+ "+u) + "\n")
+	}
+	for _, u := range units.Removed {
+		b.WriteString("  " + removedStyle.Render("- "+u) + "\n")
+	}
+	for _, u := range units.Modified {
+		b.WriteString("  " + modifiedStyle.Render("~ "+u) + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/history"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// filterHistoryName is the file the filter history is stored under.
+const filterHistoryName = "filter"
+
+// filterDebounce is how long typing must pause before the list is
+// refiltered, so large lists don't lag behind every keystroke.
+const filterDebounce = 80 * time.Millisecond
+
+// listFilter narrows the generation list to rows matching a query. While
+// editing, up and down recall earlier queries like a shell history.
+type listFilter struct {
+	input   textinput.Model
+	editing bool
+	history []string
+	// recall is the history index shown in the input, or len(history)
+	// when the user is typing a new query.
+	recall int
+	draft  string
+	// applied is the query the list is currently filtered by; it trails
+	// the input while typing. token identifies the latest keystroke so
+	// only its debounce tick applies the query.
+	applied string
+	token   int
+}
+
+type filterHistoryMsg []string
+
+type filterDebounceMsg struct{ token int }
+
+func newListFilter() listFilter {
+	input := textinput.New()
+	input.Prompt = "/"
+	input.Cursor.SetMode(cursor.CursorStatic)
+	return listFilter{input: input}
+}
+
+func (a *App) loadFilterHistory() tea.Msg {
+	entries, err := history.Load(filterHistoryName)
+	if err != nil {
+		// History is a convenience; the filter works without it.
+		return filterHistoryMsg(nil)
+	}
+	return filterHistoryMsg(entries)
+}
+
+func (f *listFilter) query() string {
+	return strings.ToLower(strings.TrimSpace(f.input.Value()))
+}
+
+// pinnedToken in a query keeps only pinned generations; the rest of the
+// query still has to match.
+const pinnedToken = "is:pinned"
+
+// matches reports whether gen's ID, timestamp, description or pin name
+// contains the applied query, ignoring case.
+func (f *listFilter) matches(gen models.Generation) bool {
+	q := f.applied
+	if q == "" {
+		return true
+	}
+	if strings.Contains(q, pinnedToken) {
+		if !gen.Pinned {
+			return false
+		}
+		q = strings.TrimSpace(strings.ReplaceAll(q, pinnedToken, ""))
+	}
+	text := strings.ToLower(gen.ID + " " + listing.Timestamp(gen.Timestamp) + " " + gen.Description + " " + gen.PinName)
+	return strings.Contains(text, q)
+}
+
+func (a *App) startFilter() {
+	a.filter.editing = true
+	a.filter.recall = len(a.filter.history)
+	a.filter.draft = a.filter.input.Value()
+	a.filter.input.Focus()
+}
+
+func (a *App) updateFilter(msg tea.KeyMsg) tea.Cmd {
+	f := &a.filter
+	switch msg.Type {
+	case tea.KeyEnter:
+		f.editing = false
+		f.input.Blur()
+		a.refilter()
+		if q := strings.TrimSpace(f.input.Value()); q != "" {
+			f.history = history.Add(f.history, q)
+			entries := f.history
+			return func() tea.Msg {
+				history.Save(filterHistoryName, entries)
+				return nil
+			}
+		}
+		return nil
+	case tea.KeyEsc:
+		a.clearFilter()
+		return nil
+	case tea.KeyUp:
+		if f.recall > 0 {
+			if f.recall == len(f.history) {
+				f.draft = f.input.Value()
+			}
+			f.recall--
+			f.input.SetValue(f.history[f.recall])
+			f.input.CursorEnd()
+		}
+	case tea.KeyDown:
+		if f.recall < len(f.history) {
+			f.recall++
+			if f.recall == len(f.history) {
+				f.input.SetValue(f.draft)
+			} else {
+				f.input.SetValue(f.history[f.recall])
+			}
+			f.input.CursorEnd()
+		}
+	default:
+		var cmd tea.Cmd
+		f.input, cmd = f.input.Update(msg)
+		f.recall = len(f.history)
+		f.token++
+		token := f.token
+		return tea.Batch(cmd, tea.Tick(filterDebounce, func(time.Time) tea.Msg {
+			return filterDebounceMsg{token}
+		}))
+	}
+	a.refilter()
+	return nil
+}
+
+// applyDebounced applies the query once typing has paused. Ticks from
+// earlier keystrokes are stale and ignored.
+func (a *App) applyDebounced(msg filterDebounceMsg) {
+	if msg.token == a.filter.token {
+		a.refilter()
+	}
+}
+
+func (a *App) clearFilter() {
+	a.filter.editing = false
+	a.filter.input.Blur()
+	a.filter.input.SetValue("")
+	a.refilter()
+}
+
+// refilter applies the input's query and keeps the cursor on a visible
+// row.
+func (a *App) refilter() {
+	a.filter.applied = a.filter.query()
+	a.clampCursor()
+	if len(a.generations) > 0 && a.hidden(a.cursor) {
+		a.moveCursor(1)
+	}
+}
+
+// filterActive reports whether the filter line should be drawn.
+func (a *App) filterActive() bool {
+	return a.filter.editing || a.filter.applied != ""
+}
+
+// filterCount returns how many rows the filter leaves visible.
+func (a *App) filterCount() int {
+	n := 0
+	for i := range a.generations {
+		if !a.hidden(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// noMatches reports whether the filter or a package search hides every
+// row, leaving the cursor on one that isn't shown.
+func (a *App) noMatches() bool {
+	return (a.filter.applied != "" || a.search != nil) && a.filterCount() == 0
+}
+
+// renderFilter draws the filter line with how many generations match.
+func (a *App) renderFilter() string {
+	out := "  " + a.filter.input.View()
+	if a.filter.applied != "" {
+		out += "  " + statsStyle.Render(a.t("filter.count", a.filterCount(), len(a.generations)))
+	}
+	return out
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// Host is a machine in the fleet view and the client that reaches it.
+type Host struct {
+	Name   string
+	Client backend.Client
+}
+
+// fleet is the dashboard of every configured host, each compared with a
+// reference host. Opening a host browses its generations with its client.
+type fleet struct {
+	hosts     []fleetHost
+	cursor    int
+	reference int
+}
+
+// fleetHost is one host's row. Its packages are those of its current
+// generation, kept to measure drift against the reference.
+type fleetHost struct {
+	name     string
+	client   backend.Client
+	loaded   bool
+	err      error
+	current  models.Generation
+	rebuilt  time.Time
+	packages []string
+	drift    *models.DiffStats
+}
+
+type fleetHostMsg struct {
+	index    int
+	current  models.Generation
+	rebuilt  time.Time
+	packages []string
+	err      error
+}
+
+// hostGenerationsMsg is a host's list, which only applies if that host is
+// still the one open.
+type hostGenerationsMsg struct {
+	client      backend.Client
+	generations []models.Generation
+}
+
+// newFleet returns the dashboard of the options' hosts, or nil when there
+// are none.
+func newFleet(opts Options) *fleet {
+	if len(opts.Hosts) == 0 {
+		return nil
+	}
+	f := &fleet{}
+	for i, h := range opts.Hosts {
+		f.hosts = append(f.hosts, fleetHost{name: h.Name, client: h.Client})
+		if h.Name == opts.Reference {
+			f.reference = i
+		}
+	}
+	return f
+}
+
+// loadFleet queries every host at once; each row fills in as its host
+// answers.
+func (a *App) loadFleet() tea.Cmd {
+	cmds := make([]tea.Cmd, len(a.fleet.hosts))
+	for i := range a.fleet.hosts {
+		a.fleet.hosts[i].loaded = false
+		client := a.fleet.hosts[i].client
+		cmds[i] = func() tea.Msg {
+			return fetchFleetHost(i, client)
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func fetchFleetHost(i int, client backend.Client) tea.Msg {
+	ctx := context.Background()
+	generations, err := client.GetGenerations(ctx)
+	if err == nil && len(generations) == 0 {
+		err = fmt.Errorf("no generations")
+	}
+	if err != nil {
+		return fleetHostMsg{index: i, err: err}
+	}
+	msg := fleetHostMsg{index: i, current: generations[len(generations)-1]}
+	for _, gen := range generations {
+		if gen.Current {
+			msg.current = gen
+		}
+		if gen.Timestamp.After(msg.rebuilt) {
+			msg.rebuilt = gen.Timestamp
+		}
+	}
+	msg.packages, msg.err = client.GetPackages(ctx, msg.current.ID)
+	return msg
+}
+
+func (a *App) applyFleetHost(msg fleetHostMsg) {
+	h := &a.fleet.hosts[msg.index]
+	h.loaded = true
+	h.err = msg.err
+	h.current, h.rebuilt, h.packages = msg.current, msg.rebuilt, msg.packages
+
+	// Drift is only known once both sides are.
+	ref := a.fleet.hosts[a.fleet.reference]
+	for i := range a.fleet.hosts {
+		h := &a.fleet.hosts[i]
+		h.drift = nil
+		if i == a.fleet.reference || !h.loaded || h.err != nil || !ref.loaded || ref.err != nil {
+			continue
+		}
+		stats := models.StatsOf(models.DiffPaths(ref.packages, h.packages))
+		h.drift = &stats
+	}
+}
+
+func (a *App) updateFleet(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, a.keys.Up):
+		if a.fleet.cursor > 0 {
+			a.fleet.cursor--
+		}
+
+	case key.Matches(msg, a.keys.Down):
+		if a.fleet.cursor < len(a.fleet.hosts)-1 {
+			a.fleet.cursor++
+		}
+
+	case key.Matches(msg, a.keys.Select):
+		return a.openHost(a.fleet.cursor)
+
+	case key.Matches(msg, a.keys.Reload):
+		a.err = nil
+		a.errDetail = false
+		return a.loadFleet()
+	}
+	return nil
+}
+
+// openHost browses a host's generations. Everything about the previous
+// host's list is dropped.
+func (a *App) openHost(i int) tea.Cmd {
+	a.client = a.fleet.hosts[i].client
+	a.state = stateGenerations
+	a.profiles = nil
+	a.profileIndex = 0
+	a.generations = nil
+	a.cursor = 0
+	a.focusID = ""
+	a.selected = nil
+	a.marked = make(map[string]bool)
+	a.undo.Clear()
+	a.stats = make(map[string]models.DiffStats)
+	a.resetPane()
+	a.rollback = nil
+	a.search = nil
+	a.loading = true
+	client := a.client
+	return tea.Batch(a.loadProfiles, a.rewatch(), func() tea.Msg {
+		generations, err := client.GetGenerations(context.Background())
+		if err != nil {
+			return errMsg{err}
+		}
+		return hostGenerationsMsg{client, generations}
+	})
+}
+
+func (a *App) renderFleet() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(a.t("fleet.title")))
+	b.WriteString("\n\n")
+
+	width := 0
+	for _, h := range a.fleet.hosts {
+		width = max(width, len(h.name))
+	}
+	for i, h := range a.fleet.hosts {
+		item := fmt.Sprintf("%-*s  ", width, h.name)
+		switch {
+		case !h.loaded:
+			item += a.t("app.loading")
+		case h.err != nil:
+			first, _, _ := strings.Cut(h.err.Error(), "\n")
+			item += a.t("fleet.error", first)
+		default:
+			item += a.t("fleet.host", h.current.ID, listing.Timestamp(h.rebuilt))
+		}
+
+		style := itemStyle
+		if i == a.fleet.cursor {
+			item = "> " + item
+			style = selectedItemStyle
+		} else {
+			item = "  " + item
+		}
+		b.WriteString(style.Render(item))
+
+		switch {
+		case i == a.fleet.reference:
+			b.WriteString("  " + statsStyle.Render(a.t("fleet.reference")))
+		case h.drift == nil:
+		case *h.drift == models.DiffStats{}:
+			b.WriteString("  " + addedStyle.Render(a.t("fleet.inSync")))
+		default:
+			b.WriteString("  " + statsStyle.Render(formatStats(*h.drift)))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// fleetHelp shows the keys the dashboard uses.
+type fleetHelp struct {
+	nav keyMap
+}
+
+func (h fleetHelp) ShortHelp() []key.Binding {
+	return []key.Binding{h.nav.Up, h.nav.Down, h.nav.Select, h.nav.Reload, h.nav.Quit}
+}
+
+func (h fleetHelp) FullHelp() [][]key.Binding {
+	return [][]key.Binding{h.ShortHelp()}
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// signedSize is listing.HumanSize with an explicit sign for deltas.
+func signedSize(n int64) string {
+	if n > 0 {
+		return "+" + listing.HumanSize(n)
+	}
+	return listing.HumanSize(n)
+}
+
+// formatStats renders stats as a compact annotation like "+3 -1 ~2 +4.0 MiB".
+func formatStats(s models.DiffStats) string {
+	out := fmt.Sprintf("+%d -%d ~%d", s.Added, s.Removed, s.Modified)
+	if s.SizeKnown {
+		out += " " + signedSize(s.SizeDelta)
+	}
+	return out
+}
+
+// relativeDuration formats d coarsely, e.g. "45s", "3m" or "2h".
+func relativeDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+}
+
+// TimestampModes are the ways the list can show when generations were
+// made, in the order the timestamps key cycles through them: the time with
+// how long ago it was, how long ago only, and the time only.
+var TimestampModes = []string{"both", "relative", "absolute"}
+
+// relativeTime says how long before now t was, e.g. "3 days ago". Times
+// in the future, from a clock set wrong, are "just now".
+func (a *App) relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return "?"
+	}
+	const day = 24 * time.Hour
+	plural := func(n int, one, many string) string {
+		if n == 1 {
+			return a.t(one)
+		}
+		return a.t(many, n)
+	}
+	switch d := a.now.Sub(t); {
+	case d < time.Minute:
+		return a.t("time.now")
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "time.minute", "time.minutes")
+	case d < day:
+		return plural(int(d/time.Hour), "time.hour", "time.hours")
+	case d < 7*day:
+		return plural(int(d/day), "time.day", "time.days")
+	case d < 30*day:
+		return plural(int(d/(7*day)), "time.week", "time.weeks")
+	case d < 365*day:
+		return plural(int(d/(30*day)), "time.month", "time.months")
+	default:
+		return plural(int(d/(365*day)), "time.year", "time.years")
+	}
+}
+
+// stamp is t as the list shows it, in the current timestamp mode. The
+// relative part is padded to relWidth, so that descriptions line up.
+func (a *App) stamp(t time.Time, relWidth int) string {
+	switch TimestampModes[a.timestamps] {
+	case "relative":
+		return pad(a.relativeTime(t), relWidth)
+	case "absolute":
+		return listing.Timestamp(t)
+	}
+	return listing.Timestamp(t) + " " + pad("("+a.relativeTime(t)+")", relWidth+2)
+}
+
+// storeHashLen is the length of the base32 hash that prefixes store path
+// names.
+const storeHashLen = 32
+
+// defaultHashLen is used when abbreviation is toggled on without a
+// configured length.
+const defaultHashLen = 8
+
+// abbreviateStorePath shortens the hash in a store path to hashLen
+// characters followed by "…", keeping the name and version intact.
+// Anything that isn't a store path is returned unchanged.
+func abbreviateStorePath(p string, hashLen int) string {
+	dir, base := path.Split(p)
+	if hashLen <= 0 || hashLen >= storeHashLen || len(base) <= storeHashLen ||
+		base[storeHashLen] != '-' || !isNixBase32(base[:storeHashLen]) {
+		return p
+	}
+	return dir + base[:hashLen] + "…" + base[storeHashLen:]
+}
+
+func isNixBase32(s string) bool {
+	const alphabet = "0123456789abcdfghijklmnpqrsvwxyz"
+	for _, r := range s {
+		if !strings.ContainsRune(alphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ageStyle returns the color for a timestamp's age bucket. Ages past the
+// last threshold share the final color; with no thresholds or colors there
+// is no age coloring.
+func (a *App) ageStyle(t time.Time) (lipgloss.Style, bool) {
+	if len(a.opts.AgeBuckets) == 0 || len(ageStyles) == 0 || t.IsZero() {
+		return lipgloss.Style{}, false
+	}
+	age := a.now.Sub(t)
+	bucket := len(a.opts.AgeBuckets)
+	for i, limit := range a.opts.AgeBuckets {
+		if age < limit {
+			bucket = i
+			break
+		}
+	}
+	return ageStyles[min(bucket, len(ageStyles)-1)], true
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// defaultGCAge is the age the garbage collection prompt starts with.
+const defaultGCAge = "30d"
+
+// gcScreen previews collecting garbage older than age before doing it.
+type gcScreen struct {
+	age     string
+	preview *models.GCPreview
+	err     error
+	running bool
+}
+
+type gcPreviewMsg struct {
+	age     string
+	preview models.GCPreview
+	err     error
+}
+
+// askGC asks how old the generations to delete must be, then previews what
+// deleting them and collecting garbage would free.
+func (a *App) askGC() {
+	initial := defaultGCAge
+	if a.gc != nil {
+		initial = a.gc.age
+	}
+	a.askPrompt(a.t("gc.prompt"), initial, func(age string) tea.Cmd {
+		age = strings.TrimSpace(age)
+		if _, err := models.ParseAge(age); err != nil {
+			a.setStatus(err.Error())
+			return nil
+		}
+		a.gc = &gcScreen{age: age}
+		a.state = stateGC
+		ctx := a.profileContext()
+		return func() tea.Msg {
+			preview, err := a.client.GCDryRun(ctx, age)
+			return gcPreviewMsg{age: age, preview: preview, err: err}
+		}
+	})
+}
+
+func (a *App) applyGCPreview(msg gcPreviewMsg) {
+	if a.gc == nil || a.gc.age != msg.age || a.gc.preview != nil {
+		return
+	}
+	if msg.err != nil {
+		a.gc.err = msg.err
+		return
+	}
+	a.gc.preview = &msg.preview
+}
+
+func (a *App) updateGC(msg tea.KeyMsg) tea.Cmd {
+	gc := a.gc
+	switch {
+	case gc.running:
+		// The collection can't be stopped halfway; wait for it.
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Select):
+		if gc.preview == nil || gcEmpty(*gc.preview) {
+			return nil
+		}
+		gc.running = true
+		return a.privileged(a.collectGarbage(gc.age, *gc.preview))
+	}
+	return nil
+}
+
+func (a *App) collectGarbage(age string, preview models.GCPreview) tea.Cmd {
+	return func() tea.Msg {
+		err := a.client.GC(context.Background(), age)
+		return gcDoneMsg{preview: preview, err: err}
+	}
+}
+
+type gcDoneMsg struct {
+	preview models.GCPreview
+	err     error
+}
+
+// applyGCDone returns to the list, which has lost the deleted generations,
+// and reports like any other finished action.
+func (a *App) applyGCDone(msg gcDoneMsg) tea.Cmd {
+	a.gc.running = false
+	a.gc.preview = nil
+	a.state = stateGenerations
+	if msg.err != nil {
+		a.err = msg.err
+		return nil
+	}
+	status := a.t("gc.done", len(msg.preview.Generations), listing.HumanSize(msg.preview.Bytes))
+	return func() tea.Msg { return actionDoneMsg{status: status} }
+}
+
+func gcEmpty(p models.GCPreview) bool {
+	return len(p.Generations) == 0 && p.Paths == 0
+}
+
+func (a *App) renderGC() string {
+	gc := a.gc
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("gc.title", gc.age)) + "\n\n")
+	switch {
+	case gc.running:
+		b.WriteString(a.spinner.View() + " " + a.t("gc.running"))
+		return b.String()
+	case gc.err != nil:
+		first, _, _ := strings.Cut(gc.err.Error(), "\n")
+		b.WriteString(a.t("gc.failed", first))
+		return b.String()
+	case gc.preview == nil:
+		b.WriteString(a.spinner.View() + " " + a.t("gc.loading"))
+		return b.String()
+	case gcEmpty(*gc.preview):
+		b.WriteString(a.t("gc.nothing"))
+		return b.String()
+	}
+
+	p := gc.preview
+	if len(p.Generations) > 0 {
+		b.WriteString(headingStyle.Render(a.t("gc.generations", len(p.Generations))) + "\n")
+		// Generations are listed by profile, in the order the backend
+		// reported the profiles.
+		var profiles []string
+		ids := make(map[string][]string)
+		for _, gen := range p.Generations {
+			if _, ok := ids[gen.Profile]; !ok {
+				profiles = append(profiles, gen.Profile)
+			}
+			ids[gen.Profile] = append(ids[gen.Profile], gen.ID)
+		}
+		for _, profile := range profiles {
+			b.WriteString(fmt.Sprintf("  %s: %s\n", removedStyle.Render(profile), strings.Join(ids[profile], ", ")))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(a.t("gc.frees", listing.HumanSize(p.Bytes), p.Paths) + "\n\n")
+	b.WriteString(statsStyle.Render(a.t("gc.choices")))
+	return b.String()
+}
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/groups"
+)
+
+type groupsLoadedMsg []groups.Group
+type groupSavedMsg string
+
+func (a *App) toggleMark() {
+	if len(a.generations) == 0 {
+		return
+	}
+	id := a.generations[a.cursor].ID
+	if a.marked[id] {
+		delete(a.marked, id)
+	} else {
+		a.marked[id] = true
+	}
+}
+
+// markedIDs returns the marked generation IDs in list order.
+func (a *App) markedIDs() []string {
+	var ids []string
+	for _, gen := range a.generations {
+		if a.marked[gen.ID] {
+			ids = append(ids, gen.ID)
+		}
+	}
+	return ids
+}
+
+func (a *App) saveGroup(name string) tea.Cmd {
+	name = strings.TrimSpace(name)
+	ids := a.markedIDs()
+	if name == "" || len(ids) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		err := groups.Put(groups.Group{Name: name, IDs: ids, Created: time.Now()})
+		if err != nil {
+			return errMsg{err}
+		}
+		return groupSavedMsg(name)
+	}
+}
+
+func (a *App) loadGroups() tea.Msg {
+	list, err := groups.Load()
+	if err != nil {
+		return errMsg{err}
+	}
+	return groupsLoadedMsg(list)
+}
+
+// missingMembers returns the group's IDs that are no longer in the list,
+// e.g. because they were garbage collected.
+func (a *App) missingMembers(g groups.Group) []string {
+	present := make(map[string]bool, len(a.generations))
+	for _, gen := range a.generations {
+		present[gen.ID] = true
+	}
+	var missing []string
+	for _, id := range g.IDs {
+		if !present[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+func (a *App) updateGroups(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+
+	case key.Matches(msg, a.keys.Up):
+		if a.groupCursor > 0 {
+			a.groupCursor--
+		}
+
+	case key.Matches(msg, a.keys.Down):
+		if a.groupCursor < len(a.groupList)-1 {
+			a.groupCursor++
+		}
+
+	case key.Matches(msg, a.keys.Select):
+		if a.groupCursor < len(a.groupList) {
+			a.applyGroup(a.groupList[a.groupCursor])
+		}
+
+	case key.Matches(msg, a.groupKeys.Delete):
+		if a.groupCursor < len(a.groupList) {
+			name := a.groupList[a.groupCursor].Name
+			a.askConfirm(a.t("confirm.deleteGroup", name), func() tea.Msg {
+				if err := groups.Delete(name); err != nil {
+					return errMsg{err}
+				}
+				return a.loadGroups()
+			})
+		}
+	}
+	return nil
+}
+
+// applyGroup marks the group's members that still exist and returns to the
+// list.
+func (a *App) applyGroup(g groups.Group) {
+	defer a.recordMarks(a.marked)
+	a.marked = make(map[string]bool)
+	missing := a.missingMembers(g)
+	gone := make(map[string]bool, len(missing))
+	for _, id := range missing {
+		gone[id] = true
+	}
+	for _, id := range g.IDs {
+		if !gone[id] {
+			a.marked[id] = true
+		}
+	}
+
+	a.state = stateGenerations
+	if len(missing) > 0 {
+		a.setStatus(a.t("groups.loadedMissing", g.Name, len(missing), strings.Join(missing, ", ")))
+	} else {
+		a.setStatus(a.t("groups.loaded", g.Name))
+	}
+}
+
+func (a *App) renderGroups() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(a.t("groups.title")))
+	b.WriteString("\n\n")
+
+	if len(a.groupList) == 0 {
+		b.WriteString(itemStyle.Render(a.t("groups.empty")))
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	for i, g := range a.groupList {
+		ids := append([]string(nil), g.IDs...)
+		sort.Strings(ids)
+		item := fmt.Sprintf("%s (%s)", g.Name, strings.Join(ids, ", "))
+		if missing := a.missingMembers(g); len(missing) > 0 {
+			item += " " + a.t("groups.missing", len(missing))
+		}
+
+		style := itemStyle
+		if i == a.groupCursor {
+			item = "> " + item
+			style = selectedItemStyle
+		} else {
+			item = "  " + item
+		}
+		b.WriteString(style.Render(item))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+type groupKeys struct {
+	Delete key.Binding
+}
+
+func newGroupKeys(t func(string, ...any) string) groupKeys {
+	return groupKeys{
+		Delete: key.NewBinding(
+			key.WithKeys("x", "delete"),
+			key.WithHelp("x", t("help.deleteGroup")),
+		),
+	}
+}
+
+// groupsHelp shows the group actions alongside navigation.
+type groupsHelp struct {
+	nav     keyMap
+	actions groupKeys
+}
+
+func (h groupsHelp) ShortHelp() []key.Binding {
+	return []key.Binding{h.nav.Up, h.nav.Down, h.nav.Select, h.actions.Delete, h.nav.Back}
+}
+
+func (h groupsHelp) FullHelp() [][]key.Binding {
+	return [][]key.Binding{h.ShortHelp()}
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+
+	"nix-timemach/internal/i18n"
+)
+
+// named returns the bindings by the names the configuration file uses for
+// them.
+func (k *keyMap) named() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":             &k.Up,
+		"down":           &k.Down,
+		"page_up":        &k.PageUp,
+		"page_down":      &k.PageDown,
+		"select":         &k.Select,
+		"mark_from":      &k.From,
+		"diff_from":      &k.DiffFrom,
+		"back":           &k.Back,
+		"quit":           &k.Quit,
+		"reload":         &k.Reload,
+		"known_good":     &k.Good,
+		"details":        &k.Details,
+		"collapse":       &k.Collapse,
+		"pending":        &k.Pending,
+		"mark":           &k.Mark,
+		"save_group":     &k.SaveGroup,
+		"groups":         &k.Groups,
+		"help":           &k.Help,
+		"hashes":         &k.Hashes,
+		"copy_command":   &k.CopyCmd,
+		"find_package":   &k.Find,
+		"filter":         &k.Filter,
+		"snapshot":       &k.Snapshot,
+		"explicit":       &k.Explicit,
+		"files":          &k.Files,
+		"rollback":       &k.Rollback,
+		"boot_default":   &k.Boot,
+		"delete":         &k.Delete,
+		"advise":         &k.Advise,
+		"attr_paths":     &k.AttrPaths,
+		"group_prefixes": &k.Group,
+		"log":            &k.Log,
+		"presets":        &k.Presets,
+		"profile":        &k.Profile,
+		"preview":        &k.Preview,
+		"gc":             &k.GC,
+		"pin":            &k.Pin,
+		"retention":      &k.Retention,
+		"trash":          &k.Trash,
+		"audit":          &k.Audit,
+		"undo":           &k.Undo,
+		"redo":           &k.Redo,
+		"theme":          &k.Theme,
+		"timestamps":     &k.Timestamps,
+		"fold_day":       &k.FoldDay,
+		"bisect":         &k.Bisect,
+		"timeline":       &k.Timeline,
+		"export":         &k.Export,
+		"changelog":      &k.Changelog,
+		"size_history":   &k.SizeHistory,
+		"disk_usage":     &k.Usage,
+		"why_depends":    &k.Why,
+	}
+}
+
+// remap gives the bindings named in custom their new keys, and the help
+// their new labels. It fails on unknown names and on a key bound to two
+// actions, which would leave one of them unreachable.
+func (k *keyMap) remap(custom map[string][]string) error {
+	named := k.named()
+	for _, name := range slices.Sorted(maps.Keys(custom)) {
+		b, ok := named[name]
+		if !ok {
+			return fmt.Errorf("unknown key binding %q", name)
+		}
+		keys := slices.Clone(custom[name])
+		if len(keys) == 0 {
+			return fmt.Errorf("%s: no keys given", name)
+		}
+		for i, s := range keys {
+			if s == "space" {
+				keys[i] = " "
+			}
+			// ctrl+c is handled before any binding.
+			if keys[i] == "ctrl+c" && name != "quit" {
+				return fmt.Errorf("%s: ctrl+c always quits", name)
+			}
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(keyLabel(keys), b.Help().Desc)
+	}
+
+	owner := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		for _, s := range named[name].Keys() {
+			if other, ok := owner[s]; ok {
+				return fmt.Errorf("%q is bound to both %s and %s", s, other, name)
+			}
+			owner[s] = name
+		}
+	}
+	return nil
+}
+
+// ValidateKeys checks custom key bindings before the App is built with
+// them.
+func ValidateKeys(custom map[string][]string) error {
+	k := newKeyMap(i18n.New("").T)
+	return k.remap(custom)
+}
+
+// keyLabel is how keys are shown in the help, e.g. "↑/k".
+func keyLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, s := range keys {
+		switch s {
+		case "up":
+			s = "↑"
+		case "down":
+			s = "↓"
+		case " ":
+			s = "space"
+		}
+		labels[i] = s
+	}
+	return strings.Join(labels, "/")
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/models"
+)
+
+// listHeaderLines is the number of lines renderGenerations draws above the
+// first row.
+const listHeaderLines = 2
+
+// duplicateOfPrevious reports whether generations[i] has the same closure
+// as the row above it. Backends that don't report closure hashes never
+// produce duplicates.
+func (a *App) duplicateOfPrevious(i int) bool {
+	if i == 0 || i >= len(a.generations) {
+		return false
+	}
+	hash := a.generations[i].ClosureHash
+	return hash != "" && hash == a.generations[i-1].ClosureHash
+}
+
+// hidden reports whether generations[i] is left out of the list, by a
+// filter or in a folded day.
+func (a *App) hidden(i int) bool {
+	return a.filteredOut(i) || a.folded(i)
+}
+
+// filteredOut reports whether generations[i] is filtered out of the list.
+func (a *App) filteredOut(i int) bool {
+	return a.collapseDuplicates && a.duplicateOfPrevious(i) ||
+		!a.filter.matches(a.generations[i]) ||
+		!a.search.matches(a.generations[i].ID)
+}
+
+// renderVersions shows the NixOS and kernel versions of generations[i] and
+// the flake revision it was built from. A kernel that differs from the
+// previous generation's is highlighted, so kernel bumps stand out.
+func (a *App) renderVersions(i int) string {
+	gen := a.generations[i]
+	var parts []string
+	if gen.NixosVersion != "" {
+		parts = append(parts, statsStyle.Render(a.t("list.nixos", gen.NixosVersion)))
+	}
+	if gen.KernelVersion != "" {
+		kernel := statsStyle.Render(a.t("list.kernel", gen.KernelVersion))
+		if prev := a.predecessor(i); prev >= 0 {
+			if old := a.generations[prev].KernelVersion; old != "" && old != gen.KernelVersion {
+				kernel = modifiedStyle.Render(a.t("list.kernel", gen.KernelVersion))
+			}
+		}
+		parts = append(parts, kernel)
+	}
+	if rev := gen.ShortRevision(); rev != "" {
+		parts = append(parts, statsStyle.Render(a.t("list.rev", rev)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "  " + strings.Join(parts, " ")
+}
+
+// duplicateRun returns how many rows directly below i repeat its closure.
+func (a *App) duplicateRun(i int) int {
+	n := 0
+	for j := i + 1; a.duplicateOfPrevious(j); j++ {
+		n++
+	}
+	return n
+}
+
+// moveCursor moves the cursor by delta visible rows, stopping at the ends
+// of the list. It reports whether the cursor moved.
+func (a *App) moveCursor(delta int) bool {
+	step := 1
+	if delta < 0 {
+		step, delta = -1, -delta
+	}
+	moved := false
+	for i := a.cursor + step; delta > 0 && i >= 0 && i < len(a.generations); i += step {
+		if a.hidden(i) {
+			continue
+		}
+		a.cursor = i
+		moved = true
+		delta--
+	}
+	return moved
+}
+
+// clampCursor keeps the cursor in range and on a visible row.
+func (a *App) clampCursor() {
+	if a.cursor >= len(a.generations) {
+		a.cursor = len(a.generations) - 1
+	}
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+	for a.cursor > 0 && a.hidden(a.cursor) {
+		a.cursor--
+	}
+}
+
+// rowAt maps a screen line to the generation drawn on it, or -1.
+func (a *App) rowAt(y int) int {
+	line := listHeaderLines - a.viewport.YOffset
+	for i := range a.generations {
+		if a.hidden(i) {
+			continue
+		}
+		if a.opts.DayGroups && a.groupLead(i) && !a.foldedDays[a.dayKey(i)] {
+			line++
+		}
+		if line == y && y >= listHeaderLines && y < listHeaderLines+a.viewport.Height {
+			return i
+		}
+		line++
+	}
+	return -1
+}
+
+// updateMouse handles wheel scrolling and click-to-select in the list. It
+// only ever runs when mouse reporting is enabled, so nothing depends on it.
+func (a *App) updateMouse(msg tea.MouseMsg) tea.Cmd {
+	if a.state != stateGenerations || a.loading {
+		return nil
+	}
+	moved := false
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		moved = a.moveCursor(-1)
+	case msg.Button == tea.MouseButtonWheelDown:
+		moved = a.moveCursor(1)
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		if i := a.rowAt(msg.Y); i >= 0 && i != a.cursor && msg.X < a.listWidth() {
+			a.cursor = i
+			moved = true
+		}
+	}
+	if moved {
+		return a.requestStats()
+	}
+	return nil
+}
+
+// refreshAfterAction reloads the list so it reflects a completed action.
+// Anything derived from the old list is stale, so cached stats are dropped.
+func (a *App) refreshAfterAction(focus string) tea.Cmd {
+	a.stats = make(map[string]models.DiffStats)
+	a.resetPane()
+	a.focusID = focus
+	a.loading = true
+	return a.fetchGenerations()
+}
+
+// restoreFocus puts the cursor on the generation requested by the last
+// action. If that generation is gone, the cursor keeps its position, which
+// lands it on a neighbour of the removed row.
+func (a *App) restoreFocus() {
+	if a.focusID == "" {
+		return
+	}
+	for i, gen := range a.generations {
+		if gen.ID == a.focusID {
+			a.cursor = i
+			break
+		}
+	}
+	a.focusID = ""
+}
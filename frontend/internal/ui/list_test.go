@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// wednesday is "now" in these tests: weeks start on the Monday two days
+// before it.
+var wednesday = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+// withGenerations is a test App listing gens, newest first, with the clock
+// at wednesday in UTC.
+func withGenerations(t *testing.T, opts Options, gens ...models.Generation) *App {
+	t.Helper()
+	loc := listing.Location
+	t.Cleanup(func() { listing.Location = loc })
+	listing.Location = time.UTC
+	a := newTestApp(t, opts)
+	a.generations = gens
+	a.cursor = 0
+	a.now = wednesday
+	return a
+}
+
+// daysAgo is a generation made n days before wednesday.
+func daysAgo(id string, n int) models.Generation {
+	return models.Generation{ID: id, Timestamp: wednesday.AddDate(0, 0, -n)}
+}
+
+func TestDayGroup(t *testing.T) {
+	a := withGenerations(t, Options{})
+	tests := []struct {
+		name    string
+		t       time.Time
+		wantKey string
+	}{
+		{"this morning", time.Date(2026, 10, 14, 0, 5, 0, 0, time.UTC), "today"},
+		{"later today", wednesday.Add(3 * time.Hour), "today"},
+		{"yesterday", wednesday.AddDate(0, 0, -1), "yesterday"},
+		{"monday", wednesday.AddDate(0, 0, -2), "week"},
+		{"sunday", wednesday.AddDate(0, 0, -3), "lastWeek"},
+		{"last monday", wednesday.AddDate(0, 0, -9), "lastWeek"},
+		{"two sundays ago", wednesday.AddDate(0, 0, -10), "2026-10"},
+		{"last month", time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC), "2026-09"},
+		{"other zone", time.Date(2026, 10, 14, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)), "yesterday"},
+		{"unknown", time.Time{}, "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key, _ := a.dayGroup(tt.t); key != tt.wantKey {
+				t.Errorf("dayGroup(%v) = %q, want %q", tt.t, key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestMoveCursor(t *testing.T) {
+	// 2 and 3 repeat 1's closure, so collapsing duplicates hides them.
+	gens := []models.Generation{
+		{ID: "5", ClosureHash: "e"},
+		{ID: "4", ClosureHash: "d"},
+		{ID: "3", ClosureHash: "a"},
+		{ID: "2", ClosureHash: "a"},
+		{ID: "1", ClosureHash: "a"},
+	}
+	tests := []struct {
+		name      string
+		collapse  bool
+		from      int
+		delta     int
+		want      int
+		wantMoved bool
+	}{
+		{"down", false, 0, 1, 1, true},
+		{"up", false, 2, -1, 1, true},
+		{"page down", false, 0, 3, 3, true},
+		{"past the end", false, 3, 5, 4, true},
+		{"at the end", false, 4, 1, 4, false},
+		{"at the top", false, 0, -1, 0, false},
+		{"over hidden rows", true, 1, 1, 2, true},
+		{"nothing visible below", true, 2, 1, 2, false},
+		{"up to visible", true, 2, -2, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := withGenerations(t, Options{}, gens...)
+			a.collapseDuplicates = tt.collapse
+			a.cursor = tt.from
+			moved := a.moveCursor(tt.delta)
+			if a.cursor != tt.want || moved != tt.wantMoved {
+				t.Errorf("moveCursor(%d) from %d: cursor %d, moved %v; want %d, %v", tt.delta, tt.from, a.cursor, moved, tt.want, tt.wantMoved)
+			}
+		})
+	}
+}
+
+func TestRowAt(t *testing.T) {
+	gens := []models.Generation{daysAgo("4", 0), daysAgo("3", 0), daysAgo("2", 1), daysAgo("1", 1)}
+	// Lines 0 and 1 are the list header. rows maps the lines after it to
+	// generations, -1 for a day heading.
+	tests := []struct {
+		name      string
+		dayGroups bool
+		fold      string
+		offset    int
+		rows      []int
+	}{
+		{"plain", false, "", 0, []int{0, 1, 2, 3, -1}},
+		{"day headings", true, "", 0, []int{-1, 0, 1, -1, 2, 3, -1}},
+		{"folded day", true, "yesterday", 0, []int{-1, 0, 1, 2, -1}},
+		{"scrolled", true, "", 2, []int{1, -1, 2, 3, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := withGenerations(t, Options{DayGroups: tt.dayGroups}, gens...)
+			if tt.fold != "" {
+				a.foldedDays[tt.fold] = true
+			}
+			a.viewport.Height = 10
+			a.viewport.YOffset = tt.offset
+			for line, want := range tt.rows {
+				if got := a.rowAt(listHeaderLines + line); got != want {
+					t.Errorf("rowAt(%d) = %d, want %d", listHeaderLines+line, got, want)
+				}
+			}
+			if got := a.rowAt(listHeaderLines - 1); got != -1 {
+				t.Errorf("rowAt on the header = %d, want -1", got)
+			}
+		})
+	}
+}
+
+func TestToggleDay(t *testing.T) {
+	gens := []models.Generation{daysAgo("4", 0), daysAgo("3", 1), daysAgo("2", 1), daysAgo("1", 1)}
+	a := withGenerations(t, Options{DayGroups: true}, gens...)
+	a.cursor = 3
+
+	a.toggleDay()
+	if !a.foldedDays["yesterday"] || a.cursor != 1 {
+		t.Fatalf("folding: folded %v, cursor %d; want yesterday folded with the cursor on its heading, 1", a.foldedDays, a.cursor)
+	}
+	if got := a.groupSize(1); got != 3 {
+		t.Errorf("groupSize = %d, want 3", got)
+	}
+	for i, want := range []bool{false, false, true, true} {
+		if a.hidden(i) != want {
+			t.Errorf("hidden(%d) = %v, want %v", i, a.hidden(i), want)
+		}
+	}
+	if a.moveCursor(1) {
+		t.Errorf("moved into the folded day, to %d", a.cursor)
+	}
+
+	a.toggleDay()
+	if len(a.foldedDays) != 0 || a.cursor != 1 {
+		t.Fatalf("unfolding: folded %v, cursor %d", a.foldedDays, a.cursor)
+	}
+	if !a.moveCursor(2) || a.cursor != 3 {
+		t.Errorf("cursor %d after unfolding, want 3", a.cursor)
+	}
+}
+
+func TestScrollRows(t *testing.T) {
+	a := withGenerations(t, Options{})
+	height := a.listHeight()
+	rows := func() []string {
+		rows := make([]string, 3*height)
+		for i := range rows {
+			rows[i] = fmt.Sprint("row ", i)
+		}
+		return rows
+	}
+	tests := []struct {
+		cursor, wantOffset int
+	}{
+		{0, 0},
+		{height - 1, 0},
+		{height, 1},
+		{3*height - 1, 2 * height},
+		// Back up: the viewport follows only as far as it must.
+		{2 * height, 2 * height},
+		{height, height},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		a.scrollRows(rows(), tt.cursor)
+		if a.viewport.YOffset != tt.wantOffset {
+			t.Errorf("cursor on row %d: scrolled to %d, want %d", tt.cursor, a.viewport.YOffset, tt.wantOffset)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+)
+
+type lockDiffMsg []models.InputChange
+
+// fetchLockDiff never reports an error: like the configuration section,
+// the flake input section stays hidden when there is nothing to show.
+func (a *App) fetchLockDiff(ctx context.Context, from, to string) tea.Msg {
+	changes, err := a.client.GetLockDiff(ctx, from, to)
+	if err != nil {
+		return lockDiffMsg(nil)
+	}
+	return lockDiffMsg(changes)
+}
+
+// renderLockDiff lists the flake inputs whose pins moved, with how far
+// their commit dates moved, so channel movement shows next to the package
+// churn it caused.
+func (a *App) renderLockDiff() string {
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(a.t("locks.title")))
+	b.WriteString("\n")
+	for _, c := range a.lockDiff {
+		line := fmt.Sprintf("  %s: %s → %s", c.Name, a.orNone(shortRev(c.From.Rev)), a.orNone(shortRev(c.To.Rev)))
+		if moved, ok := c.Moved(); ok {
+			switch days := int(math.Round(moved.Hours() / 24)); days {
+			case 0:
+			case 1, -1:
+				line += " " + statsStyle.Render(a.t("locks.day", days))
+			default:
+				line += " " + statsStyle.Render(a.t("locks.days", days))
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// shortRev abbreviates a git revision as git does.
+func shortRev(rev string) string {
+	if len(rev) > 7 {
+		return rev[:7]
+	}
+	return rev
+}
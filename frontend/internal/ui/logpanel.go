@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/backend"
+)
+
+const (
+	// maxLogLines bounds how much backend output the log panel keeps.
+	maxLogLines = 200
+	// logPanelHeight is how many lines of the log panel are visible.
+	logPanelHeight = 6
+)
+
+// logLineMsg carries one line of backend stderr, and the channel to wait on
+// for the next.
+type logLineMsg struct {
+	line  string
+	lines <-chan string
+}
+
+// withLog starts a fresh log for an operation run under the returned
+// context. close must be called once the operation's backend call returns.
+// The returned command feeds the panel until then.
+func (a *App) withLog(ctx context.Context) (context.Context, func(), tea.Cmd) {
+	a.logLines = nil
+	lines := make(chan string, 64)
+	ctx = backend.WithProgress(ctx, func(line string) {
+		select {
+		case lines <- line:
+		default:
+			// The UI is behind; dropping a progress line beats stalling
+			// the backend's output.
+		}
+	})
+	return ctx, func() { close(lines) }, waitForLog(lines)
+}
+
+func waitForLog(lines <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-lines
+		if !ok {
+			return nil
+		}
+		return logLineMsg{line, lines}
+	}
+}
+
+func (a *App) appendLog(msg logLineMsg) tea.Cmd {
+	a.logLines = append(a.logLines, msg.line)
+	if len(a.logLines) > maxLogLines {
+		a.logLines = a.logLines[len(a.logLines)-maxLogLines:]
+	}
+	return waitForLog(msg.lines)
+}
+
+func (a *App) renderLogPanel() string {
+	vp := viewport.New(a.width, logPanelHeight)
+	if len(a.logLines) == 0 {
+		vp.SetContent(a.t("log.empty"))
+	} else {
+		vp.SetContent(strings.Join(a.logLines, "\n"))
+	}
+	vp.GotoBottom()
+	return logPanelStyle.Render(vp.View())
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"nix-timemach/internal/models"
+)
+
+// splitMinWidth is the narrowest terminal the preview pane is shown in;
+// below it the list keeps the whole width.
+const splitMinWidth = 100
+
+// paneDebounce is how long the cursor must rest on a generation before its
+// diff against the previous one is fetched, so scrolling through the list
+// doesn't start a diff per row.
+const paneDebounce = 150 * time.Millisecond
+
+// previewPane is the right half of the split list: the details of the
+// generation at the cursor and what changed since the one before it.
+type previewPane struct {
+	on bool
+	// pending is the statsKey of the diff last scheduled.
+	pending string
+	diffs   map[string]paneDiff
+}
+
+type paneDiff struct {
+	diff models.GenerationDiff
+	err  error
+}
+
+type paneTickMsg struct{ from, to string }
+
+type paneDiffMsg struct {
+	from, to string
+	diff     paneDiff
+}
+
+// splitView reports whether the list is drawn with the preview pane.
+func (a *App) splitView() bool {
+	return a.pane.on && a.state == stateGenerations && a.width >= splitMinWidth
+}
+
+// listWidth is the width the generation list is drawn in.
+func (a *App) listWidth() int {
+	if a.splitView() {
+		return a.width * 3 / 5
+	}
+	return a.width
+}
+
+// schedulePane asks for the cursor's diff against the previous generation
+// once the cursor has rested on it, unless it is known already.
+func (a *App) schedulePane() tea.Cmd {
+	if !a.splitView() || a.cursor >= len(a.generations) {
+		return nil
+	}
+	prev := a.predecessor(a.cursor)
+	if prev < 0 {
+		return nil
+	}
+	from, to := a.generations[prev].ID, a.generations[a.cursor].ID
+	key := statsKey(from, to)
+	if _, ok := a.pane.diffs[key]; ok || key == a.pane.pending {
+		return nil
+	}
+	a.pane.pending = key
+	return tea.Tick(paneDebounce, func(time.Time) tea.Msg {
+		return paneTickMsg{from: from, to: to}
+	})
+}
+
+// fetchPane fetches the diff a tick was scheduled for, if the cursor is
+// still on it.
+func (a *App) fetchPane(msg paneTickMsg) tea.Cmd {
+	if statsKey(msg.from, msg.to) != a.pane.pending {
+		return nil
+	}
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		diff, err := a.client.GetDiff(ctx, msg.from, msg.to)
+		return paneDiffMsg{from: msg.from, to: msg.to, diff: paneDiff{diff, err}}
+	}
+}
+
+func (a *App) applyPaneDiff(msg paneDiffMsg) {
+	if a.pane.diffs == nil {
+		a.pane.diffs = make(map[string]paneDiff)
+	}
+	a.pane.diffs[statsKey(msg.from, msg.to)] = msg.diff
+}
+
+// resetPane drops the cached diffs, which are stale once the list is.
+func (a *App) resetPane() {
+	a.pane.diffs = nil
+	a.pane.pending = ""
+}
+
+// joinPane draws list, the rendered generation list, beside the preview
+// pane.
+func (a *App) joinPane(list string) string {
+	lw := a.listWidth()
+	left := lipgloss.NewStyle().Width(lw).MaxWidth(lw).Render(list)
+	// The pane runs the height the list may grow to, not just the rows
+	// there are.
+	height := max(lipgloss.Height(left), listHeaderLines+a.listHeight())
+	// The border and padding take two columns.
+	inner := a.width - lw - 2
+	clip := lipgloss.NewStyle().MaxWidth(inner)
+	lines := strings.Split(a.renderPane(), "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i, line := range lines {
+		lines[i] = clip.Render(line)
+	}
+	right := paneStyle.Width(inner + 1).Height(height).Render(strings.Join(lines, "\n"))
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+}
+
+func (a *App) renderPane() string {
+	if a.cursor >= len(a.generations) || a.noMatches() {
+		return ""
+	}
+	gen := a.generations[a.cursor]
+
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(a.t("details.title", gen.ID)) + "\n")
+	b.WriteString(a.renderFields(gen))
+	b.WriteString("\n")
+
+	prev := a.predecessor(a.cursor)
+	if prev < 0 {
+		b.WriteString(statsStyle.Render(a.t("pane.oldest")))
+		return b.String()
+	}
+	b.WriteString(headingStyle.Render(a.t("pane.changes", a.generations[prev].ID)) + "\n")
+	entry, ok := a.pane.diffs[statsKey(a.generations[prev].ID, gen.ID)]
+	switch {
+	case !ok:
+		b.WriteString(statsStyle.Render(a.t("diff.loading")))
+	case entry.err != nil:
+		first, _, _ := strings.Cut(entry.err.Error(), "\n")
+		b.WriteString(removedStyle.Render(first))
+	case entry.diff.Empty():
+		b.WriteString(a.t("diff.none"))
+	default:
+		b.WriteString(statsStyle.Render(formatStats(models.StatsOf(entry.diff))) + "\n")
+		for _, c := range entry.diff.Added {
+			b.WriteString(addedStyle.Render(strings.TrimSpace("+ "+c.Name+" "+c.NewVersion)) + "\n")
+		}
+		for _, c := range entry.diff.Removed {
+			b.WriteString(removedStyle.Render(strings.TrimSpace("- "+c.Name+" "+c.OldVersion)) + "\n")
+		}
+		for _, c := range entry.diff.Modified {
+			line := "~ " + c.Name
+			if c.OldVersion != "" && c.NewVersion != "" && c.OldVersion != c.NewVersion {
+				line += fmt.Sprintf(" %s → %s", c.OldVersion, c.NewVersion)
+			}
+			b.WriteString(modifiedStyle.Render(line) + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type pendingCanceledMsg struct{}
+type pendingFailedMsg struct{ err error }
+
+// startPendingDiff switches to the diff view and starts building the
+// current configuration to diff it against the running system.
+func (a *App) startPendingDiff() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, closeLog, tailLog := a.withLog(ctx)
+	a.cancelPending = cancel
+	a.pending = true
+	a.pendingStarted = time.Now()
+	a.state = stateDiff
+	a.diff = nil
+	a.configDiff = nil
+	a.lockDiff = nil
+
+	return tea.Batch(tailLog, func() tea.Msg {
+		defer closeLog()
+		diff, err := a.client.GetPendingDiff(ctx)
+		if errors.Is(err, context.Canceled) {
+			return pendingCanceledMsg{}
+		}
+		if err != nil {
+			return pendingFailedMsg{err}
+		}
+		return diffMsg(diff)
+	})
+}
+
+// stopPendingDiff cancels an in-flight pending diff, if any.
+func (a *App) stopPendingDiff() {
+	if a.cancelPending != nil {
+		a.cancelPending()
+		a.cancelPending = nil
+	}
+	a.pending = false
+}
+
+func (a *App) renderPendingProgress() string {
+	elapsed := time.Since(a.pendingStarted).Round(time.Second)
+	return a.spinner.View() + " " + a.t("pending.building", elapsed)
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+)
+
+// askPin pins gen under an optional name, or offers to unpin it if it is
+// pinned already.
+func (a *App) askPin(gen models.Generation) {
+	id := gen.ID
+	if gen.Pinned {
+		step := systemStep(a.t("undo.unpin", id), a.pin(id, gen.PinName), a.unpin(id))
+		a.askConfirm(a.t("confirm.unpin", id), a.privileged(a.undoable(a.unpin(id), step)))
+		return
+	}
+	a.askPrompt(a.t("pin.prompt", id), "", func(name string) tea.Cmd {
+		name = strings.TrimSpace(name)
+		step := systemStep(a.t("undo.pin", id), a.unpin(id), a.pin(id, name))
+		return a.privileged(a.undoable(a.pin(id, name), step))
+	})
+}
+
+func (a *App) pin(id, name string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.Pin(context.Background(), id, name); err != nil {
+			return errMsg{err}
+		}
+		return actionDoneMsg{status: a.t("status.pinned", id), focus: id}
+	}
+}
+
+func (a *App) unpin(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.Unpin(context.Background(), id); err != nil {
+			return errMsg{err}
+		}
+		return actionDoneMsg{status: a.t("status.unpinned", id), focus: id}
+	}
+}
+
+// renderPin flags a pinned generation in the list, by its pin's name when
+// it has one.
+func (a *App) renderPin(gen models.Generation) string {
+	if !gen.Pinned {
+		return ""
+	}
+	if gen.PinName != "" {
+		return "  " + presenceStyle.Render(a.t("list.pinnedAs", gen.PinName))
+	}
+	return "  " + presenceStyle.Render(a.t("list.pinned"))
+}
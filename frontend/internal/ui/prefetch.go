@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prefetchDelay is how long the cursor must rest on a generation before
+// the diffs it is likely to be opened with are computed ahead of time.
+const prefetchDelay = 300 * time.Millisecond
+
+// prefetchWorkers bounds how many diffs are computed at once.
+const prefetchWorkers = 2
+
+// prefetcher computes diffs around the cursor in the background so that
+// opening them is instant. It relies on the client caching diffs.
+type prefetcher struct {
+	// id is the generation last scheduled for.
+	id     string
+	cancel context.CancelFunc
+}
+
+type prefetchTickMsg struct{ id string }
+
+// schedulePrefetch starts the delay for the cursor's generation when the
+// cursor has moved to a new one, abandoning the diffs for the old.
+func (a *App) schedulePrefetch() tea.Cmd {
+	if !a.opts.Prefetch || a.state != stateGenerations || a.cursor >= len(a.generations) {
+		return nil
+	}
+	id := a.generations[a.cursor].ID
+	if id == a.prefetch.id {
+		return nil
+	}
+	a.stopPrefetch()
+	a.prefetch.id = id
+	return tea.Tick(prefetchDelay, func(time.Time) tea.Msg {
+		return prefetchTickMsg{id}
+	})
+}
+
+func (a *App) stopPrefetch() {
+	if a.prefetch.cancel != nil {
+		a.prefetch.cancel()
+		a.prefetch.cancel = nil
+	}
+}
+
+// startPrefetch computes the diffs against the cursor's neighbours and
+// from the marked generation, if the cursor is still where the tick was
+// scheduled. Results only warm the cache; failures are ignored.
+func (a *App) startPrefetch(msg prefetchTickMsg) tea.Cmd {
+	if msg.id != a.prefetch.id || a.state != stateGenerations || a.cursor >= len(a.generations) {
+		return nil
+	}
+	cur := a.generations[a.cursor].ID
+	var pairs [][2]string
+	if a.selected != nil && a.selected.ID != cur {
+		pairs = append(pairs, [2]string{a.selected.ID, cur})
+	}
+	if prev := a.predecessor(a.cursor); prev >= 0 {
+		pairs = append(pairs, [2]string{a.generations[prev].ID, cur})
+	}
+	if next := a.successor(a.cursor); next >= 0 {
+		pairs = append(pairs, [2]string{cur, a.generations[next].ID})
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(a.profileContext())
+	a.prefetch.cancel = cancel
+	client := a.client
+	return func() tea.Msg {
+		jobs := make(chan [2]string)
+		var wg sync.WaitGroup
+		for range min(prefetchWorkers, len(pairs)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for pair := range jobs {
+					client.GetDiff(ctx, pair[0], pair[1])
+				}
+			}()
+		}
+		for _, pair := range pairs {
+			select {
+			case jobs <- pair:
+			case <-ctx.Done():
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		return nil
+	}
+}
+
+// successor returns the index of the generation created just after
+// generations[i], or -1 if it is the newest.
+func (a *App) successor(i int) int {
+	next := -1
+	for j, gen := range a.generations {
+		if gen.Timestamp.After(a.generations[i].Timestamp) &&
+			(next < 0 || gen.Timestamp.Before(a.generations[next].Timestamp)) {
+			next = j
+		}
+	}
+	return next
+}
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"strings"
+
+	"nix-timemach/internal/models"
+)
+
+// minPrefixGroup is how many entries must share a prefix before they are
+// shown as one group.
+const minPrefixGroup = 4
+
+// changeGroup is a run of diff entries shown together. A group with a
+// prefix stands for all its items; one without is a single entry.
+type changeGroup struct {
+	prefix string
+	items  []models.PackageChange
+}
+
+// packagePrefix is the ecosystem part of a package name, e.g. "python3.11"
+// for python3.11-requests, or "" for names without one.
+func packagePrefix(path string) string {
+	_, name, _ := models.ParseStorePath(path)
+	prefix, _, ok := strings.Cut(name, "-")
+	if !ok {
+		return ""
+	}
+	return prefix
+}
+
+// groupByPrefix folds entries sharing a prefix with at least
+// minPrefixGroup-1 others into one group, placed where the first of them
+// appeared. Other entries stay as they are.
+func groupByPrefix(changes []models.PackageChange) []changeGroup {
+	count := make(map[string]int)
+	for _, c := range changes {
+		if p := packagePrefix(c.Path); p != "" {
+			count[p]++
+		}
+	}
+
+	var groups []changeGroup
+	index := make(map[string]int)
+	for _, c := range changes {
+		p := packagePrefix(c.Path)
+		if count[p] < minPrefixGroup || p == "" {
+			groups = append(groups, changeGroup{items: []models.PackageChange{c}})
+			continue
+		}
+		if i, ok := index[p]; ok {
+			groups[i].items = append(groups[i].items, c)
+			continue
+		}
+		index[p] = len(groups)
+		groups = append(groups, changeGroup{prefix: p, items: []models.PackageChange{c}})
+	}
+	return groups
+}
+
+// renderChangeList draws added or removed entries, grouped by prefix when
+// that is switched on.
+func (a *App) renderChangeList(diff models.GenerationDiff, sign string, changes []models.PackageChange) string {
+	var b strings.Builder
+	if !a.groupPrefixes {
+		for _, item := range changes {
+			b.WriteString("  " + sign + " " + a.renderChange(diff, item) + "\n")
+		}
+		return b.String()
+	}
+	for _, g := range groupByPrefix(changes) {
+		if g.prefix == "" {
+			b.WriteString("  " + sign + " " + a.renderChange(diff, g.items[0]) + "\n")
+			continue
+		}
+		b.WriteString("  " + sign + " " + a.t("diff.prefixGroup", g.prefix, len(g.items)) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// preset compares the newest generation against the one that was in
+// effect at some earlier time.
+type preset struct {
+	label string
+	// since returns the earlier time to compare against.
+	since func(now time.Time) (time.Time, error)
+}
+
+func (a *App) presets() []preset {
+	ago := func(d time.Duration) func(time.Time) (time.Time, error) {
+		return func(now time.Time) (time.Time, error) { return now.Add(-d), nil }
+	}
+	return []preset{
+		{a.t("presets.day"), ago(24 * time.Hour)},
+		{a.t("presets.week"), ago(7 * 24 * time.Hour)},
+		{a.t("presets.month"), ago(30 * 24 * time.Hour)},
+		{a.t("presets.boot"), func(time.Time) (time.Time, error) { return bootTime() }},
+	}
+}
+
+// bootTime reads when the system booted from the kernel.
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read boot time: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse boot time: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("failed to read boot time: no btime in /proc/stat")
+}
+
+// newest returns the index of the most recent generation, or -1.
+func (a *App) newest() int {
+	best := -1
+	for i, gen := range a.generations {
+		if best < 0 || gen.Timestamp.After(a.generations[best].Timestamp) {
+			best = i
+		}
+	}
+	return best
+}
+
+// inEffectAt returns the index of the latest generation created at or
+// before t, which is the one the system was running then, or -1 if every
+// generation is newer.
+func (a *App) inEffectAt(t time.Time) int {
+	best := -1
+	for i, gen := range a.generations {
+		if gen.Timestamp.After(t) {
+			continue
+		}
+		if best < 0 || gen.Timestamp.After(a.generations[best].Timestamp) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (a *App) updatePresets(msg tea.KeyMsg) tea.Cmd {
+	presets := a.presets()
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+
+	case key.Matches(msg, a.keys.Up):
+		if a.presetCursor > 0 {
+			a.presetCursor--
+		}
+
+	case key.Matches(msg, a.keys.Down):
+		if a.presetCursor < len(presets)-1 {
+			a.presetCursor++
+		}
+
+	case key.Matches(msg, a.keys.Select):
+		return a.applyPreset(presets[a.presetCursor])
+
+	default:
+		// Digits pick a preset directly.
+		if n, err := strconv.Atoi(msg.String()); err == nil && n >= 1 && n <= len(presets) {
+			a.presetCursor = n - 1
+			return a.applyPreset(presets[n-1])
+		}
+	}
+	return nil
+}
+
+func (a *App) applyPreset(p preset) tea.Cmd {
+	a.state = stateGenerations
+	to := a.newest()
+	if to < 0 {
+		return nil
+	}
+	since, err := p.since(a.now)
+	if err != nil {
+		a.setStatus(err.Error())
+		return nil
+	}
+	from := a.inEffectAt(since)
+	switch {
+	case from < 0:
+		a.setStatus(a.t("presets.noneBefore", since.Format("2006-01-02 15:04")))
+		return nil
+	case from == to:
+		a.setStatus(a.t("presets.unchanged", a.generations[to].ID, since.Format("2006-01-02 15:04")))
+		return nil
+	}
+
+	a.cursor = to
+	return a.showDiff(from)
+}
+
+func (a *App) renderPresets() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(a.t("presets.title")))
+	b.WriteString("\n\n")
+
+	for i, p := range a.presets() {
+		item := fmt.Sprintf("%d  %s", i+1, p.label)
+		style := itemStyle
+		if i == a.presetCursor {
+			item = "> " + item
+			style = selectedItemStyle
+		} else {
+			item = "  " + item
+		}
+		b.WriteString(style.Render(item))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/backend"
+)
+
+type authorizedMsg struct{ action tea.Cmd }
+
+// privileged runs action after obtaining root credentials when the client
+// escalates through sudo. The TUI is suspended while sudo prompts for a
+// password on the terminal, then resumes to run the action.
+func (a *App) privileged(action tea.Cmd) tea.Cmd {
+	cmd := a.client.AuthorizeCommand()
+	if cmd == nil {
+		return action
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if err != nil {
+			return errMsg{fmt.Errorf("%w: %v", backend.ErrEscalation, err)}
+		}
+		return authorizedMsg{action}
+	})
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+type profilesMsg []models.Profile
+
+// loadProfiles finds the profiles there are to switch between. Without
+// them the system profile is still browsed, so errors are dropped.
+func (a *App) loadProfiles() tea.Msg {
+	profiles, err := a.client.GetProfiles(context.Background())
+	if err != nil {
+		return nil
+	}
+	return profilesMsg(profiles)
+}
+
+// applyProfiles starts on the system profile, or on the one the options
+// ask for, whose generations are then loaded in place of the system's.
+func (a *App) applyProfiles(msg profilesMsg) tea.Cmd {
+	a.profiles = msg
+	a.profileIndex = 0
+	for i, p := range msg {
+		if p.System() {
+			a.profileIndex = i
+		}
+	}
+	if a.opts.Profile == "" {
+		return nil
+	}
+	for i, p := range msg {
+		if (p.Name == a.opts.Profile || p.Path == a.opts.Profile) && i != a.profileIndex {
+			return a.showProfile(i)
+		}
+	}
+	return nil
+}
+
+// activeProfile returns the profile being browsed, if profiles are known.
+func (a *App) activeProfile() (models.Profile, bool) {
+	if a.profileIndex >= len(a.profiles) {
+		return models.Profile{}, false
+	}
+	return a.profiles[a.profileIndex], true
+}
+
+// onSystemProfile reports whether the system's generations are shown,
+// which the actions that change the system require.
+func (a *App) onSystemProfile() bool {
+	p, ok := a.activeProfile()
+	return !ok || p.System()
+}
+
+// profileContext returns the context for backend calls that read the
+// generations being browsed.
+func (a *App) profileContext() context.Context {
+	p, ok := a.activeProfile()
+	if !ok {
+		return context.Background()
+	}
+	return backend.ForProfile(context.Background(), p.Path)
+}
+
+// switchProfile moves on to the next profile and loads its generations.
+func (a *App) switchProfile() tea.Cmd {
+	if len(a.profiles) < 2 {
+		return nil
+	}
+	return a.showProfile((a.profileIndex + 1) % len(a.profiles))
+}
+
+// showProfile loads the generations of profiles[i]. Everything about the
+// previous list is dropped along with it.
+func (a *App) showProfile(i int) tea.Cmd {
+	a.profileIndex = i
+	a.generations = nil
+	a.cursor = 0
+	a.focusID = ""
+	a.selected = nil
+	a.marked = make(map[string]bool)
+	a.undo.Clear()
+	a.notice = ""
+	a.stats = make(map[string]models.DiffStats)
+	a.resetPane()
+	a.rollback = nil
+	a.search = nil
+	a.loading = true
+	a.setStatus(a.t("status.profile", a.profiles[a.profileIndex].Name))
+	return tea.Batch(a.fetchGenerations(), a.rewatch())
+}
+
+// staleGenerations reports whether a list arrived for a profile that was
+// switched away from while it loaded.
+func (a *App) staleGenerations(generations []models.Generation) bool {
+	p, ok := a.activeProfile()
+	return ok && len(generations) > 0 && generations[0].Profile != "" && generations[0].Profile != p.Path
+}
+
+func (a *App) renderProfile() string {
+	p, ok := a.activeProfile()
+	if !ok || len(a.profiles) < 2 {
+		return ""
+	}
+	return "  " + itemStyle.Render(a.t("list.profile", p.Name))
+}
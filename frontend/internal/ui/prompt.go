@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prompt asks the user for a line of text and hands it to submit.
+type prompt struct {
+	label  string
+	input  textinput.Model
+	submit func(string) tea.Cmd
+	prev   state
+}
+
+func (a *App) askPrompt(label, initial string, submit func(string) tea.Cmd) {
+	input := textinput.New()
+	input.SetValue(initial)
+	input.Cursor.SetMode(cursor.CursorStatic)
+	input.Focus()
+
+	a.prompt = &prompt{label: label, input: input, submit: submit, prev: a.state}
+	a.state = statePrompt
+}
+
+func (a *App) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	p := a.prompt
+	switch msg.Type {
+	case tea.KeyEnter:
+		a.state = p.prev
+		a.prompt = nil
+		return p.submit(p.input.Value())
+	case tea.KeyEsc:
+		a.state = p.prev
+		a.prompt = nil
+		return nil
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return cmd
+}
+
+func (a *App) renderPrompt() string {
+	return titleStyle.Render(a.prompt.label) + "\n\n  " + a.prompt.input.View()
+}
@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mutating returns the bindings for actions that modify the system. New
+// mutating actions must be listed here for read-only mode to cover them;
+// the client refuses to run them regardless.
+func (k *keyMap) mutating() []*key.Binding {
+	return []*key.Binding{&k.Good, &k.Rollback, &k.Boot, &k.Delete, &k.GC, &k.Pin, &k.Retention, &k.Bisect}
+}
+
+// disableMutating hides the mutating bindings from the help.
+func (k *keyMap) disableMutating() {
+	for _, b := range k.mutating() {
+		b.SetEnabled(false)
+	}
+}
+
+// mutates reports whether msg is the key of a mutating action. Disabled
+// bindings never match, so the keys are compared directly.
+func (k *keyMap) mutates(msg tea.KeyMsg) bool {
+	for _, b := range k.mutating() {
+		if slices.Contains(b.Keys(), msg.String()) {
+			return true
+		}
+	}
+	return false
+}
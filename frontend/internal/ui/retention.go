@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/config"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// The retention policy's fields, in the order the editor shows them.
+const (
+	retainLast = iota
+	retainWeekly
+	retainPinned
+	retainFields
+)
+
+var (
+	retentionLess = key.NewBinding(key.WithKeys("left", "h", "-"))
+	retentionMore = key.NewBinding(key.WithKeys("right", "l", "+", " "))
+)
+
+// retentionScreen edits the retention policy, then reviews what applying
+// it would delete.
+type retentionScreen struct {
+	policy    models.RetentionPolicy
+	field     int
+	reviewing bool
+	doomed    []models.Generation
+	running   bool
+}
+
+func (a *App) openRetention() {
+	a.retention = &retentionScreen{policy: a.opts.Retention}
+	a.state = stateRetention
+}
+
+func (a *App) updateRetention(msg tea.KeyMsg) tea.Cmd {
+	r := a.retention
+	switch {
+	case r.running:
+		// Deleting can't be stopped halfway; wait for it.
+	case r.reviewing:
+		return a.updateRetentionReview(msg)
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		if r.field > 0 {
+			r.field--
+		}
+	case key.Matches(msg, a.keys.Down):
+		if r.field < retainFields-1 {
+			r.field++
+		}
+	case key.Matches(msg, retentionLess):
+		r.adjust(-1)
+	case key.Matches(msg, retentionMore):
+		r.adjust(1)
+	case key.Matches(msg, a.keys.Select):
+		// The policy is saved even if the review is abandoned, so the next
+		// cleanup starts from it.
+		if err := config.SaveRetention(r.policy); err != nil {
+			a.setStatus(err.Error())
+		} else {
+			a.opts.Retention = r.policy
+		}
+		r.doomed = r.policy.Doomed(a.generations, a.now)
+		r.reviewing = true
+	}
+	return nil
+}
+
+// adjust changes the field at the cursor by delta; for the flag, any
+// change flips it.
+func (r *retentionScreen) adjust(delta int) {
+	switch r.field {
+	case retainLast:
+		r.policy.KeepLast = max(0, r.policy.KeepLast+delta)
+	case retainWeekly:
+		r.policy.KeepWeekly = max(0, r.policy.KeepWeekly+delta)
+	case retainPinned:
+		r.policy.KeepPinned = !r.policy.KeepPinned
+	}
+}
+
+func (a *App) updateRetentionReview(msg tea.KeyMsg) tea.Cmd {
+	r := a.retention
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		r.reviewing = false
+	case key.Matches(msg, a.keys.Select):
+		if len(r.doomed) == 0 {
+			return nil
+		}
+		r.running = true
+		return a.privileged(a.applyRetention(r.doomed))
+	}
+	return nil
+}
+
+// applyRetention deletes the doomed generations, unpinning the pinned ones
+// first, as the backend won't delete those.
+func (a *App) applyRetention(doomed []models.Generation) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		var ids []string
+		for _, gen := range doomed {
+			if gen.Pinned {
+				if err := a.client.Unpin(ctx, gen.ID); err != nil {
+					return retentionDoneMsg{err: err}
+				}
+			}
+			ids = append(ids, gen.ID)
+		}
+		err := a.client.DeleteGenerations(ctx, ids)
+		return retentionDoneMsg{ids: ids, err: err}
+	}
+}
+
+type retentionDoneMsg struct {
+	ids []string
+	err error
+}
+
+// applyRetentionDone returns to the list, which has lost the deleted
+// generations, and reports like any other finished action.
+func (a *App) applyRetentionDone(msg retentionDoneMsg) tea.Cmd {
+	a.retention = nil
+	a.state = stateGenerations
+	if msg.err != nil {
+		a.err = msg.err
+		return nil
+	}
+	for _, id := range msg.ids {
+		delete(a.marked, id)
+	}
+	status := a.t("retention.done", len(msg.ids))
+	return func() tea.Msg { return actionDoneMsg{status: status} }
+}
+
+func (a *App) renderRetention() string {
+	r := a.retention
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("retention.title")) + "\n\n")
+	switch {
+	case r.running:
+		b.WriteString(a.spinner.View() + " " + a.t("retention.running"))
+		return b.String()
+	case r.reviewing:
+		b.WriteString(a.renderRetentionReview())
+		return b.String()
+	}
+
+	pinned := a.t("retention.no")
+	if r.policy.KeepPinned {
+		pinned = a.t("retention.yes")
+	}
+	fields := []string{
+		a.t("retention.last", r.policy.KeepLast),
+		a.t("retention.weekly", r.policy.KeepWeekly),
+		a.t("retention.pinned", pinned),
+	}
+	for i, field := range fields {
+		if i == r.field {
+			b.WriteString(selectedItemStyle.Render("> " + field))
+		} else {
+			b.WriteString(itemStyle.Render("  " + field))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("retention.editChoices")))
+	return b.String()
+}
+
+// renderRetentionReview lists the generations the policy would delete,
+// newest first.
+func (a *App) renderRetentionReview() string {
+	r := a.retention
+	if len(r.doomed) == 0 {
+		return a.t("retention.nothing") + "\n\n" + statsStyle.Render(a.t("retention.backChoice"))
+	}
+	var b strings.Builder
+	b.WriteString(headingStyle.Render(a.t("retention.doomed", len(r.doomed))) + "\n")
+	for _, gen := range r.doomed {
+		line := fmt.Sprintf("  %s  %s - %s", removedStyle.Render(gen.ID), listing.Timestamp(gen.Timestamp), gen.Description)
+		if gen.Pinned {
+			line += "  " + presenceStyle.Render(a.t("retention.unpins"))
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("retention.reviewChoices")))
+	return b.String()
+}
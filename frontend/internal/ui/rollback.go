@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// rollbackCriteria score a candidate's diff from the current generation;
+// lower is safer.
+var rollbackCriteria = map[string]func(models.DiffStats) int64{
+	"removed":  func(s models.DiffStats) int64 { return int64(s.Removed) },
+	"modified": func(s models.DiffStats) int64 { return int64(s.Modified) },
+	"added":    func(s models.DiffStats) int64 { return int64(s.Added) },
+	"changes":  func(s models.DiffStats) int64 { return int64(s.Added + s.Removed + s.Modified) },
+	"size": func(s models.DiffStats) int64 {
+		if !s.SizeKnown {
+			return math.MaxInt64
+		}
+		if s.SizeDelta < 0 {
+			return -s.SizeDelta
+		}
+		return s.SizeDelta
+	},
+}
+
+// DefaultRollbackRank prefers the fewest removals, then the smallest size
+// change.
+var DefaultRollbackRank = []string{"removed", "size"}
+
+// ParseRollbackRank parses a comma-separated list of ranking criteria, most
+// important first: removed, modified, added, changes or size.
+func ParseRollbackRank(s string) ([]string, error) {
+	var rank []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := rollbackCriteria[name]; !ok {
+			return nil, fmt.Errorf("unknown criterion %q", name)
+		}
+		rank = append(rank, name)
+	}
+	if len(rank) == 0 {
+		return nil, errors.New("no criteria given")
+	}
+	return rank, nil
+}
+
+type rollbackCandidate struct {
+	id    string
+	stats models.DiffStats
+}
+
+// rollbackMsg carries the candidates older than the current generation,
+// safest first.
+type rollbackMsg []rollbackCandidate
+
+// current returns the index of the generation the system runs, falling
+// back to the newest when the backend doesn't say, or -1.
+func (a *App) current() int {
+	for i, gen := range a.generations {
+		if gen.Current {
+			return i
+		}
+	}
+	return a.newest()
+}
+
+// adviseRollback ranks every generation older than the current one by how
+// much rolling back to it would change. Pressed again, it clears the
+// advice.
+func (a *App) adviseRollback() tea.Cmd {
+	if a.rollback != nil {
+		a.rollback = nil
+		return nil
+	}
+	cur := a.current()
+	if cur < 0 {
+		return nil
+	}
+	current := a.generations[cur]
+
+	var candidates []models.Generation
+	for _, gen := range a.generations {
+		if gen.ID != current.ID && gen.Timestamp.Before(current.Timestamp) {
+			candidates = append(candidates, gen)
+		}
+	}
+	if len(candidates) == 0 {
+		a.setStatus(a.t("rollback.none"))
+		return nil
+	}
+	// Closer generations win ties, so order newest first before ranking.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Timestamp.After(candidates[j].Timestamp)
+	})
+
+	rank := a.opts.RollbackRank
+	if len(rank) == 0 {
+		rank = DefaultRollbackRank
+	}
+	a.loading = true
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		pairs := make([][2]string, len(candidates))
+		for i, gen := range candidates {
+			pairs[i] = [2]string{current.ID, gen.ID}
+		}
+		stats, err := a.client.GetDiffStatsBulk(ctx, pairs)
+		var bulkErr *backend.BulkDiffError
+		if err != nil && !errors.As(err, &bulkErr) {
+			return errMsg{err}
+		}
+
+		var ranked rollbackMsg
+		for i, gen := range candidates {
+			// Candidates whose diff failed can't be judged, so they are left
+			// out rather than ranked as if nothing changed.
+			if bulkErr != nil && bulkErr.Errs[i] != nil {
+				continue
+			}
+			ranked = append(ranked, rollbackCandidate{id: gen.ID, stats: stats[i]})
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			for _, name := range rank {
+				score := rollbackCriteria[name]
+				if si, sj := score(ranked[i].stats), score(ranked[j].stats); si != sj {
+					return si < sj
+				}
+			}
+			return false
+		})
+		return ranked
+	}
+}
+
+func (a *App) applyRollbackAdvice(ranked rollbackMsg) {
+	a.loading = false
+	if len(ranked) == 0 {
+		a.setStatus(a.t("rollback.failed"))
+		return
+	}
+	a.rollback = ranked
+	best := ranked[0]
+	for i, gen := range a.generations {
+		if gen.ID == best.id {
+			a.cursor = i
+		}
+	}
+	a.setStatus(a.t("rollback.recommend", best.id, formatStats(best.stats)))
+}
+
+// rollbackAnnotation marks the recommended rollback target in the list.
+func (a *App) rollbackAnnotation(id string) (string, bool) {
+	if len(a.rollback) == 0 || a.rollback[0].id != id {
+		return "", false
+	}
+	return adviceStyle.Render(a.t("rollback.safest")), true
+}
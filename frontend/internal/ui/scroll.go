@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// listHeight is how many rows of the generation list fit on screen below
+// its header and above the status bar, log panel and help.
+func (a *App) listHeight() int {
+	footer := 2 + lipgloss.Height(a.help.View(a.keys))
+	if a.renderStatusBar() != "" {
+		footer++
+	}
+	if a.showLog {
+		footer += lipgloss.Height(a.renderLogPanel())
+	}
+	return max(1, a.height-listHeaderLines-footer)
+}
+
+// pageSize is how far page up and page down move the cursor.
+func (a *App) pageSize() int {
+	return max(1, a.listHeight()-1)
+}
+
+// scrollRows shows the rows that fit through the viewport, scrolled just
+// enough to keep the cursor's row in view. Rows are cut at the terminal
+// width rather than wrapped, so each takes one line and mouse clicks map
+// to them.
+func (a *App) scrollRows(rows []string, cursorRow int) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	clip := lipgloss.NewStyle().MaxWidth(a.listWidth())
+	for i, row := range rows {
+		rows[i] = clip.Render(row)
+	}
+	a.viewport.Width = a.listWidth()
+	a.viewport.Height = min(len(rows), a.listHeight())
+	a.viewport.SetContent(strings.Join(rows, "\n"))
+	switch top := a.viewport.YOffset; {
+	case cursorRow < top:
+		a.viewport.SetYOffset(cursorRow)
+	case cursorRow >= top+a.viewport.Height:
+		a.viewport.SetYOffset(cursorRow - a.viewport.Height + 1)
+	}
+	return a.viewport.View() + "\n"
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/models"
+)
+
+// packageFoundMsg carries the result of a package search: which
+// generations contain the package, keyed by generation ID. A search for a
+// version only matches generations with that version.
+type packageFoundMsg struct {
+	query   string
+	name    string
+	version string
+	found   map[string]models.PackagePresence
+}
+
+// presence is safe to call on a nil search, which means none is active.
+func (s *packageFoundMsg) presence(id string) (models.PackagePresence, bool) {
+	if s == nil {
+		return models.PackagePresence{}, false
+	}
+	p, ok := s.found[id]
+	return p, ok
+}
+
+// matches reports whether generation id has the package at the version
+// searched for. Every generation matches when no search is active.
+func (s *packageFoundMsg) matches(id string) bool {
+	if s == nil {
+		return true
+	}
+	p, ok := s.found[id]
+	return ok && p.Present && models.VersionMatches(p.Version, s.version)
+}
+
+func (a *App) askFindPackage() {
+	initial := ""
+	if a.search != nil {
+		initial = a.search.query
+	}
+	a.askPrompt(a.t("search.prompt"), initial, func(query string) tea.Cmd {
+		query = strings.TrimSpace(query)
+		name, version := models.ParsePackageQuery(query)
+		if name == "" {
+			a.search = nil
+			return nil
+		}
+		a.loading = true
+		ctx := a.profileContext()
+		return func() tea.Msg {
+			presence, err := a.client.FindPackage(ctx, name)
+			if err != nil {
+				return errMsg{err}
+			}
+			found := make(map[string]models.PackagePresence, len(presence))
+			for _, p := range presence {
+				found[p.Generation] = p
+			}
+			return packageFoundMsg{query: query, name: name, version: version, found: found}
+		}
+	})
+}
+
+// applySearch narrows the list to the generations that match the search,
+// moving the cursor onto one of them.
+func (a *App) applySearch(msg packageFoundMsg) {
+	a.loading = false
+	a.search = &msg
+	a.clampCursor()
+	if len(a.generations) > 0 && a.hidden(a.cursor) {
+		a.moveCursor(1)
+	}
+	a.setStatus(a.searchSummary())
+}
+
+// searchSummary counts the generations that match the search and names
+// the oldest and newest, which answers when the package was introduced and
+// dropped.
+func (a *App) searchSummary() string {
+	first, last, n := -1, -1, 0
+	for i, gen := range a.generations {
+		if !a.search.matches(gen.ID) {
+			continue
+		}
+		n++
+		if first < 0 || gen.Timestamp.Before(a.generations[first].Timestamp) {
+			first = i
+		}
+		if last < 0 || gen.Timestamp.After(a.generations[last].Timestamp) {
+			last = i
+		}
+	}
+	if first < 0 {
+		return a.t("search.notFound", a.search.query)
+	}
+	return a.t("search.found", a.search.query, n, a.generations[first].ID, a.generations[last].ID)
+}
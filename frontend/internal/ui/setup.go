@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+)
+
+// setupDemo leaves the first-run screen for the demo system.
+var setupDemo = key.NewBinding(key.WithKeys("d"))
+
+func (a *App) updateSetup(msg tea.KeyMsg) tea.Cmd {
+	if !key.Matches(msg, setupDemo) {
+		return nil
+	}
+	var opts []backend.Option
+	if a.opts.ReadOnly {
+		opts = append(opts, backend.ReadOnly())
+	}
+	a.client = backend.NewDemo(opts...)
+	a.opts.MissingBackend = nil
+	a.fleet = nil
+	a.state = stateGenerations
+	a.loading = true
+	return tea.Batch(a.fetchGenerations(), a.loadProfiles)
+}
+
+// renderSetup is the first-run screen shown when there is no backend
+// binary: where it was looked for and the ways to provide one.
+func (a *App) renderSetup() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("setup.title")) + "\n\n")
+	b.WriteString(a.t("setup.missing", backend.BinaryName) + "\n\n")
+	for _, place := range a.opts.MissingBackend.Searched {
+		b.WriteString("  " + statsStyle.Render(place) + "\n")
+	}
+	b.WriteString("\n" + a.t("setup.fix") + "\n\n")
+	b.WriteString(statsStyle.Render(a.t("setup.choices")))
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+const (
+	// sizeHistoryBar is how wide the per-generation bars are drawn.
+	sizeHistoryBar = 24
+	// sizeHistoryHeader is how many lines the sparkline and summary take
+	// above the rows.
+	sizeHistoryHeader = 4
+)
+
+// sizeHistory is the closure size chart, scrolled to top.
+type sizeHistory struct {
+	top int
+}
+
+// sizeHistoryMsg is the list with sizes, fetched for the chart when the
+// list was loaded without them.
+type sizeHistoryMsg []models.Generation
+
+// showSizeHistory opens the chart, fetching sizes first unless some are
+// known already.
+func (a *App) showSizeHistory() tea.Cmd {
+	if len(models.SizeHistory(a.generations)) > 0 {
+		a.sizeHistory = &sizeHistory{}
+		a.state = stateSizeHistory
+		return nil
+	}
+	a.loading = true
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		generations, err := a.client.GetGenerationsWithSizes(ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+		return sizeHistoryMsg(generations)
+	}
+}
+
+func (a *App) applySizeHistory(msg sizeHistoryMsg) {
+	a.loading = false
+	a.applySizes(sizesMsg(msg))
+	a.sizeHistory = &sizeHistory{}
+	a.state = stateSizeHistory
+}
+
+func (a *App) updateSizeHistory(msg tea.KeyMsg) tea.Cmd {
+	h := a.sizeHistory
+	last := max(0, len(models.SizeHistory(a.generations))-a.listHeight()+sizeHistoryHeader)
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		h.top = max(0, h.top-1)
+	case key.Matches(msg, a.keys.Down):
+		h.top = min(last, h.top+1)
+	case key.Matches(msg, a.keys.PageUp):
+		h.top = max(0, h.top-a.pageSize())
+	case key.Matches(msg, a.keys.PageDown):
+		h.top = min(last, h.top+a.pageSize())
+	}
+	return nil
+}
+
+func (a *App) renderSizeHistory() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("sizes.title")) + "\n\n")
+	points := models.SizeHistory(a.generations)
+	if len(points) == 0 {
+		b.WriteString(a.t("sizes.unknown") + "\n")
+		return b.String()
+	}
+
+	values := make([]int64, len(points))
+	for i, p := range points {
+		values[i] = p.Size
+	}
+	lo, hi := chartRange(values)
+	first, last := points[0], points[len(points)-1]
+	b.WriteString("  " + presenceStyle.Render(sparkline(values, a.listWidth()-4)) + "\n")
+	b.WriteString("  " + statsStyle.Render(a.t("sizes.summary",
+		listing.HumanSize(lo), listing.HumanSize(hi),
+		signedSize(last.Size-first.Size), first.Generation, last.Generation)) + "\n\n")
+
+	// Newest first, like the generation list.
+	rows := make([]string, len(points))
+	for i, p := range points {
+		delta := ""
+		switch {
+		case i == 0:
+		case p.Delta > 0:
+			delta = removedStyle.Render(signedSize(p.Delta))
+		case p.Delta < 0:
+			delta = addedStyle.Render(signedSize(p.Delta))
+		default:
+			delta = statsStyle.Render(signedSize(0))
+		}
+		rows[len(rows)-1-i] = fmt.Sprintf("  %5s  %s  %10s  %s",
+			p.Generation, bar(p.Size, lo, hi, sizeHistoryBar), listing.HumanSize(p.Size), delta)
+	}
+	h := a.sizeHistory
+	end := min(len(rows), h.top+a.listHeight()-sizeHistoryHeader)
+	for _, row := range rows[min(h.top, max(0, end)):max(0, end)] {
+		b.WriteString(row + "\n")
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("sizes.choices")))
+	return b.String()
+}
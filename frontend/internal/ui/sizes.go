@@ -0,0 +1,56 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// sizesMsg is the list again with closure sizes filled in. Sizes take a
+// query per generation, so the list is shown first and they follow.
+type sizesMsg []models.Generation
+
+func (a *App) fetchSizes() tea.Cmd {
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		generations, err := a.client.GetGenerationsWithSizes(ctx)
+		if err != nil {
+			// The list is usable without sizes.
+			return nil
+		}
+		return sizesMsg(generations)
+	}
+}
+
+func (a *App) applySizes(msg sizesMsg) {
+	if a.staleGenerations(msg) {
+		return
+	}
+	sizes := make(map[string]int64, len(msg))
+	for _, gen := range msg {
+		sizes[gen.ID] = gen.ClosureSize
+	}
+	for i := range a.generations {
+		if size, ok := sizes[a.generations[i].ID]; ok {
+			a.generations[i].ClosureSize = size
+		}
+	}
+}
+
+func renderClosureSize(gen models.Generation) string {
+	if gen.ClosureSize <= 0 {
+		return ""
+	}
+	return "  " + statsStyle.Render(listing.HumanSize(gen.ClosureSize))
+}
+
+// netSize is how much the closure grows from one generation to the other,
+// from their sizes or, failing that, from the pair's stats.
+func (a *App) netSize(from, to models.Generation) (int64, bool) {
+	if from.ClosureSize > 0 && to.ClosureSize > 0 {
+		return to.ClosureSize - from.ClosureSize, true
+	}
+	stats, ok := a.stats[statsKey(from.ID, to.ID)]
+	return stats.SizeDelta, ok && stats.SizeKnown
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"nix-timemach/internal/snapshot"
+)
+
+type snapshotsLoadedMsg []snapshot.Snapshot
+
+func (a *App) loadSnapshots() tea.Msg {
+	list, err := snapshot.List()
+	if err != nil {
+		return errMsg{err}
+	}
+	return snapshotsLoadedMsg(list)
+}
+
+// askSnapshot prompts for the snapshot to diff the cursor's generation
+// against, offering the newest one.
+func (a *App) askSnapshot(list []snapshot.Snapshot) {
+	if len(list) == 0 {
+		a.setStatus(a.t("snapshot.none"))
+		return
+	}
+	names := make([]string, len(list))
+	for i, s := range list {
+		names[i] = s.Name
+	}
+
+	a.askPrompt(a.t("snapshot.prompt", strings.Join(names, ", ")), names[0], func(name string) tea.Cmd {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil
+		}
+		a.snapshot = name
+		a.state = stateDiff
+		id := a.generations[a.cursor].ID
+		ctx := a.diffContext()
+		return func() tea.Msg {
+			diff, err := a.client.GetDiffAgainstSnapshot(ctx, id, name)
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			if err != nil {
+				return errMsg{err}
+			}
+			return diffMsg(diff)
+		}
+	})
+}
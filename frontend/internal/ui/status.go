@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultStatusInterval is how often the status bar updates when the
+// caller doesn't choose. It only drives relative times, so it can be slow.
+const defaultStatusInterval = 5 * time.Second
+
+type statusTickMsg time.Time
+
+// statusTick schedules the next status bar update. It runs on its own slow
+// ticker so the bar stays current even when the spinner is idle.
+func (a *App) statusTick() tea.Cmd {
+	return tea.Tick(a.statusInterval(), func(t time.Time) tea.Msg {
+		return statusTickMsg(t)
+	})
+}
+
+func (a *App) statusInterval() time.Duration {
+	if a.opts.StatusInterval > 0 {
+		return a.opts.StatusInterval
+	}
+	return defaultStatusInterval
+}
+
+// setStatus shows a transient message in the status bar until the next
+// key press.
+func (a *App) setStatus(msg string) {
+	a.status = msg
+}
+
+func (a *App) renderStatusBar() string {
+	var parts []string
+	if a.status != "" {
+		parts = append(parts, a.status)
+	}
+	if host := a.client.Host(); host != "" {
+		parts = append(parts, a.t("status.host", host))
+	}
+	if store := a.client.Store(); store != "" {
+		parts = append(parts, a.t("status.store", store))
+	}
+	if !a.lastRefresh.IsZero() {
+		parts = append(parts, a.t("status.refreshed", relativeDuration(a.now.Sub(a.lastRefresh))))
+	}
+	if a.opts.Clock {
+		parts = append(parts, a.now.Format("15:04"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return statusBarStyle.Render(strings.Join(parts, " · "))
+}
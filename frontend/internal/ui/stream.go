@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+)
+
+// streamBuffer is how many changes may wait for the UI before the backend
+// is held up.
+const streamBuffer = 256
+
+// diffStream is a diff arriving a change at a time. partial holds what has
+// arrived so far and is shown until the whole diff replaces it.
+type diffStream struct {
+	total   int
+	partial models.GenerationDiff
+	entries chan streamEntry
+}
+
+type streamEntry struct {
+	total int
+	entry models.DiffEntry
+}
+
+// diffChunkMsg carries the changes that arrived since the last one.
+type diffChunkMsg struct {
+	stream  *diffStream
+	entries []streamEntry
+}
+
+// streamDiff fetches the diff between two generations, showing its
+// changes as they arrive. The diff itself still ends in a diffMsg.
+func (a *App) streamDiff(ctx context.Context, from, to string) tea.Cmd {
+	s := &diffStream{entries: make(chan streamEntry, streamBuffer)}
+	a.stream = s
+	fetch := func() tea.Msg {
+		defer close(s.entries)
+		diff, err := a.client.StreamDiff(ctx, from, to, func(total int, e models.DiffEntry) {
+			select {
+			case s.entries <- streamEntry{total, e}:
+			case <-ctx.Done():
+			}
+		})
+		if errors.Is(err, context.Canceled) {
+			// The user went back; the view the diff was for is gone.
+			return nil
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return diffMsg(diff)
+	}
+	return tea.Batch(fetch, waitForStream(s))
+}
+
+// waitForStream waits for the next changes of s and takes all that are
+// ready, so a fast backend costs a redraw per batch rather than per change.
+// It returns nil once the stream is closed.
+func waitForStream(s *diffStream) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-s.entries
+		if !ok {
+			return nil
+		}
+		msg := diffChunkMsg{stream: s, entries: []streamEntry{e}}
+		for {
+			select {
+			case e, ok := <-s.entries:
+				if !ok {
+					return msg
+				}
+				msg.entries = append(msg.entries, e)
+			default:
+				return msg
+			}
+		}
+	}
+}
+
+// applyDiffChunk adds newly arrived changes to the stream they belong to,
+// if it is still the one being shown, and waits for more.
+func (a *App) applyDiffChunk(msg diffChunkMsg) tea.Cmd {
+	if a.stream != msg.stream || a.diff != nil {
+		return nil
+	}
+	for _, e := range msg.entries {
+		a.stream.total = e.total
+		a.stream.partial.Add(e.entry)
+	}
+	return waitForStream(msg.stream)
+}
+
+// renderStream shows the changes that have arrived so far and how many are
+// still to come.
+func (a *App) renderStream() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.diffTitle()))
+	b.WriteString("\n\n")
+	p := a.stream.partial
+	received := len(p.Added) + len(p.Removed) + len(p.Modified)
+	b.WriteString(statsStyle.Render(a.t("diff.streaming", received, a.stream.total)))
+	b.WriteString("\n\n")
+	b.WriteString(a.renderChanges(p, -1))
+	return b.String()
+}
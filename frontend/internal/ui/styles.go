@@ -2,25 +2,108 @@ package ui
 
 import "github.com/charmbracelet/lipgloss"
 
+// The styles are package-level so rendering code can use them directly;
+// they are (re)built from a Theme by applyTheme.
 var (
-	subtle    = lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"}
-	highlight = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	special   = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
+	titleStyle        lipgloss.Style
+	itemStyle         lipgloss.Style
+	selectedItemStyle lipgloss.Style
+	knownGoodStyle    lipgloss.Style
+	statsStyle        lipgloss.Style
+	presenceStyle     lipgloss.Style
+	adviceStyle       lipgloss.Style
+	duplicateStyle    lipgloss.Style
+	confirmStyle      lipgloss.Style
+	statusBarStyle    lipgloss.Style
+	noticeStyle       lipgloss.Style
+	helpStyle         lipgloss.Style
+	spinnerStyle      lipgloss.Style
+	addedStyle        lipgloss.Style
+	removedStyle      lipgloss.Style
+	modifiedStyle     lipgloss.Style
+	headingStyle      lipgloss.Style
+	ageStyles         []lipgloss.Style
+	logPanelStyle     lipgloss.Style
+	paneStyle         lipgloss.Style
+)
+
+func init() {
+	applyTheme(DefaultTheme())
+}
 
+func applyTheme(t Theme) {
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(highlight).
-			MarginLeft(2)
+		Bold(t.TitleBold).
+		Foreground(t.Accent.color()).
+		MarginLeft(2)
 
 	itemStyle = lipgloss.NewStyle().
-			PaddingLeft(4)
+		PaddingLeft(t.Indent)
 
 	selectedItemStyle = itemStyle.Copy().
-				Foreground(special).
-				Bold(true)
+		Foreground(t.Selected.color()).
+		Bold(t.SelectedBold)
+
+	knownGoodStyle = itemStyle.Copy().
+		Foreground(t.KnownGood.color())
+
+	statsStyle = lipgloss.NewStyle().
+		Foreground(t.Muted.color())
+
+	presenceStyle = lipgloss.NewStyle().
+		Foreground(t.Accent.color())
+
+	adviceStyle = lipgloss.NewStyle().
+		Foreground(t.KnownGood.color())
+
+	duplicateStyle = itemStyle.Copy().
+		Foreground(t.Subtle.color())
+
+	confirmStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Accent.color()).
+		Padding(1, 2)
+
+	statusBarStyle = lipgloss.NewStyle().
+		Foreground(t.Muted.color()).
+		PaddingLeft(2)
+
+	noticeStyle = lipgloss.NewStyle().
+		Foreground(t.Accent.color()).
+		PaddingLeft(2)
 
 	helpStyle = lipgloss.NewStyle().
-			Foreground(subtle).
-			PaddingLeft(4).
-			PaddingBottom(1)
-)
+		Foreground(t.Subtle.color()).
+		PaddingLeft(t.Indent).
+		PaddingBottom(1)
+
+	spinnerStyle = lipgloss.NewStyle().
+		Foreground(t.Spinner.color())
+
+	addedStyle = lipgloss.NewStyle().
+		Foreground(t.Added.color())
+
+	removedStyle = lipgloss.NewStyle().
+		Foreground(t.Removed.color())
+
+	modifiedStyle = lipgloss.NewStyle().
+		Foreground(t.Modified.color())
+
+	headingStyle = lipgloss.NewStyle().
+		Foreground(t.Accent.color())
+
+	logPanelStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), true, false, false, false).
+		BorderForeground(t.Subtle.color()).
+		Foreground(t.Muted.color())
+
+	paneStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(t.Subtle.color()).
+		PaddingLeft(1)
+
+	ageStyles = nil
+	for _, c := range t.Age {
+		ageStyles = append(ageStyles, lipgloss.NewStyle().Foreground(c.color()))
+	}
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tableColumn is one column of a table. Columns with a compare function
+// can be sorted by; it orders two rows by their index in the cells.
+type tableColumn struct {
+	title   string
+	width   int
+	right   bool
+	compare func(a, b int) int
+}
+
+// table is a scrolling list of rows with a header, sorted by one of its
+// columns.
+type table struct {
+	columns []tableColumn
+	cells   [][]string
+	order   []int
+	sortBy  int
+	cursor  int
+	top     int
+}
+
+// newTable returns a table of cells, a row each, sorted by column sortBy.
+func newTable(columns []tableColumn, cells [][]string, sortBy int) *table {
+	t := &table{columns: columns, cells: cells, order: make([]int, len(cells)), sortBy: sortBy}
+	for i := range t.order {
+		t.order[i] = i
+	}
+	t.sort()
+	return t
+}
+
+func (t *table) sort() {
+	if t.sortBy < 0 {
+		return
+	}
+	slices.SortStableFunc(t.order, t.columns[t.sortBy].compare)
+}
+
+// nextSort sorts by the next column that can be sorted by, keeping the
+// cursor on the top row.
+func (t *table) nextSort() {
+	if t.sortBy < 0 {
+		return
+	}
+	for i := 1; i <= len(t.columns); i++ {
+		if c := (t.sortBy + i) % len(t.columns); t.columns[c].compare != nil {
+			t.sortBy = c
+			break
+		}
+	}
+	t.sort()
+	t.cursor, t.top = 0, 0
+}
+
+// move moves the cursor by delta rows, scrolling to keep it within height
+// rows.
+func (t *table) move(delta, height int) {
+	t.cursor = max(0, min(len(t.order)-1, t.cursor+delta))
+	switch {
+	case t.cursor < t.top:
+		t.top = t.cursor
+	case t.cursor >= t.top+height:
+		t.top = t.cursor - height + 1
+	}
+}
+
+// selected is the index in the cells of the row under the cursor.
+func (t *table) selected() int {
+	return t.order[t.cursor]
+}
+
+// render draws the header and the rows that fit in height lines, the
+// sorted column's title marked.
+func (t *table) render(height int) string {
+	var b strings.Builder
+	header := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		header[i] = c.title
+		if i == t.sortBy {
+			header[i] += " ▼"
+		}
+	}
+	b.WriteString("  " + headingStyle.Render(t.row(header)) + "\n")
+	end := min(len(t.order), t.top+max(1, height-1))
+	for i := t.top; i < end; i++ {
+		line := t.row(t.cells[t.order[i]])
+		if i == t.cursor {
+			b.WriteString("> " + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (t *table) row(cells []string) string {
+	out := make([]string, len(cells))
+	for i, cell := range cells {
+		c := t.columns[i]
+		cell = lipgloss.NewStyle().MaxWidth(c.width).Render(cell)
+		gap := strings.Repeat(" ", max(0, c.width-lipgloss.Width(cell)))
+		if c.right {
+			out[i] = gap + cell
+		} else {
+			out[i] = cell + gap
+		}
+	}
+	return strings.Join(out, "  ")
+}
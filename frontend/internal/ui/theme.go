@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Color is a theme color: a hex value such as "#7D56F4" or an ANSI color
+// number such as "205". In a theme file it is either a single string or an
+// object with separate "light" and "dark" values for the terminal
+// background.
+type Color struct {
+	Light string `json:"light"`
+	Dark  string `json:"dark"`
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		c.Light, c.Dark = s, s
+		return nil
+	}
+	type plain Color
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+func (c Color) color() lipgloss.TerminalColor {
+	if c.Light == c.Dark {
+		return lipgloss.Color(c.Light)
+	}
+	return lipgloss.AdaptiveColor{Light: c.Light, Dark: c.Dark}
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validColor(s string) bool {
+	if hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Theme holds every color and layout choice the UI's styles are built
+// from.
+type Theme struct {
+	Accent    Color `json:"accent"`
+	Selected  Color `json:"selected"`
+	Subtle    Color `json:"subtle"`
+	Muted     Color `json:"muted"`
+	KnownGood Color `json:"known_good"`
+	Spinner   Color `json:"spinner"`
+	Added     Color `json:"added"`
+	Removed   Color `json:"removed"`
+	Modified  Color `json:"modified"`
+	// Age colors timestamps by age bucket, newest first.
+	Age []Color `json:"age"`
+
+	TitleBold    bool `json:"title_bold"`
+	SelectedBold bool `json:"selected_bold"`
+	// Indent is the left padding of list rows.
+	Indent int `json:"indent"`
+}
+
+func DefaultTheme() Theme {
+	return Theme{
+		Accent:    Color{Light: "#874BFD", Dark: "#7D56F4"},
+		Selected:  Color{Light: "#43BF6D", Dark: "#73F59F"},
+		Subtle:    Color{Light: "#D9DCCF", Dark: "#383838"},
+		Muted:     Color{Light: "8", Dark: "8"},
+		KnownGood: Color{Light: "6", Dark: "6"},
+		Spinner:   Color{Light: "205", Dark: "205"},
+		Added:     Color{Light: "#43BF6D", Dark: "#73F59F"},
+		Removed:   Color{Light: "9", Dark: "9"},
+		Modified:  Color{Light: "3", Dark: "3"},
+		Age: []Color{
+			{Light: "#43BF6D", Dark: "#73F59F"},
+			{Light: "4", Dark: "12"},
+			{Light: "0", Dark: "7"},
+			{Light: "8", Dark: "8"},
+		},
+		TitleBold:    true,
+		SelectedBold: true,
+		Indent:       4,
+	}
+}
+
+// ThemePresets names the built-in themes, in the order the theme key
+// cycles through them.
+var ThemePresets = []string{"default", "dark", "light", "solarized", "high-contrast", "colorblind-safe"}
+
+// PresetTheme returns the built-in theme called name.
+func PresetTheme(name string) (Theme, error) {
+	switch name {
+	case "default":
+		return DefaultTheme(), nil
+	case "dark":
+		return DefaultTheme().only(true), nil
+	case "light":
+		return DefaultTheme().only(false), nil
+	case "solarized":
+		return solarizedTheme(), nil
+	case "high-contrast":
+		return highContrastTheme(), nil
+	case "colorblind-safe":
+		return colorblindTheme(), nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q, want one of %s", name, strings.Join(ThemePresets, ", "))
+}
+
+// only returns t with the colors it has for one terminal background used
+// on both, for terminals that report their background wrongly.
+func (t Theme) only(dark bool) Theme {
+	pick := func(c Color) Color {
+		if dark {
+			return Color{Light: c.Dark, Dark: c.Dark}
+		}
+		return Color{Light: c.Light, Dark: c.Light}
+	}
+	for _, c := range []*Color{&t.Accent, &t.Selected, &t.Subtle, &t.Muted, &t.KnownGood, &t.Spinner, &t.Added, &t.Removed, &t.Modified} {
+		*c = pick(*c)
+	}
+	age := make([]Color, len(t.Age))
+	for i, c := range t.Age {
+		age[i] = pick(c)
+	}
+	t.Age = age
+	return t
+}
+
+// solarizedTheme uses Ethan Schoonover's Solarized accents, with the base
+// tones for whichever background the terminal has.
+func solarizedTheme() Theme {
+	t := DefaultTheme()
+	t.Accent = Color{Light: "#268BD2", Dark: "#268BD2"}
+	t.Selected = Color{Light: "#859900", Dark: "#859900"}
+	t.Subtle = Color{Light: "#93A1A1", Dark: "#586E75"}
+	t.Muted = Color{Light: "#657B83", Dark: "#839496"}
+	t.KnownGood = Color{Light: "#2AA198", Dark: "#2AA198"}
+	t.Spinner = Color{Light: "#D33682", Dark: "#D33682"}
+	t.Added = Color{Light: "#859900", Dark: "#859900"}
+	t.Removed = Color{Light: "#DC322F", Dark: "#DC322F"}
+	t.Modified = Color{Light: "#B58900", Dark: "#B58900"}
+	t.Age = []Color{t.Added, t.Accent, t.Muted, t.Subtle}
+	return t
+}
+
+// highContrastTheme keeps every color far from the background, and
+// nothing dim.
+func highContrastTheme() Theme {
+	t := DefaultTheme()
+	t.Accent = Color{Light: "#0000AF", Dark: "#5FD7FF"}
+	t.Selected = Color{Light: "#000000", Dark: "#FFFFFF"}
+	t.Subtle = Color{Light: "#5F5F5F", Dark: "#AFAFAF"}
+	t.Muted = Color{Light: "#303030", Dark: "#D0D0D0"}
+	t.KnownGood = Color{Light: "#005F5F", Dark: "#00FFFF"}
+	t.Spinner = t.Accent
+	t.Added = Color{Light: "#005F00", Dark: "#00FF00"}
+	t.Removed = Color{Light: "#AF0000", Dark: "#FF5F5F"}
+	t.Modified = Color{Light: "#875F00", Dark: "#FFFF00"}
+	t.Age = []Color{t.Added, t.Accent, t.Muted, t.Subtle}
+	return t
+}
+
+// colorblindTheme draws on the Okabe-Ito palette, whose colors stay apart
+// with any common color vision deficiency. Added is blue rather than green
+// so it can't be confused with removed.
+func colorblindTheme() Theme {
+	t := DefaultTheme()
+	t.Accent = Color{Light: "#0072B2", Dark: "#56B4E9"}
+	t.Selected = Color{Light: "#0072B2", Dark: "#56B4E9"}
+	t.KnownGood = Color{Light: "#009E73", Dark: "#009E73"}
+	t.Spinner = Color{Light: "#CC79A7", Dark: "#CC79A7"}
+	t.Added = Color{Light: "#0072B2", Dark: "#56B4E9"}
+	t.Removed = Color{Light: "#D55E00", Dark: "#E69F00"}
+	t.Modified = Color{Light: "#CC79A7", Dark: "#CC79A7"}
+	t.Age = []Color{t.Added, t.KnownGood, t.Muted, t.Subtle}
+	return t
+}
+
+// LoadTheme reads a theme from a JSON file. Fields the file leaves out keep
+// their values in base.
+func LoadTheme(path string, base Theme) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("failed to read theme: %w", err)
+	}
+
+	t := base
+	t.Age = slices.Clone(base.Age)
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("failed to parse theme %s: %w", path, err)
+	}
+	if err := t.validate(); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t Theme) validate() error {
+	colors := []struct {
+		name  string
+		color Color
+	}{
+		{"accent", t.Accent},
+		{"selected", t.Selected},
+		{"subtle", t.Subtle},
+		{"muted", t.Muted},
+		{"known_good", t.KnownGood},
+		{"spinner", t.Spinner},
+		{"added", t.Added},
+		{"removed", t.Removed},
+		{"modified", t.Modified},
+	}
+	for i, c := range t.Age {
+		colors = append(colors, struct {
+			name  string
+			color Color
+		}{fmt.Sprintf("age[%d]", i), c})
+	}
+	for _, c := range colors {
+		for _, v := range []string{c.color.Light, c.color.Dark} {
+			if !validColor(v) {
+				return fmt.Errorf("%s: %q is not a hex color or ANSI color number", c.name, v)
+			}
+		}
+	}
+	if t.Indent < 0 {
+		return fmt.Errorf("indent: must not be negative")
+	}
+	return nil
+}
+
+// themeChoice is a theme the theme key can switch to.
+type themeChoice struct {
+	name  string
+	theme Theme
+}
+
+// themeChoices returns the themes to switch between and which of them is
+// in use: the presets, and the one opts loaded if it isn't one of them.
+func themeChoices(opts Options) ([]themeChoice, int) {
+	var choices []themeChoice
+	for _, name := range ThemePresets {
+		t, _ := PresetTheme(name)
+		choices = append(choices, themeChoice{name, t})
+	}
+	if opts.Theme == nil {
+		return choices, 0
+	}
+	if i := slices.Index(ThemePresets, opts.ThemeName); i >= 0 {
+		choices[i].theme = *opts.Theme
+		return choices, i
+	}
+	return append(choices, themeChoice{cmp.Or(opts.ThemeName, "custom"), *opts.Theme}), len(choices)
+}
+
+// nextTheme switches to the next theme. Everything is rendered afresh
+// each frame, so only the spinner, which keeps its own style, needs
+// telling.
+func (a *App) nextTheme() {
+	a.themeIndex = (a.themeIndex + 1) % len(a.themes)
+	next := a.themes[a.themeIndex]
+	applyTheme(next.theme)
+	a.spinner.Style = spinnerStyle
+	a.setStatus(a.t("status.theme", next.name))
+}
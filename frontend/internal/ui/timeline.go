@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+// timeline is the version history of one package, newest change first.
+type timeline struct {
+	name   string
+	spans  []models.VersionSpan
+	cursor int
+}
+
+type timelineMsg struct {
+	name  string
+	spans []models.VersionSpan
+}
+
+func (a *App) askTimeline() {
+	initial := ""
+	if a.timeline != nil {
+		initial = a.timeline.name
+	}
+	a.askPrompt(a.t("timeline.prompt"), initial, func(name string) tea.Cmd {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil
+		}
+		a.loading = true
+		ctx := a.profileContext()
+		return func() tea.Msg {
+			presence, err := a.client.FindPackage(ctx, name)
+			if err != nil {
+				return errMsg{err}
+			}
+			return timelineMsg{name: name, spans: models.PackageHistory(presence)}
+		}
+	})
+}
+
+func (a *App) applyTimeline(msg timelineMsg) {
+	a.loading = false
+	// Newest first, like the generation list.
+	spans := make([]models.VersionSpan, len(msg.spans))
+	for i, span := range msg.spans {
+		spans[len(spans)-1-i] = span
+	}
+	a.timeline = &timeline{name: msg.name, spans: spans}
+	a.state = stateTimeline
+}
+
+func (a *App) updateTimeline(msg tea.KeyMsg) tea.Cmd {
+	t := a.timeline
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case key.Matches(msg, a.keys.Down):
+		if t.cursor < len(t.spans)-1 {
+			t.cursor++
+		}
+	case key.Matches(msg, a.keys.Select):
+		// The diff across the change that began the span: from the last
+		// generation before it to its first.
+		if t.cursor+1 >= len(t.spans) {
+			return nil
+		}
+		from := a.generationIndex(t.spans[t.cursor+1].Last)
+		to := a.generationIndex(t.spans[t.cursor].First)
+		if from < 0 || to < 0 {
+			return nil
+		}
+		a.cursor = to
+		return a.showDiff(from)
+	}
+	return nil
+}
+
+// spanRange says which generations a span covers: "since" for the one the
+// profile is on now, a range otherwise.
+func (a *App) spanRange(span models.VersionSpan, newest bool) string {
+	switch {
+	case newest:
+		return a.t("timeline.since", span.First)
+	case span.Count == 1:
+		return a.t("timeline.one", span.First)
+	}
+	return a.t("timeline.range", span.First, span.Last)
+}
+
+func (a *App) renderTimeline() string {
+	t := a.timeline
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("timeline.title", t.name)) + "\n\n")
+	if len(t.spans) == 0 || (len(t.spans) == 1 && !t.spans[0].Present) {
+		b.WriteString(a.t("search.notFound", t.name))
+		return b.String()
+	}
+
+	versions := make([]string, len(t.spans))
+	ranges := make([]string, len(t.spans))
+	width, rangeWidth := 0, 0
+	for i, span := range t.spans {
+		versions[i] = span.Version
+		if !span.Present {
+			versions[i] = a.t("timeline.absent")
+		}
+		ranges[i] = a.spanRange(span, i == 0)
+		width = max(width, utf8.RuneCountInString(versions[i]))
+		rangeWidth = max(rangeWidth, utf8.RuneCountInString(ranges[i]))
+	}
+	for i, span := range t.spans {
+		version := versions[i]
+		style := addedStyle
+		if !span.Present {
+			style = removedStyle
+		}
+		created := ""
+		if j := a.generationIndex(span.First); j >= 0 {
+			created = statsStyle.Render(listing.Timestamp(a.generations[j].Timestamp))
+		}
+		line := fmt.Sprintf("● %s  %s  %s", style.Render(pad(version, width)), pad(ranges[i], rangeWidth), created)
+		if i == t.cursor {
+			b.WriteString("> " + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+		if i < len(t.spans)-1 {
+			b.WriteString("  │\n")
+		}
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("timeline.choices")))
+	return b.String()
+}
+
+// pad fills s with spaces to width runes.
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s)))
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+var trashEmpty = key.NewBinding(key.WithKeys("x"))
+
+// trashView lists the deleted generations that can still be restored,
+// most recently deleted first.
+type trashView struct {
+	items  []models.TrashedGeneration
+	cursor int
+}
+
+type trashMsg []models.TrashedGeneration
+
+type restoredMsg string
+
+type trashEmptiedMsg struct{}
+
+// showTrash loads the trash.
+func (a *App) showTrash() tea.Cmd {
+	a.loading = true
+	return a.fetchTrash
+}
+
+func (a *App) fetchTrash() tea.Msg {
+	items, err := a.client.GetTrash(context.Background())
+	if errors.Is(err, backend.ErrUnsupported) {
+		return errMsg{errors.New(a.t("trash.unsupported"))}
+	}
+	if err != nil {
+		return errMsg{err}
+	}
+	return trashMsg(items)
+}
+
+func (a *App) applyTrash(msg trashMsg) {
+	a.loading = false
+	items := slices.Clone(msg)
+	slices.Reverse(items)
+	cursor := 0
+	if a.trash != nil {
+		cursor = min(a.trash.cursor, max(0, len(items)-1))
+	}
+	a.trash = &trashView{items: items, cursor: cursor}
+	a.state = stateTrash
+}
+
+func (a *App) updateTrash(msg tea.KeyMsg) tea.Cmd {
+	t := a.trash
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		t.cursor = max(0, t.cursor-1)
+	case key.Matches(msg, a.keys.Down):
+		t.cursor = max(0, min(len(t.items)-1, t.cursor+1))
+	case key.Matches(msg, a.keys.Select):
+		if len(t.items) == 0 {
+			return nil
+		}
+		if a.opts.ReadOnly {
+			a.setStatus(a.t("status.readOnly"))
+			return nil
+		}
+		id := t.items[t.cursor].ID
+		action := a.restoreGeneration(id)
+		if days := a.opts.TrashDays; days > 0 {
+			action = a.undoable(action, systemStep(a.t("undo.restore", id),
+				a.trashGenerations([]string{id}, days), a.restoreGenerations([]string{id})))
+		}
+		a.askConfirm(a.t("confirm.restore", id), a.privileged(action))
+	case key.Matches(msg, trashEmpty):
+		if len(t.items) == 0 {
+			return nil
+		}
+		if a.opts.ReadOnly {
+			a.setStatus(a.t("status.readOnly"))
+			return nil
+		}
+		a.askConfirm(a.t("confirm.emptyTrash", len(t.items)), a.privileged(a.emptyTrash))
+	}
+	return nil
+}
+
+func (a *App) restoreGeneration(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.RestoreGeneration(context.Background(), id); err != nil {
+			return errMsg{err}
+		}
+		return restoredMsg(id)
+	}
+}
+
+func (a *App) emptyTrash() tea.Msg {
+	if err := a.client.EmptyTrash(context.Background()); err != nil {
+		return errMsg{err}
+	}
+	return trashEmptiedMsg{}
+}
+
+// applyRestored reloads the trash and the list, which has the generation
+// back.
+func (a *App) applyRestored(msg restoredMsg) tea.Cmd {
+	a.setStatus(a.t("status.restored", string(msg)))
+	return tea.Batch(a.showTrash(), a.refreshAfterAction(string(msg)))
+}
+
+func (a *App) renderTrash() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("trash.title")) + "\n\n")
+	t := a.trash
+	if len(t.items) == 0 {
+		b.WriteString(itemStyle.Render(a.t("trash.empty")) + "\n")
+		b.WriteString("\n" + statsStyle.Render(a.t("trash.choices")))
+		return b.String()
+	}
+
+	top := max(0, t.cursor-a.listHeight()+3)
+	end := min(len(t.items), top+a.listHeight()-2)
+	for i, item := range t.items[top:end] {
+		expires := ""
+		if a.opts.TrashDays > 0 {
+			left := max(0, time.Until(item.Trashed.AddDate(0, 0, a.opts.TrashDays)))
+			if left >= 48*time.Hour {
+				expires = a.t("trash.expiresDays", int(left.Hours()/24))
+			} else {
+				expires = a.t("trash.expires", relativeDuration(left))
+			}
+		}
+		row := fmt.Sprintf("%5s  %s  %-12s  %s",
+			backend.Printable(item.ID), listing.Timestamp(item.Trashed), expires, backend.Printable(path.Base(item.StorePath)))
+		style := itemStyle
+		if top+i == t.cursor {
+			row = "> " + row
+			style = selectedItemStyle
+		} else {
+			row = "  " + row
+		}
+		b.WriteString(style.Render(row) + "\n")
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("trash.choices")))
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package ui
+
+import "strings"
+
+// treeItem is a node of a tree view, shown with its children when open.
+// Children can be left to more, which makes them the first time the item
+// is opened, for trees too large to build up front.
+type treeItem struct {
+	label    string
+	children []*treeItem
+	more     func() []*treeItem
+	open     bool
+}
+
+func (item *treeItem) expandable() bool {
+	return len(item.children) > 0 || item.more != nil
+}
+
+// tree is an expandable tree with a cursor on one of its visible rows.
+type tree struct {
+	root   *treeItem
+	cursor int
+	top    int
+}
+
+type treeRow struct {
+	item   *treeItem
+	parent int
+	depth  int
+}
+
+// rows flattens the open part of the tree, each row knowing the index of
+// its parent's row, or -1 for the root.
+func (t *tree) rows() []treeRow {
+	var rows []treeRow
+	var walk func(item *treeItem, parent, depth int)
+	walk = func(item *treeItem, parent, depth int) {
+		i := len(rows)
+		rows = append(rows, treeRow{item, parent, depth})
+		if item.open {
+			for _, child := range item.children {
+				walk(child, i, depth+1)
+			}
+		}
+	}
+	if t.root != nil {
+		walk(t.root, -1, 0)
+	}
+	return rows
+}
+
+// move moves the cursor by delta rows, scrolling to keep it within height
+// rows.
+func (t *tree) move(delta, height int) {
+	t.cursor = max(0, min(len(t.rows())-1, t.cursor+delta))
+	switch {
+	case t.cursor < t.top:
+		t.top = t.cursor
+	case t.cursor >= t.top+height:
+		t.top = t.cursor - height + 1
+	}
+}
+
+// expand opens the row under the cursor, or moves to its first child if
+// it is open already.
+func (t *tree) expand(height int) {
+	item := t.rows()[t.cursor].item
+	switch {
+	case !item.expandable():
+	case !item.open:
+		if item.more != nil {
+			item.children, item.more = item.more(), nil
+		}
+		item.open = true
+	default:
+		t.move(1, height)
+	}
+}
+
+// collapse closes the row under the cursor, or moves to its parent if it
+// is closed already.
+func (t *tree) collapse(height int) {
+	row := t.rows()[t.cursor]
+	if row.item.open && row.item.expandable() {
+		row.item.open = false
+		return
+	}
+	if row.parent >= 0 {
+		t.move(row.parent-t.cursor, height)
+	}
+}
+
+// render draws the rows that fit in height lines, indented by depth and
+// marked ▸ when they can be opened and ▾ when they are.
+func (t *tree) render(height int) string {
+	rows := t.rows()
+	var b strings.Builder
+	end := min(len(rows), t.top+height)
+	for i := t.top; i < end; i++ {
+		row := rows[i]
+		marker := "  "
+		switch {
+		case !row.item.expandable():
+		case row.item.open:
+			marker = "▾ "
+		default:
+			marker = "▸ "
+		}
+		line := strings.Repeat("  ", row.depth) + marker + row.item.label
+		if i == t.cursor {
+			b.WriteString("> " + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return b.String()
+}
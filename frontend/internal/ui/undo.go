@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"context"
+	"maps"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// undoStep is one entry in the undo history. Steps that change the system
+// are undone and redone through the backend once confirmed; the others
+// only change what the App shows.
+type undoStep struct {
+	// what describes the step in the status line and confirmations;
+	// moving between views goes without saying.
+	what string
+	// system marks a change to the system. Its undo and redo then return
+	// the backend call to make instead of changing the App themselves.
+	system     bool
+	undo, redo func() tea.Cmd
+}
+
+// viewStop is a view the undo history can go back to. A diff keeps its
+// ends so that it can be loaded again once closed.
+type viewStop struct {
+	state    state
+	from, to string
+}
+
+// undoableMsg is the result of an action that succeeded, with the step
+// that undoes it.
+type undoableMsg struct {
+	msg  tea.Msg
+	step undoStep
+}
+
+// replayedMsg reports a change to the system that was undone or redone.
+type replayedMsg struct {
+	what string
+	redo bool
+}
+
+// undoable runs action and, if it succeeds, records step to undo it.
+func (a *App) undoable(action tea.Cmd, step undoStep) tea.Cmd {
+	return func() tea.Msg {
+		msg := action()
+		if _, failed := msg.(errMsg); failed {
+			return msg
+		}
+		return undoableMsg{msg: msg, step: step}
+	}
+}
+
+// systemStep is a step that runs undo and redo against the backend.
+func systemStep(what string, undo, redo tea.Cmd) undoStep {
+	return undoStep{
+		what:   what,
+		system: true,
+		undo:   func() tea.Cmd { return undo },
+		redo:   func() tea.Cmd { return redo },
+	}
+}
+
+// navigable reports whether s is a view the undo history can return to:
+// one that keeps what it shows after being left. The pending and snapshot
+// diffs can't be loaded again from their ends, and sessions like the
+// garbage collector are left out, as returning to them wouldn't resume
+// them.
+func (a *App) navigable(s state) bool {
+	switch s {
+	case stateGenerations, stateGroups, statePresets, stateTimeline, stateChangelog,
+		stateSizeHistory, stateUsage, stateWhy, stateClosure, stateTrash, stateAudit:
+		return true
+	case stateDetails:
+		return len(a.generations) > 0
+	case stateDiff:
+		return a.selected != nil && !a.pending && a.snapshot == "" && a.cursor < len(a.generations)
+	case stateDeps:
+		return a.deps != nil
+	case stateFleet:
+		return a.fleet != nil
+	}
+	return false
+}
+
+// stop returns where the App is now, for the undo history.
+func (a *App) stop() viewStop {
+	v := viewStop{state: a.state}
+	if a.state == stateDiff {
+		v.from, v.to = a.selected.ID, a.generations[a.cursor].ID
+	}
+	return v
+}
+
+// recordView runs after every message and adds a step to the undo history
+// when it moved from one navigable view to another.
+func (a *App) recordView() {
+	if a.state == a.view.state || !a.navigable(a.state) {
+		return
+	}
+	from, to := a.view, a.stop()
+	a.view = to
+	a.undo.Do(undoStep{
+		undo: func() tea.Cmd { return a.goTo(from) },
+		redo: func() tea.Cmd { return a.goTo(to) },
+	})
+}
+
+// goTo returns to v. A diff closed since is loaded again.
+func (a *App) goTo(v viewStop) tea.Cmd {
+	if a.state == stateDiff && v.state != stateDiff && v.state != stateDeps {
+		a.closeDiff()
+	}
+	if a.state == stateDeps && v.state != stateDeps {
+		a.state = stateDiff
+		a.deps = nil
+	}
+	a.view = v
+	if v.state != stateDiff {
+		if !a.navigable(v.state) {
+			a.state = stateGenerations
+			a.view = a.stop()
+			a.setStatus(a.t("undo.gone"))
+			return nil
+		}
+		a.state = v.state
+		return nil
+	}
+
+	if a.navigable(a.state) && a.stop() == v && a.diff != nil {
+		return nil
+	}
+	from, to := a.generationIndex(v.from), a.generationIndex(v.to)
+	if from < 0 || to < 0 {
+		a.state = stateGenerations
+		a.view = a.stop()
+		a.setStatus(a.t("undo.gone"))
+		return nil
+	}
+	a.closeDiff()
+	a.cursor = to
+	return a.showDiff(from)
+}
+
+// recordMarks adds a step to the undo history that puts back the marks as
+// they were before, when they have changed.
+func (a *App) recordMarks(before map[string]bool) {
+	if maps.Equal(before, a.marked) {
+		return
+	}
+	after := maps.Clone(a.marked)
+	a.undo.Do(undoStep{
+		what: a.t("undo.marks"),
+		undo: func() tea.Cmd { a.marked = maps.Clone(before); return nil },
+		redo: func() tea.Cmd { a.marked = maps.Clone(after); return nil },
+	})
+}
+
+// replay undoes the last step, or redoes the next one. A change to the
+// system is confirmed first and stays in the history where it was if
+// the backend fails to make it.
+func (a *App) replay(redo bool) tea.Cmd {
+	step, ok := a.undo.Last()
+	if redo {
+		step, ok = a.undo.Next()
+	}
+	if !ok && redo {
+		a.setStatus(a.t("undo.nothingToRedo"))
+		return nil
+	}
+	if !ok {
+		a.setStatus(a.t("undo.nothing"))
+		return nil
+	}
+
+	if !step.system {
+		a.moveUndo(redo)
+		if step.what != "" {
+			a.setStatus(a.t(replayStatus(redo), step.what))
+		}
+		if redo {
+			return step.redo()
+		}
+		return step.undo()
+	}
+	if a.opts.ReadOnly {
+		a.setStatus(a.t("status.readOnly"))
+		return nil
+	}
+	action, prompt := step.undo(), "confirm.undo"
+	if redo {
+		action, prompt = step.redo(), "confirm.redo"
+	}
+	a.askConfirm(a.t(prompt, step.what), a.privileged(func() tea.Msg {
+		if msg, failed := action().(errMsg); failed {
+			return msg
+		}
+		return replayedMsg{what: step.what, redo: redo}
+	}))
+	return nil
+}
+
+// applyReplayed moves the replayed step in the history and reloads the
+// list the change affected.
+func (a *App) applyReplayed(msg replayedMsg) tea.Cmd {
+	a.moveUndo(msg.redo)
+	return func() tea.Msg { return actionDoneMsg{status: a.t(replayStatus(msg.redo), msg.what)} }
+}
+
+func (a *App) moveUndo(redo bool) {
+	if redo {
+		a.undo.Redo()
+	} else {
+		a.undo.Undo()
+	}
+}
+
+func replayStatus(redo bool) string {
+	if redo {
+		return "status.redone"
+	}
+	return "status.undone"
+}
+
+// restoreGenerations is the backend call that undoes moving ids to the
+// trash.
+func (a *App) restoreGenerations(ids []string) tea.Cmd {
+	return func() tea.Msg {
+		for _, id := range ids {
+			if err := a.client.RestoreGeneration(context.Background(), id); err != nil {
+				return errMsg{err}
+			}
+		}
+		return nil
+	}
+}
+
+// trashGenerations is the backend call that redoes moving ids to the
+// trash, or undoes restoring them.
+func (a *App) trashGenerations(ids []string, days int) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.client.TrashGenerations(context.Background(), ids, days); err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
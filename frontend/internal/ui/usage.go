@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/listing"
+	"nix-timemach/internal/models"
+)
+
+const (
+	// usageTop is how many of the largest paths the breakdown lists.
+	usageTop = 100
+	// usageBar is how wide the share bars are drawn.
+	usageBar = 20
+)
+
+var usageSort = key.NewBinding(key.WithKeys("s"))
+
+// usage is the breakdown of one generation's closure by store path.
+type usage struct {
+	id    string
+	paths []string
+	count int
+	total int64
+	table *table
+}
+
+type usageMsg struct {
+	id    string
+	sizes []models.PathSize
+}
+
+// showUsage loads the sizes of the paths in the focused generation's
+// closure.
+func (a *App) showUsage() tea.Cmd {
+	id := a.generations[a.cursor].ID
+	a.loading = true
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		sizes, err := a.client.GetPathSizes(ctx, id)
+		if errors.Is(err, backend.ErrUnsupported) {
+			return errMsg{errors.New(a.t("usage.unsupported"))}
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return usageMsg{id: id, sizes: sizes}
+	}
+}
+
+func (a *App) applyUsage(msg usageMsg) {
+	a.loading = false
+	var total int64
+	for _, p := range msg.sizes {
+		total += p.Size
+	}
+	top := msg.sizes[:min(len(msg.sizes), usageTop)]
+	var largest int64
+	for _, p := range top {
+		largest = max(largest, p.Size)
+	}
+
+	names := make([]string, len(top))
+	cells := make([][]string, len(top))
+	for i, p := range top {
+		_, name, version := models.ParseStorePath(p.Path)
+		names[i] = backend.Printable(strings.TrimSpace(name + " " + version))
+		share := 0.0
+		if total > 0 {
+			share = float64(p.Size) * 100 / float64(total)
+		}
+		cells[i] = []string{
+			names[i],
+			listing.HumanSize(p.Size),
+			fmt.Sprintf("%5.1f%%", share),
+			bar(p.Size, 0, largest, usageBar),
+		}
+	}
+	bySize := func(x, y int) int {
+		return cmp.Or(cmp.Compare(top[y].Size, top[x].Size), cmp.Compare(names[x], names[y]))
+	}
+	byName := func(x, y int) int {
+		return cmp.Or(cmp.Compare(names[x], names[y]), cmp.Compare(top[y].Size, top[x].Size))
+	}
+	nameWidth := 0
+	for _, name := range names {
+		nameWidth = max(nameWidth, len(name))
+	}
+	columns := []tableColumn{
+		{title: a.t("usage.name"), width: min(nameWidth, 48), compare: byName},
+		{title: a.t("usage.size"), width: 10, right: true, compare: bySize},
+		{title: a.t("usage.share"), width: 8, right: true},
+		{title: "", width: usageBar},
+	}
+	paths := make([]string, len(top))
+	for i, p := range top {
+		paths[i] = p.Path
+	}
+	a.usage = &usage{id: msg.id, paths: paths, count: len(msg.sizes), total: total, table: newTable(columns, cells, 1)}
+	a.state = stateUsage
+}
+
+// usageHeight is how many lines of the table fit below the title and
+// summary.
+func (a *App) usageHeight() int {
+	return max(2, a.listHeight()-2)
+}
+
+func (a *App) updateUsage(msg tea.KeyMsg) tea.Cmd {
+	t := a.usage.table
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = stateGenerations
+	case key.Matches(msg, a.keys.Up):
+		t.move(-1, a.usageHeight()-1)
+	case key.Matches(msg, a.keys.Down):
+		t.move(1, a.usageHeight()-1)
+	case key.Matches(msg, a.keys.PageUp):
+		t.move(-a.pageSize(), a.usageHeight()-1)
+	case key.Matches(msg, a.keys.PageDown):
+		t.move(a.pageSize(), a.usageHeight()-1)
+	case key.Matches(msg, usageSort):
+		t.nextSort()
+	case key.Matches(msg, a.keys.CopyCmd):
+		if len(t.order) > 0 {
+			a.copyToClipboard(a.usage.paths[t.selected()])
+		}
+	}
+	return nil
+}
+
+func (a *App) renderUsage() string {
+	u := a.usage
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(a.t("usage.title", u.id)) + "\n\n")
+	if u.count == 0 {
+		b.WriteString(a.t("usage.empty") + "\n")
+		return b.String()
+	}
+	b.WriteString("  " + statsStyle.Render(a.t("usage.summary", len(u.paths), u.count, listing.HumanSize(u.total))) + "\n\n")
+	b.WriteString(u.table.render(a.usageHeight()))
+	b.WriteString("\n" + statsStyle.Render(a.t("usage.choices")))
+	return b.String()
+}
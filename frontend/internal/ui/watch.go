@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/models"
+	"nix-timemach/internal/watch"
+)
+
+// profilesDir is where the system profile and its generations' links are.
+const profilesDir = "/nix/var/nix/profiles"
+
+// profileChangedMsg reports that the watched profile's directory changed,
+// say because a rebuild elsewhere added a generation.
+type profileChangedMsg struct {
+	changes <-chan struct{}
+}
+
+// watchedMsg is the list reloaded after a profileChangedMsg.
+type watchedMsg []models.Generation
+
+// watchDir is the directory of the profile being browsed, or "" when it
+// can't be watched from here: on another host, or in another store.
+func (a *App) watchDir() string {
+	if !a.opts.Watch || a.client.Host() != "" || a.client.Store() != "" {
+		return ""
+	}
+	if p, ok := a.activeProfile(); ok {
+		return filepath.Dir(p.Path)
+	}
+	return profilesDir
+}
+
+// rewatch stops watching the previous profile and starts on the current
+// one. A directory that can't be watched just isn't; r still reloads.
+func (a *App) rewatch() tea.Cmd {
+	if a.cancelWatch != nil {
+		a.cancelWatch()
+		a.cancelWatch = nil
+	}
+	dir := a.watchDir()
+	if dir == "" {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := watch.Changes(ctx, dir)
+	if err != nil {
+		cancel()
+		return nil
+	}
+	a.cancelWatch = cancel
+	return waitForChange(changes)
+}
+
+// waitForChange waits for the next change to a watched directory.
+func waitForChange(changes <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-changes; !ok {
+			return nil
+		}
+		return profileChangedMsg{changes}
+	}
+}
+
+// reloadChanged reloads the list in the background, leaving whatever is
+// shown in place until it arrives. A load already under way will see the
+// change itself.
+func (a *App) reloadChanged(msg profileChangedMsg) tea.Cmd {
+	cmds := []tea.Cmd{waitForChange(msg.changes)}
+	if !a.loading {
+		ctx := a.profileContext()
+		cmds = append(cmds, func() tea.Msg {
+			generations, err := a.client.GetGenerations(ctx)
+			if err != nil {
+				// The next change, or r, will try again.
+				return nil
+			}
+			return watchedMsg(generations)
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// applyWatched shows the reloaded list with the cursor on the same
+// generation, and notes the ones that are new.
+func (a *App) applyWatched(msg watchedMsg) tea.Cmd {
+	if a.loading || a.staleGenerations(msg) {
+		return nil
+	}
+	known := make(map[string]bool, len(a.generations))
+	for _, gen := range a.generations {
+		known[gen.ID] = true
+	}
+	var fresh []string
+	for _, gen := range msg {
+		if !known[gen.ID] {
+			fresh = append(fresh, gen.ID)
+		}
+	}
+	switch {
+	case len(fresh) == 1:
+		a.notice = a.t("watch.new", fresh[0])
+	case len(fresh) > 1:
+		a.notice = a.t("watch.newMany", strings.Join(fresh, ", "))
+	}
+	if a.focusID == "" && a.cursor < len(a.generations) {
+		a.focusID = a.generations[a.cursor].ID
+	}
+	return func() tea.Msg { return generationsMsg(msg) }
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nix-timemach/internal/backend"
+	"nix-timemach/internal/models"
+)
+
+// The keys that open and close nodes of the dependency trees.
+var (
+	treeExpand   = key.NewBinding(key.WithKeys("right", "l", " "))
+	treeCollapse = key.NewBinding(key.WithKeys("left", "h"))
+)
+
+// whyView explains why a generation's closure contains a package, as the
+// tree of reference chains from the generation down to it.
+type whyView struct {
+	id   string
+	pkg  string
+	tree *tree
+	// back is the view esc returns to.
+	back state
+}
+
+type whyMsg struct {
+	id, pkg string
+	root    *models.DependencyNode
+}
+
+// askWhy asks which package the focused generation's closure should be
+// explained for.
+func (a *App) askWhy() {
+	id := a.generations[a.cursor].ID
+	a.askPrompt(a.t("why.prompt", id), "", func(pkg string) tea.Cmd {
+		pkg = strings.TrimSpace(pkg)
+		if pkg == "" {
+			return nil
+		}
+		return a.showWhy(id, pkg)
+	})
+}
+
+// whyModified explains the modified entry under the cursor, as it is in
+// the newer generation of the diff.
+func (a *App) whyModified() tea.Cmd {
+	if a.pending || a.snapshot != "" {
+		a.setStatus(a.t("why.pending"))
+		return nil
+	}
+	modified := a.visibleDiff(*a.diff).Modified
+	if a.modifiedCursor >= len(modified) {
+		return nil
+	}
+	c := modified[a.modifiedCursor]
+	path := c.Path
+	if c.NewPath != "" {
+		path = c.NewPath
+	}
+	return a.showWhy(a.generations[a.cursor].ID, path)
+}
+
+func (a *App) showWhy(id, pkg string) tea.Cmd {
+	back := a.state
+	a.why = &whyView{id: id, pkg: pkg, back: back}
+	a.loading = true
+	ctx := a.profileContext()
+	return func() tea.Msg {
+		root, err := a.client.WhyDepends(ctx, id, pkg)
+		if errors.Is(err, backend.ErrUnsupported) {
+			return errMsg{errors.New(a.t("why.unsupported"))}
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return whyMsg{id: id, pkg: pkg, root: root}
+	}
+}
+
+func (a *App) applyWhy(msg whyMsg) {
+	a.loading = false
+	if a.why == nil || a.why.id != msg.id || a.why.pkg != msg.pkg {
+		return
+	}
+	a.why.tree = &tree{}
+	if msg.root != nil {
+		a.why.tree.root = a.whyItem(*msg.root, true)
+	}
+	a.state = stateWhy
+}
+
+// whyItem turns node into a tree item. The first chain is open, so the
+// view starts by showing one way the package is reached.
+func (a *App) whyItem(node models.DependencyNode, open bool) *treeItem {
+	item := &treeItem{label: a.displayPath(node.Path), open: open}
+	for i, child := range node.Children {
+		item.children = append(item.children, a.whyItem(child, open && i == 0))
+	}
+	return item
+}
+
+// whyHeight is how many rows of the tree fit below the title.
+func (a *App) whyHeight() int {
+	return max(1, a.listHeight())
+}
+
+func (a *App) updateWhy(msg tea.KeyMsg) tea.Cmd {
+	t := a.why.tree
+	switch {
+	case key.Matches(msg, a.keys.Back):
+		a.state = a.why.back
+	case t.root == nil:
+	case key.Matches(msg, a.keys.Up):
+		t.move(-1, a.whyHeight())
+	case key.Matches(msg, a.keys.Down):
+		t.move(1, a.whyHeight())
+	case key.Matches(msg, a.keys.PageUp):
+		t.move(-a.pageSize(), a.whyHeight())
+	case key.Matches(msg, a.keys.PageDown):
+		t.move(a.pageSize(), a.whyHeight())
+	case key.Matches(msg, treeExpand, a.keys.Select):
+		t.expand(a.whyHeight())
+	case key.Matches(msg, treeCollapse):
+		t.collapse(a.whyHeight())
+	}
+	return nil
+}
+
+func (a *App) renderWhy() string {
+	w := a.why
+	var b strings.Builder
+	_, name, _ := models.ParseStorePath(w.pkg)
+	if !strings.HasPrefix(w.pkg, "/") {
+		name = w.pkg
+	}
+	b.WriteString(titleStyle.Render(a.t("why.title", w.id, name)) + "\n\n")
+	if w.tree.root == nil {
+		b.WriteString(a.t("why.none", w.id, name) + "\n")
+	} else {
+		b.WriteString(w.tree.render(a.whyHeight()))
+	}
+	b.WriteString("\n" + statsStyle.Render(a.t("tree.choices")))
+	return b.String()
+}
@@ -0,0 +1,49 @@
+// Package watch reports changes to a directory, such as a profile gaining
+// a generation, without polling where the system can say when they happen.
+package watch
+
+import (
+	"context"
+	"time"
+)
+
+// settle is how long a directory has to stay unchanged before a burst of
+// changes to it is reported.
+const settle = 500 * time.Millisecond
+
+// Changes returns a channel that receives a value shortly after entries are
+// added to, removed from or renamed in dir, until ctx is done and it is
+// closed. A burst of changes, like the links a rebuild replaces one after
+// another, arrives as one.
+func Changes(ctx context.Context, dir string) (<-chan struct{}, error) {
+	raw, err := events(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan struct{})
+	go func() {
+		defer close(out)
+		for range raw {
+			timer := time.NewTimer(settle)
+		burst:
+			for {
+				select {
+				case _, ok := <-raw:
+					if !ok {
+						timer.Stop()
+						return
+					}
+					timer.Reset(settle)
+				case <-timer.C:
+					break burst
+				}
+			}
+			select {
+			case out <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// events reports every change to dir's entries as inotify sees it.
+func events(ctx context.Context, dir string) (<-chan struct{}, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	mask := uint32(unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO)
+	if _, err := unix.InotifyAddWatch(fd, dir, mask); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	// A non-blocking descriptor is read through the runtime's poller,
+	// so closing the file ends a read in progress.
+	f := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			if n, err := f.Read(buf); err != nil || n == 0 {
+				return
+			}
+			select {
+			case out <- struct{}{}:
+			default:
+				// One is already waiting to be reported.
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,47 @@
+//go:build !linux
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pollInterval is how often dir is looked at where there is no inotify.
+const pollInterval = 2 * time.Second
+
+// events reports changes to dir's entries by polling its modification
+// time, which adding, removing or renaming an entry updates.
+func events(ctx context.Context, dir string) (<-chan struct{}, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	last := info.ModTime()
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(dir)
+			if err != nil || info.ModTime().Equal(last) {
+				continue
+			}
+			last = info.ModTime()
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return out, nil
+}
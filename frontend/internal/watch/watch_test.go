@@ -0,0 +1,54 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := Changes(ctx, dir)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+
+	// A rebuild replaces several links one after another.
+	for i := range 3 {
+		link := filepath.Join(dir, "system-"+strconv.Itoa(i)+"-link")
+		if err := os.Symlink("/nix/store/x-nixos-system", link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-changes:
+	case <-time.After(settle + 5*time.Second):
+		t.Fatal("no change reported")
+	}
+	select {
+	case <-changes:
+		t.Fatal("a burst of changes was reported more than once")
+	case <-time.After(2 * settle):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("a change reported after the context was done")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the channel wasn't closed once the context was done")
+	}
+}
+
+func TestChangesMissingDir(t *testing.T) {
+	if _, err := Changes(context.Background(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("watching a missing directory succeeded")
+	}
+}
@@ -0,0 +1,97 @@
+// Package xdg resolves the per-user directories nix-timemach stores files
+// in, following the XDG base directory specification.
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "nix-timemach"
+
+// ConfigDir is where the user's configuration file lives.
+func ConfigDir() (string, error) {
+	return dir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir is where user-created data such as saved groups lives.
+func DataDir() (string, error) {
+	return dir("XDG_DATA_HOME", ".local/share")
+}
+
+// DataDirs is DataDir followed by the system-wide data directories, where
+// installed packages put their shared files, most important first.
+func DataDirs() []string {
+	var dirs []string
+	if d, err := DataDir(); err == nil {
+		dirs = append(dirs, d)
+	}
+	system := os.Getenv("XDG_DATA_DIRS")
+	if system == "" {
+		system = "/usr/local/share:/usr/share"
+	}
+	for _, base := range filepath.SplitList(system) {
+		if base != "" {
+			dirs = append(dirs, filepath.Join(base, appName))
+		}
+	}
+	return dirs
+}
+
+// StateDir is where history and logs that should persist, but aren't
+// worth backing up, live.
+func StateDir() (string, error) {
+	return dir("XDG_STATE_HOME", ".local/state")
+}
+
+// CacheDir is where results that can be recomputed, like diffs, are kept.
+func CacheDir() (string, error) {
+	return dir("XDG_CACHE_HOME", ".cache")
+}
+
+func dir(env, fallback string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, fallback)
+	}
+	return filepath.Join(base, appName), nil
+}
+
+// WriteFile writes data to path atomically, creating parent directories.
+func WriteFile(path string, data []byte) error {
+	return writeFile(path, data, 0o600)
+}
+
+// WriteExecutable is WriteFile for a program, which is executable by the
+// time it appears at path.
+func WriteExecutable(path string, data []byte) error {
+	return writeFile(path, data, 0o755)
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,55 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	t.Setenv("HOME", "/home/alice")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "/var/lib/alice")
+	if got, _ := ConfigDir(); got != "/home/alice/.config/nix-timemach" {
+		t.Errorf("ConfigDir = %s, want the default under $HOME", got)
+	}
+	if got, _ := StateDir(); got != "/var/lib/alice/nix-timemach" {
+		t.Errorf("StateDir = %s, want it under $XDG_STATE_HOME", got)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_DATA_DIRS", "/run/current-system/sw/share::/usr/share")
+	want := []string{"/home/alice/.local/share/nix-timemach", "/run/current-system/sw/share/nix-timemach", "/usr/share/nix-timemach"}
+	if got := DataDirs(); !slices.Equal(got, want) {
+		t.Errorf("DataDirs = %v, want %v", got, want)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a", "b", "groups.json")
+	if err := WriteFile(p, []byte("one")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := WriteFile(p, []byte("two")); err != nil {
+		t.Fatalf("WriteFile over a file: %v", err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || string(data) != "two" {
+		t.Errorf("read back %q, %v, want two", data, err)
+	}
+	if info, _ := os.Stat(p); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(p)); len(entries) != 1 {
+		t.Errorf("left %d files behind, want only the one written", len(entries))
+	}
+
+	exe := filepath.Join(filepath.Dir(p), "nix-timemach-backend")
+	if err := WriteExecutable(exe, []byte("#!/bin/sh\n")); err != nil {
+		t.Fatalf("WriteExecutable: %v", err)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm() != 0o755 {
+		t.Errorf("executable mode = %v, want 0755", info.Mode().Perm())
+	}
+}